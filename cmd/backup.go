@@ -0,0 +1,101 @@
+package portalcmd
+
+import (
+	"flag"
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"io"
+	"os"
+	"strings"
+)
+
+func init() {
+	core.RegisterCommand(core.Command{
+		Name:        "backup create",
+		Usage:       "<file|->",
+		Description: "Back up the database and the renterd object metadata of every upload",
+		Boot:        core.CommandBootServices,
+		Run:         runBackupCreate,
+	})
+	core.RegisterCommand(backupRestoreCommand())
+}
+
+func runBackupCreate(ctx core.Context, args []string) (err error) {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+
+	if args[0] != stdioPath {
+		file, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+
+		w = file
+	}
+
+	backup := core.GetService[core.BackupService](ctx, core.BACKUP_SERVICE)
+
+	manifest, err := backup.CreateBackup(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Backup created with %d objects\n", manifest.Objects)
+
+	if len(manifest.MissingObjects) > 0 {
+		fmt.Fprintf(os.Stderr, "%d uploads have no object in renterd: %s\n", len(manifest.MissingObjects), strings.Join(manifest.MissingObjects, ", "))
+	}
+
+	return nil
+}
+
+func backupRestoreCommand() core.Command {
+	var opts core.BackupRestoreOptions
+
+	return core.Command{
+		Name:        "backup restore",
+		Usage:       "<file|->",
+		Description: "Replace the database with a backup and import its object metadata into renterd",
+		Boot:        core.CommandBootServices,
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&opts.SkipDatabase, "skip-database", false, "leave the database as it is")
+			fs.BoolVar(&opts.SkipObjects, "skip-objects", false, "leave renterd as it is")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			if err := requireArgs(args, 1); err != nil {
+				return err
+			}
+
+			var r io.Reader = os.Stdin
+
+			if args[0] != stdioPath {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer func(file *os.File) {
+					_ = file.Close()
+				}(file)
+
+				r = file
+			}
+
+			backup := core.GetService[core.BackupService](ctx, core.BACKUP_SERVICE)
+
+			manifest, err := backup.RestoreBackup(ctx, r, opts)
+			if manifest != nil {
+				fmt.Fprintf(os.Stderr, "Restored backup of %s made %s\n", manifest.Portal, manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+			}
+
+			return err
+		},
+	}
+}
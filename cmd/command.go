@@ -0,0 +1,162 @@
+package portalcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go.lumeweb.com/portal"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+const helpCommand = "help"
+
+var errUsage = errors.New("invalid arguments")
+
+// runCommand runs the operator command named by args and returns the process exit code
+func runCommand(args []string) int {
+	if args[0] == helpCommand || args[0] == "-h" || args[0] == "--help" {
+		printCommands(os.Stdout, strings.Join(args[1:], " "))
+		return core.ExitCodeSuccess
+	}
+
+	cmd, cmdArgs := core.FindCommand(args)
+	if cmd == nil {
+		prefix := strings.Join(args, " ")
+		if len(core.GetCommands(prefix)) == 0 {
+			fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", prefix)
+			prefix = ""
+		}
+
+		printCommands(os.Stderr, prefix)
+		return core.ExitCodeFailedCommand
+	}
+
+	fs := flag.NewFlagSet("portal "+cmd.Name, flag.ContinueOnError)
+	fs.Usage = func() {
+		printCommandUsage(fs.Output(), cmd, fs)
+	}
+
+	if cmd.Flags != nil {
+		cmd.Flags(fs)
+	}
+
+	if err := fs.Parse(cmdArgs); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return core.ExitCodeSuccess
+		}
+
+		return core.ExitCodeFailedCommand
+	}
+
+	err := execCommand(cmd, fs.Args())
+	if errors.Is(err, errUsage) {
+		fs.Usage()
+		return core.ExitCodeFailedCommand
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.Name, err)
+		return core.ExitCodeFailedCommand
+	}
+
+	return core.ExitCodeSuccess
+}
+
+func execCommand(cmd *core.Command, args []string) error {
+	if cmd.Boot == core.CommandBootNone {
+		return cmd.Run(nil, args)
+	}
+
+	// Logs go to stderr, stdout is left to the command's output
+	portal.NewActivePortal(newContext(os.Stderr))
+
+	if err := portal.Init(); err != nil {
+		return fmt.Errorf("failed to initialize portal: %w", err)
+	}
+
+	if cmd.Boot == core.CommandBootPortal {
+		return cmd.Run(portal.Context(), args)
+	}
+
+	if err := portal.Boot(); err != nil {
+		return fmt.Errorf("failed to boot portal: %w", err)
+	}
+
+	// Exit funcs rely on what the startup funcs set up, so the portal is only stopped once it booted
+	defer func() {
+		if err := portal.Stop(); err != nil {
+			portal.Context().Logger().Error("Failed to stop portal", zap.Error(err))
+		}
+	}()
+
+	return cmd.Run(portal.Context(), args)
+}
+
+func printCommands(w io.Writer, prefix string) {
+	cmds := core.GetCommands(prefix)
+
+	if len(cmds) == 1 && cmds[0].Name == prefix {
+		fs := flag.NewFlagSet("portal "+prefix, flag.ContinueOnError)
+		if cmds[0].Flags != nil {
+			cmds[0].Flags(fs)
+		}
+
+		printCommandUsage(w, &cmds[0], fs)
+		return
+	}
+
+	_, _ = fmt.Fprintln(w, "Usage: portal [command] [flags] [arguments]")
+	_, _ = fmt.Fprintln(w, "\nWithout a command the portal is started and served.")
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+
+	tw := newTabWriter(w)
+	for _, cmd := range cmds {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", cmd.Name, cmd.Description)
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintf(w, "\nRun \"portal %s <command>\" for the flags and arguments of a command.\n", helpCommand)
+}
+
+func printCommandUsage(w io.Writer, cmd *core.Command, fs *flag.FlagSet) {
+	usage := "portal " + cmd.Name
+
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) {
+		hasFlags = true
+	})
+
+	if hasFlags {
+		usage += " [flags]"
+	}
+
+	if cmd.Usage != "" {
+		usage += " " + cmd.Usage
+	}
+
+	_, _ = fmt.Fprintf(w, "Usage: %s\n\n%s\n", usage, cmd.Description)
+
+	if hasFlags {
+		_, _ = fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
+
+func newTabWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// requireArgs fails with the usage of the command unless exactly n arguments are given
+func requireArgs(args []string, n int) error {
+	if len(args) != n {
+		return errUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package portalcmd
+
+import (
+	"fmt"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"gopkg.in/yaml.v3"
+	"os"
+)
+
+func init() {
+	core.RegisterCommand(core.Command{
+		Name:        "config get",
+		Usage:       "<key>",
+		Description: "Print a config value or section, e.g. core.port",
+		Boot:        core.CommandBootPortal,
+		Run:         runConfigGet,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "config set",
+		Usage:       "<key> <value>",
+		Description: "Change a config value and save it, after validating its section",
+		Boot:        core.CommandBootPortal,
+		Run:         runConfigSet,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "config validate",
+		Description: "Validate the config of the core and every plugin",
+		Boot:        core.CommandBootPortal,
+		Run:         runConfigValidate,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "config print-defaults",
+		Description: "Print the default core config",
+		Run:         runConfigPrintDefaults,
+	})
+}
+
+func runConfigGet(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	if !ctx.Config().Exists(args[0]) {
+		return fmt.Errorf("key %s does not exist", args[0])
+	}
+
+	return yaml.NewEncoder(os.Stdout).Encode(ctx.Config().Get(args[0]))
+}
+
+func runConfigSet(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 2); err != nil {
+		return err
+	}
+
+	if err := ctx.Config().Set(args[0], args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set %s\n", args[0])
+
+	return nil
+}
+
+// runConfigValidate has nothing left to check, every section is validated while the portal initializes
+func runConfigValidate(ctx core.Context, _ []string) error {
+	fmt.Printf("Config in %s is valid\n", ctx.Config().ConfigDir())
+
+	return nil
+}
+
+func runConfigPrintDefaults(_ core.Context, _ []string) error {
+	defaults, err := config.CoreDefaults()
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(defaults)
+
+	return err
+}
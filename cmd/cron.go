@@ -0,0 +1,280 @@
+package portalcmd
+
+import (
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"os"
+	"time"
+)
+
+func init() {
+	core.RegisterCommand(cronListCommand())
+	core.RegisterCommand(core.Command{
+		Name:        "cron show",
+		Usage:       "<job id>",
+		Description: "Show a job",
+		Boot:        core.CommandBootServices,
+		Run:         runCronShow,
+	})
+	core.RegisterCommand(cronLogsCommand())
+	core.RegisterCommand(core.Command{
+		Name:        "cron retry",
+		Usage:       "<job id>",
+		Description: "Clear the failures of a job so it runs without backoff",
+		Boot:        core.CommandBootServices,
+		Run:         runCronRetry,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "cron cancel",
+		Usage:       "<job id>",
+		Description: "Delete a job, cancelling it if it is processing",
+		Boot:        core.CommandBootServices,
+		Run:         runCronCancel,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "cron tasks",
+		Description: "List cron tasks and whether they are paused",
+		Boot:        core.CommandBootServices,
+		Run:         runCronTasks,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "cron pause",
+		Usage:       "<task>",
+		Description: "Hold back the jobs of a task until it is resumed",
+		Boot:        core.CommandBootServices,
+		Run:         runCronPause,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "cron resume",
+		Usage:       "<task>",
+		Description: "Let the jobs of a paused task run again",
+		Boot:        core.CommandBootServices,
+		Run:         runCronResume,
+	})
+}
+
+func cronListCommand() core.Command {
+	var filter core.CronJobFilter
+	var state string
+
+	return core.Command{
+		Name:        "cron list",
+		Description: "List cron jobs",
+		Boot:        core.CommandBootServices,
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&filter.Function, "function", "", "only list jobs of this task")
+			fs.StringVar(&state, "state", "", "only list jobs in this state: queued, processing, completed or failed")
+			fs.Uint64Var(&filter.MinFailures, "min-failures", 0, "only list jobs that failed at least this many times")
+			fs.IntVar(&filter.Limit, "limit", 50, "maximum number of jobs to list")
+			fs.IntVar(&filter.Offset, "offset", 0, "number of jobs to skip")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			if err := requireArgs(args, 0); err != nil {
+				return err
+			}
+
+			filter.State = models.CronJobState(state)
+
+			cron := core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+
+			jobs, total, err := cron.ListJobs(filter)
+			if err != nil {
+				return err
+			}
+
+			tw := newTabWriter(os.Stdout)
+			_, _ = fmt.Fprintln(tw, "ID\tFUNCTION\tSTATE\tFAILURES\tLAST RUN\tLAST HEARTBEAT")
+			for _, job := range jobs {
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+					job.UUID.String(),
+					job.Function,
+					job.State,
+					job.Failures,
+					formatOptionalTime(job.LastRun),
+					formatOptionalTime(job.LastHeartbeat),
+				)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			fmt.Printf("\n%d of %d jobs\n", len(jobs), total)
+
+			return nil
+		},
+	}
+}
+
+func cronLogsCommand() core.Command {
+	var limit, offset int
+
+	return core.Command{
+		Name:        "cron logs",
+		Usage:       "<job id>",
+		Description: "List the failure logs of a job, newest first",
+		Boot:        core.CommandBootServices,
+		Flags: func(fs *flag.FlagSet) {
+			fs.IntVar(&limit, "limit", 20, "maximum number of logs to list")
+			fs.IntVar(&offset, "offset", 0, "number of logs to skip")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			id, err := parseJobID(args)
+			if err != nil {
+				return err
+			}
+
+			cron := core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+
+			logs, total, err := cron.ListJobLogs(id, limit, offset)
+			if err != nil {
+				return err
+			}
+
+			tw := newTabWriter(os.Stdout)
+			_, _ = fmt.Fprintln(tw, "TIME\tTYPE\tMESSAGE")
+			for _, log := range logs {
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", log.CreatedAt.Format(time.RFC3339), log.Type, log.Message)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			fmt.Printf("\n%d of %d logs\n", len(logs), total)
+
+			return nil
+		},
+	}
+}
+
+func runCronShow(ctx core.Context, args []string) error {
+	id, err := parseJobID(args)
+	if err != nil {
+		return err
+	}
+
+	cron := core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+
+	job, err := cron.GetJob(id)
+	if err != nil {
+		return err
+	}
+
+	tw := newTabWriter(os.Stdout)
+	_, _ = fmt.Fprintf(tw, "ID:\t%s\n", job.UUID.String())
+	_, _ = fmt.Fprintf(tw, "Function:\t%s\n", job.Function)
+	_, _ = fmt.Fprintf(tw, "Args:\t%s\n", job.Args)
+	_, _ = fmt.Fprintf(tw, "State:\t%s\n", job.State)
+	_, _ = fmt.Fprintf(tw, "Failures:\t%d\n", job.Failures)
+	_, _ = fmt.Fprintf(tw, "Created:\t%s\n", job.CreatedAt.Format(time.RFC3339))
+	_, _ = fmt.Fprintf(tw, "Last run:\t%s\n", formatOptionalTime(job.LastRun))
+	_, _ = fmt.Fprintf(tw, "Last heartbeat:\t%s\n", formatOptionalTime(job.LastHeartbeat))
+
+	return tw.Flush()
+}
+
+func runCronRetry(ctx core.Context, args []string) error {
+	id, err := parseJobID(args)
+	if err != nil {
+		return err
+	}
+
+	cron := core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+	if err := cron.RetryJob(id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Retrying job %s\n", id)
+
+	return nil
+}
+
+func runCronCancel(ctx core.Context, args []string) error {
+	id, err := parseJobID(args)
+	if err != nil {
+		return err
+	}
+
+	cron := core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+	if err := cron.CancelJob(id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Cancelled job %s\n", id)
+
+	return nil
+}
+
+func runCronTasks(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 0); err != nil {
+		return err
+	}
+
+	cron := core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+
+	tasks, err := cron.ListTasks()
+	if err != nil {
+		return err
+	}
+
+	tw := newTabWriter(os.Stdout)
+	_, _ = fmt.Fprintln(tw, "TASK\tJOBS\tPAUSED")
+	for _, task := range tasks {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", task.Name, task.Jobs, formatOptionalTime(task.PausedAt))
+	}
+
+	return tw.Flush()
+}
+
+func runCronPause(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	cron := core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+	if err := cron.PauseTask(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Paused task %s\n", args[0])
+
+	return nil
+}
+
+func runCronResume(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	cron := core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+	if err := cron.ResumeTask(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Resumed task %s\n", args[0])
+
+	return nil
+}
+
+func parseJobID(args []string) (uuid.UUID, error) {
+	if err := requireArgs(args, 1); err != nil {
+		return uuid.Nil, err
+	}
+
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid job id: %w", err)
+	}
+
+	return id, nil
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.Format(time.RFC3339)
+}
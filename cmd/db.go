@@ -0,0 +1,81 @@
+package portalcmd
+
+import (
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"io"
+	"os"
+)
+
+// stdioPath stands for stdout on export and stdin on import
+const stdioPath = "-"
+
+func init() {
+	core.RegisterCommand(core.Command{
+		Name:        "db export",
+		Usage:       "<file|->",
+		Description: "Export every table of the core and plugins to a dump file",
+		Boot:        core.CommandBootPortal,
+		Run:         runDBExport,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "db import",
+		Usage:       "<file|->",
+		Description: "Replace the contents of every table with those of a dump file",
+		Boot:        core.CommandBootServices,
+		Run:         runDBImport,
+	})
+}
+
+func runDBExport(ctx core.Context, args []string) (err error) {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+
+	if args[0] != stdioPath {
+		file, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+
+		w = file
+	}
+
+	return db.Export(ctx, ctx.DB(), w, db.DumpModels())
+}
+
+func runDBImport(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+
+	if args[0] != stdioPath {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer func(file *os.File) {
+			_ = file.Close()
+		}(file)
+
+		r = file
+	}
+
+	if err := db.Import(ctx, ctx.DB(), r, db.DumpModels()); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Database imported")
+
+	return nil
+}
@@ -5,35 +5,23 @@ import (
 	"go.lumeweb.com/portal/config"
 	"go.lumeweb.com/portal/core"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"os"
 )
 
 func Main() {
+	core.RegisterCommandsFromPlugins()
 
-	cfg, err := config.NewManager()
-	logger := core.NewLogger(cfg)
-	if err != nil {
-		logger.Fatal("Failed to load config", zap.Error(err))
-	}
-
-	ctx, err := core.NewContext(cfg, logger)
-
-	if err != nil {
-		logger.Fatal("Failed to create context", zap.Error(err))
-	}
-
-	core.RegisterServicesFromPlugins()
-
-	err = cfg.Init()
-	if err != nil {
-		logger.Fatal("Failed to initialize config", zap.Error(err))
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
 	}
 
-	logger.SetLevelFromConfig()
+	ctx := newContext(os.Stdout)
+	logger := ctx.Logger()
 
 	portal.NewActivePortal(ctx)
 
-	err = portal.Init()
+	err := portal.Init()
 
 	if err != nil {
 		logger.Fatal("Failed to initialize portal", zap.Error(err))
@@ -55,3 +43,29 @@ func Main() {
 		os.Exit(core.ExitCodeFailedStartup)
 	}
 }
+
+// newContext loads the config and builds the root context, shared by serving the portal and by operator commands
+func newContext(logOutput zapcore.WriteSyncer) core.Context {
+	cfg, err := config.NewManager()
+	logger := core.NewLoggerWithOutput(cfg, logOutput)
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	ctx, err := core.NewContext(cfg, logger)
+
+	if err != nil {
+		logger.Fatal("Failed to create context", zap.Error(err))
+	}
+
+	core.RegisterServicesFromPlugins()
+
+	err = cfg.Init()
+	if err != nil {
+		logger.Fatal("Failed to initialize config", zap.Error(err))
+	}
+
+	logger.SetLevelFromConfig()
+
+	return ctx
+}
@@ -0,0 +1,153 @@
+package portalcmd
+
+import (
+	"flag"
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"io"
+	"os"
+	"time"
+)
+
+func init() {
+	core.RegisterCommand(migrateCommand())
+	core.RegisterCommand(migrateRollbackCommand())
+	core.RegisterCommand(core.Command{
+		Name:        "migrate status",
+		Description: "List the migrations of the core and every plugin and whether they are applied",
+		Boot:        core.CommandBootPortal,
+		Run:         runMigrateStatus,
+	})
+}
+
+func migrateCommand() core.Command {
+	var dryRun bool
+
+	return core.Command{
+		Name:        "migrate",
+		Description: "Apply the pending database migrations of the core and every plugin",
+		Boot:        core.CommandBootPortal,
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&dryRun, "dry-run", false, "print the SQL the migrations would execute instead of running them")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			if err := requireArgs(args, 0); err != nil {
+				return err
+			}
+
+			migrator, err := newMigrator(ctx)
+			if err != nil {
+				return err
+			}
+
+			applied, err := migrator.Migrate(ctx, dryRunOutput(dryRun))
+			if err != nil {
+				return err
+			}
+
+			if !dryRun {
+				fmt.Printf("Applied %d migrations\n", applied)
+			}
+
+			return nil
+		},
+	}
+}
+
+func migrateRollbackCommand() core.Command {
+	var dryRun, includeBaseline bool
+	var steps int
+
+	return core.Command{
+		Name:        "migrate rollback",
+		Usage:       "<core|plugin>",
+		Description: "Roll back the last applied migrations of the core or a plugin, stopping at the baseline",
+		Boot:        core.CommandBootPortal,
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&dryRun, "dry-run", false, "print the SQL the rollback would execute instead of running it")
+			fs.IntVar(&steps, "steps", 1, "number of migrations to roll back")
+			fs.BoolVar(&includeBaseline, "include-baseline", false, "also roll back the baseline, dropping every table of the core or plugin")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			if err := requireArgs(args, 1); err != nil {
+				return err
+			}
+
+			if steps < 1 {
+				return errUsage
+			}
+
+			migrator, err := newMigrator(ctx)
+			if err != nil {
+				return err
+			}
+
+			reverted, err := migrator.Rollback(ctx, args[0], steps, includeBaseline, dryRunOutput(dryRun))
+			if err != nil {
+				return err
+			}
+
+			if !dryRun {
+				fmt.Printf("Rolled back %d migrations of %s\n", reverted, args[0])
+			}
+
+			return nil
+		},
+	}
+}
+
+func runMigrateStatus(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 0); err != nil {
+		return err
+	}
+
+	migrator, err := newMigrator(ctx)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+
+	tw := newTabWriter(os.Stdout)
+	_, _ = fmt.Fprintln(tw, "OWNER\tID\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Format(time.RFC3339)
+		} else {
+			pending++
+		}
+
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", status.Owner, status.ID, applied)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d of %d migrations pending\n", pending, len(statuses))
+
+	return nil
+}
+
+func newMigrator(ctx core.Context) (*db.Migrator, error) {
+	sets, err := db.MigrationSets()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.NewMigrator(ctx, ctx.DB(), sets), nil
+}
+
+func dryRunOutput(dryRun bool) io.Writer {
+	if !dryRun {
+		return nil
+	}
+
+	return os.Stdout
+}
@@ -0,0 +1,241 @@
+package portalcmd
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	mh "github.com/multiformats/go-multihash"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/service"
+	"io"
+	"time"
+)
+
+var (
+	errInvalidHash     = errors.New("hash must be a base58 or hex encoded multihash")
+	errHashMismatch    = errors.New("object does not match its hash")
+	errScrubFoundFault = errors.New("scrub found faulty uploads")
+)
+
+func init() {
+	core.RegisterCommand(storageGCCommand())
+	core.RegisterCommand(storageScrubCommand())
+	core.RegisterCommand(core.Command{
+		Name:        "storage verify",
+		Usage:       "<hash>",
+		Description: "Download an upload and check it against its hash",
+		Boot:        core.CommandBootServices,
+		Run:         runStorageVerify,
+	})
+}
+
+func storageGCCommand() core.Command {
+	var dryRun bool
+	var minAge time.Duration
+
+	return core.Command{
+		Name:        "storage gc",
+		Description: "Delete uploads that are no longer pinned by anyone, along with their objects",
+		Boot:        core.CommandBootServices,
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&dryRun, "dry-run", false, "only list the uploads that would be deleted")
+			fs.DurationVar(&minAge, "min-age", 24*time.Hour, "skip uploads younger than this, they may still be waiting for their pin")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			if err := requireArgs(args, 0); err != nil {
+				return err
+			}
+
+			var uploads []*models.Upload
+
+			tx := ctx.DB().WithContext(ctx)
+			if err := tx.Model(&models.Upload{}).
+				Where("NOT EXISTS (?)", tx.Model(&models.Pin{}).Select("1").Where("pins.upload_id = uploads.id")).
+				Where("created_at < ?", time.Now().Add(-minAge)).
+				Find(&uploads).Error; err != nil {
+				return err
+			}
+
+			storage := core.GetService[core.StorageService](ctx, core.STORAGE_SERVICE)
+			uploadSvc := core.GetService[core.UploadService](ctx, core.UPLOAD_SERVICE)
+
+			var errs []error
+			for _, upload := range uploads {
+				fmt.Printf("%s\t%s\t%d bytes\n", upload.Hash.B58String(), upload.Protocol, upload.Size)
+
+				if dryRun {
+					continue
+				}
+
+				if err := gcUpload(ctx, storage, uploadSvc, upload); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", upload.Hash.B58String(), err))
+				}
+			}
+
+			if dryRun {
+				fmt.Printf("\n%d unpinned uploads would be deleted\n", len(uploads))
+			} else {
+				fmt.Printf("\n%d of %d unpinned uploads deleted\n", len(uploads)-len(errs), len(uploads))
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+func gcUpload(ctx core.Context, storage core.StorageService, uploadSvc core.UploadService, upload *models.Upload) error {
+	protocol, err := storageProtocol(upload.Protocol)
+	if err != nil {
+		return err
+	}
+
+	hash := service.NewStorageHashFromMultihash(upload.Hash, upload.CIDType, nil)
+
+	if err := storage.DeleteObject(ctx, protocol, hash); err != nil {
+		return err
+	}
+
+	return uploadSvc.DeleteUpload(ctx, hash)
+}
+
+func storageScrubCommand() core.Command {
+	var verify bool
+
+	return core.Command{
+		Name:        "storage scrub",
+		Description: "Check that the object of every upload exists in renterd",
+		Boot:        core.CommandBootServices,
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&verify, "verify", false, "also download every object and check it against its hash")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			if err := requireArgs(args, 0); err != nil {
+				return err
+			}
+
+			uploadSvc := core.GetService[core.UploadService](ctx, core.UPLOAD_SERVICE)
+			renter := core.GetService[core.RenterService](ctx, core.RENTER_SERVICE)
+			storage := core.GetService[core.StorageService](ctx, core.STORAGE_SERVICE)
+
+			uploads, err := uploadSvc.GetAllUploads(ctx)
+			if err != nil {
+				return err
+			}
+
+			faulty := 0
+			for _, upload := range uploads {
+				if err := scrubUpload(ctx, renter, storage, upload, verify); err != nil {
+					faulty++
+					fmt.Printf("%s\t%s\t%v\n", upload.Hash.B58String(), upload.Protocol, err)
+				}
+			}
+
+			fmt.Printf("\n%d of %d uploads faulty\n", faulty, len(uploads))
+
+			if faulty > 0 {
+				return errScrubFoundFault
+			}
+
+			return nil
+		},
+	}
+}
+
+func scrubUpload(ctx core.Context, renter core.RenterService, storage core.StorageService, upload *models.Upload, verify bool) error {
+	protocol, err := storageProtocol(upload.Protocol)
+	if err != nil {
+		return err
+	}
+
+	hash := service.NewStorageHashFromMultihash(upload.Hash, upload.CIDType, nil)
+
+	if _, err := renter.GetObjectMetadata(ctx, protocol.Name(), protocol.EncodeFileName(hash)); err != nil {
+		return err
+	}
+
+	if !verify {
+		return nil
+	}
+
+	return verifyUpload(ctx, storage, protocol, upload)
+}
+
+func runStorageVerify(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	multihash, err := parseMultihash(args[0])
+	if err != nil {
+		return err
+	}
+
+	uploadSvc := core.GetService[core.UploadService](ctx, core.UPLOAD_SERVICE)
+	storage := core.GetService[core.StorageService](ctx, core.STORAGE_SERVICE)
+
+	upload, err := uploadSvc.GetUpload(ctx, service.NewStorageHashFromMultihash(multihash, 0, nil))
+	if err != nil {
+		return err
+	}
+
+	protocol, err := storageProtocol(upload.Protocol)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyUpload(ctx, storage, protocol, upload); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s matches its hash\n", upload.Hash.B58String())
+
+	return nil
+}
+
+func verifyUpload(ctx core.Context, storage core.StorageService, protocol core.StorageProtocol, upload *models.Upload) error {
+	hash := service.NewStorageHashFromMultihash(upload.Hash, upload.CIDType, nil)
+
+	object, err := storage.DownloadObject(ctx, protocol, hash, 0)
+	if err != nil {
+		return err
+	}
+	defer func(object io.ReadCloser) {
+		_ = object.Close()
+	}(object)
+
+	computed, err := protocol.Hash(object, upload.Size)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(computed.Multihash(), upload.Hash) {
+		return errHashMismatch
+	}
+
+	return nil
+}
+
+// storageProtocol finds the registered protocol that stores uploads under the given name
+func storageProtocol(name string) (core.StorageProtocol, error) {
+	for _, protocol := range core.GetProtocols() {
+		if storageProto, ok := protocol.(core.StorageProtocol); ok && storageProto.Name() == name {
+			return storageProto, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no storage protocol registered for %s", name)
+}
+
+func parseMultihash(value string) (mh.Multihash, error) {
+	if multihash, err := mh.FromB58String(value); err == nil {
+		return multihash, nil
+	}
+
+	if multihash, err := mh.FromHexString(value); err == nil {
+		return multihash, nil
+	}
+
+	return nil, errInvalidHash
+}
@@ -0,0 +1,255 @@
+package portalcmd
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errUserNotFound    = errors.New("user not found")
+	errPasswordMissing = errors.New("a password is required, pass -password or -password-stdin")
+)
+
+func init() {
+	core.RegisterCommand(userCreateCommand())
+	core.RegisterCommand(userListCommand())
+	core.RegisterCommand(core.Command{
+		Name:        "user set-role",
+		Usage:       "<id|email> <role>",
+		Description: "Change the access role of a user to admin or user",
+		Boot:        core.CommandBootServices,
+		Run:         runUserSetRole,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "user verify",
+		Usage:       "<id|email>",
+		Description: "Mark the email of a user as verified",
+		Boot:        core.CommandBootServices,
+		Run:         runUserVerify,
+	})
+	core.RegisterCommand(core.Command{
+		Name:        "user delete",
+		Usage:       "<id|email>",
+		Description: "Delete a user account right away",
+		Boot:        core.CommandBootServices,
+		Run:         runUserDelete,
+	})
+}
+
+func userCreateCommand() core.Command {
+	var password, invite, role string
+	var passwordStdin, sendVerification, skipPolicy bool
+
+	return core.Command{
+		Name:        "user create",
+		Usage:       "<email>",
+		Description: "Create a user account, subject to the registration policy unless -skip-registration-policy is set",
+		Boot:        core.CommandBootServices,
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&password, "password", "", "password of the account")
+			fs.BoolVar(&passwordStdin, "password-stdin", false, "read the password from the first line of stdin")
+			fs.StringVar(&invite, "invite", "", "invite code to redeem when registration is invite-only")
+			fs.StringVar(&role, "role", "", "access role to give the account, admin or user")
+			fs.BoolVar(&sendVerification, "send-verification", false, "send a verification email instead of creating the account verified")
+			fs.BoolVar(&skipPolicy, "skip-registration-policy", false, "create the account even when registration is closed, invite-only or the email domain is not allowed")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			if err := requireArgs(args, 1); err != nil {
+				return err
+			}
+
+			if passwordStdin {
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("failed to read password: %w", err)
+				}
+				password = strings.TrimRight(line, "\r\n")
+			}
+
+			if password == "" {
+				return errPasswordMissing
+			}
+
+			userSvc := core.GetService[core.UserService](ctx, core.USER_SERVICE)
+			admin := core.GetService[core.AdminService](ctx, core.ADMIN_SERVICE)
+
+			var user *models.User
+			var err error
+			if skipPolicy {
+				user, err = userSvc.CreateAccountUnrestricted(args[0], password, sendVerification)
+			} else {
+				user, err = userSvc.CreateAccountWithInvite(args[0], password, invite, sendVerification)
+			}
+			if err != nil {
+				return err
+			}
+
+			if !user.Verified && !sendVerification {
+				if err := admin.VerifyUser(user.ID); err != nil {
+					return err
+				}
+			}
+
+			if role != "" {
+				if err := admin.SetUserRole(user.ID, role); err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("Created user %d <%s>\n", user.ID, user.Email)
+
+			return nil
+		},
+	}
+}
+
+func userListCommand() core.Command {
+	var filter core.UserFilter
+	var verified string
+
+	return core.Command{
+		Name:        "user list",
+		Description: "List user accounts",
+		Boot:        core.CommandBootServices,
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&filter.Search, "search", "", "only list users whose email or name contains this")
+			fs.StringVar(&filter.Role, "role", "", "only list users with this role")
+			fs.StringVar(&verified, "verified", "", "only list verified (true) or unverified (false) users")
+			fs.IntVar(&filter.Limit, "limit", 50, "maximum number of users to list")
+			fs.IntVar(&filter.Offset, "offset", 0, "number of users to skip")
+		},
+		Run: func(ctx core.Context, args []string) error {
+			if err := requireArgs(args, 0); err != nil {
+				return err
+			}
+
+			if verified != "" {
+				value, err := strconv.ParseBool(verified)
+				if err != nil {
+					return fmt.Errorf("invalid -verified value: %w", err)
+				}
+				filter.Verified = &value
+			}
+
+			admin := core.GetService[core.AdminService](ctx, core.ADMIN_SERVICE)
+
+			users, total, err := admin.ListUsers(filter)
+			if err != nil {
+				return err
+			}
+
+			tw := newTabWriter(os.Stdout)
+			_, _ = fmt.Fprintln(tw, "ID\tEMAIL\tNAME\tROLE\tVERIFIED\tCREATED")
+			for _, user := range users {
+				_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%t\t%s\n",
+					user.ID,
+					user.Email,
+					strings.TrimSpace(user.FirstName+" "+user.LastName),
+					user.Role,
+					user.Verified,
+					user.CreatedAt.Format(time.RFC3339),
+				)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			fmt.Printf("\n%d of %d users\n", len(users), total)
+
+			return nil
+		},
+	}
+}
+
+func runUserSetRole(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 2); err != nil {
+		return err
+	}
+
+	user, err := lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	admin := core.GetService[core.AdminService](ctx, core.ADMIN_SERVICE)
+	if err := admin.SetUserRole(user.ID, args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set role of user %d <%s> to %s\n", user.ID, user.Email, args[1])
+
+	return nil
+}
+
+func runUserVerify(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	user, err := lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	admin := core.GetService[core.AdminService](ctx, core.ADMIN_SERVICE)
+	if err := admin.VerifyUser(user.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Verified user %d <%s>\n", user.ID, user.Email)
+
+	return nil
+}
+
+func runUserDelete(ctx core.Context, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+
+	user, err := lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	userSvc := core.GetService[core.UserService](ctx, core.USER_SERVICE)
+	if err := userSvc.DeleteAccount(user.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted user %d <%s>\n", user.ID, user.Email)
+
+	return nil
+}
+
+// lookupUser finds a user by numeric ID or by email address
+func lookupUser(ctx core.Context, ref string) (*models.User, error) {
+	userSvc := core.GetService[core.UserService](ctx, core.USER_SERVICE)
+
+	var exists bool
+	var user *models.User
+	var err error
+
+	if id, parseErr := strconv.ParseUint(ref, 10, 64); parseErr == nil {
+		exists, user, err = userSvc.AccountExists(uint(id))
+	} else {
+		exists, user, err = userSvc.EmailExists(ref)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, errUserNotFound
+	}
+
+	return user, nil
+}
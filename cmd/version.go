@@ -0,0 +1,57 @@
+package portalcmd
+
+import (
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"runtime"
+	"runtime/debug"
+)
+
+const portalModule = "go.lumeweb.com/portal"
+
+func init() {
+	core.RegisterCommand(core.Command{
+		Name:        "version",
+		Description: "Print the portal version and the plugins built in",
+		Run:         runVersion,
+	})
+}
+
+func runVersion(_ core.Context, _ []string) error {
+	version := "(devel)"
+	revision := ""
+
+	// Builds through xportal pull the portal in as a dependency, so its version is not always the main module's
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == portalModule {
+			version = info.Main.Version
+		}
+
+		for _, dep := range info.Deps {
+			if dep.Path == portalModule {
+				version = dep.Version
+				if dep.Replace != nil {
+					version += " => " + dep.Replace.Path
+				}
+			}
+		}
+
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				revision = setting.Value
+			}
+		}
+	}
+
+	fmt.Printf("portal %s %s/%s %s\n", version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+	if revision != "" {
+		fmt.Printf("revision %s\n", revision)
+	}
+
+	for _, plugin := range core.GetPlugins() {
+		fmt.Printf("plugin %s\n", plugin.ID)
+	}
+
+	return nil
+}
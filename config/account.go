@@ -1,13 +1,55 @@
 package config
 
+import (
+	"errors"
+	"slices"
+)
+
 var _ Defaults = (*AccountConfig)(nil)
+var _ Validator = (*AccountConfig)(nil)
+
+const (
+	RegistrationModeOpen       = "open"
+	RegistrationModeInviteOnly = "invite"
+	RegistrationModeClosed     = "closed"
+)
 
 type AccountConfig struct {
-	DeletionGracePeriod uint `config:"deletion_grace_period"`
+	DeletionGracePeriod      uint     `config:"deletion_grace_period"`
+	ImpersonationMaxDuration uint     `config:"impersonation_max_duration"`
+	RegistrationMode         string   `config:"registration_mode"`
+	AllowedEmailDomains      []string `config:"allowed_email_domains"`
+	DeniedEmailDomains       []string `config:"denied_email_domains"`
+	InviteQuota              uint     `config:"invite_quota"`
+	DataExportExpiry         uint     `config:"data_export_expiry"`
+	// ActivityRetention is how many minutes activity stays available for resuming a stream
+	ActivityRetention uint                 `config:"activity_retention"`
+	PasswordPolicy    PasswordPolicyConfig `config:"password_policy"`
+	PasswordHash      PasswordHashConfig   `config:"password_hash"`
 }
 
 func (a AccountConfig) Defaults() map[string]any {
 	return map[string]any{
-		"deletion_grace_period": 24 * 2,
+		"deletion_grace_period":      24 * 2,
+		"impersonation_max_duration": 60,
+		"registration_mode":          RegistrationModeOpen,
+		"allowed_email_domains":      []string{},
+		"denied_email_domains":       []string{},
+		"invite_quota":               0,
+		"data_export_expiry":         24 * 3,
+		"activity_retention":         60,
+	}
+}
+
+func (a AccountConfig) Validate() error {
+	if !slices.Contains([]string{RegistrationModeOpen, RegistrationModeInviteOnly, RegistrationModeClosed}, a.RegistrationMode) {
+		return errors.New("core.account.registration_mode must be one of open, invite or closed")
+	}
+
+	// Presigned links expire after a week at most
+	if a.DataExportExpiry == 0 || a.DataExportExpiry > 24*7 {
+		return errors.New("core.account.data_export_expiry must be between 1 and 168 hours")
 	}
+
+	return nil
 }
@@ -0,0 +1,40 @@
+package config
+
+import "errors"
+
+var _ Defaults = (*BackupConfig)(nil)
+var _ Validator = (*BackupConfig)(nil)
+
+type BackupConfig struct {
+	// Enabled schedules a daily backup, backups can always be made with the backup command
+	Enabled bool `config:"enabled"`
+	// Path is the directory scheduled backups are written to, relative to the config directory unless absolute. On a
+	// cluster it should be shared, any node may run the job.
+	Path string `config:"path"`
+	// Retain is the number of scheduled backups kept, older ones are deleted
+	Retain uint `config:"retain"`
+}
+
+func (b BackupConfig) Defaults() map[string]any {
+	return map[string]any{
+		"enabled": false,
+		"path":    "backups",
+		"retain":  7,
+	}
+}
+
+func (b BackupConfig) Validate() error {
+	if !b.Enabled {
+		return nil
+	}
+
+	if b.Path == "" {
+		return errors.New("core.backup.path is required")
+	}
+
+	if b.Retain == 0 {
+		return errors.New("core.backup.retain must be at least 1")
+	}
+
+	return nil
+}
@@ -20,12 +20,15 @@ const (
 type CacheConfig struct {
 	Mode    CacheMode   `config:"mode"`
 	Options interface{} `config:"options"`
+	// TTL is how long, in seconds, a cached query result is served before it is read again
+	TTL uint `config:"ttl"`
 }
 
 func (c CacheConfig) Defaults() map[string]any {
 	return map[string]any{
 		"mode":    "memory",
 		"options": MemoryConfig{},
+		"ttl":     300,
 	}
 }
 
@@ -40,10 +43,16 @@ func (c CacheConfig) Validate() error {
 		return errors.New("core.db.cache.mode must be one of: memory, redis, none")
 	}
 
+	if c.TTL == 0 {
+		return errors.New("core.db.cache.ttl must be at least 1")
+	}
+
 	return nil
 }
 
 type MemoryConfig struct {
+	// MaxEntries bounds the number of cached query results, the least recently used are evicted first
+	MaxEntries int `config:"max_entries"`
 }
 
 func cacheConfigHook(cm *ManagerDefault) mapstructure.DecodeHookFuncType {
@@ -71,12 +80,20 @@ func cacheConfigHook(cm *ManagerDefault) mapstructure.DecodeHookFuncType {
 				if err := mapstructure.Decode(opts, &redisOptions); err != nil {
 					return nil, err
 				}
-				cacheConfig.Options = redisOptions
+				cacheConfig.Options = &redisOptions
 			}
 		case CacheModeMemory:
-			// For "memory", you might simply use an empty MemoryConfig,
-			// or decode options similarly if there are any specific to memory caching.
-			cacheConfig.Options = MemoryConfig{}
+			var memoryOptions MemoryConfig
+			if opts, ok := cacheConfig.Options.(map[string]interface{}); ok && opts != nil {
+				decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{TagName: "config", Result: &memoryOptions})
+				if err != nil {
+					return nil, err
+				}
+				if err := decoder.Decode(opts); err != nil {
+					return nil, err
+				}
+			}
+			cacheConfig.Options = memoryOptions
 		case "false":
 			// If "false", ensure no options are set, or set to a nil or similar neutral value.
 			cacheConfig.Options = nil
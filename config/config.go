@@ -35,6 +35,7 @@ type Manager interface {
 	ConfigFile() string
 	ConfigDir() string
 	Update(key string, value any) error
+	Set(key string, value string) error
 	Exists(key string) bool
 	Get(key string) any
 	All() map[string]any
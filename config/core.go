@@ -25,6 +25,12 @@ type CoreConfig struct {
 	NodeID          types.UUID     `config:"node_id"`
 	Cron            CronConfig     `config:"cron"`
 	Account         AccountConfig  `config:"account"`
+	Webhooks        WebhookConfig  `config:"webhooks"`
+	Outbox          OutboxConfig   `config:"outbox"`
+	Metrics         MetricsConfig  `config:"metrics"`
+	Tracing         TracingConfig  `config:"tracing"`
+	Health          HealthConfig   `config:"health"`
+	Backup          BackupConfig   `config:"backup"`
 }
 
 func (c CoreConfig) Validate() error {
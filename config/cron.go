@@ -1,15 +1,111 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"strings"
+)
+
 var _ Defaults = (*CronConfig)(nil)
+var _ Validator = (*CronConfig)(nil)
+
+type CronQueue string
+
+const (
+	// CronQueueAuto uses redis when the cluster has it configured and the database otherwise
+	CronQueueAuto     CronQueue = "auto"
+	CronQueueRedis    CronQueue = "redis"
+	CronQueueDatabase CronQueue = "database"
+)
 
 type CronConfig struct {
-	Enabled  bool `config:"enabled"`
+	Enabled bool `config:"enabled"`
+	// MaxQueue is the number of jobs a node runs at once, the others wait for their turn by the priority of their task
 	MaxQueue uint `config:"queue_limit"`
+	// Queue is how jobs are distributed and locked between the nodes of a cluster, it is unused outside of one
+	Queue CronQueue `config:"queue"`
+	// Tasks overrides the schedule and limits of tasks, by task name
+	Tasks map[string]CronTaskConfig `config:"tasks"`
+}
+
+type CronTaskConfig struct {
+	// Schedule replaces the schedule of a recurring task with a standard cron expression, such as "0 3 * * *"
+	Schedule string `config:"schedule"`
+	// Timezone is the IANA timezone the schedule is in, the timezone of the node by default
+	Timezone string `config:"timezone"`
+	// Concurrency is the number of jobs of the task a node runs at once, unbounded when 0
+	Concurrency uint `config:"concurrency"`
+	// RateLimit is the number of jobs of the task a node starts per minute, spaced evenly, unbounded when 0
+	RateLimit uint `config:"rate_limit"`
+	// Priority orders the jobs waiting to run, higher first. Tasks default to 0, background tasks such as cleanups
+	// and account deletion default below it.
+	Priority *int `config:"priority"`
 }
 
 func (c CronConfig) Defaults() map[string]any {
 	return map[string]any{
 		"enabled":     true,
 		"queue_limit": 50,
+		"queue":       string(CronQueueAuto),
+	}
+}
+
+func (c CronConfig) Validate() error {
+	if c.MaxQueue == 0 {
+		return errors.New("core.cron.queue_limit must be at least 1")
 	}
+
+	switch c.Queue {
+	case CronQueueAuto, CronQueueRedis, CronQueueDatabase, CronQueue(""):
+	default:
+		return errors.New("core.cron.queue must be one of: auto, redis, database")
+	}
+
+	for name, task := range c.Tasks {
+		if err := task.validate(); err != nil {
+			return fmt.Errorf("core.cron.tasks.%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Task returns the overrides of a task. Config keys are case-insensitive, so task names are matched regardless of case.
+func (c CronConfig) Task(name string) CronTaskConfig {
+	if task, ok := c.Tasks[name]; ok {
+		return task
+	}
+
+	for key, task := range c.Tasks {
+		if strings.EqualFold(key, name) {
+			return task
+		}
+	}
+
+	return CronTaskConfig{}
+}
+
+// CronExpression returns the schedule with its timezone, in the form gocron takes
+func (t CronTaskConfig) CronExpression() string {
+	if t.Timezone == "" {
+		return t.Schedule
+	}
+
+	return "CRON_TZ=" + t.Timezone + " " + t.Schedule
+}
+
+func (t CronTaskConfig) validate() error {
+	if t.Schedule == "" {
+		if t.Timezone != "" {
+			return errors.New("timezone requires a schedule")
+		}
+		return nil
+	}
+
+	if _, err := cron.ParseStandard(t.CronExpression()); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCronConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  CronConfig
+		err  string
+	}{
+		{name: "defaults", cfg: CronConfig{MaxQueue: 50, Queue: CronQueueAuto}},
+		{name: "no queue limit", cfg: CronConfig{MaxQueue: 0}, err: "queue_limit"},
+		{name: "unknown queue", cfg: CronConfig{MaxQueue: 1, Queue: "kafka"}, err: "core.cron.queue"},
+		{
+			name: "schedule",
+			cfg:  CronConfig{MaxQueue: 1, Tasks: map[string]CronTaskConfig{"task": {Schedule: "0 3 * * *", Timezone: "Europe/Berlin"}}},
+		},
+		{
+			name: "invalid schedule",
+			cfg:  CronConfig{MaxQueue: 1, Tasks: map[string]CronTaskConfig{"task": {Schedule: "every day"}}},
+			err:  "core.cron.tasks.task",
+		},
+		{
+			name: "timezone without schedule",
+			cfg:  CronConfig{MaxQueue: 1, Tasks: map[string]CronTaskConfig{"task": {Timezone: "UTC"}}},
+			err:  "timezone requires a schedule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("expected the config to be valid, got %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error about %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
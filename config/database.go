@@ -8,15 +8,47 @@ var _ Defaults = (*DatabaseConfig)(nil)
 var _ Validator = (*DatabaseConfig)(nil)
 
 type DatabaseConfig struct {
-	Type     string       `config:"type"`
-	File     string       `config:"file"`
-	Charset  string       `config:"charset"`
-	Host     string       `config:"host"`
-	Name     string       `config:"name"`
-	Password string       `config:"password"`
-	Port     int          `config:"port"`
-	Username string       `config:"username"`
-	Cache    *CacheConfig `config:"cache"`
+	Type     string                  `config:"type"`
+	File     string                  `config:"file"`
+	Charset  string                  `config:"charset"`
+	Host     string                  `config:"host"`
+	Name     string                  `config:"name"`
+	Password string                  `config:"password"`
+	Port     int                     `config:"port"`
+	Username string                  `config:"username"`
+	SSLMode  string                  `config:"ssl_mode"`
+	Cache    *CacheConfig            `config:"cache"`
+	Replicas []DatabaseReplicaConfig `config:"replicas"`
+	// ReadYourWritesWindow is how long, in seconds, the reads of a user stay on the primary after they wrote, so they
+	// do not miss their own writes on a lagging replica. Writes made outside of a user's request keep all reads on the
+	// primary for as long.
+	ReadYourWritesWindow uint `config:"read_your_writes_window"`
+}
+
+// DatabaseReplicaConfig is a read replica of the primary database. Credentials left empty are those of the primary.
+type DatabaseReplicaConfig struct {
+	Host     string `config:"host"`
+	Port     int    `config:"port"`
+	Username string `config:"username"`
+	Password string `config:"password"`
+}
+
+// Replica returns the config of the primary with the connection details of the replica
+func (d DatabaseConfig) Replica(replica DatabaseReplicaConfig) DatabaseConfig {
+	d.Host = replica.Host
+
+	if replica.Port != 0 {
+		d.Port = replica.Port
+	}
+
+	if replica.Username != "" {
+		d.Username = replica.Username
+		d.Password = replica.Password
+	}
+
+	d.Replicas = nil
+
+	return d
 }
 
 func (d DatabaseConfig) CacheEnabled() bool {
@@ -30,7 +62,7 @@ func (d DatabaseConfig) Validate() error {
 		}
 	}
 
-	if d.Type == "mysql" {
+	if d.Type == "mysql" || d.Type == "postgres" {
 		if d.Host == "" {
 			return errors.New("core.db.host is required")
 		}
@@ -48,11 +80,33 @@ func (d DatabaseConfig) Validate() error {
 		}
 	}
 
+	if len(d.Replicas) > 0 {
+		if d.Type == "sqlite" {
+			return errors.New("core.db.replicas are not supported with sqlite")
+		}
+
+		for _, replica := range d.Replicas {
+			if replica.Host == "" {
+				return errors.New("core.db.replicas.host is required")
+			}
+		}
+	}
+
+	if d.Type == "postgres" {
+		switch d.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			return errors.New("core.db.ssl_mode must be one of disable, allow, prefer, require, verify-ca or verify-full")
+		}
+	}
+
 	return nil
 }
 
 func (d DatabaseConfig) Defaults() map[string]any {
-	def := map[string]any{}
+	def := map[string]any{
+		"read_your_writes_window": 5,
+	}
 
 	if d.Type == "sqlite" || d.Type == "" {
 		def["file"] = "portal.db"
@@ -68,5 +122,12 @@ func (d DatabaseConfig) Defaults() map[string]any {
 		def["name"] = "portal"
 	}
 
+	if d.Type == "postgres" {
+		def["host"] = "localhost"
+		def["port"] = 5432
+		def["name"] = "portal"
+		def["ssl_mode"] = "disable"
+	}
+
 	return def
 }
@@ -0,0 +1,29 @@
+package config
+
+import "errors"
+
+var _ Defaults = (*HealthConfig)(nil)
+var _ Validator = (*HealthConfig)(nil)
+
+type HealthConfig struct {
+	// CheckTimeout is in seconds and applies to each readiness check on its own
+	CheckTimeout uint `config:"check_timeout"`
+	// ShutdownDelay is the number of seconds the node keeps serving after reporting not ready on shutdown, so load
+	// balancers can stop routing to it first
+	ShutdownDelay uint `config:"shutdown_delay"`
+}
+
+func (h HealthConfig) Defaults() map[string]any {
+	return map[string]any{
+		"check_timeout":  5,
+		"shutdown_delay": 0,
+	}
+}
+
+func (h HealthConfig) Validate() error {
+	if h.CheckTimeout == 0 {
+		return errors.New("core.health.check_timeout must be at least 1")
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/knadh/koanf/parsers/yaml"
@@ -258,18 +259,28 @@ func (m *ManagerDefault) reconfigureSection(key string) error {
 
 func (m *ManagerDefault) pruneConfig(prefix string, cfg Defaults) error {
 	validKeys := make(map[string]bool)
-	err := m.FieldProcessor(cfg, prefix, func(_ *reflect.StructField, field reflect.StructField, value reflect.Value, prefix string) error {
+	// Maps and slices of structs are kept as a whole, their entries are keyed by name or index rather than by field
+	var validSubtrees []string
+	err := m.FieldProcessor(cfg, prefix, func(parent *reflect.StructField, field reflect.StructField, value reflect.Value, prefix string) error {
 		if field.Type == nil {
 			return nil
 		}
 
+		// Fields of an entry carry the prefix of the entry, the map or slice is the part before its name or index
+		if parent != nil && (parent.Type.Kind() == reflect.Map || parent.Type.Kind() == reflect.Slice) {
+			if i := strings.LastIndex(prefix, "."); i > 0 {
+				validSubtrees = append(validSubtrees, prefix[:i])
+			}
+			return nil
+		}
+
 		if field.Type.Kind() == reflect.Struct {
 			if _, ok := value.Interface().(yamlCore.Marshaler); !ok {
 				return nil
 			}
 		}
 
-		if field.Type.Kind() == reflect.Map || field.Type.Kind() == reflect.Slice {
+		if field.Type.Kind() == reflect.Map || (field.Type.Kind() == reflect.Slice && !isScalarSlice(field.Type)) {
 			return nil
 		}
 
@@ -289,7 +300,9 @@ func (m *ManagerDefault) pruneConfig(prefix string, cfg Defaults) error {
 
 	for _, key := range keysToCheck {
 		fullKey := prefix + "." + key
-		if !validKeys[fullKey] {
+		if !validKeys[fullKey] && !lo.SomeBy(validSubtrees, func(subtree string) bool {
+			return fullKey == subtree || strings.HasPrefix(fullKey, subtree+".")
+		}) {
 			m.config.Delete(fullKey)
 			m.changes = true
 			m.changedSections = append(m.changedSections, fullKey)
@@ -683,7 +696,13 @@ func (m *ManagerDefault) fieldProcessorRecursive(obj any, prefix string, parentF
 				}
 			}
 		case reflect.Slice:
-			if field.Len() > 0 {
+			// Slices of plain values are leaf config keys
+			if isScalarSlice(fieldType.Type) {
+				err := runProcessors()
+				if err != nil {
+					return err
+				}
+			} else if field.Len() > 0 {
 				for i := 0; i < field.Len(); i++ {
 					fieldPrefix := fmt.Sprintf("%s.%d", newPrefix, i)
 					if err := m.fieldProcessorRecursive(field.Index(i).Interface(), fieldPrefix, &fieldType, depth+1, processors...); err != nil {
@@ -1055,6 +1074,105 @@ func (m *ManagerDefault) Update(key string, value any) error {
 	return nil
 }
 
+// Set changes an existing key from its string form and saves it. The section the key belongs to is validated first, an
+// invalid value is rolled back. Synced keys of a cluster are written to etcd, where the other nodes pick them up.
+func (m *ManagerDefault) Set(key string, value string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.config.Exists(key) {
+		return fmt.Errorf("key %s does not exist", key)
+	}
+
+	section := sectionForKey(key)
+	if section == "" {
+		return fmt.Errorf("key %s is not part of a config section", key)
+	}
+
+	var parsedValue interface{}
+	if err := m.parseValue(value, &parsedValue); err != nil {
+		return err
+	}
+
+	previous := m.config.Get(key)
+
+	// Cluster values override local ones whenever a section is configured, so they have to change first
+	synced := m.shouldSyncKey(key) && m.root.Core.ClusterEnabled() && m.root.Core.Clustered.Etcd != nil
+	if synced {
+		if err := m.putClusterKey(key, parsedValue); err != nil {
+			return err
+		}
+	}
+
+	if err := m.config.Set(key, parsedValue); err != nil {
+		return err
+	}
+
+	if err := m.reconfigureSection(key); err != nil {
+		if synced {
+			if restoreErr := m.putClusterKey(key, previous); restoreErr != nil {
+				m.logger.Error("Failed to restore cluster config", zap.String("key", key), zap.Error(restoreErr))
+			}
+		}
+
+		if restoreErr := m.config.Set(key, previous); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+
+		return errors.Join(err, m.reconfigureSection(key))
+	}
+
+	m.changes = true
+	m.changedSections = append(m.changedSections, section)
+
+	return m.maybeSave()
+}
+
+func (m *ManagerDefault) putClusterKey(key string, value any) error {
+	client, err := m.root.Core.Clustered.Etcd.Client()
+	if err != nil {
+		return err
+	}
+
+	etcdKey := "/" + CLUSTER_CONFIG_KEY + "/" + strings.ReplaceAll(key, ".", "/")
+	_, err = client.Put(context.Background(), etcdKey, fmt.Sprintf("%v", value))
+
+	return err
+}
+
+// sectionForKey returns the name of the section a key is saved with, the way saving tracks changed sections
+func sectionForKey(key string) string {
+	parts := strings.Split(key, ".")
+
+	switch {
+	case parts[0] == "core":
+		return "core"
+	case parts[0] == "plugin" && len(parts) > 3 && parts[2] == "protocol":
+		return GetProtoSectionSpecifier(parts[1])
+	case parts[0] == "plugin" && len(parts) > 3 && parts[2] == "api":
+		return GetAPISectionSpecifier(parts[1])
+	case parts[0] == "plugin" && len(parts) > 4 && parts[2] == "service":
+		return GetServiceSectionSpecifier(parts[1], parts[3])
+	}
+
+	return ""
+}
+
+// CoreDefaults returns the default core config as YAML, the way it is written for a new install.
+func CoreDefaults() ([]byte, error) {
+	m, err := NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	root := &Config{}
+	if err := m.setDefaultsForObject(&root.Core, "core"); err != nil {
+		return nil, err
+	}
+
+	return m.config.Cut("core").Marshal(yaml.Parser())
+}
+
 func (m *ManagerDefault) Exists(key string) bool {
 	return m.config.Exists(key)
 }
@@ -1195,6 +1313,19 @@ func GetServiceSectionSpecifier(pluginName string, serviceName string) string {
 	return fmt.Sprintf(serviceSectionSpecifier, pluginName, serviceName)
 }
 
+func isScalarSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+
+	switch t.Elem().Kind() {
+	case reflect.Struct, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return false
+	}
+
+	return true
+}
+
 func processStruct(obj any) bool {
 	if _, ok := obj.(yamlCore.Marshaler); ok {
 		return true
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestPruneConfigKeepsMapsAndSlicesOfStructs(t *testing.T) {
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	m.SetLogger(zap.NewNop())
+
+	values := map[string]any{
+		"core.db.type":                           "postgres",
+		"core.db.replicas":                       []any{map[string]any{"host": "replica-a", "port": 5433}},
+		"core.cron.tasks.deleteaccount.schedule": "0 3 * * *",
+		"core.cron.tasks.deleteaccount.priority": -10,
+		"core.unknown":                           true,
+		"core.db.unknown":                        true,
+	}
+	for key, value := range values {
+		if err := m.config.Set(key, value); err != nil {
+			t.Fatalf("failed to set %s: %v", key, err)
+		}
+	}
+
+	cfg := &CoreConfig{
+		DB: DatabaseConfig{
+			Type:     "postgres",
+			Replicas: []DatabaseReplicaConfig{{Host: "replica-a", Port: 5433}},
+		},
+		Cron: CronConfig{
+			Tasks: map[string]CronTaskConfig{"deleteaccount": {Schedule: "0 3 * * *", Priority: lo.ToPtr(-10)}},
+		},
+	}
+
+	if err := m.pruneConfig("core", cfg); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+
+	for _, key := range []string{"core.db.type", "core.db.replicas", "core.cron.tasks.deleteaccount.schedule", "core.cron.tasks.deleteaccount.priority"} {
+		if !m.config.Exists(key) {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+
+	for _, key := range []string{"core.unknown", "core.db.unknown"} {
+		if m.config.Exists(key) {
+			t.Errorf("expected %s to be pruned", key)
+		}
+	}
+}
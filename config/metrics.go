@@ -0,0 +1,27 @@
+package config
+
+import "errors"
+
+var _ Defaults = (*MetricsConfig)(nil)
+var _ Validator = (*MetricsConfig)(nil)
+
+type MetricsConfig struct {
+	Enabled bool `config:"enabled"`
+	// Token must be sent by scrapers as a bearer token, it is required once metrics are enabled
+	Token string `config:"token"`
+}
+
+func (m MetricsConfig) Defaults() map[string]any {
+	return map[string]any{
+		"enabled": false,
+		"token":   "",
+	}
+}
+
+func (m MetricsConfig) Validate() error {
+	if m.Enabled && m.Token == "" {
+		return errors.New("core.metrics.token is required when metrics are enabled")
+	}
+
+	return nil
+}
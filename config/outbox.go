@@ -0,0 +1,41 @@
+package config
+
+import "errors"
+
+var _ Defaults = (*OutboxConfig)(nil)
+var _ Validator = (*OutboxConfig)(nil)
+
+type OutboxConfig struct {
+	// PollInterval is in milliseconds
+	PollInterval uint `config:"poll_interval"`
+	BatchSize    uint `config:"batch_size"`
+	// Retention is the number of days dispatched and dead events are kept for replay
+	Retention uint `config:"retention"`
+	// MaxAttempts is how many times delivery of an event is tried before it is set aside as dead, until replayed
+	MaxAttempts uint `config:"max_attempts"`
+}
+
+func (o OutboxConfig) Defaults() map[string]any {
+	return map[string]any{
+		"poll_interval": 1000,
+		"batch_size":    100,
+		"retention":     30,
+		"max_attempts":  10,
+	}
+}
+
+func (o OutboxConfig) Validate() error {
+	if o.PollInterval == 0 {
+		return errors.New("core.outbox.poll_interval must be at least 1")
+	}
+
+	if o.BatchSize == 0 {
+		return errors.New("core.outbox.batch_size must be at least 1")
+	}
+
+	if o.MaxAttempts == 0 {
+		return errors.New("core.outbox.max_attempts must be at least 1")
+	}
+
+	return nil
+}
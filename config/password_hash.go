@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"slices"
+)
+
+var _ Defaults = (*PasswordHashConfig)(nil)
+var _ Validator = (*PasswordHashConfig)(nil)
+var _ Defaults = (*Argon2Config)(nil)
+var _ Validator = (*Argon2Config)(nil)
+
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+type PasswordHashConfig struct {
+	Algorithm  string       `config:"algorithm"`
+	BcryptCost uint         `config:"bcrypt_cost"`
+	Argon2     Argon2Config `config:"argon2"`
+}
+
+type Argon2Config struct {
+	// Memory is in KiB
+	Memory      uint `config:"memory"`
+	Iterations  uint `config:"iterations"`
+	Parallelism uint `config:"parallelism"`
+	SaltLength  uint `config:"salt_length"`
+	KeyLength   uint `config:"key_length"`
+}
+
+func (p PasswordHashConfig) Defaults() map[string]any {
+	return map[string]any{
+		"algorithm":   PasswordHashArgon2id,
+		"bcrypt_cost": 10,
+	}
+}
+
+func (p PasswordHashConfig) Validate() error {
+	if !slices.Contains([]string{PasswordHashArgon2id, PasswordHashBcrypt}, p.Algorithm) {
+		return errors.New("core.account.password_hash.algorithm must be one of argon2id or bcrypt")
+	}
+
+	if p.BcryptCost < 4 || p.BcryptCost > 31 {
+		return errors.New("core.account.password_hash.bcrypt_cost must be between 4 and 31")
+	}
+
+	return nil
+}
+
+func (a Argon2Config) Defaults() map[string]any {
+	return map[string]any{
+		"memory":      64 * 1024,
+		"iterations":  3,
+		"parallelism": 2,
+		"salt_length": 16,
+		"key_length":  32,
+	}
+}
+
+func (a Argon2Config) Validate() error {
+	if a.Iterations == 0 || a.Parallelism == 0 || a.SaltLength == 0 || a.KeyLength == 0 {
+		return errors.New("core.account.password_hash.argon2 iterations, parallelism, salt_length and key_length must be at least 1")
+	}
+
+	if a.Memory < 8*a.Parallelism {
+		return errors.New("core.account.password_hash.argon2.memory must be at least 8 KiB per thread")
+	}
+
+	return nil
+}
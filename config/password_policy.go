@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+)
+
+var _ Defaults = (*PasswordPolicyConfig)(nil)
+var _ Validator = (*PasswordPolicyConfig)(nil)
+
+type PasswordPolicyConfig struct {
+	MinLength            uint `config:"min_length"`
+	RequireUpper         bool `config:"require_upper"`
+	RequireLower         bool `config:"require_lower"`
+	RequireDigit         bool `config:"require_digit"`
+	RequireSymbol        bool `config:"require_symbol"`
+	MaxRepeats           uint `config:"max_repeats"`
+	DisallowPersonalInfo bool `config:"disallow_personal_info"`
+	// BreachedList is a directory of range files named after the first five hex characters of the SHA-1 hash,
+	// each holding SUFFIX:COUNT lines, as published for k-anonymity lookups
+	BreachedList     string `config:"breached_list"`
+	BreachedMinCount uint   `config:"breached_min_count"`
+}
+
+func (p PasswordPolicyConfig) Defaults() map[string]any {
+	return map[string]any{
+		"min_length":             8,
+		"require_upper":          false,
+		"require_lower":          false,
+		"require_digit":          false,
+		"require_symbol":         false,
+		"max_repeats":            0,
+		"disallow_personal_info": true,
+		"breached_list":          "",
+		"breached_min_count":     1,
+	}
+}
+
+func (p PasswordPolicyConfig) Validate() error {
+	if p.MinLength == 0 {
+		return errors.New("core.account.password_policy.min_length must be at least 1")
+	}
+
+	return nil
+}
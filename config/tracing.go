@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"slices"
+)
+
+var _ Defaults = (*TracingConfig)(nil)
+var _ Validator = (*TracingConfig)(nil)
+
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+type TracingConfig struct {
+	Enabled  bool   `config:"enabled"`
+	Exporter string `config:"exporter"`
+	// Endpoint is the host:port of an OTLP/HTTP collector
+	Endpoint string `config:"endpoint"`
+	Insecure bool   `config:"insecure"`
+	// SampleRatio is the share of new traces that are recorded, traces started elsewhere follow the caller's decision
+	SampleRatio float64 `config:"sample_ratio"`
+}
+
+func (t TracingConfig) Defaults() map[string]any {
+	return map[string]any{
+		"enabled":      false,
+		"exporter":     TracingExporterOTLP,
+		"endpoint":     "localhost:4318",
+		"insecure":     false,
+		"sample_ratio": 1.0,
+	}
+}
+
+func (t TracingConfig) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+
+	if !slices.Contains([]string{TracingExporterOTLP, TracingExporterStdout}, t.Exporter) {
+		return errors.New("core.tracing.exporter must be one of otlp or stdout")
+	}
+
+	if t.Exporter == TracingExporterOTLP && t.Endpoint == "" {
+		return errors.New("core.tracing.endpoint is required for the otlp exporter")
+	}
+
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return errors.New("core.tracing.sample_ratio must be between 0 and 1")
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import "errors"
+
+var _ Defaults = (*WebhookConfig)(nil)
+var _ Validator = (*WebhookConfig)(nil)
+
+type WebhookConfig struct {
+	MaxAttempts uint `config:"max_attempts"`
+	// Timeout is in seconds
+	Timeout uint `config:"timeout"`
+	// AllowPrivateNetworks permits endpoints resolving to loopback, private or link-local addresses
+	AllowPrivateNetworks bool `config:"allow_private_networks"`
+	MaxEndpointsPerUser  uint `config:"max_endpoints_per_user"`
+}
+
+func (w WebhookConfig) Defaults() map[string]any {
+	return map[string]any{
+		"max_attempts":           8,
+		"timeout":                10,
+		"allow_private_networks": false,
+		"max_endpoints_per_user": 10,
+	}
+}
+
+func (w WebhookConfig) Validate() error {
+	if w.MaxAttempts == 0 {
+		return errors.New("core.webhooks.max_attempts must be at least 1")
+	}
+
+	if w.Timeout == 0 {
+		return errors.New("core.webhooks.timeout must be at least 1")
+	}
+
+	return nil
+}
@@ -1,5 +1,17 @@
 package core
 
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+var (
+	accessAttributeProviders   = make(map[string]AccessAttributeProvider)
+	accessAttributeProvidersMu sync.RWMutex
+)
+
 const (
 	ACCESS_SERVICE = "access"
 
@@ -15,15 +27,44 @@ type AccessService interface {
 	// RegisterRole adds a new role with its associated permissions
 	AssignRoleToUser(userId uint, role string) error
 
+	// RemoveRoleFromUser removes a role from a user
+	RemoveRoleFromUser(userId uint, role string) error
+
+	// UserHasRole checks if a user has been assigned a role
+	UserHasRole(userId uint, role string) (bool, error)
+
+	// RegisterConditionalRoute adds a new route that a role may only access when the condition holds.
+	// The condition is an expression over request attributes, e.g. `attr.user.verified == true`
+	RegisterConditionalRoute(subdomain, path, method, role, condition string) error
+
 	// CheckAccess checks if a given role has access to a specific route
 	CheckAccess(userId uint, fqdn, path, method string) (bool, error)
 
+	// CheckRequestAccess checks if a user has access to the route of a request, evaluating conditional routes
+	// against the attributes resolved by the registered attribute providers
+	CheckRequestAccess(userId uint, r *http.Request) (bool, error)
+
 	// ExportUserPolicy returns the policy for a specific user
 	ExportUserPolicy(userId uint) ([]*AccessPolicy, error)
 
 	// ExportModel returns the model for the access service
 	ExportModel() *AccessModel
 
+	// AssignOrgRoleToUser assigns a role to a user within an organization domain
+	AssignOrgRoleToUser(userId uint, orgId uint, role string) error
+
+	// RemoveOrgRolesFromUser removes all roles a user holds within an organization domain
+	RemoveOrgRolesFromUser(userId uint, orgId uint) error
+
+	// RegisterOrgPolicy grants a role an action on an object within an organization domain
+	RegisterOrgPolicy(orgId uint, role, obj, act string) error
+
+	// CheckOrgAccess checks if a user has access to an object within an organization domain
+	CheckOrgAccess(userId uint, orgId uint, obj, act string) (bool, error)
+
+	// DeleteOrgPolicies removes all policies and role assignments for an organization domain
+	DeleteOrgPolicies(orgId uint) error
+
 	Service
 }
 
@@ -45,4 +86,48 @@ type AccessModel struct {
 	RoleDefinition    AccessModelDef `json:"role_definition"`
 	PolicyEffect      AccessModelDef `json:"policy_effect"`
 	Matchers          AccessModelDef `json:"matchers"`
+	// Additional holds the numbered definitions of every section, such as r2 and m2, sorted by key
+	Additional []AccessModelDef `json:"additional"`
+}
+
+// AccessAttributeProvider resolves request attributes that conditional routes can reference.
+// Attributes are namespaced by the provider ID, so a provider with the ID "plan" returning
+// a "name" attribute is referenced as attr.plan.name in conditions.
+type AccessAttributeProvider interface {
+	// ID returns the namespace the attributes are exposed under
+	ID() string
+
+	// Attributes resolves the attributes for the given user and request. A provider should always
+	// return the same set of keys, as a condition referencing a missing key fails to evaluate.
+	Attributes(ctx Context, userId uint, r *http.Request) (map[string]any, error)
+}
+
+func RegisterAccessAttributeProvider(provider AccessAttributeProvider) {
+	accessAttributeProvidersMu.Lock()
+	defer accessAttributeProvidersMu.Unlock()
+
+	if _, ok := accessAttributeProviders[provider.ID()]; ok {
+		panic(fmt.Sprintf("access attribute provider already registered: %s", provider.ID()))
+	}
+
+	accessAttributeProviders[provider.ID()] = provider
+}
+
+func GetAccessAttributeProviders() []AccessAttributeProvider {
+	accessAttributeProvidersMu.RLock()
+	defer accessAttributeProvidersMu.RUnlock()
+
+	keys := make([]string, 0, len(accessAttributeProviders))
+	for k := range accessAttributeProviders {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	providers := make([]AccessAttributeProvider, 0, len(keys))
+	for _, k := range keys {
+		providers = append(providers, accessAttributeProviders[k])
+	}
+
+	return providers
 }
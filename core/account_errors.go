@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 type AccountErrorType string
@@ -14,6 +15,26 @@ const (
 	ErrKeyUpdatingSameEmail     AccountErrorType = "ErrUpdatingSameEmail"
 	ErrKeyPasswordHashingFailed AccountErrorType = "ErrPasswordHashingFailed"
 
+	// Registration policy errors
+	ErrKeyRegistrationClosed         AccountErrorType = "ErrRegistrationClosed"
+	ErrKeyRegistrationInviteRequired AccountErrorType = "ErrRegistrationInviteRequired"
+	ErrKeyInviteInvalid              AccountErrorType = "ErrInviteInvalid"
+	ErrKeyInviteNotFound             AccountErrorType = "ErrInviteNotFound"
+	ErrKeyInviteQuotaExceeded        AccountErrorType = "ErrInviteQuotaExceeded"
+	ErrKeyEmailDomainNotAllowed      AccountErrorType = "ErrEmailDomainNotAllowed"
+
+	// Password policy errors
+	ErrKeyPasswordPolicyViolation      AccountErrorType = "ErrPasswordPolicyViolation"
+	ErrKeyPasswordTooShort             AccountErrorType = "ErrPasswordTooShort"
+	ErrKeyPasswordMissingUpper         AccountErrorType = "ErrPasswordMissingUpper"
+	ErrKeyPasswordMissingLower         AccountErrorType = "ErrPasswordMissingLower"
+	ErrKeyPasswordMissingDigit         AccountErrorType = "ErrPasswordMissingDigit"
+	ErrKeyPasswordMissingSymbol        AccountErrorType = "ErrPasswordMissingSymbol"
+	ErrKeyPasswordTooManyRepeats       AccountErrorType = "ErrPasswordTooManyRepeats"
+	ErrKeyPasswordContainsPersonalInfo AccountErrorType = "ErrPasswordContainsPersonalInfo"
+	ErrKeyPasswordBreached             AccountErrorType = "ErrPasswordBreached"
+	ErrKeyPasswordPolicyCheckFailed    AccountErrorType = "ErrPasswordPolicyCheckFailed"
+
 	// Account role errors
 	ErrKeyAssigningAdminRoleFailed AccountErrorType = "ErrAssigningAdminRoleFailed"
 	ErrorAssigningUserRoleFailed   AccountErrorType = "ErrorAssigningUserRoleFailed"
@@ -24,6 +45,21 @@ const (
 
 	// Account deletion errors
 	ErrKeyAccountDeletionRequestAlreadyExists AccountErrorType = "ErrAccountDeletionRequestAlreadyExists"
+	ErrKeyAccountDeletionRequestNotFound      AccountErrorType = "ErrAccountDeletionRequestNotFound"
+
+	// Account data export errors
+	ErrKeyDataExportInProgress AccountErrorType = "ErrDataExportInProgress"
+	ErrKeyDataExportFailed     AccountErrorType = "ErrDataExportFailed"
+
+	// Webhook errors
+	ErrKeyWebhookNotFound       AccountErrorType = "ErrWebhookNotFound"
+	ErrKeyWebhookInvalidURL     AccountErrorType = "ErrWebhookInvalidURL"
+	ErrKeyWebhookInvalidEvent   AccountErrorType = "ErrWebhookInvalidEvent"
+	ErrKeyWebhookLimitReached   AccountErrorType = "ErrWebhookLimitReached"
+	ErrKeyWebhookDeliveryFailed AccountErrorType = "ErrWebhookDeliveryFailed"
+
+	// Outbox errors
+	ErrKeyOutboxInvalidReplayRange AccountErrorType = "ErrOutboxInvalidReplayRange"
 
 	// Authentication and login errors
 	ErrKeyInvalidLogin           AccountErrorType = "ErrInvalidLogin"
@@ -34,10 +70,12 @@ const (
 	ErrKeyHashingFailed          AccountErrorType = "ErrHashingFailed"
 	ErrKeyAccountPendingDeletion AccountErrorType = "ErrAccountPendingDeletion"
 	ErrKeyAccountNotVerified     AccountErrorType = "ErrAccountNotVerified"
+	ErrKeyAccountSuspended       AccountErrorType = "ErrAccountSuspended"
 
 	// Account update errors
 	ErrKeyAccountUpdateFailed    AccountErrorType = "ErrAccountUpdateFailed"
 	ErrKeyAccountAlreadyVerified AccountErrorType = "ErrAccountAlreadyVerified"
+	ErrKeyAccountNotSuspended    AccountErrorType = "ErrAccountNotSuspended"
 
 	// JWT generation errors
 	ErrKeyJWTGenerationFailed AccountErrorType = "ErrJWTGenerationFailed"
@@ -63,6 +101,23 @@ const (
 	ErrKeySecurityTokenExpired AccountErrorType = "ErrSecurityTokenExpired"
 	ErrKeySecurityInvalidToken AccountErrorType = "ErrSecurityInvalidToken"
 
+	// Admin errors
+	ErrKeyInvalidRole                 AccountErrorType = "ErrInvalidRole"
+	ErrKeyImpersonationNotAllowed     AccountErrorType = "ErrImpersonationNotAllowed"
+	ErrKeyImpersonationSessionInvalid AccountErrorType = "ErrImpersonationSessionInvalid"
+	ErrKeyImpersonationActionDenied   AccountErrorType = "ErrImpersonationActionDenied"
+
+	// Organization errors
+	ErrKeyOrganizationNotFound         AccountErrorType = "ErrOrganizationNotFound"
+	ErrKeyOrganizationExists           AccountErrorType = "ErrOrganizationExists"
+	ErrKeyOrganizationPermissionDenied AccountErrorType = "ErrOrganizationPermissionDenied"
+	ErrKeyOrganizationMemberExists     AccountErrorType = "ErrOrganizationMemberExists"
+	ErrKeyOrganizationMemberNotFound   AccountErrorType = "ErrOrganizationMemberNotFound"
+	ErrKeyOrganizationLastOwner        AccountErrorType = "ErrOrganizationLastOwner"
+	ErrKeyOrganizationQuotaExceeded    AccountErrorType = "ErrOrganizationQuotaExceeded"
+	ErrKeyOrganizationInviteInvalid    AccountErrorType = "ErrOrganizationInviteInvalid"
+	ErrKeyOrganizationInvalidRole      AccountErrorType = "ErrOrganizationInvalidRole"
+
 	// Internal errors
 	ErrKeyAccountSubdomainNotSet AccountErrorType = "ErrAccountSubdomainNotSet"
 )
@@ -74,6 +129,26 @@ var defaultErrorMessages = map[AccountErrorType]string{
 	ErrKeyPasswordHashingFailed: "Failed to secure the password, please try again later.",
 	ErrKeyUpdatingSameEmail:     "The email address provided is the same as your current one.",
 
+	// Registration policy errors
+	ErrKeyRegistrationClosed:         "Registration is closed.",
+	ErrKeyRegistrationInviteRequired: "An invite code is required to register.",
+	ErrKeyInviteInvalid:              "The invite code is invalid, expired or fully used.",
+	ErrKeyInviteNotFound:             "The invite code was not found.",
+	ErrKeyInviteQuotaExceeded:        "You have no invites left.",
+	ErrKeyEmailDomainNotAllowed:      "Registration is not allowed for this email domain.",
+
+	// Password policy errors
+	ErrKeyPasswordPolicyViolation:      "The password does not meet the password policy.",
+	ErrKeyPasswordTooShort:             "The password is too short.",
+	ErrKeyPasswordMissingUpper:         "The password must contain an uppercase letter.",
+	ErrKeyPasswordMissingLower:         "The password must contain a lowercase letter.",
+	ErrKeyPasswordMissingDigit:         "The password must contain a digit.",
+	ErrKeyPasswordMissingSymbol:        "The password must contain a symbol.",
+	ErrKeyPasswordTooManyRepeats:       "The password repeats the same character too many times.",
+	ErrKeyPasswordContainsPersonalInfo: "The password must not contain your email address or name.",
+	ErrKeyPasswordBreached:             "The password has appeared in a data breach.",
+	ErrKeyPasswordPolicyCheckFailed:    "Failed to check the password against the password policy.",
+
 	// Account role errors
 	ErrKeyAssigningAdminRoleFailed: "Failed to assign the admin role to the account.",
 	ErrorAssigningUserRoleFailed:   "Failed to assign the user role to the account.",
@@ -85,6 +160,21 @@ var defaultErrorMessages = map[AccountErrorType]string{
 
 	// Account deletion errors
 	ErrKeyAccountDeletionRequestAlreadyExists: "An account deletion request already exists for this account.",
+	ErrKeyAccountDeletionRequestNotFound:      "No account deletion request exists for this account.",
+
+	// Account data export errors
+	ErrKeyDataExportInProgress: "A data export is already in progress for this account.",
+	ErrKeyDataExportFailed:     "Failed to export the account data.",
+
+	// Webhook errors
+	ErrKeyWebhookNotFound:       "The webhook was not found.",
+	ErrKeyWebhookInvalidURL:     "The webhook URL must be an absolute http or https URL.",
+	ErrKeyWebhookInvalidEvent:   "The webhook subscribes to an unknown event.",
+	ErrKeyWebhookLimitReached:   "You have reached the maximum number of webhooks.",
+	ErrKeyWebhookDeliveryFailed: "Failed to deliver the webhook.",
+
+	// Outbox errors
+	ErrKeyOutboxInvalidReplayRange: "The replay range must end after it starts.",
 
 	// Authentication and login errors
 	ErrKeyInvalidLogin:           "The login credentials provided are invalid.",
@@ -94,10 +184,12 @@ var defaultErrorMessages = map[AccountErrorType]string{
 	ErrKeyLoginFailed:            "Login failed due to an internal error.",
 	ErrKeyAccountPendingDeletion: "This account is pending deletion.",
 	ErrKeyAccountNotVerified:     "The account is not verified.",
+	ErrKeyAccountSuspended:       "This account has been suspended.",
 
 	// Account update errors
 	ErrKeyAccountUpdateFailed:    "Failed to update account information.",
 	ErrKeyAccountAlreadyVerified: "Account is already verified.",
+	ErrKeyAccountNotSuspended:    "Account is not suspended.",
 
 	// JWT generation errors
 	ErrKeyJWTGenerationFailed: "Failed to generate a new JWT token.",
@@ -123,6 +215,23 @@ var defaultErrorMessages = map[AccountErrorType]string{
 	ErrKeySecurityTokenExpired: "The security token has expired.",
 	ErrKeySecurityInvalidToken: "The security token is invalid.",
 
+	// Admin errors
+	ErrKeyInvalidRole:                 "The role provided is invalid.",
+	ErrKeyImpersonationNotAllowed:     "This account cannot be impersonated.",
+	ErrKeyImpersonationSessionInvalid: "The impersonation session is invalid or has ended.",
+	ErrKeyImpersonationActionDenied:   "This action is not available during an impersonation session.",
+
+	// Organization errors
+	ErrKeyOrganizationNotFound:         "The requested organization was not found.",
+	ErrKeyOrganizationExists:           "An organization with this name already exists.",
+	ErrKeyOrganizationPermissionDenied: "You do not have permission to perform this action in the organization.",
+	ErrKeyOrganizationMemberExists:     "The user is already a member of the organization.",
+	ErrKeyOrganizationMemberNotFound:   "The user is not a member of the organization.",
+	ErrKeyOrganizationLastOwner:        "The organization must have at least one owner.",
+	ErrKeyOrganizationQuotaExceeded:    "The organization storage quota has been exceeded.",
+	ErrKeyOrganizationInviteInvalid:    "The organization invite is invalid or has expired.",
+	ErrKeyOrganizationInvalidRole:      "The organization role provided is invalid.",
+
 	// Internal errors
 	ErrKeyAccountSubdomainNotSet: "The account subdomain is not set.",
 }
@@ -134,6 +243,26 @@ var (
 		ErrKeyEmailAlreadyExists:    http.StatusConflict,
 		ErrKeyPasswordHashingFailed: http.StatusInternalServerError,
 
+		// Registration policy errors
+		ErrKeyRegistrationClosed:         http.StatusForbidden,
+		ErrKeyRegistrationInviteRequired: http.StatusForbidden,
+		ErrKeyInviteInvalid:              http.StatusBadRequest,
+		ErrKeyInviteNotFound:             http.StatusNotFound,
+		ErrKeyInviteQuotaExceeded:        http.StatusForbidden,
+		ErrKeyEmailDomainNotAllowed:      http.StatusForbidden,
+
+		// Password policy errors
+		ErrKeyPasswordPolicyViolation:      http.StatusBadRequest,
+		ErrKeyPasswordTooShort:             http.StatusBadRequest,
+		ErrKeyPasswordMissingUpper:         http.StatusBadRequest,
+		ErrKeyPasswordMissingLower:         http.StatusBadRequest,
+		ErrKeyPasswordMissingDigit:         http.StatusBadRequest,
+		ErrKeyPasswordMissingSymbol:        http.StatusBadRequest,
+		ErrKeyPasswordTooManyRepeats:       http.StatusBadRequest,
+		ErrKeyPasswordContainsPersonalInfo: http.StatusBadRequest,
+		ErrKeyPasswordBreached:             http.StatusBadRequest,
+		ErrKeyPasswordPolicyCheckFailed:    http.StatusInternalServerError,
+
 		// Account role errors
 		ErrKeyAssigningAdminRoleFailed: http.StatusInternalServerError,
 		ErrorAssigningUserRoleFailed:   http.StatusInternalServerError,
@@ -144,6 +273,21 @@ var (
 
 		// Account deletion errors
 		ErrKeyAccountDeletionRequestAlreadyExists: http.StatusConflict,
+		ErrKeyAccountDeletionRequestNotFound:      http.StatusNotFound,
+
+		// Account data export errors
+		ErrKeyDataExportInProgress: http.StatusConflict,
+		ErrKeyDataExportFailed:     http.StatusInternalServerError,
+
+		// Webhook errors
+		ErrKeyWebhookNotFound:       http.StatusNotFound,
+		ErrKeyWebhookInvalidURL:     http.StatusBadRequest,
+		ErrKeyWebhookInvalidEvent:   http.StatusBadRequest,
+		ErrKeyWebhookLimitReached:   http.StatusForbidden,
+		ErrKeyWebhookDeliveryFailed: http.StatusBadGateway,
+
+		// Outbox errors
+		ErrKeyOutboxInvalidReplayRange: http.StatusBadRequest,
 
 		// Authentication and login errors
 		ErrKeyInvalidLogin:           http.StatusUnauthorized,
@@ -153,10 +297,12 @@ var (
 		ErrKeyLoginFailed:            http.StatusInternalServerError,
 		ErrKeyAccountPendingDeletion: http.StatusForbidden,
 		ErrKeyAccountNotVerified:     http.StatusForbidden,
+		ErrKeyAccountSuspended:       http.StatusForbidden,
 
 		// Account update errors
 		ErrKeyAccountUpdateFailed:    http.StatusInternalServerError,
 		ErrKeyAccountAlreadyVerified: http.StatusConflict,
+		ErrKeyAccountNotSuspended:    http.StatusConflict,
 
 		// JWT generation errors
 		ErrKeyJWTGenerationFailed: http.StatusInternalServerError,
@@ -183,18 +329,43 @@ var (
 		ErrKeySecurityTokenExpired: http.StatusUnauthorized,
 		ErrKeySecurityInvalidToken: http.StatusUnauthorized,
 
+		// Admin errors
+		ErrKeyInvalidRole:                 http.StatusBadRequest,
+		ErrKeyImpersonationNotAllowed:     http.StatusForbidden,
+		ErrKeyImpersonationSessionInvalid: http.StatusUnauthorized,
+		ErrKeyImpersonationActionDenied:   http.StatusForbidden,
+
+		// Organization errors
+		ErrKeyOrganizationNotFound:         http.StatusNotFound,
+		ErrKeyOrganizationExists:           http.StatusConflict,
+		ErrKeyOrganizationPermissionDenied: http.StatusForbidden,
+		ErrKeyOrganizationMemberExists:     http.StatusConflict,
+		ErrKeyOrganizationMemberNotFound:   http.StatusNotFound,
+		ErrKeyOrganizationLastOwner:        http.StatusConflict,
+		ErrKeyOrganizationQuotaExceeded:    http.StatusForbidden,
+		ErrKeyOrganizationInviteInvalid:    http.StatusBadRequest,
+		ErrKeyOrganizationInvalidRole:      http.StatusBadRequest,
+
 		// Internal errors
 		ErrKeyAccountSubdomainNotSet: http.StatusInternalServerError,
 	}
 )
 
 type AccountError struct {
-	Key     AccountErrorType // A unique identifier for the error type
-	Message string           // Human-readable error message
-	Err     error            // Underlying error, if any
+	Key        AccountErrorType // A unique identifier for the error type
+	Message    string           // Human-readable error message
+	Err        error            // Underlying error, if any
+	Violations []*AccountError  // Individual rule failures of a policy error, if any
 }
 
 func (e *AccountError) Error() string {
+	if len(e.Violations) > 0 {
+		messages := make([]string, 0, len(e.Violations))
+		for _, violation := range e.Violations {
+			messages = append(messages, violation.Message)
+		}
+		return fmt.Sprintf("%s %s", e.Message, strings.Join(messages, " "))
+	}
 	if e.Err != nil {
 		return fmt.Sprintf("%s: %v", e.Message, e.Err)
 	}
@@ -227,6 +398,13 @@ func NewAccountError(key AccountErrorType, err error, customMessage ...string) *
 	}
 }
 
+// NewPolicyError wraps the given rule failures in a single error of the given policy key.
+func NewPolicyError(key AccountErrorType, violations []*AccountError) *AccountError {
+	err := NewAccountError(key, nil)
+	err.Violations = violations
+	return err
+}
+
 func IsAccountError(err error) bool {
 	if err == nil {
 		return false
@@ -0,0 +1,19 @@
+package core
+
+import "go.lumeweb.com/portal/db/models"
+
+const ACTIVITY_SERVICE = "activity"
+
+type ActivityService interface {
+	// Record adds an entry to the activity log of the user with the given ID and streams it to the user on every node.
+	Record(userId uint, activityType string, data any) (*models.ActivityEvent, error)
+
+	// Since returns the retained activity of the user with the given ID after the entry with the given ID, oldest first.
+	Since(userId uint, lastId uint) ([]*models.ActivityEvent, error)
+
+	// Subscribe streams new activity of the user with the given ID. The channel is closed when the returned function
+	// is called, or when the subscriber falls too far behind and has to resume through Since.
+	Subscribe(userId uint) (<-chan *models.ActivityEvent, func())
+
+	Service
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"go.lumeweb.com/portal/db/models"
+	"time"
+)
+
+const ADMIN_SERVICE = "admin"
+
+type AdminService interface {
+	// ListUsers retrieves the users matching the given filter along with the total number of matches.
+	ListUsers(filter UserFilter) ([]*models.User, int64, error)
+
+	// VerifyUser marks the email of the user with the given ID as verified without a verification token.
+	VerifyUser(userId uint) error
+
+	// DisableUserOTP disables OTP for the user with the given ID without requiring a code.
+	DisableUserOTP(userId uint) error
+
+	// ForcePasswordReset invalidates the current password of the user, revokes their tokens and impersonation sessions
+	// and sends a password reset email.
+	ForcePasswordReset(userId uint) error
+
+	// SetUserRole changes the access role of the user with the given ID.
+	SetUserRole(userId uint, role string) error
+
+	// CancelAccountDeletion cancels a pending account deletion request for the user with the given ID.
+	CancelAccountDeletion(userId uint) error
+
+	// Impersonate starts an audited impersonation session of a user by an admin.
+	// It returns a time-limited token authenticating as the user.
+	Impersonate(adminId uint, userId uint, reason string, ip string, duration time.Duration) (string, *models.ImpersonationSession, error)
+
+	// EndImpersonation ends an impersonation session, invalidating its token.
+	EndImpersonation(sessionId uint) error
+
+	// ValidImpersonation checks if the impersonation session with the given ID is still active.
+	ValidImpersonation(sessionId uint) (bool, *models.ImpersonationSession, error)
+
+	// ListImpersonations retrieves the impersonation sessions for the user with the given ID.
+	ListImpersonations(userId uint) ([]*models.ImpersonationSession, error)
+
+	Service
+}
+
+type UserFilter struct {
+	Search   string
+	Verified *bool
+	Role     string
+	Limit    int
+	Offset   int
+}
@@ -25,12 +25,14 @@ var (
 	ErrJWTUnexpectedClaimsType = errors.New("unexpected claims type")
 	ErrJWTUnexpectedIssuer     = errors.New("unexpected issuer")
 	ErrJWTInvalid              = errors.New("invalid JWT")
+	ErrJWTRevoked              = errors.New("revoked JWT")
 )
 
 const (
-	JWTPurposeLogin JWTPurpose = "login"
-	JWTPurpose2FA   JWTPurpose = "2fa"
-	JWTPurposeNone  JWTPurpose = ""
+	JWTPurposeLogin       JWTPurpose = "login"
+	JWTPurpose2FA         JWTPurpose = "2fa"
+	JWTPurposeImpersonate JWTPurpose = "impersonate"
+	JWTPurposeNone        JWTPurpose = ""
 )
 
 func JWTGenerateToken(domain string, privateKey ed25519.PrivateKey, userID uint, purpose JWTPurpose, rememberMe bool) (string, error) {
@@ -66,6 +68,28 @@ func JWTGenerateTokenWithDuration(domain string, privateKey ed25519.PrivateKey,
 	return tokenString, nil
 }
 
+// JWTGenerateImpersonationToken generates a token that authenticates as userID on behalf of an admin.
+// The session ID is stored as the token ID so every use can be tied back to an audited impersonation session.
+func JWTGenerateImpersonationToken(domain string, privateKey ed25519.PrivateKey, userID uint, sessionID uint, duration time.Duration) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Issuer:    domain,
+		Subject:   strconv.Itoa(int(userID)),
+		ID:        strconv.Itoa(int(sessionID)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Audience:  []string{string(JWTPurposeImpersonate)},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+
+	tokenString, err := token.SignedString(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
 func JWTVerifyToken(token string, domain string, privateKey ed25519.PrivateKey, verifyFunc VerifyTokenFunc) (*jwt.RegisteredClaims, error) {
 	validatedToken, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+const BACKUP_SERVICE = "backup"
+
+var (
+	ErrBackupVersion = errors.New("unsupported backup version")
+	ErrBackupCorrupt = errors.New("backup archive is corrupt")
+	// ErrBackupSchema is returned when restoring a backup made after migrations this portal has not applied
+	ErrBackupSchema = errors.New("backup is of a newer schema than this portal")
+)
+
+// BackupManifest describes a backup archive, it is the first file of the archive.
+type BackupManifest struct {
+	Version   int       `json:"version"`
+	Portal    string    `json:"portal"`
+	Database  string    `json:"database"`
+	CreatedAt time.Time `json:"created_at"`
+	// Migrations lists the migrations applied to the database when it was backed up, by owner
+	Migrations map[string][]string `json:"migrations"`
+	Objects    int                 `json:"objects"`
+	// MissingObjects lists the uploads renterd had no object for when the backup was made, by hash
+	MissingObjects []string `json:"missing_objects,omitempty"`
+	// Files holds the SHA-256 digest of every other file of the archive
+	Files map[string]string `json:"files"`
+}
+
+type BackupRestoreOptions struct {
+	// SkipDatabase leaves the database as it is, for restoring the object metadata of a backup to a fresh renterd
+	SkipDatabase bool
+	// SkipObjects leaves renterd as it is
+	SkipObjects bool
+}
+
+type BackupService interface {
+	// CreateBackup writes an archive of a consistent dump of the database and of the renterd object metadata of
+	// every upload.
+	CreateBackup(ctx context.Context, w io.Writer) (*BackupManifest, error)
+
+	// RestoreBackup verifies an archive written by CreateBackup, then replaces the database with its dump and imports
+	// its object metadata into renterd. Objects that fail to import do not stop the restore, they are returned
+	// together once it is done.
+	RestoreBackup(ctx context.Context, r io.Reader, opts BackupRestoreOptions) (*BackupManifest, error)
+
+	Service
+}
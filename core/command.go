@@ -0,0 +1,116 @@
+package core
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CommandBoot is how much of the portal a command needs before it runs.
+type CommandBoot int
+
+const (
+	// CommandBootNone runs the command without loading the config.
+	CommandBootNone CommandBoot = iota
+	// CommandBootPortal loads the config of the core and every plugin and builds the service graph, without running
+	// any startup funcs.
+	CommandBootPortal
+	// CommandBootServices also runs the startup funcs, so services are ready to use. Cron, protocols and HTTP are never
+	// started for commands, and services start their background workers only once the portal boot completes, so a
+	// command does not dispatch outbox events, take in cluster events or run queued jobs while it works on the database.
+	CommandBootServices
+)
+
+// Command is an operator subcommand of the portal binary.
+type Command struct {
+	// Name is the command path with subcommands separated by spaces, e.g. "user create".
+	Name string
+	// Usage lists the positional arguments for the help output.
+	Usage       string
+	Description string
+	Boot        CommandBoot
+	// Flags registers the flags of the command, Run reads them through the closure that created both.
+	Flags func(fs *flag.FlagSet)
+	// Run gets the arguments left after the flags. It gets a nil context for CommandBootNone.
+	Run func(ctx Context, args []string) error
+}
+
+var (
+	commands   = make(map[string]Command)
+	commandsMu sync.RWMutex
+)
+
+func PluginHasCommands(plugin PluginInfo) bool {
+	return plugin.Commands != nil
+}
+
+func RegisterCommandsFromPlugins() {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	for _, plugin := range plugins {
+		if PluginHasCommands(plugin) {
+			for _, cmd := range plugin.Commands() {
+				RegisterCommand(cmd)
+			}
+		}
+	}
+}
+
+func RegisterCommand(cmd Command) {
+	name := strings.Join(strings.Fields(cmd.Name), " ")
+
+	if name == "" {
+		panic("command name must not be empty")
+	}
+
+	if cmd.Run == nil {
+		panic("command run func must not be nil")
+	}
+
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+
+	if _, ok := commands[name]; ok {
+		panic(fmt.Sprintf("command already registered: %s", name))
+	}
+
+	cmd.Name = name
+	commands[name] = cmd
+}
+
+// FindCommand returns the command with the longest name matching the start of args, and the args after its name.
+func FindCommand(args []string) (*Command, []string) {
+	commandsMu.RLock()
+	defer commandsMu.RUnlock()
+
+	for i := len(args); i > 0; i-- {
+		if cmd, ok := commands[strings.Join(args[:i], " ")]; ok {
+			return &cmd, args[i:]
+		}
+	}
+
+	return nil, args
+}
+
+// GetCommands returns the commands sorted by name, limited to those under prefix when it is not empty.
+func GetCommands(prefix string) []Command {
+	commandsMu.RLock()
+	defer commandsMu.RUnlock()
+
+	var list []Command
+
+	for name, cmd := range commands {
+		if prefix == "" || name == prefix || strings.HasPrefix(name, prefix+" ") {
+			list = append(list, cmd)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
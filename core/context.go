@@ -7,6 +7,7 @@ import (
 	"go.lumeweb.com/portal/config"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"time"
 )
 
 var _ Context = (*defaultContext)(nil)
@@ -31,6 +32,7 @@ type Context interface {
 	Cancel()
 	ExitCode() int
 	Event() *event.Manager
+	Metrics() *Metrics
 	SetExitCode(code int)
 	GetContext() context.Context
 }
@@ -47,6 +49,7 @@ type defaultContext struct {
 	db           *gorm.DB
 	cancel       context.CancelFunc
 	event        *event.Manager
+	metrics      *Metrics
 }
 
 // NewContext creates a new Context
@@ -60,6 +63,7 @@ func NewContext(config config.Manager, logger *Logger, options ...ContextBuilder
 		cfg:      config,
 		logger:   logger,
 		event:    event.NewManager(""),
+		metrics:  NewMetrics(),
 		cancel:   cancel,
 	}
 
@@ -86,6 +90,37 @@ func NewContext(config config.Manager, logger *Logger, options ...ContextBuilder
 	return newCtx, nil
 }
 
+// WithContext returns ctx with its cancellation, deadline and values taken from goCtx instead, so request scoped
+// values such as the active trace span reach code that only takes a Context.
+func WithContext(ctx Context, goCtx context.Context) Context {
+	return &scopedContext{Context: ctx, goCtx: goCtx}
+}
+
+type scopedContext struct {
+	Context
+	goCtx context.Context
+}
+
+func (ctx *scopedContext) Deadline() (time.Time, bool) {
+	return ctx.goCtx.Deadline()
+}
+
+func (ctx *scopedContext) Done() <-chan struct{} {
+	return ctx.goCtx.Done()
+}
+
+func (ctx *scopedContext) Err() error {
+	return ctx.goCtx.Err()
+}
+
+func (ctx *scopedContext) Value(key any) any {
+	return ctx.goCtx.Value(key)
+}
+
+func (ctx *scopedContext) GetContext() context.Context {
+	return ctx.goCtx
+}
+
 // Implement the Context interface methods for defaultContext
 
 func (ctx *defaultContext) Service(id string) any {
@@ -135,6 +170,10 @@ func (ctx *defaultContext) Event() *event.Manager {
 	return ctx.event
 }
 
+func (ctx *defaultContext) Metrics() *Metrics {
+	return ctx.metrics
+}
+
 func (ctx *defaultContext) SetExitCode(code int) {
 	ctx.exitCode = code
 }
@@ -212,6 +251,16 @@ func ContextWithDB(db *gorm.DB) ContextBuilderOption {
 	}
 }
 
+// ContextWithMetrics carries an existing registry over to a new context, collectors registered on it keep reporting
+func ContextWithMetrics(metrics *Metrics) ContextBuilderOption {
+	return func(ctx Context) (Context, error) {
+		if defaultCtx, ok := ctx.(*defaultContext); ok {
+			defaultCtx.metrics = metrics
+		}
+		return ctx, nil
+	}
+}
+
 func ContextWithCron(factory CronFactory) ContextBuilderOption {
 	return func(ctx Context) (Context, error) {
 		cron, err := factory(ctx)
@@ -1,10 +1,13 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/go-co-op/gocron/v2"
 	"github.com/google/uuid"
 	"go.lumeweb.com/portal/db/models"
+	"time"
 )
 
 type CronTaskFunction[T CronTaskArgs] func(T, Context) error
@@ -15,15 +18,86 @@ const CRON_SERVICE = "cron"
 
 type CronTaskArgs interface{}
 
+var (
+	ErrCronJobNotFound   = errors.New("cron job not found")
+	ErrCronJobProcessing = errors.New("cron job is processing")
+	ErrCronTaskInvalid   = errors.New("cron task is unknown")
+)
+
+type CronJobFilter struct {
+	Function string
+	State    models.CronJobState
+	// MinFailures only matches jobs that failed at least this many times since they last succeeded
+	MinFailures uint64
+	Limit       int
+	Offset      int
+}
+
+// CronTask is a task that jobs are run for, as known to this node or from the jobs and pauses in the database.
+type CronTask struct {
+	Name     string     `json:"name"`
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	Jobs     int64      `json:"jobs"`
+}
+
+// CronTaskBackoff is how long a failed job of a task waits before it is retried. The delay is scaled from Base,
+// doubling with each failure, and capped at Max.
+type CronTaskBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// CronTaskPriorityBackground is the priority of background tasks that can wait for user-facing jobs to run first,
+// such as cleanups
+const CronTaskPriorityBackground = -10
+
 type CronService interface {
 	RegisterEntity(entity Cronable)
 	RegisterTask(name string, taskFunc CronTaskFunction[CronTaskArgs], taskDefFunc CronTaskDefArgsFactoryFunction, taskArgFunc CronTaskArgsFactoryFunction, recurring bool)
+	// SetTaskBackoff replaces the retry delays of a task, for tasks whose failures need time to clear up, such as
+	// a remote server being down
+	SetTaskBackoff(name string, backoff CronTaskBackoff)
+	// SetTaskPriority sets the priority a task has unless one is configured for it, jobs of higher priority are run
+	// first. Tasks default to 0.
+	SetTaskPriority(name string, priority int)
 	CreateJob(function string, args any) error
+	// CreateJobWithContext creates the job as part of the trace carried by ctx, its runs are traced as children of it
+	CreateJobWithContext(ctx context.Context, function string, args any) error
 	JobExists(function string, args any) (bool, *models.CronJob)
 	CreateJobScheduled(function string, args any) error
 	CreateExistingJobScheduled(uuid uuid.UUID) error
 	CreateJobIfNotExists(function string, args any) error
 
+	// ListJobs retrieves the jobs matching the given filter along with the total number of matches.
+	ListJobs(filter CronJobFilter) ([]*models.CronJob, int64, error)
+
+	// RetryJob clears the failures of a job that is not processing so it runs without backoff. It runs right away
+	// when this node can run it, otherwise it is picked up the next time cron starts.
+	RetryJob(id uuid.UUID) error
+
+	// GetJob retrieves the job with the given ID.
+	GetJob(id uuid.UUID) (*models.CronJob, error)
+
+	// ListJobLogs retrieves the failure logs of a job, newest first, along with their total number. Logs are kept
+	// after their job is cancelled or completes.
+	ListJobLogs(id uuid.UUID, limit int, offset int) ([]*models.CronJobLog, int64, error)
+
+	// CancelJob deletes a job, along with any run of it already scheduled on this node. A run in progress has its
+	// context cancelled, right away on this node and within a few seconds on others.
+	CancelJob(id uuid.UUID) error
+
+	// ListTasks retrieves every task with the number of its jobs and whether it is paused, sorted by name.
+	ListTasks() ([]*CronTask, error)
+
+	// PauseTask holds back the jobs of a task, on every node, until it is resumed. The task must be registered or have
+	// jobs, otherwise ErrCronTaskInvalid is returned. Runs in progress are not interrupted, jobs coming up while it is
+	// paused are checked again every minute.
+	PauseTask(function string) error
+
+	// ResumeTask lets the jobs of a paused task run again.
+	ResumeTask(function string) error
+
 	Start() error
 	Service
 }
@@ -42,6 +116,10 @@ func CronTaskDefinitionDaily() gocron.JobDefinition {
 	return gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(0, 0, 0)))
 }
 
+func CronTaskDefinitionHourly() gocron.JobDefinition {
+	return gocron.DurationJob(time.Hour)
+}
+
 func CronTaskNoArgsFactory() any {
 	return &CronTaskNoArgs{}
 }
@@ -0,0 +1,22 @@
+package core
+
+import "go.lumeweb.com/portal/db/models"
+
+const DATA_EXPORT_SERVICE = "data_export"
+
+type DataExportService interface {
+	// RequestExport schedules an export of all data held for the user with the given ID.
+	// The archive is built in the background and a download link is emailed once it is ready.
+	RequestExport(userId uint) (*models.DataExport, error)
+
+	// ListExports returns the exports requested by the user with the given ID, newest first.
+	ListExports(userId uint) ([]*models.DataExport, error)
+
+	// ExportInProgress checks if an export for the user with the given ID is pending or being built.
+	ExportInProgress(userId uint) (bool, error)
+
+	// BuildExport assembles, signs and uploads the archive of the given export and emails the download link.
+	BuildExport(exportId uint) error
+
+	Service
+}
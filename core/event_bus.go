@@ -0,0 +1,31 @@
+package core
+
+import (
+	"context"
+	"github.com/gookit/event"
+)
+
+const EVENT_BUS_SERVICE = "event_bus"
+
+// EVENT_ORIGIN_NODE_KEY is the event data key holding the ID of the node an event received from the cluster was fired on.
+const EVENT_ORIGIN_NODE_KEY = "origin_node"
+
+// ClusterEventName is the name events received from other nodes are fired under locally. Only listeners subscribed
+// cluster-wide listen on it, so local-only listeners never see an event twice or handle another node's work.
+func ClusterEventName(name string) string {
+	return "cluster." + name
+}
+
+type EventBusService interface {
+	// Publish sends the event to the other nodes of the cluster if any listener subscribed to it cluster-wide.
+	// It does nothing when the portal is not clustered. The trace carried by ctx continues on the receiving nodes.
+	Publish(ctx context.Context, evt event.Event) error
+
+	// Subscribe marks the event as having cluster-wide listeners, so it is published when fired.
+	Subscribe(eventName string)
+
+	// Enabled reports whether events are exchanged with other nodes.
+	Enabled() bool
+
+	Service
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	eventDataTypes   = make(map[string]reflect.Type)
+	eventDataTypesMu sync.RWMutex
+)
+
+func init() {
+	for _, v := range []any{"", false, int(0), int64(0), uint(0), uint64(0), float64(0), time.Time{}, &time.Time{}, time.Duration(0)} {
+		RegisterEventDataType(v)
+	}
+}
+
+// eventDataValue is a single event data value along with the name of its registered type
+type eventDataValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// RegisterEventDataType allows values of the type of v to be carried as event data when events leave the process,
+// through the outbox or the cluster event bus. Data is serialized as JSON, so the concrete type is needed to restore
+// it for typed listeners.
+func RegisterEventDataType(v any) {
+	typ := reflect.TypeOf(v)
+
+	eventDataTypesMu.Lock()
+	defer eventDataTypesMu.Unlock()
+
+	eventDataTypes[eventDataTypeName(typ)] = typ
+}
+
+// EncodeEventData serializes event data, failing if a value is of an unregistered type. Nil values are dropped.
+func EncodeEventData(data map[string]any) ([]byte, error) {
+	values := make(map[string]eventDataValue, len(data))
+
+	for key, value := range data {
+		if value == nil {
+			continue
+		}
+
+		typeName := eventDataTypeName(reflect.TypeOf(value))
+		if _, err := getEventDataType(typeName); err != nil {
+			return nil, fmt.Errorf("event data %s: %w", key, err)
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("event data %s: %w", key, err)
+		}
+
+		values[key] = eventDataValue{Type: typeName, Value: encoded}
+	}
+
+	return json.Marshal(values)
+}
+
+// DecodeEventData restores event data serialized by EncodeEventData.
+func DecodeEventData(encoded []byte) (map[string]any, error) {
+	var values map[string]eventDataValue
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]any, len(values))
+
+	for key, value := range values {
+		typ, err := getEventDataType(value.Type)
+		if err != nil {
+			return nil, fmt.Errorf("event data %s: %w", key, err)
+		}
+
+		decoded := reflect.New(typ)
+		if err := json.Unmarshal(value.Value, decoded.Interface()); err != nil {
+			return nil, fmt.Errorf("event data %s: %w", key, err)
+		}
+
+		data[key] = decoded.Elem().Interface()
+	}
+
+	return data, nil
+}
+
+func getEventDataType(name string) (reflect.Type, error) {
+	eventDataTypesMu.RLock()
+	defer eventDataTypesMu.RUnlock()
+
+	typ, ok := eventDataTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("event data type %s is not registered", name)
+	}
+
+	return typ, nil
+}
+
+// eventDataTypeName returns the fully qualified name of the type, including pointer indirections
+func eventDataTypeName(typ reflect.Type) string {
+	prefix := ""
+	for typ.Kind() == reflect.Pointer {
+		prefix += "*"
+		typ = typ.Elem()
+	}
+
+	if typ.PkgPath() == "" {
+		return prefix + typ.Name()
+	}
+
+	return prefix + typ.PkgPath() + "." + typ.Name()
+}
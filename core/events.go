@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"github.com/gookit/event"
+	"reflect"
 	"sort"
 	"sync"
 )
@@ -148,3 +149,28 @@ func GetEvents() []Eventer {
 
 	return events
 }
+
+// NewEvent returns a new, empty instance of the event registered under the given name.
+func NewEvent(id string) (Eventer, error) {
+	eventRegistryMutex.RLock()
+	registered, ok := eventRegistry[id]
+	eventRegistryMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("event %s not registered", id)
+	}
+
+	typ := reflect.TypeOf(registered)
+	if typ.Kind() != reflect.Pointer {
+		return nil, fmt.Errorf("event %s is not registered as a pointer", id)
+	}
+
+	evt, ok := reflect.New(typ.Elem()).Interface().(Eventer)
+	if !ok {
+		return nil, fmt.Errorf("event %s does not implement Eventer", id)
+	}
+
+	evt.SetName(id)
+
+	return evt, nil
+}
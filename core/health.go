@@ -0,0 +1,53 @@
+package core
+
+import "context"
+
+const HEALTH_SERVICE = "health"
+
+type HealthStatus string
+
+const (
+	HealthStatusOK   HealthStatus = "ok"
+	HealthStatusFail HealthStatus = "fail"
+	// HealthStatusDegraded is reported when only optional checks fail, the node still takes traffic
+	HealthStatusDegraded HealthStatus = "degraded"
+)
+
+// HealthCheck is a single named check of something the node depends on.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Optional checks are reported without failing readiness, for dependencies only some requests need
+	Optional bool
+}
+
+// HealthChecker is implemented by services, including those of plugins, whose dependencies decide whether the node
+// can take traffic. The health service runs the checks of every service implementing it on each readiness probe.
+type HealthChecker interface {
+	HealthChecks() []HealthCheck
+}
+
+type HealthCheckResult struct {
+	Name     string       `json:"name"`
+	Status   HealthStatus `json:"status"`
+	Optional bool         `json:"optional,omitempty"`
+	// Latency is in milliseconds
+	Latency int64  `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Status HealthStatus        `json:"status"`
+	Checks []HealthCheckResult `json:"checks,omitempty"`
+}
+
+type HealthService interface {
+	// Live reports whether the process is up. It runs no checks, a failing dependency is not a reason to restart.
+	Live() HealthReport
+
+	// Ready runs all health checks and reports whether the node can take traffic, which only failing required
+	// checks prevent. It always fails once the portal is shutting down.
+	Ready(ctx context.Context) HealthReport
+
+	Service
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"go.lumeweb.com/portal/db/models"
+	"time"
+)
+
+const INVITE_SERVICE = "invite"
+
+type InviteService interface {
+	// CreateInvite creates a new registration invite code issued by the given user.
+	// Admins may issue any number of invites, other users are limited by the configured invite quota.
+	// A maxUses of 0 allows unlimited uses and a nil expiry never expires.
+	CreateInvite(creatorId uint, maxUses uint, expiresAt *time.Time) (*models.InviteCode, error)
+
+	// ListInvites retrieves the invite codes issued by the given user.
+	ListInvites(creatorId uint) ([]*models.InviteCode, error)
+
+	// RevokeInvite deletes an invite code issued by the given user. Admins may revoke any invite code.
+	RevokeInvite(creatorId uint, code string) error
+
+	// ValidateInvite checks if an invite code exists, has not expired and has uses left.
+	ValidateInvite(code string) error
+
+	// RedeemInvite consumes one use of an invite code.
+	RedeemInvite(code string) error
+
+	// ReleaseInvite returns a use of an invite code that was redeemed for a registration that failed.
+	ReleaseInvite(code string) error
+
+	Service
+}
@@ -14,6 +14,11 @@ type Logger struct {
 }
 
 func NewLogger(cm config.Manager) *Logger {
+	return NewLoggerWithOutput(cm, os.Stdout)
+}
+
+// NewLoggerWithOutput creates a logger writing to out, which lets operator commands keep stdout for their own output.
+func NewLoggerWithOutput(cm config.Manager, out zapcore.WriteSyncer) *Logger {
 	// Create a new atomic level
 	atomicLevel := zap.NewAtomicLevel()
 
@@ -27,7 +32,7 @@ func NewLogger(cm config.Manager) *Logger {
 	// Create the logger with the atomic level
 	zapLogger := zap.New(zapcore.NewCore(
 		zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
-		zapcore.Lock(os.Stdout),
+		zapcore.Lock(out),
 		atomicLevel,
 	), zap.AddCaller())
 
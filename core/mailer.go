@@ -8,6 +8,8 @@ const MAILER_SERVICE = "mailer"
 
 const MAILER_TPL_PASSWORD_RESET = "password_reset"
 const MAILER_TPL_VERIFY_EMAIL = "verify_email"
+const MAILER_TPL_ORG_INVITE = "org_invite"
+const MAILER_TPL_DATA_EXPORT = "data_export"
 
 type MailerTemplateData = map[string]any
 
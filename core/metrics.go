@@ -0,0 +1,140 @@
+package core
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"time"
+)
+
+const (
+	METRICS_NAMESPACE = "portal"
+
+	METRICS_DIRECTION_UPLOAD   = "upload"
+	METRICS_DIRECTION_DOWNLOAD = "download"
+)
+
+// Metrics holds the portal's Prometheus registry along with the collectors shared between subsystems.
+// Plugins that need their own collectors register them on Registry().
+type Metrics struct {
+	registry *prometheus.Registry
+
+	HTTPRequestDuration  *prometheus.HistogramVec
+	TransferBytes        *prometheus.CounterVec
+	TransferDuration     *prometheus.HistogramVec
+	TUSActiveUploads     *prometheus.GaugeVec
+	CronJobFailures      *prometheus.CounterVec
+	DBQueryDuration      *prometheus.HistogramVec
+	DBCacheRequests      *prometheus.CounterVec
+	DBCacheInvalidations *prometheus.CounterVec
+	RenterCallDuration   *prometheus.HistogramVec
+	RenterCallErrors     *prometheus.CounterVec
+	SiaRate              *prometheus.GaugeVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by API subdomain, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"subdomain", "method", "status"}),
+		TransferBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "transfer",
+			Name:      "bytes_total",
+			Help:      "Bytes uploaded and downloaded by protocol.",
+		}, []string{"direction", "protocol"}),
+		TransferDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "transfer",
+			Name:      "duration_seconds",
+			Help:      "Duration of completed uploads and downloads by protocol.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 4, 10),
+		}, []string{"direction", "protocol"}),
+		TUSActiveUploads: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "tus",
+			Name:      "active_uploads",
+			Help:      "TUS uploads created on this node that have not completed or been terminated.",
+		}, []string{"protocol"}),
+		CronJobFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "cron",
+			Name:      "job_failures_total",
+			Help:      "Failed cron job runs by task.",
+		}, []string{"task"}),
+		DBQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Database query latency.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 10),
+		}, []string{"status"}),
+		DBCacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "db",
+			Name:      "cache_requests_total",
+			Help:      "Query cache lookups by table and result, hit or miss.",
+		}, []string{"table", "result"}),
+		DBCacheInvalidations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "db",
+			Name:      "cache_invalidations_total",
+			Help:      "Query cache invalidations by table and origin, this node or another.",
+		}, []string{"table", "origin"}),
+		RenterCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "renter",
+			Name:      "call_duration_seconds",
+			Help:      "Latency of calls to renterd by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		RenterCallErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "renter",
+			Name:      "call_errors_total",
+			Help:      "Failed calls to renterd by operation.",
+		}, []string{"operation"}),
+		SiaRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "price_tracker",
+			Name:      "siacoin_rate",
+			Help:      "Siacoin exchange rate tracked for pricing, as the latest and the averaged rate.",
+		}, []string{"currency", "type"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequestDuration,
+		m.TransferBytes,
+		m.TransferDuration,
+		m.TUSActiveUploads,
+		m.CronJobFailures,
+		m.DBQueryDuration,
+		m.DBCacheRequests,
+		m.DBCacheInvalidations,
+		m.RenterCallDuration,
+		m.RenterCallErrors,
+		m.SiaRate,
+	)
+
+	return m
+}
+
+// Registry returns the registry served on /metrics.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// ObserveTransfer records a completed upload or download. A zero duration records the bytes only.
+func (m *Metrics) ObserveTransfer(direction string, protocol string, bytes uint64, duration time.Duration) {
+	m.TransferBytes.WithLabelValues(direction, protocol).Add(float64(bytes))
+
+	if duration > 0 {
+		m.TransferDuration.WithLabelValues(direction, protocol).Observe(duration.Seconds())
+	}
+}
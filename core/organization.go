@@ -0,0 +1,87 @@
+package core
+
+import (
+	"go.lumeweb.com/portal/db/models"
+)
+
+const ORGANIZATION_SERVICE = "organization"
+
+type OrganizationRole string
+
+const (
+	ORG_ROLE_OWNER     OrganizationRole = "owner"
+	ORG_ROLE_ADMIN     OrganizationRole = "admin"
+	ORG_ROLE_MEMBER    OrganizationRole = "member"
+	ORG_ROLE_READ_ONLY OrganizationRole = "read_only"
+)
+
+// Objects and actions used for organization scoped access policies
+const (
+	ORG_OBJECT_PINS     = "pins"
+	ORG_OBJECT_MEMBERS  = "members"
+	ORG_OBJECT_SETTINGS = "settings"
+	ORG_OBJECT_BILLING  = "billing"
+
+	ORG_ACTION_READ  = "read"
+	ORG_ACTION_WRITE = "write"
+)
+
+// Valid reports whether the role is one of the known organization roles.
+func (r OrganizationRole) Valid() bool {
+	switch r {
+	case ORG_ROLE_OWNER, ORG_ROLE_ADMIN, ORG_ROLE_MEMBER, ORG_ROLE_READ_ONLY:
+		return true
+	}
+
+	return false
+}
+
+type OrganizationService interface {
+	// CreateOrganization creates a new organization owned by the given user.
+	CreateOrganization(ownerId uint, name string) (*models.Organization, error)
+
+	// GetOrganization retrieves an organization by ID.
+	GetOrganization(orgId uint) (*models.Organization, error)
+
+	// ListUserOrganizations retrieves all organizations the user is a member of.
+	ListUserOrganizations(userId uint) ([]*models.Organization, error)
+
+	// UpdateOrganization updates the given fields of an organization.
+	UpdateOrganization(orgId uint, updates map[string]any) error
+
+	// DeleteOrganization deletes an organization, its memberships and its access policies.
+	// Pins owned by the organization are returned to their creators.
+	DeleteOrganization(orgId uint) error
+
+	// AddMember adds a user to an organization with the given role.
+	AddMember(orgId uint, userId uint, role OrganizationRole) error
+
+	// UpdateMemberRole changes the role of an existing member.
+	UpdateMemberRole(orgId uint, userId uint, role OrganizationRole) error
+
+	// RemoveMember removes a user from an organization.
+	RemoveMember(orgId uint, userId uint) error
+
+	// ListMembers retrieves all members of an organization.
+	ListMembers(orgId uint) ([]*models.OrganizationMember, error)
+
+	// GetMemberRole retrieves the role of a user within an organization.
+	GetMemberRole(orgId uint, userId uint) (OrganizationRole, error)
+
+	// InviteMember creates an invite for the given email and sends it via the mailer.
+	InviteMember(orgId uint, inviterId uint, email string, role OrganizationRole) (*models.OrganizationInvite, error)
+
+	// AcceptInvite accepts an invite on behalf of the given user, adding them as a member.
+	AcceptInvite(token string, userId uint) (*models.OrganizationMember, error)
+
+	// CheckAccess checks if a user may perform an action on an object within an organization.
+	CheckAccess(userId uint, orgId uint, obj, act string) (bool, error)
+
+	// OrganizationUsage returns the total size in bytes of all uploads pinned by the organization.
+	OrganizationUsage(orgId uint) (uint64, error)
+
+	// QuotaExceeded checks if adding the given number of bytes would exceed the organization quota.
+	QuotaExceeded(orgId uint, additional uint64) (bool, error)
+
+	Service
+}
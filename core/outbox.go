@@ -0,0 +1,28 @@
+package core
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const OUTBOX_SERVICE = "outbox"
+
+// OUTBOX_EVENT_ID_KEY is the event data key holding the idempotency key of an event delivered through the outbox.
+// Listeners with side effects should ignore keys they have already processed, delivery is at-least-once.
+const OUTBOX_EVENT_ID_KEY = "outbox_event_id"
+
+type OutboxService interface {
+	// Enqueue persists the event with the given data in the transaction, so it is only delivered if the transaction commits.
+	// Every value must be of a type registered with RegisterEventDataType.
+	Enqueue(tx *gorm.DB, eventName string, data map[string]any) error
+
+	// Dispatch delivers a batch of pending events to their listeners and returns the number delivered.
+	Dispatch() (int, error)
+
+	// Replay marks the events created within the time range as pending so they are delivered again, including those
+	// set aside after failing every attempt.
+	// It returns the number of events that will be replayed.
+	Replay(from time.Time, to time.Time) (int64, error)
+
+	Service
+}
@@ -9,14 +9,17 @@ import (
 const PIN_SERVICE = "pin"
 
 type PinService interface {
-	// AccountPins retrieves the list of pins (uploads) for the given user ID,
+	// AccountPins retrieves the list of personal pins (uploads) for the given user ID,
 	// created after the specified timestamp.
 	AccountPins(id uint, createdAfter uint64) ([]*models.Pin, error)
 
-	// AllAccountPins retrieves all pins (uploads) for the given user ID.
+	// AllAccountPins retrieves all personal pins (uploads) for the given user ID, organization pins are left out.
 	AllAccountPins(id uint) ([]*models.Pin, error)
 
-	// DeletePinByHash deletes the pin associated with the given hash and user ID.
+	// OrganizationPins retrieves all pins (uploads) owned by the given organization ID.
+	OrganizationPins(orgId uint) ([]*models.Pin, error)
+
+	// DeletePinByHash deletes the personal pin associated with the given hash and user ID.
 	DeletePinByHash(hash StorageHash, userId uint) error
 
 	// PinByHash creates a new pin for the given hash and user ID if it doesn't exist.
@@ -25,10 +28,23 @@ type PinService interface {
 	// PinByID creates a new pin for the given upload ID and user ID if it doesn't exist.
 	PinByID(uploadId uint, userId uint, protocolData any) error
 
+	// OrganizationPinByHash creates a new pin for the given hash on behalf of the organization if it doesn't exist.
+	// The user must be allowed to write the organization pins and the upload must fit in its quota.
+	OrganizationPinByHash(hash StorageHash, userId uint, orgId uint, protocolData any) error
+
+	// OrganizationPinByID creates a new pin for the given upload ID on behalf of the organization if it doesn't exist.
+	// Uploads requested for an organization are pinned through it.
+	OrganizationPinByID(uploadId uint, userId uint, orgId uint, protocolData any) error
+
 	// UploadPinnedGlobal checks if the upload with the given hash is pinned globally.
 	UploadPinnedGlobal(hash StorageHash) (bool, error)
 
-	// UploadPinnedByUser checks if the upload with the given hash is pinned by the specified user.
+	// UploadFrozen checks if every pin of the upload with the given hash belongs to a suspended account
+	// with frozen pins. Download handlers should refuse to serve frozen uploads.
+	UploadFrozen(hash StorageHash) (bool, error)
+
+	// UploadPinnedByUser checks if the upload with the given hash is pinned by the specified user personally, or by
+	// anyone when userId is 0.
 	UploadPinnedByUser(hash StorageHash, userId uint) (bool, error)
 
 	// GetPinsByUploadID retrieves the list of pins for the given upload ID.
@@ -62,7 +78,10 @@ type PinService interface {
 }
 
 type PinFilter struct {
-	UserID       uint
+	UserID         uint
+	OrganizationID uint
+	// PersonalOnly leaves out organization pins, which carry the ID of the member who created them as well
+	PersonalOnly bool
 	UploadID     uint
 	Hash         StorageHash
 	CreatedAfter time.Time
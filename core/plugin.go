@@ -18,6 +18,19 @@ type MailerTemplates map[string]MailerTemplate
 
 type DBMigration func(*gorm.DB) error
 
+// Migration is a versioned change to the schema of the core or a plugin. Migrations of an owner are applied in order of
+// their IDs, so IDs are best prefixed with a zero padded sequence number, e.g. "0001_add_upload_index". Models are
+// migrated as a whole the first time an owner is seen and every migration is then recorded as applied, so a migration
+// is written against the schema of the release before it, and a model added later needs a migration creating its table.
+type Migration struct {
+	ID   string
+	Up   DBMigration
+	Down DBMigration
+}
+
+// DataExportFunc returns the plugin section of a user data export. The result is encoded as JSON.
+type DataExportFunc func(ctx Context, userId uint) (any, error)
+
 type PluginInfo struct {
 	ID              string
 	Meta            func(Context, PortalMetaBuilder) error
@@ -25,11 +38,13 @@ type PluginInfo struct {
 	Protocol        func() (Protocol, []ContextBuilderOption, error)
 	Services        func() ([]ServiceInfo, error)
 	Models          []any
-	Migrations      []DBMigration
+	Migrations      []Migration
 	Events          []Eventer
 	Depends         []string
 	Cron            func() CronFactory
 	MailerTemplates MailerTemplates
+	DataExport      DataExportFunc
+	Commands        func() []Command
 }
 
 type Configurable interface {
@@ -56,8 +71,8 @@ func RegisterPlugin(info PluginInfo) {
 		panic("plugin ID must not be empty")
 	}
 
-	if info.API == nil && info.Protocol == nil && info.Services == nil {
-		panic("plugin must have at least one of GetAPI, GetProtocol, or GetServices")
+	if info.API == nil && info.Protocol == nil && info.Services == nil && info.Commands == nil {
+		panic("plugin must have at least one of GetAPI, GetProtocol, GetServices, or Commands")
 	}
 
 	pluginsMu.Lock()
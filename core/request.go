@@ -49,9 +49,10 @@ type RequestService interface {
 }
 
 type RequestFilter struct {
-	Protocol  string
-	Operation models.RequestOperationType
-	UserID    uint
-	Limit     int
-	Offset    int
+	Protocol       string
+	Operation      models.RequestOperationType
+	UserID         uint
+	OrganizationID uint
+	Limit          int
+	Offset         int
 }
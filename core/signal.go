@@ -5,4 +5,5 @@ const (
 	ExitCodeFailedStartup
 	ExitCodeForceQuit
 	ExitCodeFailedQuit
+	ExitCodeFailedCommand
 )
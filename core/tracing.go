@@ -0,0 +1,41 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const TRACER_NAME = "go.lumeweb.com/portal"
+
+// Tracer returns the portal's tracer. Until tracing is set up, and when it is disabled, it records nothing.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TRACER_NAME)
+}
+
+// StartSpan starts a span as a child of any span carried by ctx. The returned func ends the span and records *err
+// on it when set, so it is meant to be deferred with a named error result.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(err *error)) {
+	ctx, span := Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+
+	return ctx, func(err *error) {
+		if err != nil {
+			RecordSpanError(span, *err)
+		}
+		span.End()
+	}
+}
+
+// RecordSpanError marks the span as failed. A record that was not found is an answer rather than a failure.
+func RecordSpanError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
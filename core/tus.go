@@ -12,6 +12,8 @@ type TUSService interface {
 	UploadHashExists(ctx context.Context, hash StorageHash) (bool, *models.TUSRequest)
 	Uploads(ctx context.Context, uploaderID uint) ([]*models.TUSRequest, error)
 	CreateUpload(ctx context.Context, hash StorageHash, uploadID string, uploaderID uint, uploaderIP string, protocol StorageProtocol, mimeType string) (*models.TUSRequest, error)
+	// CreateOrganizationUpload creates an upload on behalf of the organization, the uploader must be allowed to write its pins
+	CreateOrganizationUpload(ctx context.Context, hash StorageHash, uploadID string, uploaderID uint, orgID uint, uploaderIP string, protocol StorageProtocol, mimeType string) (*models.TUSRequest, error)
 	UploadProgress(ctx context.Context, uploadID string) error
 	UploadProcessing(ctx context.Context, uploadID string) error
 	UploadCompleted(ctx context.Context, uploadID string) error
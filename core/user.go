@@ -2,6 +2,7 @@ package core
 
 import (
 	"go.lumeweb.com/portal/db/models"
+	"time"
 )
 
 const USER_SERVICE = "user"
@@ -19,12 +20,35 @@ type UserService interface {
 	// AccountExists checks if an account with the given ID exists.
 	AccountExists(id uint) (bool, *models.User, error)
 
-	// HashPassword hashes the provided password using bcrypt.
+	// HashPassword checks the provided password against the password policy and hashes it using the configured algorithm.
 	HashPassword(password string) (string, error)
 
+	// HashUserPassword checks the provided password against the password policy, including the rules on the
+	// email address and names of the given user, and hashes it using the configured algorithm.
+	HashUserPassword(password string, user *models.User) (string, error)
+
+	// VerifyPassword checks if the provided password matches the stored hash of the given user, whatever algorithm produced it.
+	VerifyPassword(user *models.User, password string) bool
+
+	// UpgradePasswordHash re-hashes the already verified password of the given user when the stored hash
+	// uses an outdated algorithm or parameters.
+	UpgradePasswordHash(user *models.User, password string) error
+
+	// ValidatePassword checks the provided password against the password policy, including the rules on the
+	// email address and names of the given user if not nil. Violations are reported as a single error listing each failed rule.
+	ValidatePassword(password string, user *models.User) error
+
 	// CreateAccount creates a new user account with the given email and password.
+	// It is subject to the configured registration mode and email domain policy.
 	CreateAccount(email string, password string, verifyEmail bool) (*models.User, error)
 
+	// CreateAccountWithInvite creates a new user account, redeeming the invite code when registration is invite-only.
+	CreateAccountWithInvite(email string, password string, inviteCode string, verifyEmail bool) (*models.User, error)
+
+	// CreateAccountUnrestricted creates a new user account regardless of the registration mode and email domain
+	// policy, for operators. The password policy still applies.
+	CreateAccountUnrestricted(email string, password string, verifyEmail bool) (*models.User, error)
+
 	// UpdateAccountInfo updates the account information of the user with the given ID.
 	UpdateAccountInfo(userId uint, info map[string]any) error
 
@@ -57,9 +81,26 @@ type UserService interface {
 	// RequestAccountDeletion requests the deletion of the account of the user with the given ID.
 	RequestAccountDeletion(userId uint, userIP string) error
 
+	// CancelAccountDeletion cancels a pending deletion request for the account of the user with the given ID.
+	CancelAccountDeletion(userId uint) error
+
 	// IsAccountPendingDeletion checks if the account deletion is pending for the user with the given ID.
 	IsAccountPendingDeletion(userId uint) (bool, error)
 
+	// SuspendAccount suspends the account of the user with the given ID on behalf of the actor.
+	// A nil expiry bans the account until the suspension is lifted. When freezePins is set,
+	// uploads only pinned by suspended accounts should no longer be served.
+	SuspendAccount(userId uint, actorId uint, reason string, expiresAt *time.Time, freezePins bool) (*models.AccountSuspension, error)
+
+	// LiftAccountSuspension lifts the active suspension of the user with the given ID on behalf of the actor.
+	LiftAccountSuspension(userId uint, actorId uint) error
+
+	// GetAccountSuspension returns the active suspension of the user with the given ID, or nil if there is none.
+	GetAccountSuspension(userId uint) (*models.AccountSuspension, error)
+
+	// IsAccountSuspended checks if the account of the user with the given ID is currently suspended.
+	IsAccountSuspended(userId uint) (bool, error)
+
 	// GetAccountsPendingDeletion returns a list of accounts that are pending deletion.
 	GetAccountsPendingDeletion() ([]*models.User, error)
 
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"github.com/gookit/event"
+	"go.lumeweb.com/portal/db/models"
+	"sort"
+	"sync"
+)
+
+const WEBHOOK_SERVICE = "webhook"
+
+// WEBHOOK_EVENT_PING is delivered by test pings only and cannot be subscribed to
+const WEBHOOK_EVENT_PING = "webhook.ping"
+
+// WEBHOOK_EVENT_ALL subscribes an endpoint to every webhook event
+const WEBHOOK_EVENT_ALL = "*"
+
+// WebhookPayloadFunc converts a fired event into the payload delivered to webhooks. It returns the ID of the
+// user the event concerns, or 0 for portal wide events that only reach admin-level endpoints.
+type WebhookPayloadFunc func(evt event.Event) (userId uint, payload any, err error)
+
+var (
+	webhookEvents   = make(map[string]WebhookPayloadFunc)
+	webhookEventsMu sync.RWMutex
+)
+
+// RegisterWebhookEvent makes a registered event available to webhooks. Events carry models that are not safe to
+// publish as is, so every event needs a payload function choosing what leaves the portal.
+func RegisterWebhookEvent(name string, payload WebhookPayloadFunc) {
+	webhookEventsMu.Lock()
+	defer webhookEventsMu.Unlock()
+
+	if _, ok := webhookEvents[name]; ok {
+		panic(fmt.Sprintf("webhook event %s already registered", name))
+	}
+
+	webhookEvents[name] = payload
+}
+
+func GetWebhookEvent(name string) (WebhookPayloadFunc, bool) {
+	webhookEventsMu.RLock()
+	defer webhookEventsMu.RUnlock()
+
+	payload, ok := webhookEvents[name]
+
+	return payload, ok
+}
+
+func GetWebhookEvents() []string {
+	webhookEventsMu.RLock()
+	defer webhookEventsMu.RUnlock()
+
+	names := make([]string, 0, len(webhookEvents))
+	for name := range webhookEvents {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+type WebhookService interface {
+	// CreateEndpoint registers a webhook endpoint for the user with the given ID, or an admin-level endpoint
+	// receiving events of all users when userId is nil. A signing secret is generated for the endpoint.
+	CreateEndpoint(userId *uint, url string, events []string, description string) (*models.WebhookEndpoint, error)
+
+	// UpdateEndpoint updates the target, event filter, description and state of the endpoint with the given ID.
+	UpdateEndpoint(id uint, url string, events []string, description string, enabled bool) (*models.WebhookEndpoint, error)
+
+	// GetEndpoint returns the endpoint with the given ID.
+	GetEndpoint(id uint) (*models.WebhookEndpoint, error)
+
+	// ListEndpoints returns the endpoints of the user with the given ID, or the admin-level endpoints when userId is nil.
+	ListEndpoints(userId *uint) ([]*models.WebhookEndpoint, error)
+
+	// DeleteEndpoint deletes the endpoint with the given ID along with its pending deliveries.
+	DeleteEndpoint(id uint) error
+
+	// ListDeliveries returns the delivery log of the endpoint with the given ID, newest first.
+	ListDeliveries(endpointId uint, limit int, offset int) ([]*models.WebhookDelivery, error)
+
+	// Ping synchronously delivers a test event to the endpoint with the given ID and returns the logged delivery.
+	Ping(endpointId uint) (*models.WebhookDelivery, error)
+
+	// Dispatch queues a delivery of the payload to every enabled endpoint subscribed to the event that may see it.
+	Dispatch(eventName string, userId uint, payload any) error
+
+	// Deliver makes a single attempt to deliver the delivery with the given ID.
+	Deliver(deliveryId uint) error
+
+	Service
+}
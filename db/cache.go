@@ -0,0 +1,417 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-gorm/caches/v4"
+	"github.com/redis/go-redis/v9"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	cacheInvalidateChannel = "portal:db:cache:invalidate"
+	cacheSkipSetting       = "portal:cache_skip"
+
+	// cacheAllTables is part of every cache key, it is invalidated by writes whose tables can not be told
+	cacheAllTables = "*"
+
+	cacheResultHit  = "hit"
+	cacheResultMiss = "miss"
+
+	cacheOriginLocal  = "local"
+	cacheOriginRemote = "remote"
+)
+
+// tableCacher is a query cache whose entries are scoped to the tables they were read from. Every table has a
+// generation that is part of the keys of the results read from it, so invalidating a table is bumping its generation
+// and its stale entries are left to expire.
+type tableCacher interface {
+	caches.Cacher
+	generations(ctx context.Context, tables []string) ([]uint64, error)
+	invalidateTables(ctx context.Context, tables []string) error
+}
+
+type cacheScopeKey struct{}
+
+// cacheScope is attached to the context of a cacheable query, its tables end with cacheAllTables. The generations are
+// taken when the result is looked up, so a result read while its tables are written to is stored under the
+// generations it is already stale for.
+type cacheScope struct {
+	tables      []string
+	replica     bool
+	generations []uint64
+}
+
+// Uncached returns a session whose queries bypass the query cache, for reads that must see the database as it is, such
+// as those of a transaction reading a snapshot of it
+func Uncached(db *gorm.DB) *gorm.DB {
+	return db.Set(cacheSkipSetting, true).Session(&gorm.Session{})
+}
+
+type cacheInvalidation struct {
+	Node   string   `json:"node"`
+	Tables []string `json:"tables"`
+}
+
+// cacheInvalidator registers the callbacks scoping queries to their tables and invalidating the tables that are
+// written to. With a memory cache on a cluster, invalidations are published to the other nodes over redis.
+type cacheInvalidator struct {
+	cacher  tableCacher
+	metrics *core.Metrics
+	logger  *core.Logger
+	nodeID  string
+	client  *redis.Client
+	pubsub  *redis.PubSub
+}
+
+func (i *cacheInvalidator) register(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Query().Before("gorm:query").Register("portal:cache_scope", i.scope),
+		cb.Create().After("gorm:create").Register("portal:cache_invalidate", i.invalidateWrite),
+		cb.Update().After("gorm:update").Register("portal:cache_invalidate", i.invalidateWrite),
+		cb.Delete().After("gorm:delete").Register("portal:cache_invalidate", i.invalidateWrite),
+		cb.Raw().After("gorm:raw").Register("portal:cache_invalidate", i.invalidateRaw),
+	)
+}
+
+// scope attaches the tables of the query to its context. Queries reading tables that can not be told, through raw
+// SQL, raw joins or subqueries, are not cached, which also keeps preloads from using the scope of their parent query.
+// Neither are queries of sessions made with Uncached.
+func (i *cacheInvalidator) scope(db *gorm.DB) {
+	if db.Statement.Context == nil {
+		return
+	}
+
+	var scope *cacheScope
+
+	_, uncached := db.Get(cacheSkipSetting)
+
+	if tables, ok := queryTables(db.Statement); ok && !uncached {
+		_, replica := db.Get(replicaReadSetting)
+		scope = &cacheScope{
+			tables:  append(tables, cacheAllTables),
+			replica: replica,
+		}
+	}
+
+	db.Statement.Context = context.WithValue(db.Statement.Context, cacheScopeKey{}, scope)
+}
+
+// invalidateWrite runs after the statement. Writes in a transaction, which gorm wraps single writes in as well, are
+// invalidated once it commits, a result read before then would otherwise be cached under the new generation.
+func (i *cacheInvalidator) invalidateWrite(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Table == "" {
+		return
+	}
+
+	i.invalidateStatement(db, db.Statement.Table)
+}
+
+func (i *cacheInvalidator) invalidateRaw(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+
+	if sql := strings.TrimSpace(db.Statement.SQL.String()); len(sql) >= 6 && strings.EqualFold(sql[:6], "select") {
+		return
+	}
+
+	i.invalidateStatement(db, cacheAllTables)
+}
+
+func (i *cacheInvalidator) invalidateStatement(db *gorm.DB, table string) {
+	if tx, ok := db.Statement.ConnPool.(*cacheTx); ok {
+		tx.invalidateOnCommit(table)
+		return
+	}
+
+	i.invalidate(db.Statement.Context, []string{table})
+}
+
+func (i *cacheInvalidator) invalidate(ctx context.Context, tables []string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := i.cacher.invalidateTables(ctx, tables); err != nil {
+		i.logger.Error("Failed to invalidate query cache", zap.Strings("tables", tables), zap.Error(err))
+	}
+
+	for _, table := range tables {
+		i.metrics.DBCacheInvalidations.WithLabelValues(table, cacheOriginLocal).Inc()
+	}
+
+	if i.client == nil {
+		return
+	}
+
+	msg, err := json.Marshal(cacheInvalidation{Node: i.nodeID, Tables: tables})
+	if err != nil {
+		return
+	}
+
+	if err := i.client.Publish(ctx, cacheInvalidateChannel, msg).Err(); err != nil {
+		i.logger.Error("Failed to publish query cache invalidation", zap.Strings("tables", tables), zap.Error(err))
+	}
+}
+
+// listen applies the invalidations published by other nodes. Invalidations missed while redis is unreachable are
+// caught up with by the entries expiring.
+func (i *cacheInvalidator) listen(ctx context.Context) {
+	i.pubsub = i.client.Subscribe(ctx, cacheInvalidateChannel)
+	messages := i.pubsub.Channel()
+
+	go func() {
+		for message := range messages {
+			var invalidation cacheInvalidation
+			if err := json.Unmarshal([]byte(message.Payload), &invalidation); err != nil {
+				i.logger.Error("Failed to decode query cache invalidation", zap.Error(err))
+				continue
+			}
+
+			if invalidation.Node == i.nodeID {
+				continue
+			}
+
+			if err := i.cacher.invalidateTables(context.Background(), invalidation.Tables); err != nil {
+				i.logger.Error("Failed to invalidate query cache", zap.Strings("tables", invalidation.Tables), zap.Error(err))
+				continue
+			}
+
+			for _, table := range invalidation.Tables {
+				i.metrics.DBCacheInvalidations.WithLabelValues(table, cacheOriginRemote).Inc()
+			}
+		}
+	}()
+}
+
+func (i *cacheInvalidator) close() error {
+	if i.pubsub == nil {
+		return nil
+	}
+
+	return i.pubsub.Close()
+}
+
+var _ gorm.ConnPoolBeginner = (*cacheConnPool)(nil)
+var _ gorm.GetDBConnector = (*cacheConnPool)(nil)
+var _ gorm.TxCommitter = (*cacheTx)(nil)
+
+// cacheConnPool begins transactions that invalidate the tables written in them once they commit
+type cacheConnPool struct {
+	gorm.ConnPool
+	invalidator *cacheInvalidator
+}
+
+func (p *cacheConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		sqlTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = sqlTx
+	case gorm.ConnPoolBeginner:
+		poolTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = poolTx
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+
+	committer, ok := tx.(gorm.TxCommitter)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+
+	return &cacheTx{
+		ConnPool:    tx,
+		committer:   committer,
+		ctx:         ctx,
+		invalidator: p.invalidator,
+	}, nil
+}
+
+func (p *cacheConnPool) GetDBConn() (*sql.DB, error) {
+	if sqlDB, ok := p.ConnPool.(*sql.DB); ok {
+		return sqlDB, nil
+	}
+
+	if connector, ok := p.ConnPool.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+
+	return nil, gorm.ErrInvalidDB
+}
+
+// cacheTx holds back the invalidations of the writes made in a transaction until it commits
+type cacheTx struct {
+	gorm.ConnPool
+	committer   gorm.TxCommitter
+	ctx         context.Context
+	invalidator *cacheInvalidator
+	mu          sync.Mutex
+	tables      []string
+}
+
+func (t *cacheTx) invalidateOnCommit(table string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !slices.Contains(t.tables, table) {
+		t.tables = append(t.tables, table)
+	}
+}
+
+func (t *cacheTx) Commit() error {
+	if err := t.committer.Commit(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	tables := t.tables
+	t.tables = nil
+	t.mu.Unlock()
+
+	if len(tables) > 0 {
+		// The transaction's context may end with it, the invalidation must still go out
+		t.invalidator.invalidate(context.WithoutCancel(t.ctx), tables)
+	}
+
+	return nil
+}
+
+func (t *cacheTx) Rollback() error {
+	t.mu.Lock()
+	t.tables = nil
+	t.mu.Unlock()
+
+	return t.committer.Rollback()
+}
+
+// cacheLookup resolves the key of a cached result. Queries without a scope are not cached and get no scope back.
+func cacheLookup(ctx context.Context, cacher tableCacher, key string) (string, *cacheScope, error) {
+	scope, _ := ctx.Value(cacheScopeKey{}).(*cacheScope)
+	if scope == nil {
+		return "", nil, nil
+	}
+
+	if scope.generations == nil {
+		generations, err := cacher.generations(ctx, scope.tables)
+		if err != nil {
+			return "", nil, err
+		}
+		scope.generations = generations
+	}
+
+	return scopedCacheKey(key, scope), scope, nil
+}
+
+func observeCacheLookup(metrics *core.Metrics, scope *cacheScope, hit bool) {
+	result := cacheResultMiss
+	if hit {
+		result = cacheResultHit
+	}
+
+	metrics.DBCacheRequests.WithLabelValues(scope.tables[0], result).Inc()
+}
+
+// scopedCacheKey prefixes the hashed query identifier with the generations of the tables it reads
+func scopedCacheKey(key string, scope *cacheScope) string {
+	var sb strings.Builder
+	sb.WriteString(caches.IdentifierPrefix)
+
+	if scope.replica {
+		sb.WriteString("replica:")
+	}
+
+	for i, table := range scope.tables {
+		_, _ = fmt.Fprintf(&sb, "%s@%d:", table, scope.generations[i])
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	sb.WriteString(hex.EncodeToString(sum[:]))
+
+	return sb.String()
+}
+
+// queryTables lists the tables a query reads, sorted, or reports that they can not be told
+func queryTables(stmt *gorm.Statement) ([]string, bool) {
+	if stmt.Table == "" || stmt.TableExpr != nil || stmt.SQL.Len() > 0 {
+		return nil, false
+	}
+
+	tables := []string{stmt.Table}
+
+	for _, join := range stmt.Joins {
+		if stmt.Schema == nil {
+			return nil, false
+		}
+
+		rel, ok := stmt.Schema.Relationships.Relations[join.Name]
+		if !ok {
+			return nil, false
+		}
+
+		tables = append(tables, rel.FieldSchema.Table)
+	}
+
+	if where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where); ok && hasSubquery(where.Exprs) {
+		return nil, false
+	}
+
+	sort.Strings(tables[1:])
+
+	return tables, true
+}
+
+func hasSubquery(exprs []clause.Expression) bool {
+	for _, expr := range exprs {
+		var vars []interface{}
+
+		switch e := expr.(type) {
+		case clause.Expr:
+			vars = e.Vars
+		case clause.NamedExpr:
+			vars = e.Vars
+		case clause.AndConditions:
+			if hasSubquery(e.Exprs) {
+				return true
+			}
+		case clause.OrConditions:
+			if hasSubquery(e.Exprs) {
+				return true
+			}
+		case clause.NotConditions:
+			if hasSubquery(e.Exprs) {
+				return true
+			}
+		}
+
+		for _, v := range vars {
+			if _, ok := v.(*gorm.DB); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -1,31 +1,66 @@
 package db
 
 import (
+	"container/list"
 	"context"
 	"sync"
+	"time"
 
 	"github.com/go-gorm/caches/v4"
+	"go.lumeweb.com/portal/core"
 )
 
+const defaultCacheMaxEntries = 10000
+
+var _ tableCacher = (*memoryCacher)(nil)
+
+// memoryCacher is a size-bounded LRU of query results, its table generations are local to the node
 type memoryCacher struct {
-	store *sync.Map
-	mu    sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	metrics    *core.Metrics
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	gens    map[string]uint64
 }
 
-func (c *memoryCacher) init() {
-	if c.store == nil {
-		c.store = &sync.Map{}
+type memoryCacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func newMemoryCacher(ttl time.Duration, maxEntries int, metrics *core.Metrics) *memoryCacher {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+
+	return &memoryCacher{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		metrics:    metrics,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		gens:       make(map[string]uint64),
 	}
 }
 
 func (c *memoryCacher) Get(ctx context.Context, key string, q *caches.Query[any]) (*caches.Query[any], error) {
-	c.init()
-	val, ok := c.store.Load(key)
-	if !ok {
+	key, scope, err := cacheLookup(ctx, c, key)
+	if err != nil || scope == nil {
+		return nil, err
+	}
+
+	value := c.load(key)
+	observeCacheLookup(c.metrics, scope, value != nil)
+
+	if value == nil {
 		return nil, nil
 	}
 
-	if err := q.Unmarshal(val.([]byte)); err != nil {
+	if err := q.Unmarshal(value); err != nil {
 		return nil, err
 	}
 
@@ -33,19 +68,85 @@ func (c *memoryCacher) Get(ctx context.Context, key string, q *caches.Query[any]
 }
 
 func (c *memoryCacher) Store(ctx context.Context, key string, val *caches.Query[any]) error {
-	c.init()
+	key, scope, err := cacheLookup(ctx, c, key)
+	if err != nil || scope == nil {
+		return err
+	}
+
 	res, err := val.Marshal()
 	if err != nil {
 		return err
 	}
 
-	c.store.Store(key, res)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryCacheEntry{key: key, value: res, expires: time.Now().Add(c.ttl)}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return nil
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+
+	return nil
+}
+
+// Invalidate is called by the caches plugin before every write, invalidation is scoped to the written tables instead
+func (c *memoryCacher) Invalidate(context.Context) error {
 	return nil
 }
 
-func (c *memoryCacher) Invalidate(ctx context.Context) error {
+func (c *memoryCacher) generations(_ context.Context, tables []string) ([]uint64, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.store = &sync.Map{}
+
+	gens := make([]uint64, len(tables))
+	for i, table := range tables {
+		gens[i] = c.gens[table]
+	}
+
+	return gens, nil
+}
+
+func (c *memoryCacher) invalidateTables(_ context.Context, tables []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, table := range tables {
+		c.gens[table]++
+	}
+
 	return nil
 }
+
+func (c *memoryCacher) load(key string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := elem.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		return nil
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return entry.value
+}
+
+func (c *memoryCacher) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*memoryCacheEntry).key)
+}
@@ -3,20 +3,34 @@ package db
 import (
 	"context"
 	"errors"
-	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-gorm/caches/v4"
 	"github.com/redis/go-redis/v9"
+	"go.lumeweb.com/portal/core"
 )
 
+const cacheGenerationKey = "portal:db:cache:gen:"
+
+var _ tableCacher = (*redisCacher)(nil)
+
+// redisCacher shares query results and table generations between the nodes using the same redis
 type redisCacher struct {
-	rdb *redis.Client
+	rdb     *redis.Client
+	ttl     time.Duration
+	metrics *core.Metrics
 }
 
 func (c *redisCacher) Get(ctx context.Context, key string, q *caches.Query[any]) (*caches.Query[any], error) {
+	key, scope, err := cacheLookup(ctx, c, key)
+	if err != nil || scope == nil {
+		return nil, err
+	}
+
 	res, err := c.rdb.Get(ctx, key).Result()
 	if errors.Is(err, redis.Nil) {
+		observeCacheLookup(c.metrics, scope, false)
 		return nil, nil
 	}
 
@@ -24,6 +38,8 @@ func (c *redisCacher) Get(ctx context.Context, key string, q *caches.Query[any])
 		return nil, err
 	}
 
+	observeCacheLookup(c.metrics, scope, true)
+
 	if err := q.Unmarshal([]byte(res)); err != nil {
 		return nil, err
 	}
@@ -32,39 +48,57 @@ func (c *redisCacher) Get(ctx context.Context, key string, q *caches.Query[any])
 }
 
 func (c *redisCacher) Store(ctx context.Context, key string, val *caches.Query[any]) error {
+	key, scope, err := cacheLookup(ctx, c, key)
+	if err != nil || scope == nil {
+		return err
+	}
+
 	res, err := val.Marshal()
 	if err != nil {
 		return err
 	}
 
-	c.rdb.Set(ctx, key, res, 300*time.Second) // Set proper cache time
+	return c.rdb.Set(ctx, key, res, c.ttl).Err()
+}
+
+// Invalidate is called by the caches plugin before every write, invalidation is scoped to the written tables instead
+func (c *redisCacher) Invalidate(context.Context) error {
 	return nil
 }
 
-func (c *redisCacher) Invalidate(ctx context.Context) error {
-	var (
-		cursor uint64
-		keys   []string
-	)
-	for {
-		var (
-			k   []string
-			err error
-		)
-		k, cursor, err = c.rdb.Scan(ctx, cursor, fmt.Sprintf("%s*", caches.IdentifierPrefix), 0).Result()
-		if err != nil {
-			return err
+func (c *redisCacher) generations(ctx context.Context, tables []string) ([]uint64, error) {
+	keys := make([]string, len(tables))
+	for i, table := range tables {
+		keys[i] = cacheGenerationKey + table
+	}
+
+	values, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	gens := make([]uint64, len(tables))
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
 		}
-		keys = append(keys, k...)
-		if cursor == 0 {
-			break
+
+		if gens[i], err = strconv.ParseUint(s, 10, 64); err != nil {
+			return nil, err
 		}
 	}
 
-	if len(keys) > 0 {
-		if _, err := c.rdb.Del(ctx, keys...).Result(); err != nil {
-			return err
+	return gens, nil
+}
+
+func (c *redisCacher) invalidateTables(ctx context.Context, tables []string) error {
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, table := range tables {
+			pipe.Incr(ctx, cacheGenerationKey+table)
 		}
-	}
-	return nil
+		return nil
+	})
+
+	return err
 }
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type cacheTestItem struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func newTestCachedDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gdb := openTestDB(t)
+	if err := gdb.AutoMigrate(&cacheTestItem{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	cfg := &config.Config{Core: config.CoreConfig{DB: config.DatabaseConfig{
+		Cache: &config.CacheConfig{Mode: config.CacheModeMemory, Options: config.MemoryConfig{}, TTL: 300},
+	}}}
+
+	if _, err := registerCache(&testConfigManager{cfg: cfg}, gdb, &core.Logger{Logger: zap.NewNop()}, core.NewMetrics()); err != nil {
+		t.Fatalf("failed to register cache: %v", err)
+	}
+
+	if err := gdb.Create(&cacheTestItem{ID: 1, Name: "old"}).Error; err != nil {
+		t.Fatalf("failed to create item: %v", err)
+	}
+
+	return gdb
+}
+
+func readCacheTestItem(t *testing.T, gdb *gorm.DB) string {
+	t.Helper()
+
+	var item cacheTestItem
+	if err := gdb.WithContext(context.Background()).First(&item, 1).Error; err != nil {
+		t.Fatalf("failed to read item: %v", err)
+	}
+
+	return item.Name
+}
+
+func TestCacheServesUntilWrite(t *testing.T) {
+	gdb := newTestCachedDB(t)
+
+	if name := readCacheTestItem(t, gdb); name != "old" {
+		t.Fatalf("expected old, got %s", name)
+	}
+
+	// Changed behind the cache's back, so only a cached result still reads old
+	if err := Uncached(gdb).Exec("UPDATE cache_test_items SET name = ? WHERE id = ?", "sneaky", 1).Error; err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+	if name := readCacheTestItem(t, gdb); name != "sneaky" {
+		t.Fatalf("expected the raw write to invalidate, got %s", name)
+	}
+
+	if err := gdb.Model(&cacheTestItem{ID: 1}).Update("name", "new").Error; err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+	if name := readCacheTestItem(t, gdb); name != "new" {
+		t.Fatalf("expected new after the write, got %s", name)
+	}
+}
+
+func TestCacheInvalidatesAfterCommit(t *testing.T) {
+	gdb := newTestCachedDB(t)
+
+	if name := readCacheTestItem(t, gdb); name != "old" {
+		t.Fatalf("expected old, got %s", name)
+	}
+
+	err := gdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&cacheTestItem{ID: 1}).Update("name", "new").Error; err != nil {
+			return err
+		}
+
+		// Read outside of the transaction before it commits, this must not be cached past the commit
+		if name := readCacheTestItem(t, gdb); name != "old" {
+			t.Errorf("expected old before the commit, got %s", name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+
+	if name := readCacheTestItem(t, gdb); name != "new" {
+		t.Fatalf("expected new after the commit, got %s", name)
+	}
+}
+
+func TestCacheKeepsResultsOnRollback(t *testing.T) {
+	gdb := newTestCachedDB(t)
+
+	if name := readCacheTestItem(t, gdb); name != "old" {
+		t.Fatalf("expected old, got %s", name)
+	}
+
+	tx := gdb.Begin()
+	if err := tx.Model(&cacheTestItem{ID: 1}).Update("name", "new").Error; err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+	if err := tx.Rollback().Error; err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+
+	if name := readCacheTestItem(t, gdb); name != "old" {
+		t.Fatalf("expected old after the rollback, got %s", name)
+	}
+}
+
+func TestMemoryCacheNeedsRedisOnClusters(t *testing.T) {
+	cluster := &config.ClusterConfig{Enabled: true}
+	logger := &core.Logger{Logger: zap.NewNop()}
+
+	cfg := &config.Config{Core: config.CoreConfig{
+		DB: config.DatabaseConfig{Cache: &config.CacheConfig{Mode: config.CacheModeMemory}},
+	}}
+
+	if mode := getCacheMode(&testConfigManager{cfg: cfg}, logger); mode != "memory" {
+		t.Fatalf("expected a single node to use the memory cache, got %s", mode)
+	}
+
+	cfg.Core.Clustered = cluster
+	if mode := getCacheMode(&testConfigManager{cfg: cfg}, logger); mode != "none" {
+		t.Fatalf("expected a cluster without redis not to cache, got %s", mode)
+	}
+
+	cluster.Redis = &config.RedisConfig{}
+	if mode := getCacheMode(&testConfigManager{cfg: cfg}, logger); mode != "memory" {
+		t.Fatalf("expected a cluster with redis to use the memory cache, got %s", mode)
+	}
+}
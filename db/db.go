@@ -1,18 +1,23 @@
 package db
 
 import (
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.lumeweb.com/portal/config"
 	"go.lumeweb.com/portal/core"
-	"go.lumeweb.com/portal/db/models"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"log"
 	"math"
 	"math/rand/v2"
+	"net"
+	"net/url"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +29,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// Postgres error codes that are worth retrying the operation for
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgLockNotAvailable     = "55P03"
+	pgTooManyConnections   = "53300"
+)
+
 func NewDatabase(ctx core.Context) (*gorm.DB, []core.ContextBuilderOption) {
 	cfg := ctx.Config()
 	rootLogger := ctx.Logger()
@@ -34,7 +47,9 @@ func NewDatabase(ctx core.Context) (*gorm.DB, []core.ContextBuilderOption) {
 
 	switch dbType {
 	case "mysql":
-		db, err = openMySQLDatabase(cfg, rootLogger)
+		db, err = openMySQLDatabase(cfg, rootLogger, ctx.Metrics())
+	case "postgres":
+		db, err = openPostgresDatabase(cfg, rootLogger, ctx.Metrics())
 	case "sqlite":
 		var dbFile string
 
@@ -44,7 +59,7 @@ func NewDatabase(ctx core.Context) (*gorm.DB, []core.ContextBuilderOption) {
 			dbFile = path.Join(cfg.ConfigDir(), cfg.Config().Core.DB.File)
 		}
 
-		db, err = openSQLiteDatabase(dbFile, rootLogger)
+		db, err = openSQLiteDatabase(dbFile, rootLogger, ctx.Metrics())
 	default:
 		panic(fmt.Sprintf("unsupported database type: %s", dbType))
 	}
@@ -53,23 +68,34 @@ func NewDatabase(ctx core.Context) (*gorm.DB, []core.ContextBuilderOption) {
 		panic(err)
 	}
 
-	cacher := getCacher(cfg, rootLogger)
-	if cacher != nil {
-		cache := &caches.Caches{Conf: &caches.Config{
-			Cacher: cacher,
-		}}
-		err := db.Use(cache)
-		if err != nil {
-			return nil, nil
-		}
+	if err = registerTracing(db); err != nil {
+		panic(err)
+	}
+
+	if err = registerReplicas(cfg, db); err != nil {
+		panic(err)
+	}
+
+	invalidator, err := registerCache(cfg, db, rootLogger, ctx.Metrics())
+	if err != nil {
+		panic(err)
 	}
 
 	ctxOpts := []core.ContextBuilderOption{
+		core.ContextWithDB(db),
 		core.ContextWithStartupFunc(func(ctx core.Context) error {
-			return db.AutoMigrate(models.GetModels()...)
+			if invalidator != nil && invalidator.client != nil {
+				invalidator.listen(ctx)
+			}
+			return nil
 		}),
-		core.ContextWithDB(db),
 		core.ContextWithExitFunc(func(ctx core.Context) error {
+			if invalidator != nil {
+				if err := invalidator.close(); err != nil {
+					return err
+				}
+			}
+
 			sqlDB, err := db.DB()
 			if err != nil {
 				return err
@@ -90,6 +116,11 @@ func getCacheMode(cm config.Manager, logger *core.Logger) string {
 	case "", "none":
 		return "none"
 	case "memory":
+		// Without redis, writes on other nodes could never invalidate the results this node cached
+		if coreCfg := cm.Config().Core; coreCfg.ClusterEnabled() && !coreCfg.Clustered.RedisEnabled() {
+			logger.Warn("Query cache disabled, a memory cache needs redis to be shared by the nodes of a cluster")
+			return "none"
+		}
 		return "memory"
 	case "redis":
 		return "redis"
@@ -100,53 +131,110 @@ func getCacheMode(cm config.Manager, logger *core.Logger) string {
 	return "none"
 }
 
-func openMySQLDatabase(cfg config.Manager, rootLogger *core.Logger) (*gorm.DB, error) {
-	username := cfg.Config().Core.DB.Username
-	password := cfg.Config().Core.DB.Password
-	host := cfg.Config().Core.DB.Host
-	port := cfg.Config().Core.DB.Port
-	dbname := cfg.Config().Core.DB.Name
-	charset := cfg.Config().Core.DB.Charset
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local", username, password, host, port, dbname, charset)
+func openMySQLDatabase(cfg config.Manager, rootLogger *core.Logger, metrics *core.Metrics) (*gorm.DB, error) {
+	return gorm.Open(mysqlDialector(cfg.Config().Core.DB), &gorm.Config{
+		Logger: newLogger(rootLogger.Logger, rootLogger.Level(), metrics),
+	})
+}
 
-	return gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: newLogger(rootLogger.Logger, rootLogger.Level()),
+func openPostgresDatabase(cfg config.Manager, rootLogger *core.Logger, metrics *core.Metrics) (*gorm.DB, error) {
+	return gorm.Open(postgresDialector(cfg.Config().Core.DB), &gorm.Config{
+		Logger: newLogger(rootLogger.Logger, rootLogger.Level(), metrics),
 	})
 }
 
-func openSQLiteDatabase(file string, rootLogger *core.Logger) (*gorm.DB, error) {
+func mysqlDialector(dbCfg config.DatabaseConfig) gorm.Dialector {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local", dbCfg.Username, dbCfg.Password, dbCfg.Host, dbCfg.Port, dbCfg.Name, dbCfg.Charset)
+
+	return mysql.Open(dsn)
+}
+
+func postgresDialector(dbCfg config.DatabaseConfig) gorm.Dialector {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(dbCfg.Username, dbCfg.Password),
+		Host:     net.JoinHostPort(dbCfg.Host, strconv.Itoa(dbCfg.Port)),
+		Path:     dbCfg.Name,
+		RawQuery: url.Values{"sslmode": []string{dbCfg.SSLMode}}.Encode(),
+	}
+
+	return postgres.Open(dsn.String())
+}
+
+func openSQLiteDatabase(file string, rootLogger *core.Logger, metrics *core.Metrics) (*gorm.DB, error) {
 	return gorm.Open(sqlite.Open(file), &gorm.Config{
-		Logger: newLogger(rootLogger.Logger, rootLogger.Level()),
+		Logger: newLogger(rootLogger.Logger, rootLogger.Level(), metrics),
 	})
 }
 
-func getCacher(cm config.Manager, logger *core.Logger) caches.Cacher {
+// registerCache sets up the query cache, invalidated by table as they are written to. A memory cache on a cluster
+// publishes its invalidations to the other nodes, a redis cache is shared by them.
+func registerCache(cm config.Manager, db *gorm.DB, logger *core.Logger, metrics *core.Metrics) (*cacheInvalidator, error) {
 	mode := getCacheMode(cm, logger)
+	if mode == "none" {
+		return nil, nil
+	}
 
-	switch mode {
-	case "none":
-		return nil
+	cfg := cm.Config().Core
+	ttl := time.Duration(cfg.DB.Cache.TTL) * time.Second
+
+	var clusterRedis *redis.Client
+	if cfg.ClusterEnabled() && cfg.Clustered.RedisEnabled() {
+		client, err := cfg.Clustered.Redis.Client()
+		if err != nil {
+			return nil, err
+		}
+		clusterRedis = client
+	}
+
+	invalidator := &cacheInvalidator{
+		metrics: metrics,
+		logger:  logger,
+		nodeID:  cfg.NodeID.String(),
+	}
 
+	switch mode {
 	case "memory":
-		return &memoryCacher{}
-	case "redis":
-		rcfg, ok := cm.Config().Core.DB.Cache.Options.(*config.RedisConfig)
-		if !ok {
-			logger.Fatal("invalid redis config")
-			return nil
+		var memCfg config.MemoryConfig
+		if opts, ok := cfg.DB.Cache.Options.(config.MemoryConfig); ok {
+			memCfg = opts
 		}
-		return &redisCacher{
-			redis.NewClient(&redis.Options{
-				Addr:     rcfg.Address,
-				Password: rcfg.Password,
-				DB:       rcfg.DB,
-			}),
+
+		invalidator.cacher = newMemoryCacher(ttl, memCfg.MaxEntries, metrics)
+		invalidator.client = clusterRedis
+	case "redis":
+		client := clusterRedis
+		if client == nil {
+			rcfg, ok := cfg.DB.Cache.Options.(*config.RedisConfig)
+			if !ok {
+				return nil, errors.New("invalid redis config")
+			}
+
+			var err error
+			if client, err = rcfg.Client(); err != nil {
+				return nil, err
+			}
 		}
+
+		invalidator.cacher = &redisCacher{rdb: client, ttl: ttl, metrics: metrics}
+	}
+
+	if err := db.Use(&caches.Caches{Conf: &caches.Config{Cacher: invalidator.cacher}}); err != nil {
+		return nil, err
+	}
+
+	if err := invalidator.register(db); err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Transactions begin on the pool, so the tables written in them are invalidated once they commit
+	pool := &cacheConnPool{ConnPool: db.ConnPool, invalidator: invalidator}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+
+	return invalidator, nil
 }
+
 func RetryOnLock(db *gorm.DB, operation func(*gorm.DB) *gorm.DB) error {
 	initialBackoff := 100 * time.Millisecond
 	maxBackoff := 10 * time.Second
@@ -184,16 +272,30 @@ func RetryOnLock(db *gorm.DB, operation func(*gorm.DB) *gorm.DB) error {
 	}
 }
 
+// RetryableTransaction runs the operation in a transaction and retries the whole transaction on lock errors. A
+// deadlock rolls back the transaction, and postgres refuses further statements in a transaction once one failed, so
+// retrying just the statement would not help.
 func RetryableTransaction(ctx core.Context, db *gorm.DB, operation func(*gorm.DB) *gorm.DB) error {
-	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return RetryOnLock(tx, func(tx *gorm.DB) *gorm.DB {
-			return operation(tx)
+	return RetryOnLock(db.WithContext(ctx), func(db *gorm.DB) *gorm.DB {
+		result := db.Session(&gorm.Session{NewDB: true})
+		result.Error = db.Transaction(func(tx *gorm.DB) error {
+			return operation(tx).Error
 		})
+
+		return result
 	})
 }
 
 // isLockError checks if the given error is a database lock error
 func isLockError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgSerializationFailure, pgDeadlockDetected, pgLockNotAvailable, pgTooManyConnections:
+			return true
+		}
+	}
+
 	errMsg := strings.ToLower(err.Error())
 	return strings.Contains(errMsg, "deadlock") ||
 		strings.Contains(errMsg, "lock wait timeout") ||
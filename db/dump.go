@@ -0,0 +1,261 @@
+package db
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"io"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	dumpFormatVersion = 1
+	dumpBatchSize     = 500
+)
+
+var (
+	ErrDumpVersion      = errors.New("unsupported dump version")
+	ErrDumpUnknownTable = errors.New("dump contains a table of no registered model")
+)
+
+type dumpHeader struct {
+	Version int
+}
+
+// dumpBatch precedes each batch of rows, the rows follow as a slice of the table's model. An empty table name ends the
+// dump.
+type dumpBatch struct {
+	Table string
+}
+
+type dumpTable struct {
+	name  string
+	model any
+	typ   reflect.Type
+	// primaryKey is empty for tables without one, which are dumped in a single batch
+	primaryKey string
+	// serial is set when the primary key is generated by the database
+	serial bool
+}
+
+// DumpModels returns the models of the core and every plugin
+func DumpModels() []any {
+	dump := append([]any(nil), models.GetModels()...)
+
+	for _, plugin := range core.GetPlugins() {
+		dump = append(dump, plugin.Models...)
+	}
+
+	return dump
+}
+
+// Export writes every row of the given models, soft deleted ones included, as a gzip compressed dump. Tables are
+// written parents first, so Import can insert them in the order they come.
+func Export(ctx context.Context, db *gorm.DB, w io.Writer, models []any) error {
+	tables, err := dumpTables(db, models)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := gob.NewEncoder(zw)
+
+	if err := enc.Encode(dumpHeader{Version: dumpFormatVersion}); err != nil {
+		return err
+	}
+
+	// A new session, so the order of one table's query is not carried into the next
+	tx := db.WithContext(ctx).Unscoped().Session(&gorm.Session{})
+
+	for _, table := range tables {
+		batch := reflect.New(reflect.SliceOf(table.typ))
+
+		write := func() error {
+			if batch.Elem().Len() == 0 {
+				return nil
+			}
+
+			if err := enc.Encode(dumpBatch{Table: table.name}); err != nil {
+				return err
+			}
+
+			return enc.EncodeValue(batch.Elem())
+		}
+
+		if table.primaryKey == "" {
+			if err := tx.Model(table.model).Find(batch.Interface()).Error; err != nil {
+				return fmt.Errorf("failed to export %s: %w", table.name, err)
+			}
+
+			if err := write(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := tx.Model(table.model).Order(table.primaryKey).FindInBatches(batch.Interface(), dumpBatchSize, func(_ *gorm.DB, _ int) error {
+			return write()
+		}).Error; err != nil {
+			return fmt.Errorf("failed to export %s: %w", table.name, err)
+		}
+	}
+
+	if err := enc.Encode(dumpBatch{}); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// Import replaces the rows of the given models with those of a dump written by Export, in a single transaction. Hooks
+// are skipped so rows are restored exactly as they were exported.
+func Import(ctx context.Context, db *gorm.DB, r io.Reader, models []any) error {
+	tables, err := dumpTables(db, models)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]dumpTable, len(tables))
+	for _, table := range tables {
+		byName[table.name] = table
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	dec := gob.NewDecoder(zr)
+
+	var header dumpHeader
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+
+	if header.Version != dumpFormatVersion {
+		return fmt.Errorf("%w: %d", ErrDumpVersion, header.Version)
+	}
+
+	return db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		// Children go first, so clearing never trips over a foreign key
+		for i := len(tables) - 1; i >= 0; i-- {
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(tables[i].model).Error; err != nil {
+				return fmt.Errorf("failed to clear %s: %w", tables[i].name, err)
+			}
+		}
+
+		for {
+			var batch dumpBatch
+			if err := dec.Decode(&batch); err != nil {
+				return err
+			}
+
+			if batch.Table == "" {
+				return resetSequences(tx, tables)
+			}
+
+			table, ok := byName[batch.Table]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrDumpUnknownTable, batch.Table)
+			}
+
+			rows := reflect.New(reflect.SliceOf(table.typ))
+			if err := dec.DecodeValue(rows); err != nil {
+				return fmt.Errorf("failed to read %s: %w", table.name, err)
+			}
+
+			if err := tx.Omit(clause.Associations).CreateInBatches(rows.Interface(), dumpBatchSize).Error; err != nil {
+				return fmt.Errorf("failed to import %s: %w", table.name, err)
+			}
+		}
+	})
+}
+
+// resetSequences moves the sequences of serial primary keys past the imported rows on postgres, which unlike the other
+// databases does not advance them when rows are inserted with their keys
+func resetSequences(tx *gorm.DB, tables []dumpTable) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	for _, table := range tables {
+		if !table.serial {
+			continue
+		}
+
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			tx.Statement.Quote(table.primaryKey), tx.Statement.Quote(table.name))
+
+		if err := tx.Exec(query, table.name, table.primaryKey).Error; err != nil {
+			return fmt.Errorf("failed to reset sequence of %s: %w", table.name, err)
+		}
+	}
+
+	return nil
+}
+
+// dumpTables resolves the tables of the models, ordered so every table comes after the tables it belongs to
+func dumpTables(db *gorm.DB, models []any) ([]dumpTable, error) {
+	cache := &sync.Map{}
+	schemas := make(map[string]*schema.Schema, len(models))
+	tables := make(map[string]dumpTable, len(models))
+	var names []string
+
+	for _, model := range models {
+		s, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := tables[s.Table]; ok {
+			continue
+		}
+
+		table := dumpTable{name: s.Table, model: model, typ: s.ModelType}
+		if s.PrioritizedPrimaryField != nil {
+			table.primaryKey = s.PrioritizedPrimaryField.DBName
+			table.serial = s.PrioritizedPrimaryField.AutoIncrement
+		}
+
+		schemas[s.Table] = s
+		tables[s.Table] = table
+		names = append(names, s.Table)
+	}
+
+	ordered := make([]dumpTable, 0, len(names))
+	visited := make(map[string]bool, len(names))
+
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+
+		// Marking before the parents are visited lets cycles fall back to registration order instead of looping
+		visited[name] = true
+
+		for _, rel := range schemas[name].Relationships.BelongsTo {
+			if _, ok := tables[rel.FieldSchema.Table]; ok {
+				visit(rel.FieldSchema.Table)
+			}
+		}
+
+		ordered = append(ordered, tables[name])
+	}
+
+	for _, name := range names {
+		visit(name)
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	dbLogger "gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+// testConfigManager serves a fixed config, the methods a test does not need are left unimplemented
+type testConfigManager struct {
+	config.Manager
+	cfg *config.Config
+}
+
+func (m *testConfigManager) Config() *config.Config {
+	return m.cfg
+}
+
+// openTestDB opens a fresh sqlite database
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "portal.db")), &gorm.Config{
+		Logger: dbLogger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	return gdb
+}
+
+// newTestContext creates a context backed by the given database
+func newTestContext(t *testing.T, cfg *config.Config, gdb *gorm.DB) core.Context {
+	t.Helper()
+
+	ctx, err := core.NewContext(&testConfigManager{cfg: cfg}, &core.Logger{Logger: zap.NewNop()}, core.ContextWithDB(gdb))
+	if err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+
+	t.Cleanup(ctx.Cancel)
+
+	return ctx
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"go.lumeweb.com/portal/core"
 	"gorm.io/gorm"
 
 	"go.uber.org/zap"
@@ -24,8 +25,9 @@ var (
 )
 
 type logger struct {
-	logger *zap.Logger
-	level  *zap.AtomicLevel
+	logger  *zap.Logger
+	level   *zap.AtomicLevel
+	metrics *core.Metrics
 }
 
 func (l logger) LogMode(level dbLogger.LogLevel) dbLogger.Interface {
@@ -51,6 +53,14 @@ func (l logger) Error(ctx context.Context, s string, i ...interface{}) {
 }
 
 func (l logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.metrics != nil {
+		status := "ok"
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+		l.metrics.DBQueryDuration.WithLabelValues(status).Observe(time.Since(begin).Seconds())
+	}
+
 	if l.level.Level() <= zap.DebugLevel {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
@@ -69,8 +79,8 @@ func (l logger) Trace(ctx context.Context, begin time.Time, fc func() (sql strin
 	}
 }
 
-func newLogger(zlog *zap.Logger, zlogLevel *zap.AtomicLevel) *logger {
-	return &logger{logger: zlog, level: zlogLevel}
+func newLogger(zlog *zap.Logger, zlogLevel *zap.AtomicLevel, metrics *core.Metrics) *logger {
+	return &logger{logger: zlog, level: zlogLevel, metrics: metrics}
 }
 
 func interfacesToFields(i ...interface{}) []zap.Field {
@@ -0,0 +1,552 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	dbLogger "gorm.io/gorm/logger"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+)
+
+const (
+	CoreMigrationOwner = "core"
+
+	// BaselineMigrationID is recorded for an owner once its models have been migrated as a whole
+	BaselineMigrationID = "0000_baseline"
+
+	migrationLockID            = 1
+	migrationLockStaleAfter    = 10 * time.Minute
+	migrationLockHeartbeat     = time.Minute
+	migrationLockRetryInterval = time.Second
+)
+
+var (
+	ErrMigrationDuplicate    = errors.New("duplicate migration ID")
+	ErrMigrationInvalid      = errors.New("migration must have an ID and an up step")
+	ErrMigrationIrreversible = errors.New("migration can not be rolled back")
+	ErrMigrationUnknownOwner = errors.New("no migrations registered for owner")
+)
+
+// coreMigrations are the versioned schema changes of the core models, in order
+var coreMigrations = []core.Migration{
+	{
+		ID: "0001_cron_task_pauses",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.CronTaskPause{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.CronTaskPause{})
+		},
+	},
+	{
+		ID: "0002_cron_job_logs_job_index",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateIndex(&models.CronJobLog{}, "CronJobID")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&models.CronJobLog{}, "CronJobID")
+		},
+	},
+	{
+		ID: "0003_cron_locks",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.CronLock{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.CronLock{})
+		},
+	},
+	{
+		ID: "0004_outbox_dead_events",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&models.OutboxEvent{}, "DeadAt"); err != nil {
+				return err
+			}
+
+			return tx.Migrator().CreateIndex(&models.OutboxEvent{}, "DeadAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.OutboxEvent{}, "DeadAt"); err != nil {
+				return err
+			}
+
+			return tx.Migrator().DropColumn(&models.OutboxEvent{}, "DeadAt")
+		},
+	},
+}
+
+type schemaMigration struct {
+	Owner     string `gorm:"primaryKey;size:128"`
+	ID        string `gorm:"primaryKey;size:128"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// schemaMigrationLock has at most a single row, held by the node that is migrating
+type schemaMigrationLock struct {
+	ID       uint `gorm:"primaryKey;autoIncrement:false"`
+	NodeID   string
+	LockedAt time.Time
+}
+
+func (schemaMigrationLock) TableName() string {
+	return "schema_migration_locks"
+}
+
+// MigrationSet is the schema of the core or of a plugin
+type MigrationSet struct {
+	Owner      string
+	Models     []any
+	Migrations []core.Migration
+}
+
+type MigrationStatus struct {
+	Owner     string
+	ID        string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db     *gorm.DB
+	logger *core.Logger
+	nodeID string
+	sets   []MigrationSet
+}
+
+// MigrationSets collects the schema of the core and of every plugin, the core first and plugins after the plugins they
+// depend on
+func MigrationSets() ([]MigrationSet, error) {
+	sets := []MigrationSet{{
+		Owner:      CoreMigrationOwner,
+		Models:     models.GetModels(),
+		Migrations: coreMigrations,
+	}}
+
+	for _, plugin := range core.GetPlugins() {
+		if len(plugin.Models) == 0 && len(plugin.Migrations) == 0 {
+			continue
+		}
+
+		for _, model := range plugin.Models {
+			if reflect.TypeOf(model).Kind() != reflect.Ptr {
+				return nil, fmt.Errorf("%w: %s model %T must be a pointer", core.ErrInvalidModel, plugin.ID, model)
+			}
+		}
+
+		sets = append(sets, MigrationSet{
+			Owner:      plugin.ID,
+			Models:     plugin.Models,
+			Migrations: plugin.Migrations,
+		})
+	}
+
+	for i := range sets {
+		migrations, err := sortMigrations(sets[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i].Migrations = migrations
+	}
+
+	return sets, nil
+}
+
+func NewMigrator(ctx core.Context, db *gorm.DB, sets []MigrationSet) *Migrator {
+	return &Migrator{
+		db:     db,
+		logger: ctx.Logger(),
+		nodeID: ctx.Config().Config().Core.NodeID.String(),
+		sets:   sets,
+	}
+}
+
+// Migrate applies every pending migration. An owner without any applied migration has its models migrated as a whole
+// instead, after which all of its migrations are recorded as applied. With dryRun set, nothing is changed and the
+// statements that would be executed are written to it.
+func (m *Migrator) Migrate(ctx context.Context, dryRun io.Writer) (int, error) {
+	applied := 0
+
+	err := m.withLock(ctx, dryRun, func(done map[string]map[string]time.Time) error {
+		for _, set := range m.sets {
+			if len(set.Models) > 0 && len(done[set.Owner]) == 0 {
+				if err := m.runBaseline(ctx, set, dryRun); err != nil {
+					return err
+				}
+				applied++
+				continue
+			}
+
+			for _, migration := range set.Migrations {
+				if _, ok := done[set.Owner][migration.ID]; ok {
+					continue
+				}
+
+				if err := m.run(ctx, set.Owner, migration.ID, migration.Up, true, dryRun); err != nil {
+					return err
+				}
+				applied++
+			}
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Rollback reverts the last steps applied migrations of the owner, newest first. It stops at the baseline, whose
+// rollback drops the tables of the owner's models, unless includeBaseline is set.
+func (m *Migrator) Rollback(ctx context.Context, owner string, steps int, includeBaseline bool, dryRun io.Writer) (int, error) {
+	set, ok := m.set(owner)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrMigrationUnknownOwner, owner)
+	}
+
+	reverted := 0
+
+	err := m.withLock(ctx, dryRun, func(done map[string]map[string]time.Time) error {
+		migrations := m.setMigrations(set)
+
+		for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := migrations[i]
+			if _, ok := done[owner][migration.ID]; !ok {
+				continue
+			}
+
+			if migration.ID == BaselineMigrationID && !includeBaseline {
+				break
+			}
+
+			if migration.Down == nil {
+				return fmt.Errorf("%w: %s %s", ErrMigrationIrreversible, owner, migration.ID)
+			}
+
+			if err := m.run(ctx, owner, migration.ID, migration.Down, false, dryRun); err != nil {
+				return err
+			}
+			reverted++
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every migration of every owner, the baseline included, along with when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, set := range m.sets {
+		for _, migration := range m.setMigrations(set) {
+			status := MigrationStatus{Owner: set.Owner, ID: migration.ID}
+			if appliedAt, ok := done[set.Owner][migration.ID]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) set(owner string) (MigrationSet, bool) {
+	for _, set := range m.sets {
+		if set.Owner == owner {
+			return set, true
+		}
+	}
+
+	return MigrationSet{}, false
+}
+
+// setMigrations prepends the baseline of a set with models to its migrations
+func (m *Migrator) setMigrations(set MigrationSet) []core.Migration {
+	if len(set.Models) == 0 {
+		return set.Migrations
+	}
+
+	baseline := core.Migration{
+		ID: BaselineMigrationID,
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(set.Models...)
+		},
+		Down: func(tx *gorm.DB) error {
+			tables, err := dumpTables(tx, set.Models)
+			if err != nil {
+				return err
+			}
+
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(tables[i].model); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	return append([]core.Migration{baseline}, set.Migrations...)
+}
+
+func (m *Migrator) runBaseline(ctx context.Context, set MigrationSet, dryRun io.Writer) error {
+	baseline := m.setMigrations(set)[0]
+
+	if err := m.run(ctx, set.Owner, baseline.ID, baseline.Up, true, dryRun); err != nil {
+		return err
+	}
+
+	if dryRun != nil || len(set.Migrations) == 0 {
+		return nil
+	}
+
+	// The models already are at the state the migrations lead to
+	now := time.Now()
+	records := make([]schemaMigration, 0, len(set.Migrations))
+	for _, migration := range set.Migrations {
+		records = append(records, schemaMigration{Owner: set.Owner, ID: migration.ID, AppliedAt: now})
+	}
+
+	return m.db.WithContext(ctx).Create(&records).Error
+}
+
+// run applies a single migration step and records it, within a transaction where the database allows DDL in one
+func (m *Migrator) run(ctx context.Context, owner, id string, step core.DBMigration, up bool, dryRun io.Writer) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+
+	if dryRun != nil {
+		if _, err := fmt.Fprintf(dryRun, "-- %s %s (%s)\n", owner, id, direction); err != nil {
+			return err
+		}
+
+		return step(newDryRunSession(ctx, m.db, dryRun))
+	}
+
+	m.logger.Info("Running migration", zap.String("owner", owner), zap.String("id", id), zap.String("direction", direction))
+
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := step(tx); err != nil {
+			return err
+		}
+
+		if up {
+			return tx.Create(&schemaMigration{Owner: owner, ID: id, AppliedAt: time.Now()}).Error
+		}
+
+		return tx.Delete(&schemaMigration{Owner: owner, ID: id}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %s %s (%s) failed: %w", owner, id, direction, err)
+	}
+
+	return nil
+}
+
+// withLock runs fn with the applied migrations while holding the cluster wide migration lock. Dry runs neither take
+// the lock nor create the tracking tables.
+func (m *Migrator) withLock(ctx context.Context, dryRun io.Writer, fn func(map[string]map[string]time.Time) error) error {
+	if dryRun == nil {
+		if err := m.db.WithContext(ctx).AutoMigrate(&schemaMigration{}, &schemaMigrationLock{}); err != nil {
+			return err
+		}
+
+		unlock, err := m.lock(ctx)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	done, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	return fn(done)
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[string]map[string]time.Time, error) {
+	done := make(map[string]map[string]time.Time)
+
+	tx := m.db.WithContext(ctx)
+	if !tx.Migrator().HasTable(&schemaMigration{}) {
+		return done, nil
+	}
+
+	var records []schemaMigration
+	if err := tx.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if done[record.Owner] == nil {
+			done[record.Owner] = make(map[string]time.Time)
+		}
+		done[record.Owner][record.ID] = record.AppliedAt
+	}
+
+	return done, nil
+}
+
+// lock takes the migration lock, waiting for another node to finish migrating first. A lock that has not been
+// refreshed in a while is left over by a node that died while migrating and is taken over.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	tx := m.db.WithContext(ctx)
+	// The insert failing is how a held lock shows, which is not worth logging
+	quiet := tx.Session(&gorm.Session{Logger: tx.Logger.LogMode(dbLogger.Silent)})
+	waiting := false
+
+	for {
+		err := quiet.Create(&schemaMigrationLock{ID: migrationLockID, NodeID: m.nodeID, LockedAt: time.Now()}).Error
+		if err == nil {
+			break
+		}
+
+		var held schemaMigrationLock
+		if takeErr := tx.Take(&held, migrationLockID).Error; takeErr != nil {
+			if !errors.Is(takeErr, gorm.ErrRecordNotFound) {
+				return nil, takeErr
+			}
+
+			// Released in the meantime, or the insert failed for another reason
+			if waiting {
+				return nil, fmt.Errorf("failed to take migration lock: %w", err)
+			}
+			waiting = true
+			continue
+		}
+
+		if time.Since(held.LockedAt) > migrationLockStaleAfter {
+			m.logger.Warn("Taking over stale migration lock", zap.String("node", held.NodeID), zap.Time("locked_at", held.LockedAt))
+			if err := tx.Where("locked_at = ?", held.LockedAt).Delete(&schemaMigrationLock{ID: migrationLockID}).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !waiting {
+			m.logger.Info("Waiting for another node to finish migrating", zap.String("node", held.NodeID))
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(migrationLockRetryInterval):
+		}
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(migrationLockHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := m.db.Model(&schemaMigrationLock{ID: migrationLockID}).Update("locked_at", time.Now()).Error; err != nil {
+					m.logger.Error("Failed to refresh migration lock", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+
+		// Released without the caller's context, so a cancelled migration does not leave the lock behind
+		if err := m.db.Delete(&schemaMigrationLock{ID: migrationLockID}).Error; err != nil {
+			m.logger.Error("Failed to release migration lock", zap.Error(err))
+		}
+	}, nil
+}
+
+func sortMigrations(set MigrationSet) ([]core.Migration, error) {
+	migrations := append([]core.Migration(nil), set.Migrations...)
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].ID < migrations[j].ID
+	})
+
+	for i, migration := range migrations {
+		if migration.ID == "" || migration.ID == BaselineMigrationID || migration.Up == nil {
+			return nil, fmt.Errorf("%w: %s %q", ErrMigrationInvalid, set.Owner, migration.ID)
+		}
+
+		if i > 0 && migrations[i-1].ID == migration.ID {
+			return nil, fmt.Errorf("%w: %s %s", ErrMigrationDuplicate, set.Owner, migration.ID)
+		}
+	}
+
+	return migrations, nil
+}
+
+// dryRunConnPool writes the statements executed through it instead of running them. Queries still reach the database,
+// so migrations can inspect the current schema.
+type dryRunConnPool struct {
+	gorm.ConnPool
+	dialector gorm.Dialector
+	out       io.Writer
+}
+
+var _ gorm.TxCommitter = (*dryRunConnPool)(nil)
+
+// newDryRunSession returns a session whose statements are written to out. It acts as a transaction already, so
+// migrations that open one do not reach the database either.
+func newDryRunSession(ctx context.Context, db *gorm.DB, out io.Writer) *gorm.DB {
+	tx := db.Session(&gorm.Session{Context: ctx, DisableNestedTransaction: true})
+	tx.Statement.ConnPool = &dryRunConnPool{ConnPool: db.Statement.ConnPool, dialector: db.Dialector, out: out}
+
+	return tx
+}
+
+func (p *dryRunConnPool) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	if _, err := fmt.Fprintf(p.out, "%s;\n", p.dialector.Explain(query, args...)); err != nil {
+		return nil, err
+	}
+
+	return dryRunResult{}, nil
+}
+
+func (p *dryRunConnPool) Commit() error {
+	return nil
+}
+
+func (p *dryRunConnPool) Rollback() error {
+	return nil
+}
+
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (dryRunResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type migrateTestWidget struct {
+	ID    uint `gorm:"primaryKey"`
+	Name  string
+	Color string
+}
+
+type migrateTestGadget struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, *gorm.DB) {
+	t.Helper()
+
+	gdb := openTestDB(t)
+	ctx := newTestContext(t, &config.Config{}, gdb)
+
+	set := MigrationSet{
+		Owner:  "test",
+		Models: []any{&migrateTestWidget{}, &migrateTestGadget{}},
+		Migrations: []core.Migration{
+			{
+				ID: "0001_widget_color",
+				Up: func(tx *gorm.DB) error {
+					return tx.Migrator().AddColumn(&migrateTestWidget{}, "Color")
+				},
+				Down: func(tx *gorm.DB) error {
+					return tx.Migrator().DropColumn(&migrateTestWidget{}, "Color")
+				},
+			},
+			{
+				ID: "0002_gadgets",
+				Up: func(tx *gorm.DB) error {
+					return tx.Migrator().CreateTable(&migrateTestGadget{})
+				},
+				Down: func(tx *gorm.DB) error {
+					return tx.Migrator().DropTable(&migrateTestGadget{})
+				},
+			},
+		},
+	}
+
+	return NewMigrator(ctx, gdb, []MigrationSet{set}), gdb
+}
+
+func TestMigrateFreshInstallRecordsEveryMigration(t *testing.T) {
+	migrator, gdb := newTestMigrator(t)
+
+	applied, err := migrator.Migrate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if applied != 1 {
+		t.Fatalf("expected only the baseline to run, got %d steps", applied)
+	}
+
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected the baseline and 2 migrations, got %d", len(statuses))
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Fatalf("expected %s to be recorded as applied", status.ID)
+		}
+	}
+
+	if !gdb.Migrator().HasColumn(&migrateTestWidget{}, "Color") || !gdb.Migrator().HasTable(&migrateTestGadget{}) {
+		t.Fatal("expected the baseline to migrate the models as a whole")
+	}
+
+	applied, err = migrator.Migrate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("second migrate failed: %v", err)
+	}
+	if applied != 0 {
+		t.Fatalf("expected nothing left to apply, got %d steps", applied)
+	}
+}
+
+func TestRollbackStopsAtBaseline(t *testing.T) {
+	migrator, gdb := newTestMigrator(t)
+
+	if _, err := migrator.Migrate(context.Background(), nil); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	reverted, err := migrator.Rollback(context.Background(), "test", 10, false, nil)
+	if err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if reverted != 2 {
+		t.Fatalf("expected the 2 migrations to be rolled back, got %d", reverted)
+	}
+
+	if gdb.Migrator().HasTable(&migrateTestGadget{}) || gdb.Migrator().HasColumn(&migrateTestWidget{}, "Color") {
+		t.Fatal("expected the migrations to be reverted")
+	}
+	if !gdb.Migrator().HasTable(&migrateTestWidget{}) {
+		t.Fatal("expected the baseline tables to be kept")
+	}
+
+	// The migrations are pending again and reapply on top of the baseline
+	applied, err := migrator.Migrate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("migrate after rollback failed: %v", err)
+	}
+	if applied != 2 {
+		t.Fatalf("expected the 2 migrations to be reapplied, got %d", applied)
+	}
+	if !gdb.Migrator().HasColumn(&migrateTestWidget{}, "Color") {
+		t.Fatal("expected the color column to be back")
+	}
+}
+
+func TestRollbackIncludingBaselineDropsTables(t *testing.T) {
+	migrator, gdb := newTestMigrator(t)
+
+	if _, err := migrator.Migrate(context.Background(), nil); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	reverted, err := migrator.Rollback(context.Background(), "test", 10, true, nil)
+	if err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if reverted != 3 {
+		t.Fatalf("expected the migrations and the baseline to be rolled back, got %d", reverted)
+	}
+
+	if gdb.Migrator().HasTable(&migrateTestWidget{}) {
+		t.Fatal("expected the baseline rollback to drop the tables")
+	}
+}
+
+func TestRollbackUnknownOwner(t *testing.T) {
+	migrator, _ := newTestMigrator(t)
+
+	if _, err := migrator.Rollback(context.Background(), "missing", 1, false, nil); err == nil {
+		t.Fatal("expected an error for an owner without migrations")
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(&AccountSuspension{})
+}
+
+type AccountSuspension struct {
+	gorm.Model
+	UserID        uint `gorm:"index"`
+	User          User
+	Reason        string
+	SuspendedByID uint
+	SuspendedBy   User
+	// ExpiresAt is nil for a permanent ban
+	ExpiresAt  *time.Time
+	FreezePins bool `gorm:"default:false"`
+	LiftedAt   *time.Time
+	LiftedByID *uint
+}
+
+// Active reports whether the suspension is currently in effect
+func (s *AccountSuspension) Active() bool {
+	if s.LiftedAt != nil {
+		return false
+	}
+
+	return s.ExpiresAt == nil || s.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(&ActivityEvent{})
+}
+
+// ActivityEvent is an entry in the short-lived log of a user's activity, its ID doubles as the stream position
+type ActivityEvent struct {
+	gorm.Model
+	UserID uint `gorm:"index"`
+	Type   string
+	Data   datatypes.JSON
+}
@@ -22,14 +22,16 @@ func init() {
 
 type CronJob struct {
 	gorm.Model
-	UUID          types.BinaryUUID `gorm:"type:binary(16);uniqueIndex"`
+	UUID          types.BinaryUUID `gorm:"uniqueIndex"`
 	Function      string           `gorm:"type:varchar(255);"`
-	Args          string           `gorm:"type:longtext;"`
+	Args          string
 	LastRun       *time.Time
 	Failures      uint64
 	State         CronJobState `gorm:"type:varchar(20);default:'queued'"`
 	LastHeartbeat *time.Time
 	Version       uint64 `gorm:"default:0"`
+	// TraceContext links the job's runs to the trace it was created in, as propagated headers in JSON
+	TraceContext string `gorm:"type:text;"`
 }
 
 func (t *CronJob) BeforeCreate(_ *gorm.DB) error {
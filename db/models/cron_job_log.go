@@ -16,7 +16,7 @@ func init() {
 
 type CronJobLog struct {
 	gorm.Model
-	CronJobID uint
+	CronJobID uint `gorm:"index"`
 	CronJob   CronJob
 	Type      CronJobLogType
 	Message   string
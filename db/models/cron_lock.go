@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+func init() {
+	registerModel(&CronLock{})
+}
+
+// CronLock is a run of a cron job locked by a node of a cluster without redis. It is held until it expires unless the
+// node extends it.
+type CronLock struct {
+	Name      string `gorm:"primaryKey;size:255"`
+	Owner     string `gorm:"size:64"`
+	ExpiresAt time.Time
+}
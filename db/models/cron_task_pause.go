@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+func init() {
+	registerModel(&CronTaskPause{})
+}
+
+// CronTaskPause holds back the jobs of a task until it is resumed, which deletes it
+type CronTaskPause struct {
+	Function  string `gorm:"primaryKey;size:255"`
+	CreatedAt time.Time
+}
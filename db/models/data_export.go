@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DataExportStatusType string
+
+const (
+	DataExportStatusPending    DataExportStatusType = "pending"
+	DataExportStatusProcessing DataExportStatusType = "processing"
+	DataExportStatusCompleted  DataExportStatusType = "completed"
+	DataExportStatusFailed     DataExportStatusType = "failed"
+	DataExportStatusExpired    DataExportStatusType = "expired"
+)
+
+func init() {
+	registerModel(&DataExport{})
+}
+
+type DataExport struct {
+	gorm.Model
+	UserID        uint `gorm:"index"`
+	User          User
+	Status        DataExportStatusType
+	StatusMessage string
+	ObjectKey     string
+	Size          uint64
+	ExpiresAt     *time.Time
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(&ImpersonationSession{})
+}
+
+type ImpersonationSession struct {
+	gorm.Model
+	AdminID   uint
+	Admin     User
+	UserID    uint
+	User      User
+	Reason    string
+	IP        string
+	ExpiresAt time.Time
+	EndedAt   *time.Time
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(&InviteCode{})
+}
+
+type InviteCode struct {
+	gorm.Model
+	Code        string `gorm:"unique;size:64"`
+	CreatedByID uint
+	CreatedBy   User
+	// MaxUses of 0 allows unlimited uses
+	MaxUses   uint
+	Uses      uint `gorm:"default:0"`
+	ExpiresAt *time.Time
+}
@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+func init() {
+	registerModel(&Organization{})
+}
+
+type Organization struct {
+	gorm.Model
+	Name         string
+	Slug         string `gorm:"unique;size:255"`
+	OwnerID      uint
+	Owner        User
+	StorageQuota uint64 `gorm:"default:0"`
+	Members      []OrganizationMember
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(&OrganizationInvite{})
+}
+
+type OrganizationInvite struct {
+	gorm.Model
+	OrganizationID uint
+	Organization   Organization
+	InvitedByID    uint
+	InvitedBy      User
+	Email          string
+	Role           string `gorm:"type:varchar(20)"`
+	Token          string `gorm:"index"`
+	ExpiresAt      time.Time
+	AcceptedAt     *time.Time
+}
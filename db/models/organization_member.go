@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+func init() {
+	registerModel(&OrganizationMember{})
+}
+
+type OrganizationMember struct {
+	gorm.Model
+	OrganizationID uint `gorm:"uniqueIndex:idx_org_member"`
+	Organization   Organization
+	UserID         uint `gorm:"uniqueIndex:idx_org_member"`
+	User           User
+	Role           string `gorm:"type:varchar(20)"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(&OutboxEvent{})
+}
+
+// OutboxEvent is an event persisted in the transaction of the change it describes, pending delivery to listeners.
+type OutboxEvent struct {
+	gorm.Model
+	// EventID is the idempotency key handed to listeners, it stays the same across redeliveries and replays
+	EventID      string `gorm:"type:varchar(36);uniqueIndex"`
+	Name         string `gorm:"index"`
+	Data         datatypes.JSON
+	DispatchedAt *time.Time `gorm:"index"`
+	Attempts     uint
+	LastError    string
+	// ClaimedBy is the node currently dispatching the event, the claim lapses at ClaimedAt plus the lease
+	ClaimedBy string `gorm:"type:varchar(36)"`
+	ClaimedAt *time.Time
+	// DeadAt is set once the event has failed every attempt, it is no longer dispatched unless replayed
+	DeadAt *time.Time `gorm:"index"`
+}
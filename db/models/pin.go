@@ -8,8 +8,10 @@ func init() {
 
 type Pin struct {
 	gorm.Model
-	UploadID uint
-	Upload   Upload
-	UserID   uint
-	User     User
+	UploadID       uint
+	Upload         Upload
+	UserID         uint
+	User           User
+	OrganizationID *uint `gorm:"index"`
+	Organization   *Organization
 }
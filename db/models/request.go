@@ -32,11 +32,12 @@ type Request struct {
 	System            bool `gorm:"default:false;index:idx_request_operation_system"`
 	UserID            uint
 	User              User
+	OrganizationID    *uint `gorm:"index"`
 	SourceIP          string
 	HashType          uint64
-	Hash              mh.Multihash `gorm:"type:varbinary(64);index"`
+	Hash              mh.Multihash `gorm:"size:64;index"`
 	CIDType           uint64       `gorm:"null;column:cid_type"`
-	UploadHash        mh.Multihash `gorm:"type:varbinary(64);index"`
+	UploadHash        mh.Multihash `gorm:"size:64;index"`
 	UploadHashCIDType uint64       `gorm:"null;column:upload_hash_cid_type"`
 	Size              uint64
 	MimeType          string
@@ -30,6 +30,8 @@ type User struct {
 	Verified           bool `gorm:"default:false;"`
 	EmailVerifications []EmailVerification
 	PasswordResets     []PasswordReset
+	// TokensRevokedAt rejects every token issued up to this time, it is set when the account credentials are reset
+	TokensRevokedAt *time.Time
 }
 
 func (u *User) BeforeUpdate(tx *gorm.DB) error {
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type WebhookDeliveryStatusType string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatusType = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatusType = "delivered"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatusType = "failed"
+)
+
+func init() {
+	registerModel(&WebhookDelivery{})
+	registerModel(&WebhookDeliveryAttempt{})
+}
+
+type WebhookDelivery struct {
+	gorm.Model
+	EndpointID uint            `gorm:"index"`
+	Endpoint   WebhookEndpoint `json:"-"`
+	// EventID is sent with every attempt so receivers can discard redelivered events
+	EventID     string `gorm:"type:varchar(36);index"`
+	Event       string
+	Payload     datatypes.JSON
+	Status      WebhookDeliveryStatusType
+	Attempts    uint
+	DeliveredAt *time.Time
+	Log         []WebhookDeliveryAttempt `gorm:"foreignKey:DeliveryID"`
+}
+
+type WebhookDeliveryAttempt struct {
+	gorm.Model
+	DeliveryID uint `gorm:"index"`
+	StatusCode int
+	Error      string
+	Response   string
+	DurationMS int64
+}
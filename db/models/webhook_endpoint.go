@@ -0,0 +1,40 @@
+package models
+
+import (
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerModel(&WebhookEndpoint{})
+}
+
+type WebhookEndpoint struct {
+	gorm.Model
+	// UserID is nil for admin-level endpoints receiving the events of all users
+	UserID      *uint `gorm:"index"`
+	User        *User
+	URL         string
+	Secret      string `json:"-"`
+	Events      string
+	Description string
+	Enabled     bool `gorm:"default:true"`
+}
+
+// EventList returns the comma separated event filter as a list
+func (w *WebhookEndpoint) EventList() []string {
+	if w.Events == "" {
+		return nil
+	}
+
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribed reports whether the endpoint receives the given event
+func (w *WebhookEndpoint) Subscribed(event string) bool {
+	events := w.EventList()
+
+	return slices.Contains(events, "*") || slices.Contains(events, event)
+}
@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/middleware"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	replicaRouterName  = "portal:replica_router"
+	replicaReadSetting = "portal:replica_read"
+	recentWriteKey     = "portal:db:recent_write:%s"
+
+	// recentWriteAnyScope tracks writes that can not be tied to a user, they keep every read on the primary
+	recentWriteAnyScope = "any"
+)
+
+var _ gorm.Plugin = (*replicaRouter)(nil)
+
+// replicaRouter keeps queries on the primary unless they are marked for a replica with Replica, and remembers which
+// users wrote recently so their reads can stay on the primary. Writes outside of a user's request, such as those of
+// jobs and anonymous requests, keep all reads on the primary instead.
+type replicaRouter struct {
+	window  time.Duration
+	tracker writeTracker
+}
+
+type writeTracker interface {
+	markWrite(ctx context.Context, scope string, window time.Duration)
+	// recentWrite reports whether any of the scopes was written to within its window
+	recentWrite(ctx context.Context, scopes ...string) bool
+}
+
+// Replica returns a session whose reads are served by a read replica, for reads that can live with replication lag.
+// Reads of a user that wrote within the read-your-writes window stay on the primary, as do all reads within the window
+// of a write not made by a user, and everything when no replicas are configured.
+func Replica(ctx context.Context, db *gorm.DB) *gorm.DB {
+	tx := db.WithContext(ctx)
+
+	router, ok := db.Config.Plugins[replicaRouterName].(*replicaRouter)
+	if !ok {
+		return tx
+	}
+
+	if router.recentWrite(ctx) {
+		return tx
+	}
+
+	return tx.Set(replicaReadSetting, true).Session(&gorm.Session{})
+}
+
+// ReplicaRead runs a read on a replica like RetryOnLock does. A record missing on the replica is looked up on the
+// primary as well, since the replica may not have caught up with it being created.
+func ReplicaRead(ctx context.Context, db *gorm.DB, operation func(*gorm.DB) *gorm.DB) error {
+	err := RetryOnLock(Replica(ctx, db), operation)
+
+	if _, ok := db.Config.Plugins[replicaRouterName]; ok && errors.Is(err, gorm.ErrRecordNotFound) {
+		return RetryOnLock(db.WithContext(ctx), operation)
+	}
+
+	return err
+}
+
+func registerReplicas(cm config.Manager, db *gorm.DB) error {
+	dbCfg := cm.Config().Core.DB
+	if len(dbCfg.Replicas) == 0 {
+		return nil
+	}
+
+	replicas := make([]gorm.Dialector, 0, len(dbCfg.Replicas))
+	for _, replica := range dbCfg.Replicas {
+		switch dbCfg.Type {
+		case "mysql":
+			replicas = append(replicas, mysqlDialector(dbCfg.Replica(replica)))
+		case "postgres":
+			replicas = append(replicas, postgresDialector(dbCfg.Replica(replica)))
+		default:
+			return fmt.Errorf("read replicas are not supported for database type %s", dbCfg.Type)
+		}
+	}
+
+	// Transactions and writes always go to the primary
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	})); err != nil {
+		return err
+	}
+
+	var tracker writeTracker = &memoryWriteTracker{}
+	if cm.Config().Core.ClusterEnabled() && cm.Config().Core.Clustered.RedisEnabled() {
+		client, err := cm.Config().Core.Clustered.Redis.Client()
+		if err != nil {
+			return err
+		}
+
+		// Shared, so a user's next request sees their write whichever node serves it
+		tracker = &redisWriteTracker{client: client}
+	}
+
+	return db.Use(&replicaRouter{
+		window:  time.Duration(dbCfg.ReadYourWritesWindow) * time.Second,
+		tracker: tracker,
+	})
+}
+
+func (r *replicaRouter) Name() string {
+	return replicaRouterName
+}
+
+func (r *replicaRouter) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Query().After("gorm:db_resolver").Before("gorm:query").Register("portal:replica_route", r.route),
+		cb.Row().After("gorm:db_resolver").Before("gorm:row").Register("portal:replica_route", r.route),
+		cb.Raw().After("gorm:db_resolver").Before("gorm:raw").Register("portal:replica_route", r.route),
+		cb.Create().After("gorm:create").Register("portal:replica_track_write", r.trackWrite),
+		cb.Update().After("gorm:update").Register("portal:replica_track_write", r.trackWrite),
+		cb.Delete().After("gorm:delete").Register("portal:replica_track_write", r.trackWrite),
+		cb.Raw().After("gorm:raw").Register("portal:replica_track_write", r.trackWrite),
+	)
+}
+
+// route overrides the resolver's default of sending every read to a replica, switching the connection again right
+// after the resolver picked one
+func (r *replicaRouter) route(db *gorm.DB) {
+	if _, ok := db.Get(replicaReadSetting); ok {
+		dbresolver.Read.ModifyStatement(db.Statement)
+		return
+	}
+
+	dbresolver.Write.ModifyStatement(db.Statement)
+}
+
+func (r *replicaRouter) trackWrite(db *gorm.DB) {
+	if r.window == 0 || db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	r.tracker.markWrite(ctx, writeScope(ctx), r.window)
+}
+
+func (r *replicaRouter) recentWrite(ctx context.Context) bool {
+	if r.window == 0 {
+		return false
+	}
+
+	scopes := []string{recentWriteAnyScope}
+	if scope := writeScope(ctx); scope != recentWriteAnyScope {
+		scopes = append(scopes, scope)
+	}
+
+	return r.tracker.recentWrite(ctx, scopes...)
+}
+
+// writeScope is the user the context belongs to, or recentWriteAnyScope when there is none
+func writeScope(ctx context.Context) string {
+	userID, err := middleware.GetUserFromContext(ctx)
+	if err != nil {
+		return recentWriteAnyScope
+	}
+
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+type memoryWriteTracker struct {
+	writes sync.Map
+}
+
+func (t *memoryWriteTracker) markWrite(_ context.Context, scope string, window time.Duration) {
+	t.writes.Store(scope, time.Now().Add(window))
+}
+
+func (t *memoryWriteTracker) recentWrite(_ context.Context, scopes ...string) bool {
+	recent := false
+
+	for _, scope := range scopes {
+		until, ok := t.writes.Load(scope)
+		if !ok {
+			continue
+		}
+
+		if time.Now().After(until.(time.Time)) {
+			t.writes.CompareAndDelete(scope, until)
+			continue
+		}
+
+		recent = true
+	}
+
+	return recent
+}
+
+type redisWriteTracker struct {
+	client *redis.Client
+}
+
+func (t *redisWriteTracker) markWrite(ctx context.Context, scope string, window time.Duration) {
+	_ = t.client.Set(ctx, fmt.Sprintf(recentWriteKey, scope), 1, window).Err()
+}
+
+// recentWrite errs on the side of the primary when redis can not be asked
+func (t *redisWriteTracker) recentWrite(ctx context.Context, scopes ...string) bool {
+	keys := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		keys = append(keys, fmt.Sprintf(recentWriteKey, scope))
+	}
+
+	exists, err := t.client.Exists(ctx, keys...).Result()
+
+	return err != nil || exists > 0
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"go.lumeweb.com/portal/core"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "portal:tracing_span"
+
+type tracingSpan struct {
+	span   trace.Span
+	parent context.Context
+}
+
+// registerTracing wraps every gorm operation in a span. Queries only get a span when the statement context already
+// carries one, so background polling does not flood the collector with single span traces.
+func registerTracing(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("portal:tracing_before_create", tracingBefore("create")),
+		cb.Create().After("gorm:create").Register("portal:tracing_after_create", tracingAfter),
+		cb.Query().Before("gorm:query").Register("portal:tracing_before_query", tracingBefore("query")),
+		cb.Query().After("gorm:query").Register("portal:tracing_after_query", tracingAfter),
+		cb.Update().Before("gorm:update").Register("portal:tracing_before_update", tracingBefore("update")),
+		cb.Update().After("gorm:update").Register("portal:tracing_after_update", tracingAfter),
+		cb.Delete().Before("gorm:delete").Register("portal:tracing_before_delete", tracingBefore("delete")),
+		cb.Delete().After("gorm:delete").Register("portal:tracing_after_delete", tracingAfter),
+		cb.Row().Before("gorm:row").Register("portal:tracing_before_row", tracingBefore("row")),
+		cb.Row().After("gorm:row").Register("portal:tracing_after_row", tracingAfter),
+		cb.Raw().Before("gorm:raw").Register("portal:tracing_before_raw", tracingBefore("raw")),
+		cb.Raw().After("gorm:raw").Register("portal:tracing_after_raw", tracingAfter),
+	)
+}
+
+func tracingBefore(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+
+		spanCtx, span := core.Tracer().Start(ctx, "db."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			attribute.String("db.system", tx.Dialector.Name()),
+			attribute.String("db.sql.table", tx.Statement.Table),
+		))
+
+		tx.Statement.Context = spanCtx
+		tx.InstanceSet(tracingSpanKey, tracingSpan{span: span, parent: ctx})
+	}
+}
+
+func tracingAfter(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+
+	traced := value.(tracingSpan)
+	// The statement may run again, it must not end up nested in a span that already ended
+	tx.Statement.Context = traced.parent
+
+	span := traced.span
+	span.SetAttributes(
+		attribute.String("db.statement", tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.RowsAffected),
+	)
+	core.RecordSpanError(span, tx.Error)
+	span.End()
+}
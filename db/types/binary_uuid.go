@@ -6,6 +6,8 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // Credits to https://github.com/dipeshdulal/binary-uuid-gorm
@@ -41,6 +43,15 @@ func (BinaryUUID) GormDataType() string {
 	return "binary(16)"
 }
 
+// GormDBDataType -> sql data type of the database dialect, postgres has no fixed size binary type
+func (BinaryUUID) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "bytea"
+	}
+
+	return "binary(16)"
+}
+
 // Scan -> scan value into BinaryUUID
 func (b *BinaryUUID) Scan(value interface{}) error {
 	bytes, ok := value.([]byte)
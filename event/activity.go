@@ -0,0 +1,34 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	EVENT_ACTIVITY_RECORDED = "activity.recorded"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_ACTIVITY_RECORDED, &ActivityRecordedEvent{})
+}
+
+// ActivityRecordedEvent is fired when an entry is added to a user's activity log, for streaming to the user
+type ActivityRecordedEvent struct {
+	core.Event
+}
+
+func (e *ActivityRecordedEvent) SetActivity(activity *models.ActivityEvent) {
+	e.Set("activity", activity)
+}
+
+func (e ActivityRecordedEvent) Activity() *models.ActivityEvent {
+	return e.Get("activity").(*models.ActivityEvent)
+}
+
+func FireActivityRecordedEvent(ctx core.Context, activity *models.ActivityEvent) error {
+	return Fire[*ActivityRecordedEvent](ctx, EVENT_ACTIVITY_RECORDED, func(evt *ActivityRecordedEvent) error {
+		evt.SetActivity(activity)
+		return nil
+	})
+}
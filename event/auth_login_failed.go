@@ -0,0 +1,60 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+)
+
+const (
+	EVENT_AUTH_LOGIN_FAILED = "auth.login.failed"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_AUTH_LOGIN_FAILED, &AuthLoginFailedEvent{})
+}
+
+// AuthLoginFailedEvent is fired for rejected credentials. UserID is 0 when the login did not match an account.
+type AuthLoginFailedEvent struct {
+	core.Event
+}
+
+func (e *AuthLoginFailedEvent) SetEmail(email string) {
+	e.Set("email", email)
+}
+
+func (e AuthLoginFailedEvent) Email() string {
+	return e.Get("email").(string)
+}
+
+func (e *AuthLoginFailedEvent) SetUserID(userId uint) {
+	e.Set("user_id", userId)
+}
+
+func (e AuthLoginFailedEvent) UserID() uint {
+	return e.Get("user_id").(uint)
+}
+
+func (e *AuthLoginFailedEvent) SetIP(ip string) {
+	e.Set("ip", ip)
+}
+
+func (e AuthLoginFailedEvent) IP() string {
+	return e.Get("ip").(string)
+}
+
+func (e *AuthLoginFailedEvent) SetMethod(method string) {
+	e.Set("method", method)
+}
+
+func (e AuthLoginFailedEvent) Method() string {
+	return e.Get("method").(string)
+}
+
+func FireAuthLoginFailedEvent(ctx core.Context, email string, userId uint, ip string, method string) error {
+	return Fire[*AuthLoginFailedEvent](ctx, EVENT_AUTH_LOGIN_FAILED, func(evt *AuthLoginFailedEvent) error {
+		evt.SetEmail(email)
+		evt.SetUserID(userId)
+		evt.SetIP(ip)
+		evt.SetMethod(method)
+		return nil
+	})
+}
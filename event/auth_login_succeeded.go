@@ -0,0 +1,60 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	EVENT_AUTH_LOGIN_SUCCEEDED = "auth.login.succeeded"
+)
+
+// Login methods reported by the login events
+const (
+	LOGIN_METHOD_PASSWORD = "password"
+	LOGIN_METHOD_OTP      = "otp"
+	LOGIN_METHOD_PUBKEY   = "pubkey"
+	LOGIN_METHOD_ID       = "id"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_AUTH_LOGIN_SUCCEEDED, &AuthLoginSucceededEvent{})
+}
+
+// AuthLoginSucceededEvent is fired when a login token is issued, for accounts with OTP enabled that is after the second factor
+type AuthLoginSucceededEvent struct {
+	core.Event
+}
+
+func (e *AuthLoginSucceededEvent) SetUser(user *models.User) {
+	e.Set("user", user)
+}
+
+func (e AuthLoginSucceededEvent) User() *models.User {
+	return e.Get("user").(*models.User)
+}
+
+func (e *AuthLoginSucceededEvent) SetIP(ip string) {
+	e.Set("ip", ip)
+}
+
+func (e AuthLoginSucceededEvent) IP() string {
+	return e.Get("ip").(string)
+}
+
+func (e *AuthLoginSucceededEvent) SetMethod(method string) {
+	e.Set("method", method)
+}
+
+func (e AuthLoginSucceededEvent) Method() string {
+	return e.Get("method").(string)
+}
+
+func FireAuthLoginSucceededEvent(ctx core.Context, user *models.User, ip string, method string) error {
+	return Fire[*AuthLoginSucceededEvent](ctx, EVENT_AUTH_LOGIN_SUCCEEDED, func(evt *AuthLoginSucceededEvent) error {
+		evt.SetUser(user)
+		evt.SetIP(ip)
+		evt.SetMethod(method)
+		return nil
+	})
+}
@@ -0,0 +1,42 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	EVENT_CRON_JOB_FAILED = "cron.job.failed"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_CRON_JOB_FAILED, &CronJobFailedEvent{})
+}
+
+type CronJobFailedEvent struct {
+	core.Event
+}
+
+func (e *CronJobFailedEvent) SetJob(job *models.CronJob) {
+	e.Set("job", job)
+}
+
+func (e CronJobFailedEvent) Job() *models.CronJob {
+	return e.Get("job").(*models.CronJob)
+}
+
+func (e *CronJobFailedEvent) SetError(err string) {
+	e.Set("error", err)
+}
+
+func (e CronJobFailedEvent) Error() string {
+	return e.Get("error").(string)
+}
+
+func FireCronJobFailedEvent(ctx core.Context, job *models.CronJob, jobErr string) error {
+	return Fire[*CronJobFailedEvent](ctx, EVENT_CRON_JOB_FAILED, func(evt *CronJobFailedEvent) error {
+		evt.SetJob(job)
+		evt.SetError(jobErr)
+		return nil
+	})
+}
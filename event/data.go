@@ -0,0 +1,18 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+// Models carried by the events in this package, so they survive the outbox and the cluster event bus
+func init() {
+	core.RegisterEventDataType(&models.User{})
+	core.RegisterEventDataType(&models.Pin{})
+	core.RegisterEventDataType(&models.AccountSuspension{})
+	core.RegisterEventDataType(&models.Request{})
+	core.RegisterEventDataType(&models.ActivityEvent{})
+	core.RegisterEventDataType(&models.Upload{})
+	core.RegisterEventDataType(&models.CronJob{})
+	core.RegisterEventDataType(models.RequestStatusType(""))
+}
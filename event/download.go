@@ -1,6 +1,9 @@
 package event
 
-import "go.lumeweb.com/portal/core"
+import (
+	"go.lumeweb.com/portal/core"
+	"time"
+)
 
 const EVENT_DOWNLOAD_COMPLETED = "download.completed"
 
@@ -36,11 +39,22 @@ func (e *DownloadCompletedEvent) SetIP(ip string) {
 	e.Set("ip", ip)
 }
 
+func (e *DownloadCompletedEvent) SetDuration(duration time.Duration) {
+	e.Set("duration", duration)
+}
+
+// Duration is zero when the protocol did not time the download
+func (e DownloadCompletedEvent) Duration() time.Duration {
+	duration, _ := e.Get("duration").(time.Duration)
+	return duration
+}
+
 func FireDownloadCompletedEvent(ctx core.Context, uploadID uint, bytes uint64, ip string) error {
 	return Fire[*DownloadCompletedEvent](ctx, EVENT_DOWNLOAD_COMPLETED, func(evt *DownloadCompletedEvent) error {
 		evt.SetUploadID(uploadID)
 		evt.SetBytes(bytes)
 		evt.SetIP(ip)
+		evt.SetDuration(0)
 		return nil
 	})
 }
@@ -50,6 +64,28 @@ func FireDownloadCompletedEventAsync(ctx core.Context, uploadID uint, bytes uint
 		evt.SetUploadID(uploadID)
 		evt.SetBytes(bytes)
 		evt.SetIP(ip)
+		evt.SetDuration(0)
+		return nil
+	})
+}
+
+// FireTimedDownloadCompletedEvent also reports how long the download took, for the transfer metrics.
+func FireTimedDownloadCompletedEvent(ctx core.Context, uploadID uint, bytes uint64, ip string, duration time.Duration) error {
+	return Fire[*DownloadCompletedEvent](ctx, EVENT_DOWNLOAD_COMPLETED, func(evt *DownloadCompletedEvent) error {
+		evt.SetUploadID(uploadID)
+		evt.SetBytes(bytes)
+		evt.SetIP(ip)
+		evt.SetDuration(duration)
+		return nil
+	})
+}
+
+func FireTimedDownloadCompletedEventAsync(ctx core.Context, uploadID uint, bytes uint64, ip string, duration time.Duration) error {
+	return FireAsync[*DownloadCompletedEvent](ctx, EVENT_DOWNLOAD_COMPLETED, func(evt *DownloadCompletedEvent) error {
+		evt.SetUploadID(uploadID)
+		evt.SetBytes(bytes)
+		evt.SetIP(ip)
+		evt.SetDuration(duration)
 		return nil
 	})
 }
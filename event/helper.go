@@ -1,9 +1,13 @@
 package event
 
 import (
+	"context"
 	"fmt"
 	"github.com/gookit/event"
 	"go.lumeweb.com/portal/core"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 // Helper function to get and check an event
@@ -71,14 +75,23 @@ func DoFire(
 	event event.Event,
 	async bool,
 ) error {
+	spanCtx, span := core.Tracer().Start(ctx, "event."+event.Name(), trace.WithAttributes(attribute.Bool("event.async", async)))
+	defer span.End()
+
+	publish(spanCtx, ctx, event)
+
 	if async {
 		ctx.Event().FireAsync(event)
 		return nil
 	}
 
-	return ctx.Event().FireEvent(event)
+	err := ctx.Event().FireEvent(event)
+	core.RecordSpanError(span, err)
+
+	return err
 }
 
+// Listen subscribes the handler to the event as fired on this node only.
 func Listen[T event.Event](
 	ctx core.Context,
 	eventName string,
@@ -87,6 +100,43 @@ func Listen[T event.Event](
 	ctx.Event().On(eventName, EventHandler(eventName, handler))
 }
 
+// ListenCluster subscribes the handler to the event as fired on any node of the cluster. Events from other nodes
+// carry the ID of the node they were fired on, see OriginNode.
+func ListenCluster[T event.Event](
+	ctx core.Context,
+	eventName string,
+	handler func(T) error,
+) {
+	ctx.Event().On(eventName, EventHandler(eventName, handler))
+	ctx.Event().On(core.ClusterEventName(eventName), EventHandler(eventName, handler))
+
+	if core.ServiceExists(ctx, core.EVENT_BUS_SERVICE) {
+		core.GetService[core.EventBusService](ctx, core.EVENT_BUS_SERVICE).Subscribe(eventName)
+	}
+}
+
+// OriginNode returns the ID of the node an event received from the cluster was fired on, or an empty string for local events.
+func OriginNode(evt event.Event) string {
+	node, ok := evt.Get(core.EVENT_ORIGIN_NODE_KEY).(string)
+	if !ok {
+		return ""
+	}
+
+	return node
+}
+
+// publish hands the event to the cluster event bus. Failing to reach other nodes must not fail the local fire.
+func publish(spanCtx context.Context, ctx core.Context, evt event.Event) {
+	if !core.ServiceExists(ctx, core.EVENT_BUS_SERVICE) {
+		return
+	}
+
+	bus := core.GetService[core.EventBusService](ctx, core.EVENT_BUS_SERVICE)
+	if err := bus.Publish(spanCtx, evt); err != nil {
+		ctx.Logger().Error("Failed to publish event to the cluster", zap.String("event", evt.Name()), zap.Error(err))
+	}
+}
+
 func fire[T event.Event](
 	ctx core.Context,
 	eventName string,
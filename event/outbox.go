@@ -0,0 +1,69 @@
+package event
+
+import (
+	"github.com/gookit/event"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+// FireTx persists the event to the outbox within the transaction instead of firing it right away. Listeners receive
+// it once the transaction has committed, at least once, with OutboxEventID identifying redeliveries.
+// Every value set on the event must be of a type registered with core.RegisterEventDataType.
+func FireTx[T core.Eventer](
+	ctx core.Context,
+	tx *gorm.DB,
+	eventName string,
+	cb func(evt T) error,
+) error {
+	// A fresh instance, concurrent transactions must not share the data of the registered one
+	instance, err := core.NewEvent(eventName)
+	if err != nil {
+		return err
+	}
+
+	evt, err := AssertEventType[T](instance, eventName)
+	if err != nil {
+		return err
+	}
+
+	if cb != nil {
+		err = cb(evt)
+		if err != nil {
+			return err
+		}
+	}
+
+	outbox := core.GetService[core.OutboxService](ctx, core.OUTBOX_SERVICE)
+
+	return outbox.Enqueue(tx, eventName, evt.Data())
+}
+
+// OutboxEventID returns the idempotency key of an event delivered through the outbox, or an empty string for events fired directly.
+func OutboxEventID(evt event.Event) string {
+	id, ok := evt.Get(core.OUTBOX_EVENT_ID_KEY).(string)
+	if !ok {
+		return ""
+	}
+
+	return id
+}
+
+// outboxUser copies the user without its credentials or associations, outbox rows outlive the change they describe
+// and must not hold secrets
+func outboxUser(user *models.User) *models.User {
+	if user == nil {
+		return nil
+	}
+
+	clean := *user
+	clean.PasswordHash = ""
+	clean.OTPSecret = ""
+	clean.OTPAuthUrl = ""
+	clean.PublicKeys = nil
+	clean.Uploads = nil
+	clean.EmailVerifications = nil
+	clean.PasswordResets = nil
+
+	return &clean
+}
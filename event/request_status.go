@@ -0,0 +1,42 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	EVENT_REQUEST_STATUS_UPDATED = "request.status.updated"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_REQUEST_STATUS_UPDATED, &RequestStatusUpdatedEvent{})
+}
+
+type RequestStatusUpdatedEvent struct {
+	core.Event
+}
+
+func (e *RequestStatusUpdatedEvent) SetRequest(request *models.Request) {
+	e.Set("request", request)
+}
+
+func (e RequestStatusUpdatedEvent) Request() *models.Request {
+	return e.Get("request").(*models.Request)
+}
+
+func (e *RequestStatusUpdatedEvent) SetPreviousStatus(status models.RequestStatusType) {
+	e.Set("previous_status", status)
+}
+
+func (e RequestStatusUpdatedEvent) PreviousStatus() models.RequestStatusType {
+	return e.Get("previous_status").(models.RequestStatusType)
+}
+
+func FireRequestStatusUpdatedEvent(ctx core.Context, request *models.Request, previousStatus models.RequestStatusType) error {
+	return Fire[*RequestStatusUpdatedEvent](ctx, EVENT_REQUEST_STATUS_UPDATED, func(evt *RequestStatusUpdatedEvent) error {
+		evt.SetRequest(request)
+		evt.SetPreviousStatus(previousStatus)
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+)
+
+const (
+	EVENT_STORAGE_QUOTA_WARNING = "storage.quota.warning"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_STORAGE_QUOTA_WARNING, &StorageQuotaWarningEvent{})
+}
+
+// StorageQuotaWarningEvent is fired when a user's pin brings an organization close to its storage quota
+type StorageQuotaWarningEvent struct {
+	core.Event
+}
+
+func (e *StorageQuotaWarningEvent) SetUserID(userId uint) {
+	e.Set("user_id", userId)
+}
+
+func (e StorageQuotaWarningEvent) UserID() uint {
+	return e.Get("user_id").(uint)
+}
+
+func (e *StorageQuotaWarningEvent) SetOrganizationID(orgId uint) {
+	e.Set("organization_id", orgId)
+}
+
+func (e StorageQuotaWarningEvent) OrganizationID() uint {
+	return e.Get("organization_id").(uint)
+}
+
+func (e *StorageQuotaWarningEvent) SetUsage(usage uint64) {
+	e.Set("usage", usage)
+}
+
+func (e StorageQuotaWarningEvent) Usage() uint64 {
+	return e.Get("usage").(uint64)
+}
+
+func (e *StorageQuotaWarningEvent) SetQuota(quota uint64) {
+	e.Set("quota", quota)
+}
+
+func (e StorageQuotaWarningEvent) Quota() uint64 {
+	return e.Get("quota").(uint64)
+}
+
+func FireStorageQuotaWarningEvent(ctx core.Context, userId uint, orgId uint, usage uint64, quota uint64) error {
+	return Fire[*StorageQuotaWarningEvent](ctx, EVENT_STORAGE_QUOTA_WARNING, func(evt *StorageQuotaWarningEvent) error {
+		evt.SetUserID(userId)
+		evt.SetOrganizationID(orgId)
+		evt.SetUsage(usage)
+		evt.SetQuota(quota)
+		return nil
+	})
+}
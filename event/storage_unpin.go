@@ -10,7 +10,7 @@ const (
 )
 
 func init() {
-	core.RegisterEvent(EVENT_STORAGE_OBJECT_UNPINNED, &StorageObjectPinnedEvent{})
+	core.RegisterEvent(EVENT_STORAGE_OBJECT_UNPINNED, &StorageObjectUnpinnedEvent{})
 }
 
 type StorageObjectUnpinnedEvent struct {
@@ -29,7 +29,7 @@ func (e StorageObjectUnpinnedEvent) IP() string {
 }
 
 func FireStorageObjectUnpinnedEvent(ctx core.Context, pin *models.Pin) error {
-	return Fire[*StorageObjectPinnedEvent](ctx, EVENT_STORAGE_OBJECT_UNPINNED, func(evt *StorageObjectPinnedEvent) error {
+	return Fire[*StorageObjectUnpinnedEvent](ctx, EVENT_STORAGE_OBJECT_UNPINNED, func(evt *StorageObjectUnpinnedEvent) error {
 		evt.SetPin(pin)
 		return nil
 	})
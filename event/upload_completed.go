@@ -0,0 +1,34 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	EVENT_UPLOAD_COMPLETED = "upload.completed"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_UPLOAD_COMPLETED, &UploadCompletedEvent{})
+}
+
+// UploadCompletedEvent is fired once an upload request has been completed by its protocol
+type UploadCompletedEvent struct {
+	core.Event
+}
+
+func (e *UploadCompletedEvent) SetRequest(request *models.Request) {
+	e.Set("request", request)
+}
+
+func (e UploadCompletedEvent) Request() *models.Request {
+	return e.Get("request").(*models.Request)
+}
+
+func FireUploadCompletedEvent(ctx core.Context, request *models.Request) error {
+	return Fire[*UploadCompletedEvent](ctx, EVENT_UPLOAD_COMPLETED, func(evt *UploadCompletedEvent) error {
+		evt.SetRequest(request)
+		return nil
+	})
+}
@@ -0,0 +1,33 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	EVENT_UPLOAD_DELETED = "upload.deleted"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_UPLOAD_DELETED, &UploadDeletedEvent{})
+}
+
+type UploadDeletedEvent struct {
+	core.Event
+}
+
+func (e *UploadDeletedEvent) SetUpload(upload *models.Upload) {
+	e.Set("upload", upload)
+}
+
+func (e UploadDeletedEvent) Upload() *models.Upload {
+	return e.Get("upload").(*models.Upload)
+}
+
+func FireUploadDeletedEvent(ctx core.Context, upload *models.Upload) error {
+	return Fire[*UploadDeletedEvent](ctx, EVENT_UPLOAD_DELETED, func(evt *UploadDeletedEvent) error {
+		evt.SetUpload(upload)
+		return nil
+	})
+}
@@ -0,0 +1,34 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	EVENT_UPLOAD_FAILED = "upload.failed"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_UPLOAD_FAILED, &UploadFailedEvent{})
+}
+
+// UploadFailedEvent is fired when an upload request moves to the failed status
+type UploadFailedEvent struct {
+	core.Event
+}
+
+func (e *UploadFailedEvent) SetRequest(request *models.Request) {
+	e.Set("request", request)
+}
+
+func (e UploadFailedEvent) Request() *models.Request {
+	return e.Get("request").(*models.Request)
+}
+
+func FireUploadFailedEvent(ctx core.Context, request *models.Request) error {
+	return Fire[*UploadFailedEvent](ctx, EVENT_UPLOAD_FAILED, func(evt *UploadFailedEvent) error {
+		evt.SetRequest(request)
+		return nil
+	})
+}
@@ -0,0 +1,34 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	EVENT_UPLOAD_STARTED = "upload.started"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_UPLOAD_STARTED, &UploadStartedEvent{})
+}
+
+// UploadStartedEvent is fired when the request for a new upload is created
+type UploadStartedEvent struct {
+	core.Event
+}
+
+func (e *UploadStartedEvent) SetRequest(request *models.Request) {
+	e.Set("request", request)
+}
+
+func (e UploadStartedEvent) Request() *models.Request {
+	return e.Get("request").(*models.Request)
+}
+
+func FireUploadStartedEvent(ctx core.Context, request *models.Request) error {
+	return Fire[*UploadStartedEvent](ctx, EVENT_UPLOAD_STARTED, func(evt *UploadStartedEvent) error {
+		evt.SetRequest(request)
+		return nil
+	})
+}
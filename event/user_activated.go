@@ -3,6 +3,7 @@ package event
 import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
 )
 
 const (
@@ -31,3 +32,10 @@ func FireUserActivatedEvent(ctx core.Context, user *models.User) error {
 		return nil
 	})
 }
+
+func FireUserActivatedEventTx(ctx core.Context, tx *gorm.DB, user *models.User) error {
+	return FireTx[*UserActivatedEvent](ctx, tx, EVENT_USER_ACTIVATED, func(evt *UserActivatedEvent) error {
+		evt.SetUser(outboxUser(user))
+		return nil
+	})
+}
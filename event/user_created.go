@@ -3,6 +3,7 @@ package event
 import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
 )
 
 const (
@@ -31,3 +32,10 @@ func FireUserCreatedEvent(ctx core.Context, user *models.User) error {
 		return nil
 	})
 }
+
+func FireUserCreatedEventTx(ctx core.Context, tx *gorm.DB, user *models.User) error {
+	return FireTx[*UserCreatedEvent](ctx, tx, EVENT_USER_CREATED, func(evt *UserCreatedEvent) error {
+		evt.SetUser(outboxUser(user))
+		return nil
+	})
+}
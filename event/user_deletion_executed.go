@@ -0,0 +1,41 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EVENT_USER_DELETION_EXECUTED = "user.deletion.executed"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_USER_DELETION_EXECUTED, &UserDeletionExecutedEvent{})
+}
+
+type UserDeletionExecutedEvent struct {
+	core.Event
+}
+
+func (e *UserDeletionExecutedEvent) SetUser(user *models.User) {
+	e.Set("user", user)
+}
+
+func (e UserDeletionExecutedEvent) User() *models.User {
+	return e.Get("user").(*models.User)
+}
+
+func FireUserDeletionExecutedEvent(ctx core.Context, user *models.User) error {
+	return Fire[*UserDeletionExecutedEvent](ctx, EVENT_USER_DELETION_EXECUTED, func(evt *UserDeletionExecutedEvent) error {
+		evt.SetUser(user)
+		return nil
+	})
+}
+
+func FireUserDeletionExecutedEventTx(ctx core.Context, tx *gorm.DB, user *models.User) error {
+	return FireTx[*UserDeletionExecutedEvent](ctx, tx, EVENT_USER_DELETION_EXECUTED, func(evt *UserDeletionExecutedEvent) error {
+		evt.SetUser(outboxUser(user))
+		return nil
+	})
+}
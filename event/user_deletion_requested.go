@@ -0,0 +1,51 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EVENT_USER_DELETION_REQUESTED = "user.deletion.requested"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_USER_DELETION_REQUESTED, &UserDeletionRequestedEvent{})
+}
+
+type UserDeletionRequestedEvent struct {
+	core.Event
+}
+
+func (e *UserDeletionRequestedEvent) SetUser(user *models.User) {
+	e.Set("user", user)
+}
+
+func (e UserDeletionRequestedEvent) User() *models.User {
+	return e.Get("user").(*models.User)
+}
+
+func (e *UserDeletionRequestedEvent) SetIP(ip string) {
+	e.Set("ip", ip)
+}
+
+func (e UserDeletionRequestedEvent) IP() string {
+	return e.Get("ip").(string)
+}
+
+func FireUserDeletionRequestedEvent(ctx core.Context, user *models.User, ip string) error {
+	return Fire[*UserDeletionRequestedEvent](ctx, EVENT_USER_DELETION_REQUESTED, func(evt *UserDeletionRequestedEvent) error {
+		evt.SetUser(user)
+		evt.SetIP(ip)
+		return nil
+	})
+}
+
+func FireUserDeletionRequestedEventTx(ctx core.Context, tx *gorm.DB, user *models.User, ip string) error {
+	return FireTx[*UserDeletionRequestedEvent](ctx, tx, EVENT_USER_DELETION_REQUESTED, func(evt *UserDeletionRequestedEvent) error {
+		evt.SetUser(outboxUser(user))
+		evt.SetIP(ip)
+		return nil
+	})
+}
@@ -0,0 +1,51 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EVENT_USER_EMAIL_CHANGED = "user.email.changed"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_USER_EMAIL_CHANGED, &UserEmailChangedEvent{})
+}
+
+type UserEmailChangedEvent struct {
+	core.Event
+}
+
+func (e *UserEmailChangedEvent) SetUser(user *models.User) {
+	e.Set("user", user)
+}
+
+func (e UserEmailChangedEvent) User() *models.User {
+	return e.Get("user").(*models.User)
+}
+
+func (e *UserEmailChangedEvent) SetOldEmail(email string) {
+	e.Set("old_email", email)
+}
+
+func (e UserEmailChangedEvent) OldEmail() string {
+	return e.Get("old_email").(string)
+}
+
+func FireUserEmailChangedEvent(ctx core.Context, user *models.User, oldEmail string) error {
+	return Fire[*UserEmailChangedEvent](ctx, EVENT_USER_EMAIL_CHANGED, func(evt *UserEmailChangedEvent) error {
+		evt.SetUser(user)
+		evt.SetOldEmail(oldEmail)
+		return nil
+	})
+}
+
+func FireUserEmailChangedEventTx(ctx core.Context, tx *gorm.DB, user *models.User, oldEmail string) error {
+	return FireTx[*UserEmailChangedEvent](ctx, tx, EVENT_USER_EMAIL_CHANGED, func(evt *UserEmailChangedEvent) error {
+		evt.SetUser(outboxUser(user))
+		evt.SetOldEmail(oldEmail)
+		return nil
+	})
+}
@@ -0,0 +1,40 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"gorm.io/gorm"
+)
+
+const (
+	EVENT_USER_OTP_DISABLED = "user.otp.disabled"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_USER_OTP_DISABLED, &UserOTPDisabledEvent{})
+}
+
+type UserOTPDisabledEvent struct {
+	core.Event
+}
+
+func (e *UserOTPDisabledEvent) SetUserID(userId uint) {
+	e.Set("user_id", userId)
+}
+
+func (e UserOTPDisabledEvent) UserID() uint {
+	return e.Get("user_id").(uint)
+}
+
+func FireUserOTPDisabledEvent(ctx core.Context, userId uint) error {
+	return Fire[*UserOTPDisabledEvent](ctx, EVENT_USER_OTP_DISABLED, func(evt *UserOTPDisabledEvent) error {
+		evt.SetUserID(userId)
+		return nil
+	})
+}
+
+func FireUserOTPDisabledEventTx(ctx core.Context, tx *gorm.DB, userId uint) error {
+	return FireTx[*UserOTPDisabledEvent](ctx, tx, EVENT_USER_OTP_DISABLED, func(evt *UserOTPDisabledEvent) error {
+		evt.SetUserID(userId)
+		return nil
+	})
+}
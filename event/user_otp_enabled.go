@@ -0,0 +1,40 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"gorm.io/gorm"
+)
+
+const (
+	EVENT_USER_OTP_ENABLED = "user.otp.enabled"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_USER_OTP_ENABLED, &UserOTPEnabledEvent{})
+}
+
+type UserOTPEnabledEvent struct {
+	core.Event
+}
+
+func (e *UserOTPEnabledEvent) SetUserID(userId uint) {
+	e.Set("user_id", userId)
+}
+
+func (e UserOTPEnabledEvent) UserID() uint {
+	return e.Get("user_id").(uint)
+}
+
+func FireUserOTPEnabledEvent(ctx core.Context, userId uint) error {
+	return Fire[*UserOTPEnabledEvent](ctx, EVENT_USER_OTP_ENABLED, func(evt *UserOTPEnabledEvent) error {
+		evt.SetUserID(userId)
+		return nil
+	})
+}
+
+func FireUserOTPEnabledEventTx(ctx core.Context, tx *gorm.DB, userId uint) error {
+	return FireTx[*UserOTPEnabledEvent](ctx, tx, EVENT_USER_OTP_ENABLED, func(evt *UserOTPEnabledEvent) error {
+		evt.SetUserID(userId)
+		return nil
+	})
+}
@@ -0,0 +1,41 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EVENT_USER_PASSWORD_CHANGED = "user.password.changed"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_USER_PASSWORD_CHANGED, &UserPasswordChangedEvent{})
+}
+
+type UserPasswordChangedEvent struct {
+	core.Event
+}
+
+func (e *UserPasswordChangedEvent) SetUser(user *models.User) {
+	e.Set("user", user)
+}
+
+func (e UserPasswordChangedEvent) User() *models.User {
+	return e.Get("user").(*models.User)
+}
+
+func FireUserPasswordChangedEvent(ctx core.Context, user *models.User) error {
+	return Fire[*UserPasswordChangedEvent](ctx, EVENT_USER_PASSWORD_CHANGED, func(evt *UserPasswordChangedEvent) error {
+		evt.SetUser(user)
+		return nil
+	})
+}
+
+func FireUserPasswordChangedEventTx(ctx core.Context, tx *gorm.DB, user *models.User) error {
+	return FireTx[*UserPasswordChangedEvent](ctx, tx, EVENT_USER_PASSWORD_CHANGED, func(evt *UserPasswordChangedEvent) error {
+		evt.SetUser(outboxUser(user))
+		return nil
+	})
+}
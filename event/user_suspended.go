@@ -0,0 +1,41 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EVENT_USER_SUSPENDED = "user.suspended"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_USER_SUSPENDED, &UserSuspendedEvent{})
+}
+
+type UserSuspendedEvent struct {
+	core.Event
+}
+
+func (e *UserSuspendedEvent) SetSuspension(suspension *models.AccountSuspension) {
+	e.Set("suspension", suspension)
+}
+
+func (e UserSuspendedEvent) Suspension() *models.AccountSuspension {
+	return e.Get("suspension").(*models.AccountSuspension)
+}
+
+func FireUserSuspendedEvent(ctx core.Context, suspension *models.AccountSuspension) error {
+	return Fire[*UserSuspendedEvent](ctx, EVENT_USER_SUSPENDED, func(evt *UserSuspendedEvent) error {
+		evt.SetSuspension(suspension)
+		return nil
+	})
+}
+
+func FireUserSuspendedEventTx(ctx core.Context, tx *gorm.DB, suspension *models.AccountSuspension) error {
+	return FireTx[*UserSuspendedEvent](ctx, tx, EVENT_USER_SUSPENDED, func(evt *UserSuspendedEvent) error {
+		evt.SetSuspension(suspension)
+		return nil
+	})
+}
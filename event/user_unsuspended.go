@@ -0,0 +1,41 @@
+package event
+
+import (
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EVENT_USER_UNSUSPENDED = "user.unsuspended"
+)
+
+func init() {
+	core.RegisterEvent(EVENT_USER_UNSUSPENDED, &UserUnsuspendedEvent{})
+}
+
+type UserUnsuspendedEvent struct {
+	core.Event
+}
+
+func (e *UserUnsuspendedEvent) SetSuspension(suspension *models.AccountSuspension) {
+	e.Set("suspension", suspension)
+}
+
+func (e UserUnsuspendedEvent) Suspension() *models.AccountSuspension {
+	return e.Get("suspension").(*models.AccountSuspension)
+}
+
+func FireUserUnsuspendedEvent(ctx core.Context, suspension *models.AccountSuspension) error {
+	return Fire[*UserUnsuspendedEvent](ctx, EVENT_USER_UNSUSPENDED, func(evt *UserUnsuspendedEvent) error {
+		evt.SetSuspension(suspension)
+		return nil
+	})
+}
+
+func FireUserUnsuspendedEventTx(ctx core.Context, tx *gorm.DB, suspension *models.AccountSuspension) error {
+	return FireTx[*UserUnsuspendedEvent](ctx, tx, EVENT_USER_UNSUSPENDED, func(evt *UserUnsuspendedEvent) error {
+		evt.SetSuspension(suspension)
+		return nil
+	})
+}
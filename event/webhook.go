@@ -0,0 +1,211 @@
+package event
+
+import (
+	"github.com/gookit/event"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+func init() {
+	core.RegisterWebhookEvent(EVENT_USER_CREATED, webhookUserPayload)
+	core.RegisterWebhookEvent(EVENT_USER_ACTIVATED, webhookUserPayload)
+	core.RegisterWebhookEvent(EVENT_USER_SUSPENDED, webhookSuspensionPayload)
+	core.RegisterWebhookEvent(EVENT_USER_UNSUSPENDED, webhookSuspensionPayload)
+	core.RegisterWebhookEvent(EVENT_STORAGE_OBJECT_PINNED, webhookPinPayload)
+	core.RegisterWebhookEvent(EVENT_STORAGE_OBJECT_UNPINNED, webhookPinPayload)
+	core.RegisterWebhookEvent(EVENT_DOWNLOAD_COMPLETED, webhookDownloadPayload)
+	core.RegisterWebhookEvent(EVENT_CONFIG_PROPERTY_UPDATE, webhookConfigPayload)
+	core.RegisterWebhookEvent(EVENT_REQUEST_STATUS_UPDATED, webhookRequestPayload)
+	core.RegisterWebhookEvent(EVENT_UPLOAD_STARTED, webhookRequestPayload)
+	core.RegisterWebhookEvent(EVENT_UPLOAD_COMPLETED, webhookRequestPayload)
+	core.RegisterWebhookEvent(EVENT_UPLOAD_FAILED, webhookRequestPayload)
+	core.RegisterWebhookEvent(EVENT_UPLOAD_DELETED, webhookUploadPayload)
+	core.RegisterWebhookEvent(EVENT_AUTH_LOGIN_SUCCEEDED, webhookLoginPayload)
+	core.RegisterWebhookEvent(EVENT_AUTH_LOGIN_FAILED, webhookLoginFailedPayload)
+	core.RegisterWebhookEvent(EVENT_USER_PASSWORD_CHANGED, webhookUserIDPayload)
+	core.RegisterWebhookEvent(EVENT_USER_EMAIL_CHANGED, webhookEmailChangedPayload)
+	core.RegisterWebhookEvent(EVENT_USER_OTP_ENABLED, webhookUserIDPayload)
+	core.RegisterWebhookEvent(EVENT_USER_OTP_DISABLED, webhookUserIDPayload)
+	core.RegisterWebhookEvent(EVENT_USER_DELETION_REQUESTED, webhookUserIDPayload)
+	core.RegisterWebhookEvent(EVENT_USER_DELETION_EXECUTED, webhookUserIDPayload)
+	core.RegisterWebhookEvent(EVENT_CRON_JOB_FAILED, webhookCronJobPayload)
+}
+
+func webhookUserPayload(evt event.Event) (uint, any, error) {
+	user, ok := evt.Get("user").(*models.User)
+	if !ok || user == nil {
+		return 0, nil, nil
+	}
+
+	return user.ID, map[string]any{
+		"user_id":    user.ID,
+		"email":      user.Email,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"verified":   user.Verified,
+	}, nil
+}
+
+func webhookSuspensionPayload(evt event.Event) (uint, any, error) {
+	suspension, ok := evt.Get("suspension").(*models.AccountSuspension)
+	if !ok || suspension == nil {
+		return 0, nil, nil
+	}
+
+	return suspension.UserID, map[string]any{
+		"user_id":     suspension.UserID,
+		"reason":      suspension.Reason,
+		"expires_at":  suspension.ExpiresAt,
+		"freeze_pins": suspension.FreezePins,
+		"lifted_at":   suspension.LiftedAt,
+	}, nil
+}
+
+func webhookPinPayload(evt event.Event) (uint, any, error) {
+	pin, ok := evt.Get("pin").(*models.Pin)
+	if !ok || pin == nil {
+		return 0, nil, nil
+	}
+
+	return pin.UserID, map[string]any{
+		"pin_id":          pin.ID,
+		"upload_id":       pin.UploadID,
+		"user_id":         pin.UserID,
+		"organization_id": pin.OrganizationID,
+	}, nil
+}
+
+// webhookDownloadPayload reports downloads to admin-level endpoints only, the downloader is not known
+func webhookDownloadPayload(evt event.Event) (uint, any, error) {
+	return 0, map[string]any{
+		"upload_id": evt.Get("upload_id"),
+		"bytes":     evt.Get("bytes"),
+		"ip":        evt.Get("ip"),
+	}, nil
+}
+
+// webhookConfigPayload leaves the new value out, config properties include secrets
+func webhookConfigPayload(evt event.Event) (uint, any, error) {
+	return 0, map[string]any{
+		"key":        evt.Get("property_key"),
+		"category":   evt.Get("category"),
+		"entity":     evt.Get("entity"),
+		"sub_entity": evt.Get("sub_entity"),
+	}, nil
+}
+
+func webhookRequestPayload(evt event.Event) (uint, any, error) {
+	request, ok := evt.Get("request").(*models.Request)
+	if !ok || request == nil {
+		return 0, nil, nil
+	}
+
+	payload := map[string]any{
+		"request_id":     request.ID,
+		"operation":      request.Operation,
+		"protocol":       request.Protocol,
+		"status":         request.Status,
+		"status_message": request.StatusMessage,
+		"size":           request.Size,
+		"mime_type":      request.MimeType,
+	}
+
+	if previous, ok := evt.Get("previous_status").(models.RequestStatusType); ok {
+		payload["previous_status"] = previous
+	}
+
+	return request.UserID, payload, nil
+}
+
+func webhookUploadPayload(evt event.Event) (uint, any, error) {
+	upload, ok := evt.Get("upload").(*models.Upload)
+	if !ok || upload == nil {
+		return 0, nil, nil
+	}
+
+	return upload.UserID, map[string]any{
+		"upload_id": upload.ID,
+		"protocol":  upload.Protocol,
+		"size":      upload.Size,
+		"mime_type": upload.MimeType,
+	}, nil
+}
+
+// webhookUserIDPayload only identifies the account, for events about changes whose details are sensitive
+func webhookUserIDPayload(evt event.Event) (uint, any, error) {
+	var userId uint
+
+	switch {
+	case evt.Get("user") != nil:
+		user, ok := evt.Get("user").(*models.User)
+		if !ok || user == nil {
+			return 0, nil, nil
+		}
+		userId = user.ID
+	case evt.Get("user_id") != nil:
+		id, ok := evt.Get("user_id").(uint)
+		if !ok {
+			return 0, nil, nil
+		}
+		userId = id
+	default:
+		return 0, nil, nil
+	}
+
+	return userId, map[string]any{
+		"user_id": userId,
+	}, nil
+}
+
+func webhookEmailChangedPayload(evt event.Event) (uint, any, error) {
+	user, ok := evt.Get("user").(*models.User)
+	if !ok || user == nil {
+		return 0, nil, nil
+	}
+
+	return user.ID, map[string]any{
+		"user_id":   user.ID,
+		"email":     user.Email,
+		"old_email": evt.Get("old_email"),
+	}, nil
+}
+
+func webhookLoginPayload(evt event.Event) (uint, any, error) {
+	user, ok := evt.Get("user").(*models.User)
+	if !ok || user == nil {
+		return 0, nil, nil
+	}
+
+	return user.ID, map[string]any{
+		"user_id": user.ID,
+		"ip":      evt.Get("ip"),
+		"method":  evt.Get("method"),
+	}, nil
+}
+
+// webhookLoginFailedPayload reaches the account owner only when the login matched an account, attempts against
+// unknown emails are for admin-level endpoints
+func webhookLoginFailedPayload(evt event.Event) (uint, any, error) {
+	userId, _ := evt.Get("user_id").(uint)
+
+	return userId, map[string]any{
+		"user_id": userId,
+		"email":   evt.Get("email"),
+		"ip":      evt.Get("ip"),
+		"method":  evt.Get("method"),
+	}, nil
+}
+
+func webhookCronJobPayload(evt event.Event) (uint, any, error) {
+	job, ok := evt.Get("job").(*models.CronJob)
+	if !ok || job == nil {
+		return 0, nil, nil
+	}
+
+	return 0, map[string]any{
+		"job_id":   job.UUID.String(),
+		"function": job.Function,
+		"failures": job.Failures,
+		"error":    evt.Get("error"),
+	}, nil
+}
@@ -27,7 +27,7 @@ func AccessMiddleware(ctx core.Context) func(http.Handler) http.Handler {
 				return
 			}
 
-			ok, err := accessService.CheckAccess(m.ID, r.Host, r.URL.Path, r.Method)
+			ok, err := accessService.CheckRequestAccess(m.ID, r)
 			if err != nil {
 				deny()
 				return
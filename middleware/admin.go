@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"go.lumeweb.com/portal/core"
+	"net/http"
+)
+
+func AdminMiddleware(ctx core.Context) func(http.Handler) http.Handler {
+	accessService := ctx.Service(core.ACCESS_SERVICE).(core.AccessService)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deny := func() {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			}
+
+			user, err := GetUserFromContext(r.Context())
+			if err != nil {
+				deny()
+				return
+			}
+
+			// Impersonation sessions never carry admin privileges
+			if _, impersonating := GetImpersonatorFromContext(r.Context()); impersonating {
+				deny()
+				return
+			}
+
+			ok, err := accessService.UserHasRole(user, core.ACCESS_ADMIN_ROLE)
+			if err != nil || !ok {
+				deny()
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
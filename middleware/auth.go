@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
 	"net/http"
 	"strconv"
 	"strings"
@@ -91,6 +92,11 @@ func AuthMiddleware(options AuthMiddlewareOptions) func(http.Handler) http.Handl
 
 				audList = &aud
 
+				// Impersonation tokens stand in for login tokens
+				if options.Purpose == core.JWTPurposeLogin && jwtPurposeEqual(aud, core.JWTPurposeImpersonate) {
+					return nil
+				}
+
 				if options.Purpose != core.JWTPurposeNone && !jwtPurposeEqual(aud, options.Purpose) {
 					return core.ErrJWTInvalid
 				}
@@ -145,13 +151,18 @@ func AuthMiddleware(options AuthMiddlewareOptions) func(http.Handler) http.Handl
 				return
 			}
 
-			exists, _, err := userService.AccountExists(uint(userId))
+			exists, user, err := userService.AccountExists(uint(userId))
 
 			if !exists || err != nil {
 				http.Error(w, core.ErrJWTInvalid.Error(), http.StatusBadRequest)
 				return
 			}
 
+			if tokenRevoked(user, claim) {
+				http.Error(w, core.ErrJWTRevoked.Error(), http.StatusUnauthorized)
+				return
+			}
+
 			pendingDelete, err := userService.IsAccountPendingDeletion(uint(userId))
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -164,11 +175,89 @@ func AuthMiddleware(options AuthMiddlewareOptions) func(http.Handler) http.Handl
 				return
 			}
 
+			suspended, err := userService.IsAccountSuspended(uint(userId))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if suspended {
+				acctErr := core.NewAccountError(core.ErrKeyAccountSuspended, nil)
+				http.Error(w, acctErr.Error(), acctErr.HttpStatus())
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), UserIdContextKeyType(options.AuthContextKey), uint(userId))
 			ctx = context.WithValue(ctx, AUTH_TOKEN_CONTEXT_KEY, authToken)
+
+			if jwtPurposeEqual(claim.Audience, core.JWTPurposeImpersonate) {
+				session, err := validImpersonationSession(options.Context, claim)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				if session == nil {
+					acctErr := core.NewAccountError(core.ErrKeyImpersonationSessionInvalid, nil)
+					http.Error(w, acctErr.Error(), acctErr.HttpStatus())
+					return
+				}
+
+				ctx = context.WithValue(ctx, IMPERSONATOR_CONTEXT_KEY, session.AdminID)
+			}
 			r = r.WithContext(ctx)
 
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// tokenRevoked reports whether the token was issued before the user's tokens were revoked. Issue times only
+// have second precision, so a token from the same second as the revocation counts as revoked.
+func tokenRevoked(user *models.User, claim *jwt.RegisteredClaims) bool {
+	if user.TokensRevokedAt == nil {
+		return false
+	}
+
+	if claim.IssuedAt == nil {
+		return true
+	}
+
+	return !claim.IssuedAt.Time.After(*user.TokensRevokedAt)
+}
+
+// DenyImpersonationMiddleware rejects requests made through an impersonation session. It goes after AuthMiddleware
+// on routes that act on the account itself, such as changing the password, email or OTP settings or deleting the account.
+func DenyImpersonationMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, impersonating := GetImpersonatorFromContext(r.Context()); impersonating {
+				acctErr := core.NewAccountError(core.ErrKeyImpersonationActionDenied, nil)
+				http.Error(w, acctErr.Error(), acctErr.HttpStatus())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func validImpersonationSession(ctx core.Context, claim *jwt.RegisteredClaims) (*models.ImpersonationSession, error) {
+	sessionId, err := strconv.ParseUint(claim.ID, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	adminService := core.GetService[core.AdminService](ctx, core.ADMIN_SERVICE)
+
+	valid, session, err := adminService.ValidImpersonation(uint(sessionId))
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid || strconv.FormatUint(uint64(session.UserID), 10) != claim.Subject {
+		return nil, nil
+	}
+
+	return session, nil
+}
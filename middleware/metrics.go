@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"github.com/felixge/httpsnoop"
+	"go.lumeweb.com/portal/core"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	metricsSubdomainRoot  = "root"
+	metricsSubdomainOther = "other"
+	metricsMethodOther    = "OTHER"
+)
+
+var metricsMethods = map[string]struct{}{
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodPatch:   {},
+	http.MethodDelete:  {},
+	http.MethodOptions: {},
+}
+
+// MetricsMiddleware records the latency and status of every request against the API subdomain it was made to.
+// Hosts and methods outside the known set are grouped so clients cannot grow the label space.
+func MetricsMiddleware(ctx core.Context) func(h http.Handler) http.Handler {
+	domain := ctx.Config().Config().Core.Domain
+	subdomains := make(map[string]struct{})
+
+	for _, api := range core.GetAPIs() {
+		subdomains[api.Subdomain()] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// httpsnoop keeps the optional interfaces such as http.Flusher that streaming handlers rely on
+			m := httpsnoop.CaptureMetrics(next, w, r)
+
+			method := r.Method
+			if _, ok := metricsMethods[method]; !ok {
+				method = metricsMethodOther
+			}
+
+			ctx.Metrics().HTTPRequestDuration.
+				WithLabelValues(metricsSubdomain(r.Host, domain, subdomains), method, strconv.Itoa(m.Code)).
+				Observe(m.Duration.Seconds())
+		})
+	}
+}
+
+// MetricsTokenMiddleware requires the configured bearer token, an empty token rejects every request.
+func MetricsTokenMiddleware(token string) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func metricsSubdomain(host string, domain string, subdomains map[string]struct{}) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(host)
+
+	if host == domain {
+		return metricsSubdomainRoot
+	}
+
+	subdomain, ok := strings.CutSuffix(host, "."+domain)
+	if !ok {
+		return metricsSubdomainOther
+	}
+
+	if _, ok := subdomains[subdomain]; !ok {
+		return metricsSubdomainOther
+	}
+
+	return subdomain
+}
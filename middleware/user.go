@@ -7,6 +7,7 @@ import (
 
 const DEFAULT_USER_ID_CONTEXT_KEY UserIdContextKeyType = "user_id"
 const AUTH_TOKEN_CONTEXT_KEY AuthTokenContextKeyType = "auth_token"
+const IMPERSONATOR_CONTEXT_KEY UserIdContextKeyType = "impersonator_id"
 
 var (
 	ErrorUserContextInvalid      = errors.New("user id stored in context is not of type uint")
@@ -44,3 +45,10 @@ func GetAuthTokenFromContext(ctx context.Context) (string, error) {
 
 	return authToken, nil
 }
+
+// GetImpersonatorFromContext returns the ID of the admin impersonating the current user, if any.
+func GetImpersonatorFromContext(ctx context.Context) (uint, bool) {
+	adminId, ok := ctx.Value(IMPERSONATOR_CONTEXT_KEY).(uint)
+
+	return adminId, ok
+}
@@ -6,10 +6,10 @@ import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/event"
+	"go.lumeweb.com/portal/tracing"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"os"
-	"reflect"
 	"sync"
 )
 
@@ -19,6 +19,7 @@ var (
 
 type Portal interface {
 	Init() error
+	Boot() error
 	Start() error
 	Stop() error
 	Context() core.Context
@@ -35,7 +36,14 @@ func (p *PortalImpl) Init() error {
 
 	ctx.Logger().Info("Initializing portal")
 
+	tracingOpts, err := tracing.NewTracerProvider(ctx)
+	if err != nil {
+		return err
+	}
+
 	dbInst, ctxOpts := db.NewDatabase(ctx)
+	ctxOpts = append(ctxOpts, core.ContextWithMetrics(ctx.Metrics()))
+	ctxOpts = append(ctxOpts, tracingOpts...)
 
 	opts, err := p.initModels(ctx, dbInst)
 	if err != nil {
@@ -125,6 +133,16 @@ func (p *PortalImpl) Start() error {
 	return nil
 }
 
+// Boot runs the startup funcs so services are ready to use, without starting protocols, cron or HTTP. The boot
+// complete event is not fired either, so services hold back their background workers, such as the outbox dispatcher
+// and the event bus subscription. Operator commands use it in place of Start.
+func (p *PortalImpl) Boot() error {
+	ctx := p.Context()
+	ctx.Logger().Debug("Booting portal")
+
+	return p.startStartupFuncs(ctx)
+}
+
 func (p *PortalImpl) Stop() error {
 	ctx := p.Context()
 	ctx.Logger().Info("Stopping portal")
@@ -247,43 +265,16 @@ func (p *PortalImpl) registerAPIs(ctx core.Context) (ctxOpts []core.ContextBuild
 }
 
 func (p *PortalImpl) initModels(ctx core.Context, dbInst *gorm.DB) (ctxOpts []core.ContextBuilderOption, err error) {
-	plugins := core.GetPlugins()
-
-	models := make([]interface{}, 0)
-	for _, plugin := range plugins {
-		if plugin.Models != nil && len(plugin.Models) > 0 {
-			for _, model := range plugin.Models {
-				typ := reflect.TypeOf(model)
-				if typ.Kind() != reflect.Ptr {
-					ctx.Logger().Error("Model must be a pointer", zap.String("model", typ.Name()))
-					return nil, core.ErrInvalidModel
-				}
-			}
-			models = append(models, plugin.Models...)
-		}
-	}
-
-	migrations := make([]core.DBMigration, 0)
-	for _, plugin := range plugins {
-		if plugin.Migrations != nil && len(plugin.Migrations) > 0 {
-			migrations = append(migrations, plugin.Migrations...)
-		}
+	sets, err := db.MigrationSets()
+	if err != nil {
+		ctx.Logger().Error("Error collecting migrations", zap.Error(err))
+		return nil, err
 	}
 
 	ctxOpts = append(ctxOpts, core.ContextWithStartupFunc(func(ctx core.Context) error {
-		for _, model := range models {
-			typ := reflect.TypeOf(model)
-			if err = dbInst.AutoMigrate(model); err != nil {
-				ctx.Logger().Error("Error migrating model", zap.String("model", typ.Name()), zap.Error(err))
-				return err
-			}
-		}
-
-		for _, migration := range migrations {
-			if err = migration(p.ctx.DB()); err != nil {
-				ctx.Logger().Error("Error running migration", zap.Error(err))
-				return err
-			}
+		if _, err := db.NewMigrator(ctx, dbInst, sets).Migrate(ctx, nil); err != nil {
+			ctx.Logger().Error("Error migrating database", zap.Error(err))
+			return err
 		}
 
 		return nil
@@ -519,6 +510,10 @@ func Init() error {
 	return activePortal.Init()
 }
 
+func Boot() error {
+	return activePortal.Boot()
+}
+
 func Stop() error {
 	return activePortal.Stop()
 }
@@ -8,10 +8,19 @@ import (
 	"github.com/casbin/gorm-adapter/v3"
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/service/internal/access"
+	"gorm.io/gorm"
+	"net/http"
 	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 )
 
+// accessConditionAttr matches attribute references in route conditions so they can be bound to the request attributes
+var accessConditionAttr = regexp.MustCompile(`\battr\.`)
+
 var _ core.AccessService = (*AccessServiceDefault)(nil)
 
 type AccessServiceDefault struct {
@@ -26,6 +35,9 @@ func init() {
 			return NewAccessService()
 		},
 	})
+
+	core.RegisterAccessAttributeProvider(&access.UserAttributeProvider{})
+	core.RegisterAccessAttributeProvider(&access.RequestAttributeProvider{})
 }
 
 func NewAccessService() (*AccessServiceDefault, []core.ContextBuilderOption, error) {
@@ -51,6 +63,12 @@ func (a *AccessServiceDefault) RegisterRoute(subdomain, path, method, role strin
 	return err
 }
 
+func (a *AccessServiceDefault) RegisterConditionalRoute(subdomain, path, method, role, condition string) error {
+	fqdn := fmt.Sprintf("%s.%s", subdomain, a.ctx.Config().Config().Core.Domain)
+	_, err := a.enforcer.AddNamedPolicy("p3", role, fqdn, path, method, accessConditionAttr.ReplaceAllString(condition, "r3.attr."))
+	return err
+}
+
 func (a *AccessServiceDefault) AssignRoleToUser(userId uint, role string) error {
 	userIdStr := strconv.FormatUint(uint64(userId), 10)
 	_, err := a.enforcer.AddRoleForUser(userIdStr, role)
@@ -58,10 +76,91 @@ func (a *AccessServiceDefault) AssignRoleToUser(userId uint, role string) error
 	return err
 }
 
+func (a *AccessServiceDefault) RemoveRoleFromUser(userId uint, role string) error {
+	userIdStr := strconv.FormatUint(uint64(userId), 10)
+	_, err := a.enforcer.DeleteRoleForUser(userIdStr, role)
+
+	return err
+}
+
+func (a *AccessServiceDefault) UserHasRole(userId uint, role string) (bool, error) {
+	return a.enforcer.HasRoleForUser(strconv.FormatUint(uint64(userId), 10), role)
+}
+
 func (a *AccessServiceDefault) CheckAccess(userId uint, fqdn, path, method string) (bool, error) {
 	return a.enforcer.Enforce(strconv.FormatUint(uint64(userId), 10), fqdn, path, method)
 }
 
+func (a *AccessServiceDefault) AssignOrgRoleToUser(userId uint, orgId uint, role string) error {
+	userIdStr := strconv.FormatUint(uint64(userId), 10)
+	_, err := a.enforcer.AddNamedGroupingPolicy("g2", userIdStr, role, orgDomain(orgId))
+
+	return err
+}
+
+func (a *AccessServiceDefault) RemoveOrgRolesFromUser(userId uint, orgId uint) error {
+	userIdStr := strconv.FormatUint(uint64(userId), 10)
+	_, err := a.enforcer.RemoveFilteredNamedGroupingPolicy("g2", 0, userIdStr, "", orgDomain(orgId))
+
+	return err
+}
+
+func (a *AccessServiceDefault) RegisterOrgPolicy(orgId uint, role, obj, act string) error {
+	_, err := a.enforcer.AddNamedPolicy("p2", role, orgDomain(orgId), obj, act)
+	return err
+}
+
+func (a *AccessServiceDefault) CheckOrgAccess(userId uint, orgId uint, obj, act string) (bool, error) {
+	return a.enforcer.Enforce(casbin.NewEnforceContext("2"), strconv.FormatUint(uint64(userId), 10), orgDomain(orgId), obj, act)
+}
+
+func (a *AccessServiceDefault) DeleteOrgPolicies(orgId uint) error {
+	dom := orgDomain(orgId)
+
+	if _, err := a.enforcer.RemoveFilteredNamedPolicy("p2", 1, dom); err != nil {
+		return err
+	}
+
+	_, err := a.enforcer.RemoveFilteredNamedGroupingPolicy("g2", 2, dom)
+
+	return err
+}
+
+func (a *AccessServiceDefault) CheckRequestAccess(userId uint, r *http.Request) (bool, error) {
+	ok, err := a.CheckAccess(userId, r.Host, r.URL.Path, r.Method)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	userIdStr := strconv.FormatUint(uint64(userId), 10)
+
+	// Only resolve attributes when a conditional route could apply
+	policies, err := a.enforcer.GetFilteredNamedPolicy("p3", 1, r.Host)
+	if err != nil {
+		return false, err
+	}
+
+	if len(policies) == 0 {
+		return false, nil
+	}
+
+	attrs := make(map[string]any)
+	for _, provider := range core.GetAccessAttributeProviders() {
+		providerAttrs, err := provider.Attributes(a.ctx, userId, r)
+		if err != nil {
+			return false, err
+		}
+
+		if providerAttrs == nil {
+			providerAttrs = make(map[string]any)
+		}
+
+		attrs[provider.ID()] = providerAttrs
+	}
+
+	return a.enforcer.Enforce(casbin.NewEnforceContext("3"), userIdStr, r.Host, r.URL.Path, r.Method, attrs)
+}
+
 func (a *AccessServiceDefault) ExportUserPolicy(userId uint) ([]*core.AccessPolicy, error) {
 	userIdStr := strconv.FormatUint(uint64(userId), 10)
 	// Get all roles for the user
@@ -118,6 +217,19 @@ func (a *AccessServiceDefault) init() error {
 	// Matchers
 	m.AddDef("m", "m", "g(r.sub, p.sub) && r.dom == p.dom && keyMatch5(r.obj, p.obj) && r.act == p.act")
 
+	// Organization scoped definitions, roles are bound per organization domain
+	m.AddDef("r", "r2", "sub, org, obj, act")
+	m.AddDef("p", "p2", "sub, org, obj, act")
+	m.AddDef("g", "g2", "_, _, _")
+	m.AddDef("e", "e2", "some(where (p.eft == allow))")
+	m.AddDef("m", "m2", "g2(r2.sub, p2.sub, r2.org) && r2.org == p2.org && keyMatch5(r2.obj, p2.obj) && r2.act == p2.act")
+
+	// Conditional route definitions, the policy condition is evaluated against the request attributes
+	m.AddDef("r", "r3", "sub, dom, obj, act, attr")
+	m.AddDef("p", "p3", "sub, dom, obj, act, cond")
+	m.AddDef("e", "e3", "some(where (p.eft == allow))")
+	m.AddDef("m", "m3", "g(r3.sub, p3.sub) && r3.dom == p3.dom && keyMatch5(r3.obj, p3.obj) && r3.act == p3.act && eval(p3.cond)")
+
 	// Load the model
 	enforcer, err := casbin.NewEnforcer(m)
 	if err != nil {
@@ -128,13 +240,18 @@ func (a *AccessServiceDefault) init() error {
 
 	a.enforcer = enforcer
 
-	db := a.ctx.DB()
+	// The adapter gets a session of its own, turning off its migrations must not leak into the shared handle. The
+	// table is migrated with the other models, in the column types of the database in use.
+	db := a.ctx.DB().Session(&gorm.Session{NewDB: true})
 
 	// Load policies from database
 	gormadapter.TurnOffAutoMigrate(db)
 	tbl := models.AccessRule{}
 	tableName := db.NamingStrategy.TableName(reflect.TypeOf(tbl).Name())
-	adapter, _ := gormadapter.NewAdapterByDBWithCustomTable(db, &tbl, tableName)
+	adapter, err := gormadapter.NewAdapterByDBWithCustomTable(db, &tbl, tableName)
+	if err != nil {
+		return err
+	}
 
 	return a.enforcer.InitWithModelAndAdapter(m, adapter)
 }
@@ -150,6 +267,11 @@ func (a *AccessServiceDefault) ExportModel() *core.AccessModel {
 				Value: ast.Value,
 			}
 
+			if key != sec {
+				accessModel.Additional = append(accessModel.Additional, def)
+				continue
+			}
+
 			switch sec {
 			case "r":
 				accessModel.RequestDefinition = def
@@ -165,5 +287,13 @@ func (a *AccessServiceDefault) ExportModel() *core.AccessModel {
 		}
 	}
 
+	slices.SortFunc(accessModel.Additional, func(a, b core.AccessModelDef) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
 	return accessModel
 }
+
+func orgDomain(orgId uint) string {
+	return fmt.Sprintf("org:%d", orgId)
+}
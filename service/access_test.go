@@ -0,0 +1,164 @@
+package service
+
+import (
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/service/internal/access"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestAccessService(t *testing.T) (*AccessServiceDefault, *UserServiceDefault) {
+	t.Helper()
+
+	users := &UserServiceDefault{}
+	ctx := newTestContext(t, &config.Config{Core: config.CoreConfig{Domain: "example.com"}},
+		[]any{&models.User{}, &models.PublicKey{}, &models.Upload{}, &models.EmailVerification{}, &models.PasswordReset{}, &models.AccessRule{}},
+		core.ContextWithService(core.USER_SERVICE, users),
+	)
+
+	*users = UserServiceDefault{ctx: ctx, logger: ctx.Logger(), config: ctx.Config(), db: ctx.DB()}
+
+	service := &AccessServiceDefault{ctx: ctx}
+	if err := service.init(); err != nil {
+		t.Fatalf("failed to init the access service: %v", err)
+	}
+
+	return service, users
+}
+
+// createAccessUser creates a user holding the user role
+func createAccessUser(t *testing.T, service *AccessServiceDefault, users *UserServiceDefault, email string, verified bool) *models.User {
+	t.Helper()
+
+	user := &models.User{Email: email, Verified: verified, Role: core.ACCESS_USER_ROLE}
+	if err := users.db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create the user: %v", err)
+	}
+
+	if err := service.AssignRoleToUser(user.ID, core.ACCESS_USER_ROLE); err != nil {
+		t.Fatalf("failed to assign the role: %v", err)
+	}
+
+	return user
+}
+
+func accessRequest(path string, remoteAddr string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://api.example.com"+path, nil)
+	r.RemoteAddr = remoteAddr
+
+	return r
+}
+
+func TestConditionalRouteEvaluatesUserAttributes(t *testing.T) {
+	service, users := newTestAccessService(t)
+
+	if err := service.RegisterConditionalRoute("api", "/api/reports", http.MethodGet, core.ACCESS_USER_ROLE, "attr.user.verified == true"); err != nil {
+		t.Fatalf("failed to register the route: %v", err)
+	}
+
+	verified := createAccessUser(t, service, users, "verified@example.com", true)
+	unverified := createAccessUser(t, service, users, "unverified@example.com", false)
+
+	if ok, err := service.CheckRequestAccess(verified.ID, accessRequest("/api/reports", "10.0.0.1:1234")); err != nil || !ok {
+		t.Fatalf("expected a verified user to be allowed, got %v, err %v", ok, err)
+	}
+
+	if ok, err := service.CheckRequestAccess(unverified.ID, accessRequest("/api/reports", "10.0.0.1:1234")); err != nil || ok {
+		t.Fatalf("expected an unverified user to be denied, got %v, err %v", ok, err)
+	}
+
+	// The condition only opens the route it was registered for
+	if ok, err := service.CheckRequestAccess(verified.ID, accessRequest("/api/other", "10.0.0.1:1234")); err != nil || ok {
+		t.Fatalf("expected another route to be denied, got %v, err %v", ok, err)
+	}
+}
+
+func TestConditionalRouteEvaluatesRequestAttributes(t *testing.T) {
+	service, users := newTestAccessService(t)
+
+	if err := service.RegisterConditionalRoute("api", "/api/internal", http.MethodGet, core.ACCESS_USER_ROLE, `attr.request.ip == "10.0.0.1"`); err != nil {
+		t.Fatalf("failed to register the route: %v", err)
+	}
+
+	user := createAccessUser(t, service, users, "user@example.com", true)
+
+	if ok, err := service.CheckRequestAccess(user.ID, accessRequest("/api/internal", "10.0.0.1:1234")); err != nil || !ok {
+		t.Fatalf("expected the internal address to be allowed, got %v, err %v", ok, err)
+	}
+
+	if ok, err := service.CheckRequestAccess(user.ID, accessRequest("/api/internal", "192.0.2.1:1234")); err != nil || ok {
+		t.Fatalf("expected an outside address to be denied, got %v, err %v", ok, err)
+	}
+}
+
+func TestConditionalRouteDeniesMissingAttributes(t *testing.T) {
+	service, users := newTestAccessService(t)
+
+	for path, condition := range map[string]string{
+		"/api/unknown-attribute": "attr.user.plan == \"pro\"",
+		"/api/unknown-provider":  "attr.billing.plan == \"pro\"",
+	} {
+		if err := service.RegisterConditionalRoute("api", path, http.MethodGet, core.ACCESS_USER_ROLE, condition); err != nil {
+			t.Fatalf("failed to register the route: %v", err)
+		}
+	}
+
+	user := createAccessUser(t, service, users, "user@example.com", true)
+
+	// A condition on an attribute no provider resolves fails to evaluate and never grants access
+	for _, path := range []string{"/api/unknown-attribute", "/api/unknown-provider"} {
+		if ok, err := service.CheckRequestAccess(user.ID, accessRequest(path, "10.0.0.1:1234")); err == nil || ok {
+			t.Fatalf("expected %s to be denied with an error, got %v, err %v", path, ok, err)
+		}
+	}
+}
+
+func TestUserAttributeProvider(t *testing.T) {
+	service, users := newTestAccessService(t)
+	user := createAccessUser(t, service, users, "user@example.com", true)
+
+	attrs, err := access.UserAttributeProvider{}.Attributes(service.ctx, user.ID, nil)
+	if err != nil {
+		t.Fatalf("failed to resolve the attributes: %v", err)
+	}
+
+	if attrs["id"] != user.ID || attrs["verified"] != true || attrs["otp_enabled"] != false || attrs["role"] != core.ACCESS_USER_ROLE {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+
+	// An unknown user resolves to the defaults so conditions never see a partial set
+	attrs, err = access.UserAttributeProvider{}.Attributes(service.ctx, user.ID+1, nil)
+	if err != nil {
+		t.Fatalf("failed to resolve the attributes: %v", err)
+	}
+
+	if attrs["verified"] != false || attrs["otp_enabled"] != false || attrs["role"] != "" {
+		t.Fatalf("expected the default attributes, got %v", attrs)
+	}
+}
+
+func TestRequestAttributeProvider(t *testing.T) {
+	for remoteAddr, ip := range map[string]string{
+		"10.0.0.1:1234":     "10.0.0.1",
+		"[2001:db8::1]:443": "2001:db8::1",
+		"10.0.0.2":          "10.0.0.2",
+	} {
+		r := accessRequest("/", remoteAddr)
+
+		attrs, err := access.RequestAttributeProvider{}.Attributes(nil, 0, r)
+		if err != nil {
+			t.Fatalf("failed to resolve the attributes: %v", err)
+		}
+
+		if attrs["ip"] != ip || attrs["method"] != http.MethodGet {
+			t.Fatalf("expected ip %s for %s, got %v", ip, remoteAddr, attrs)
+		}
+
+		if hour := attrs["hour"].(int); hour < 0 || hour > 23 {
+			t.Fatalf("unexpected hour %d", hour)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"encoding/json"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+var _ core.ActivityService = (*ActivityServiceDefault)(nil)
+var _ core.Cronable = (*ActivityServiceDefault)(nil)
+
+const (
+	cronTaskPruneActivityName = "PruneActivity"
+
+	// activitySubscriberBuffer is how far a subscriber may fall behind before it is dropped
+	activitySubscriberBuffer = 64
+)
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.ACTIVITY_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewActivityService()
+		},
+		Depends: []string{core.CRON_SERVICE, core.EVENT_BUS_SERVICE},
+	})
+}
+
+type ActivityServiceDefault struct {
+	ctx         core.Context
+	logger      *core.Logger
+	config      config.Manager
+	db          *gorm.DB
+	cron        core.CronService
+	subscribers map[uint]map[chan *models.ActivityEvent]struct{}
+	mu          sync.Mutex
+}
+
+func NewActivityService() (*ActivityServiceDefault, []core.ContextBuilderOption, error) {
+	activity := &ActivityServiceDefault{
+		subscribers: make(map[uint]map[chan *models.ActivityEvent]struct{}),
+	}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			activity.ctx = ctx
+			activity.logger = ctx.ServiceLogger(activity)
+			activity.config = ctx.Config()
+			activity.db = ctx.DB()
+			activity.cron = core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+
+			activity.cron.RegisterEntity(activity)
+
+			// Activity is recorded once, on the node the change happened on, and then streamed from every node
+			event.Listen[*event.RequestStatusUpdatedEvent](ctx, event.EVENT_REQUEST_STATUS_UPDATED, activity.handleRequestStatusUpdated)
+			event.Listen[*event.StorageObjectPinnedEvent](ctx, event.EVENT_STORAGE_OBJECT_PINNED, activity.handlePinned)
+			event.Listen[*event.StorageObjectUnpinnedEvent](ctx, event.EVENT_STORAGE_OBJECT_UNPINNED, activity.handleUnpinned)
+			event.Listen[*event.StorageQuotaWarningEvent](ctx, event.EVENT_STORAGE_QUOTA_WARNING, activity.handleQuotaWarning)
+			event.ListenCluster[*event.ActivityRecordedEvent](ctx, event.EVENT_ACTIVITY_RECORDED, activity.handleActivityRecorded)
+
+			return nil
+		}),
+	)
+
+	return activity, opts, nil
+}
+
+func (a *ActivityServiceDefault) ID() string {
+	return core.ACTIVITY_SERVICE
+}
+
+func (a *ActivityServiceDefault) RegisterTasks(crn core.CronService) error {
+	crn.RegisterTask(cronTaskPruneActivityName, core.CronTaskFuncHandler(a.cronTaskPruneActivity), core.CronTaskDefinitionHourly, core.CronTaskNoArgsFactory, true)
+	crn.SetTaskPriority(cronTaskPruneActivityName, core.CronTaskPriorityBackground)
+
+	return nil
+}
+
+func (a *ActivityServiceDefault) ScheduleJobs(crn core.CronService) error {
+	return crn.CreateJobIfNotExists(cronTaskPruneActivityName, nil)
+}
+
+func (a *ActivityServiceDefault) Record(userId uint, activityType string, data any) (*models.ActivityEvent, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := models.ActivityEvent{
+		UserID: userId,
+		Type:   activityType,
+		Data:   encoded,
+	}
+
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&activity)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := event.FireActivityRecordedEvent(a.ctx, &activity); err != nil {
+		return nil, err
+	}
+
+	return &activity, nil
+}
+
+func (a *ActivityServiceDefault) Since(userId uint, lastId uint) ([]*models.ActivityEvent, error) {
+	var activity []*models.ActivityEvent
+
+	// Streams read the log again for activity that just committed, possibly on another node
+	if err := db.RetryableTransaction(a.ctx, db.Uncached(a.db), func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.ActivityEvent{}).
+			Where("user_id = ? AND id > ?", userId, lastId).
+			Order("id ASC").
+			Find(&activity)
+	}); err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
+
+func (a *ActivityServiceDefault) Subscribe(userId uint) (<-chan *models.ActivityEvent, func()) {
+	ch := make(chan *models.ActivityEvent, activitySubscriberBuffer)
+
+	a.mu.Lock()
+	if a.subscribers[userId] == nil {
+		a.subscribers[userId] = make(map[chan *models.ActivityEvent]struct{})
+	}
+	a.subscribers[userId][ch] = struct{}{}
+	a.mu.Unlock()
+
+	return ch, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		a.unsubscribe(userId, ch)
+	}
+}
+
+// unsubscribe removes and closes the channel, the caller must hold the lock
+func (a *ActivityServiceDefault) unsubscribe(userId uint, ch chan *models.ActivityEvent) {
+	if _, ok := a.subscribers[userId][ch]; !ok {
+		return
+	}
+
+	delete(a.subscribers[userId], ch)
+	if len(a.subscribers[userId]) == 0 {
+		delete(a.subscribers, userId)
+	}
+
+	close(ch)
+}
+
+func (a *ActivityServiceDefault) handleActivityRecorded(evt *event.ActivityRecordedEvent) error {
+	activity := evt.Activity()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for ch := range a.subscribers[activity.UserID] {
+		select {
+		case ch <- activity:
+		default:
+			// A stalled stream must not hold up event delivery, the client resumes from the log when it reconnects
+			a.unsubscribe(activity.UserID, ch)
+		}
+	}
+
+	return nil
+}
+
+func (a *ActivityServiceDefault) handleRequestStatusUpdated(evt *event.RequestStatusUpdatedEvent) error {
+	request := evt.Request()
+
+	a.record(request.UserID, evt.Name(), map[string]any{
+		"request_id":     request.ID,
+		"operation":      request.Operation,
+		"protocol":       request.Protocol,
+		"status":         request.Status,
+		"status_message": request.StatusMessage,
+	})
+
+	return nil
+}
+
+func (a *ActivityServiceDefault) handlePinned(evt *event.StorageObjectPinnedEvent) error {
+	a.recordPin(evt.Name(), evt.Pin())
+
+	return nil
+}
+
+func (a *ActivityServiceDefault) handleUnpinned(evt *event.StorageObjectUnpinnedEvent) error {
+	a.recordPin(evt.Name(), evt.Pin())
+
+	return nil
+}
+
+func (a *ActivityServiceDefault) handleQuotaWarning(evt *event.StorageQuotaWarningEvent) error {
+	a.record(evt.UserID(), evt.Name(), map[string]any{
+		"organization_id": evt.OrganizationID(),
+		"usage":           evt.Usage(),
+		"quota":           evt.Quota(),
+	})
+
+	return nil
+}
+
+func (a *ActivityServiceDefault) recordPin(eventName string, pin *models.Pin) {
+	a.record(pin.UserID, eventName, map[string]any{
+		"pin_id":          pin.ID,
+		"upload_id":       pin.UploadID,
+		"organization_id": pin.OrganizationID,
+	})
+}
+
+// record logs failures instead of returning them, activity must never break the code path that fired the event
+func (a *ActivityServiceDefault) record(userId uint, activityType string, data any) {
+	if userId == 0 {
+		return
+	}
+
+	if _, err := a.Record(userId, activityType, data); err != nil {
+		a.logger.Error("Failed to record activity", zap.Uint("user_id", userId), zap.String("type", activityType), zap.Error(err))
+	}
+}
+
+func (a *ActivityServiceDefault) cronTaskPruneActivity(_ *core.CronTaskNoArgs, ctx core.Context) error {
+	cutoff := time.Now().Add(-time.Duration(a.config.Config().Core.Account.ActivityRetention) * time.Minute)
+
+	return db.RetryableTransaction(ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped().Where("created_at < ?", cutoff).Delete(&models.ActivityEvent{})
+	})
+}
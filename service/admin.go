@@ -0,0 +1,334 @@
+package service
+
+import (
+	"errors"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+var _ core.AdminService = (*AdminServiceDefault)(nil)
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.ADMIN_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewAdminService()
+		},
+		Depends: []string{core.USER_SERVICE, core.OTP_SERVICE, core.PASSWORD_RESET_SERVICE},
+	})
+}
+
+type AdminServiceDefault struct {
+	ctx           core.Context
+	logger        *core.Logger
+	config        config.Manager
+	db            *gorm.DB
+	user          core.UserService
+	otp           core.OTPService
+	passwordReset core.PasswordResetService
+	access        core.AccessService
+}
+
+func NewAdminService() (*AdminServiceDefault, []core.ContextBuilderOption, error) {
+	admin := &AdminServiceDefault{}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			admin.ctx = ctx
+			admin.logger = ctx.ServiceLogger(admin)
+			admin.config = ctx.Config()
+			admin.db = ctx.DB()
+			admin.user = core.GetService[core.UserService](ctx, core.USER_SERVICE)
+			admin.otp = core.GetService[core.OTPService](ctx, core.OTP_SERVICE)
+			admin.passwordReset = core.GetService[core.PasswordResetService](ctx, core.PASSWORD_RESET_SERVICE)
+			admin.access = core.GetService[core.AccessService](ctx, core.ACCESS_SERVICE)
+			return nil
+		}),
+	)
+
+	return admin, opts, nil
+}
+
+func (a AdminServiceDefault) ID() string {
+	return core.ADMIN_SERVICE
+}
+
+func (a AdminServiceDefault) ListUsers(filter core.UserFilter) ([]*models.User, int64, error) {
+	var users []*models.User
+	var total int64
+
+	query := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.User{})
+
+		if filter.Search != "" {
+			search := "%" + strings.ToLower(filter.Search) + "%"
+			// Lowered on both sides, LIKE is case-sensitive on postgres
+			tx = tx.Where("LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?", search, search, search)
+		}
+
+		if filter.Verified != nil {
+			tx = tx.Where("verified = ?", *filter.Verified)
+		}
+
+		if filter.Role != "" {
+			tx = tx.Where("role = ?", filter.Role)
+		}
+
+		return tx
+	}
+
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		return query(tx).Count(&total)
+	}); err != nil {
+		return nil, 0, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		tx = query(tx)
+
+		if filter.Limit > 0 {
+			tx = tx.Limit(filter.Limit)
+		}
+
+		if filter.Offset > 0 {
+			tx = tx.Offset(filter.Offset)
+		}
+
+		return tx.Order("id ASC").Find(&users)
+	}); err != nil {
+		return nil, 0, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return users, total, nil
+}
+
+func (a AdminServiceDefault) VerifyUser(userId uint) error {
+	user, err := a.getUser(userId)
+	if err != nil {
+		return err
+	}
+
+	if user.Verified {
+		return core.NewAccountError(core.ErrKeyAccountAlreadyVerified, nil)
+	}
+
+	user.Verified = true
+
+	// The account is only reported activated once the verification is committed
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Model(&models.User{}).Where("id = ?", userId).Update("verified", true).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := tx.Where(&models.EmailVerification{UserID: userId}).Delete(&models.EmailVerification{}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := event.FireUserActivatedEventTx(a.ctx, tx, user); err != nil {
+			_ = tx.AddError(err)
+		}
+
+		return tx
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
+func (a AdminServiceDefault) DisableUserOTP(userId uint) error {
+	if _, err := a.getUser(userId); err != nil {
+		return err
+	}
+
+	if err := a.otp.OTPDisable(userId); err != nil {
+		return core.NewAccountError(core.ErrKeyOTPDisableFailed, err)
+	}
+
+	return nil
+}
+
+func (a AdminServiceDefault) ForcePasswordReset(userId uint) error {
+	user, err := a.getUser(userId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// An empty hash never validates, so the account can only be recovered through the reset email. Tokens issued
+	// so far and open impersonation sessions are revoked along with it.
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Model(&models.User{}).Where("id = ?", userId).Updates(map[string]any{"password_hash": "", "tokens_revoked_at": &now}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		return tx.Model(&models.ImpersonationSession{}).
+			Where("user_id = ? AND ended_at IS NULL", userId).
+			Update("ended_at", &now)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return a.passwordReset.SendPasswordReset(user)
+}
+
+func (a AdminServiceDefault) SetUserRole(userId uint, role string) error {
+	if role != core.ACCESS_ADMIN_ROLE && role != core.ACCESS_USER_ROLE {
+		return core.NewAccountError(core.ErrKeyInvalidRole, nil)
+	}
+
+	if _, err := a.getUser(userId); err != nil {
+		return err
+	}
+
+	if err := a.user.UpdateAccountInfo(userId, map[string]any{"role": role}); err != nil {
+		return err
+	}
+
+	// Every account keeps the user role, admins are granted the admin role on top of it
+	if role == core.ACCESS_ADMIN_ROLE {
+		if err := a.access.AssignRoleToUser(userId, core.ACCESS_ADMIN_ROLE); err != nil {
+			return core.NewAccountError(core.ErrKeyAssigningAdminRoleFailed, err)
+		}
+
+		return nil
+	}
+
+	return a.access.RemoveRoleFromUser(userId, core.ACCESS_ADMIN_ROLE)
+}
+
+func (a AdminServiceDefault) CancelAccountDeletion(userId uint) error {
+	if _, err := a.getUser(userId); err != nil {
+		return err
+	}
+
+	return a.user.CancelAccountDeletion(userId)
+}
+
+func (a AdminServiceDefault) Impersonate(adminId uint, userId uint, reason string, ip string, duration time.Duration) (string, *models.ImpersonationSession, error) {
+	if adminId == userId {
+		return "", nil, core.NewAccountError(core.ErrKeyImpersonationNotAllowed, nil)
+	}
+
+	if _, err := a.getUser(userId); err != nil {
+		return "", nil, err
+	}
+
+	isAdmin, err := a.access.UserHasRole(userId, core.ACCESS_ADMIN_ROLE)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Admins may not impersonate each other to escalate or hide their actions
+	if isAdmin {
+		return "", nil, core.NewAccountError(core.ErrKeyImpersonationNotAllowed, nil)
+	}
+
+	maxDuration := time.Duration(a.config.Config().Core.Account.ImpersonationMaxDuration) * time.Minute
+	if duration <= 0 || duration > maxDuration {
+		duration = maxDuration
+	}
+
+	session := models.ImpersonationSession{
+		AdminID:   adminId,
+		UserID:    userId,
+		Reason:    reason,
+		IP:        ip,
+		ExpiresAt: time.Now().Add(duration),
+	}
+
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&session)
+	}); err != nil {
+		return "", nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	token, err := core.JWTGenerateImpersonationToken(a.config.Config().Core.Domain, a.config.Config().Core.Identity.PrivateKey(), userId, session.ID, duration)
+	if err != nil {
+		return "", nil, core.NewAccountError(core.ErrKeyJWTGenerationFailed, err)
+	}
+
+	a.logger.Info("Impersonation session started", zap.Uint("session_id", session.ID), zap.Uint("admin_id", adminId), zap.Uint("user_id", userId), zap.String("reason", reason), zap.String("ip", ip), zap.Time("expires_at", session.ExpiresAt))
+
+	return token, &session, nil
+}
+
+func (a AdminServiceDefault) EndImpersonation(sessionId uint) error {
+	valid, session, err := a.ValidImpersonation(sessionId)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return core.NewAccountError(core.ErrKeyImpersonationSessionInvalid, nil)
+	}
+
+	now := time.Now()
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(session).Update("ended_at", &now)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	a.logger.Info("Impersonation session ended", zap.Uint("session_id", session.ID), zap.Uint("admin_id", session.AdminID), zap.Uint("user_id", session.UserID))
+
+	return nil
+}
+
+func (a AdminServiceDefault) ValidImpersonation(sessionId uint) (bool, *models.ImpersonationSession, error) {
+	var session models.ImpersonationSession
+
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.ImpersonationSession{}).First(&session, sessionId)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil, nil
+		}
+		return false, nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if session.EndedAt != nil || session.ExpiresAt.Before(time.Now()) {
+		return false, &session, nil
+	}
+
+	return true, &session, nil
+}
+
+func (a AdminServiceDefault) ListImpersonations(userId uint) ([]*models.ImpersonationSession, error) {
+	var sessions []*models.ImpersonationSession
+
+	if err := db.RetryableTransaction(a.ctx, a.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.ImpersonationSession{}).
+			Where(&models.ImpersonationSession{UserID: userId}).
+			Order("created_at DESC").
+			Find(&sessions)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return sessions, nil
+}
+
+func (a AdminServiceDefault) getUser(userId uint) (*models.User, error) {
+	exists, user, err := a.user.AccountExists(userId)
+	if err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if !exists {
+		return nil, core.NewAccountError(core.ErrKeyUserNotFound, nil)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/config/types"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/middleware"
+	"go.sia.tech/coreutils/wallet"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testPasswordReset records the users a reset was sent to instead of mailing them
+type testPasswordReset struct {
+	core.PasswordResetService
+	sent []uint
+}
+
+func (p *testPasswordReset) SendPasswordReset(user *models.User) error {
+	p.sent = append(p.sent, user.ID)
+	return nil
+}
+
+type testAuth struct {
+	ctx     core.Context
+	users   *UserServiceDefault
+	admin   *AdminServiceDefault
+	resets  *testPasswordReset
+	handler http.Handler
+}
+
+// newTestAuth serves a handler behind the login auth middleware and the given middlewares, the handler answers
+// with 200 and records the impersonator of the request, if any
+func newTestAuth(t *testing.T, impersonator *uint, mws ...func(http.Handler) http.Handler) *testAuth {
+	t.Helper()
+
+	cfg := &config.Config{Core: config.CoreConfig{
+		Domain:   "example.com",
+		Identity: *types.NewIdentityFromSeed(wallet.NewSeedPhrase()),
+	}}
+
+	users := &UserServiceDefault{}
+	admin := &AdminServiceDefault{}
+	ctx := newTestContext(t, cfg, []any{&models.User{}, &models.PublicKey{}, &models.Upload{}, &models.EmailVerification{},
+		&models.PasswordReset{}, &models.AccountDeletion{}, &models.AccountSuspension{}, &models.ImpersonationSession{}},
+		core.ContextWithService(core.USER_SERVICE, users),
+		core.ContextWithService(core.ADMIN_SERVICE, admin),
+	)
+
+	*users = UserServiceDefault{ctx: ctx, logger: ctx.Logger(), config: ctx.Config(), db: ctx.DB()}
+	resets := &testPasswordReset{}
+	*admin = AdminServiceDefault{ctx: ctx, logger: ctx.Logger(), config: ctx.Config(), db: ctx.DB(), user: users, passwordReset: resets}
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if impersonator != nil {
+			*impersonator, _ = middleware.GetImpersonatorFromContext(r.Context())
+		}
+	})
+
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+
+	handler = middleware.AuthMiddleware(middleware.AuthMiddlewareOptions{
+		Context: ctx,
+		Purpose: core.JWTPurposeLogin,
+	})(handler)
+
+	return &testAuth{ctx: ctx, users: users, admin: admin, resets: resets, handler: handler}
+}
+
+func (a *testAuth) createUser(t *testing.T, email string) *models.User {
+	t.Helper()
+
+	user := &models.User{Email: email, PasswordHash: "hash"}
+	if err := a.users.db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create the user: %v", err)
+	}
+
+	return user
+}
+
+func (a *testAuth) loginToken(t *testing.T, userId uint) string {
+	t.Helper()
+
+	token, err := core.JWTGenerateToken(a.ctx.Config().Config().Core.Domain, a.ctx.Config().Config().Core.Identity.PrivateKey(), userId, core.JWTPurposeLogin, false)
+	if err != nil {
+		t.Fatalf("failed to generate the token: %v", err)
+	}
+
+	return token
+}
+
+func (a *testAuth) impersonationToken(t *testing.T, adminId uint, userId uint, tokenUserId uint) (string, *models.ImpersonationSession) {
+	t.Helper()
+
+	session := &models.ImpersonationSession{AdminID: adminId, UserID: userId, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := a.users.db.Create(session).Error; err != nil {
+		t.Fatalf("failed to create the session: %v", err)
+	}
+
+	token, err := core.JWTGenerateImpersonationToken(a.ctx.Config().Config().Core.Domain, a.ctx.Config().Config().Core.Identity.PrivateKey(), tokenUserId, session.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate the token: %v", err)
+	}
+
+	return token, session
+}
+
+func (a *testAuth) status(token string) int {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	a.handler.ServeHTTP(w, r)
+
+	return w.Code
+}
+
+func TestImpersonationTokenFollowsSession(t *testing.T) {
+	var impersonator uint
+	auth := newTestAuth(t, &impersonator)
+	admin := auth.createUser(t, "admin@example.com")
+	user := auth.createUser(t, "user@example.com")
+	other := auth.createUser(t, "other@example.com")
+
+	token, session := auth.impersonationToken(t, admin.ID, user.ID, user.ID)
+
+	if code := auth.status(token); code != http.StatusOK {
+		t.Fatalf("expected an active session to be accepted, got %d", code)
+	}
+
+	if impersonator != admin.ID {
+		t.Fatalf("expected the request to carry impersonator %d, got %d", admin.ID, impersonator)
+	}
+
+	// A token pointing at a session of another user is not accepted
+	stolen, _ := auth.impersonationToken(t, admin.ID, user.ID, other.ID)
+	if code := auth.status(stolen); code != http.StatusUnauthorized {
+		t.Fatalf("expected a token for another user to be rejected, got %d", code)
+	}
+
+	if err := auth.admin.EndImpersonation(session.ID); err != nil {
+		t.Fatalf("failed to end the session: %v", err)
+	}
+
+	if code := auth.status(token); code != http.StatusUnauthorized {
+		t.Fatalf("expected an ended session to be rejected, got %d", code)
+	}
+}
+
+func TestDenyImpersonationMiddleware(t *testing.T) {
+	auth := newTestAuth(t, nil, middleware.DenyImpersonationMiddleware())
+	admin := auth.createUser(t, "admin@example.com")
+	user := auth.createUser(t, "user@example.com")
+
+	if code := auth.status(auth.loginToken(t, user.ID)); code != http.StatusOK {
+		t.Fatalf("expected the account owner to be accepted, got %d", code)
+	}
+
+	token, _ := auth.impersonationToken(t, admin.ID, user.ID, user.ID)
+	if code := auth.status(token); code != http.StatusForbidden {
+		t.Fatalf("expected an impersonation session to be denied, got %d", code)
+	}
+}
+
+func TestForcePasswordResetRevokesTokens(t *testing.T) {
+	auth := newTestAuth(t, nil)
+	admin := auth.createUser(t, "admin@example.com")
+	user := auth.createUser(t, "user@example.com")
+
+	login := auth.loginToken(t, user.ID)
+	impersonation, session := auth.impersonationToken(t, admin.ID, user.ID, user.ID)
+
+	if code := auth.status(login); code != http.StatusOK {
+		t.Fatalf("expected the login token to be accepted, got %d", code)
+	}
+
+	if err := auth.admin.ForcePasswordReset(user.ID); err != nil {
+		t.Fatalf("failed to force the reset: %v", err)
+	}
+
+	if code := auth.status(login); code != http.StatusUnauthorized {
+		t.Fatalf("expected the login token to be revoked, got %d", code)
+	}
+
+	if code := auth.status(impersonation); code != http.StatusUnauthorized {
+		t.Fatalf("expected the impersonation token to be revoked, got %d", code)
+	}
+
+	if valid, _, err := auth.admin.ValidImpersonation(session.ID); err != nil || valid {
+		t.Fatalf("expected the impersonation session to be ended, got valid %v, err %v", valid, err)
+	}
+
+	var stored models.User
+	if err := auth.users.db.First(&stored, user.ID).Error; err != nil || stored.PasswordHash != "" {
+		t.Fatalf("expected the password to be cleared, got %+v, err %v", stored, err)
+	}
+
+	if len(auth.resets.sent) != 1 || auth.resets.sent[0] != user.ID {
+		t.Fatalf("expected a reset to be sent to user %d, got %v", user.ID, auth.resets.sent)
+	}
+}
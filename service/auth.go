@@ -6,7 +6,8 @@ import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/db/models"
-	"golang.org/x/crypto/bcrypt"
+	"go.lumeweb.com/portal/event"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"time"
 )
@@ -25,6 +26,7 @@ func init() {
 
 type AuthServiceDefault struct {
 	ctx    core.Context
+	logger *core.Logger
 	config config.Manager
 	db     *gorm.DB
 	user   core.UserService
@@ -36,6 +38,7 @@ func NewAuthService() (*AuthServiceDefault, []core.ContextBuilderOption, error)
 	opts := core.ContextOptions(
 		core.ContextWithStartupFunc(func(ctx core.Context) error {
 			authService.ctx = ctx
+			authService.logger = ctx.ServiceLogger(authService)
 			authService.config = ctx.Config()
 			authService.db = ctx.DB()
 			authService.user = core.GetService[core.UserService](ctx, core.USER_SERVICE)
@@ -55,14 +58,28 @@ func (a AuthServiceDefault) LoginPassword(email string, password string, ip stri
 	valid, user, err := a.ValidLoginByEmail(email, password)
 
 	if err != nil {
+		if core.IsAccountError(err) && core.AsAccountError(err).IsErrorType(core.ErrKeyInvalidLogin) {
+			a.fireLoginFailed(email, 0, ip, event.LOGIN_METHOD_PASSWORD)
+		}
 		return "", nil, err
 	}
 
 	if !valid {
+		// The account exists, only the password was wrong
+		var userId uint
+		if exists, existing, err := a.user.EmailExists(email); err == nil && exists {
+			userId = existing.ID
+		}
+
+		a.fireLoginFailed(email, userId, ip, event.LOGIN_METHOD_PASSWORD)
 		return "", nil, nil
 	}
 
-	token, err := a.doLogin(user, ip, false, rememberMe)
+	if err := a.user.UpgradePasswordHash(user, password); err != nil {
+		a.logger.Warn("Failed to upgrade password hash", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+
+	token, err := a.doLogin(user, ip, false, rememberMe, event.LOGIN_METHOD_PASSWORD)
 
 	if err != nil {
 		return "", nil, err
@@ -79,17 +96,29 @@ func (a AuthServiceDefault) LoginOTP(userId uint, code string) (string, error) {
 	}
 
 	if !valid {
+		a.fireLoginFailed("", userId, "", event.LOGIN_METHOD_OTP)
 		return "", core.NewAccountError(core.ErrKeyInvalidOTPCode, nil)
 	}
 
+	suspended, err := a.user.IsAccountSuspended(userId)
+	if err != nil {
+		return "", err
+	}
+
+	if suspended {
+		return "", core.NewAccountError(core.ErrKeyAccountSuspended, nil)
+	}
+
 	var user models.User
 	user.ID = userId
 
 	token, tokenErr := core.JWTGenerateToken(a.config.Config().Core.Domain, a.ctx.Config().Config().Core.Identity.PrivateKey(), user.ID, core.JWTPurposeLogin, false)
 	if tokenErr != nil {
-		return "", err
+		return "", core.NewAccountError(core.ErrKeyJWTGenerationFailed, tokenErr)
 	}
 
+	a.fireLoginSucceeded(&user, "", event.LOGIN_METHOD_OTP)
+
 	return token, nil
 }
 
@@ -112,7 +141,7 @@ func (a AuthServiceDefault) LoginPubkey(pubkey string, ip string) (string, error
 
 	user := model.User
 
-	token, err := a.doLogin(&user, ip, true, false)
+	token, err := a.doLogin(&user, ip, true, false, event.LOGIN_METHOD_PUBKEY)
 
 	if err != nil {
 		return "", err
@@ -140,7 +169,7 @@ func (a AuthServiceDefault) LoginID(id uint, ip string) (string, error) {
 		return "", core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
 	}
 
-	token, err := a.doLogin(&user, ip, true, false)
+	token, err := a.doLogin(&user, ip, true, false, event.LOGIN_METHOD_ID)
 
 	if err != nil {
 		return "", err
@@ -206,7 +235,7 @@ func (a AuthServiceDefault) ValidLoginByUserID(id uint, password string) (bool,
 
 	return true, &user, nil
 }
-func (a AuthServiceDefault) doLogin(user *models.User, ip string, bypassSecurity bool, rememberMe bool) (string, error) {
+func (a AuthServiceDefault) doLogin(user *models.User, ip string, bypassSecurity bool, rememberMe bool, method string) (string, error) {
 	purpose := core.JWTPurposeLogin
 
 	if user.OTPEnabled && !bypassSecurity {
@@ -222,6 +251,15 @@ func (a AuthServiceDefault) doLogin(user *models.User, ip string, bypassSecurity
 		return "", core.NewAccountError(core.ErrKeyAccountPendingDeletion, nil)
 	}
 
+	suspended, err := a.user.IsAccountSuspended(user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if suspended {
+		return "", core.NewAccountError(core.ErrKeyAccountSuspended, nil)
+	}
+
 	token, jwtErr := core.JWTGenerateToken(a.config.Config().Core.Domain, a.ctx.Config().Config().Core.Identity.PrivateKey(), user.ID, purpose, rememberMe)
 	if jwtErr != nil {
 		return "", core.NewAccountError(core.ErrKeyJWTGenerationFailed, jwtErr)
@@ -234,10 +272,27 @@ func (a AuthServiceDefault) doLogin(user *models.User, ip string, bypassSecurity
 		return "", err
 	}
 
+	// With OTP enabled the login only succeeds once the second factor is verified
+	if purpose == core.JWTPurposeLogin {
+		a.fireLoginSucceeded(user, ip, method)
+	}
+
 	return token, nil
 }
-func (a AuthServiceDefault) validPassword(user *models.User, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 
-	return err == nil
+// fireLoginSucceeded logs failures instead of returning them, the login already happened and its token must reach the user
+func (a AuthServiceDefault) fireLoginSucceeded(user *models.User, ip string, method string) {
+	if err := event.FireAuthLoginSucceededEvent(a.ctx, user, ip, method); err != nil {
+		a.logger.Error("Failed to fire login succeeded event", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// fireLoginFailed logs failures instead of returning them, the caller is already reporting the failed login
+func (a AuthServiceDefault) fireLoginFailed(email string, userId uint, ip string, method string) {
+	if err := event.FireAuthLoginFailedEvent(a.ctx, email, userId, ip, method); err != nil {
+		a.logger.Error("Failed to fire login failed event", zap.Error(err))
+	}
+}
+func (a AuthServiceDefault) validPassword(user *models.User, password string) bool {
+	return a.user.VerifyPassword(user, password)
 }
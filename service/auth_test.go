@@ -0,0 +1,71 @@
+package service
+
+import (
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/config/types"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/service/internal/password"
+	"go.sia.tech/coreutils/wallet"
+	"strings"
+	"testing"
+)
+
+func newTestAuthService(t *testing.T, hash config.PasswordHashConfig) (*AuthServiceDefault, *UserServiceDefault) {
+	t.Helper()
+
+	cfg := &config.Config{Core: config.CoreConfig{
+		Domain:   "example.com",
+		Identity: *types.NewIdentityFromSeed(wallet.NewSeedPhrase()),
+	}}
+
+	ctx := newTestContext(t, cfg, []any{&models.User{}, &models.AccountDeletion{}, &models.AccountSuspension{}},
+		core.ContextWithEvents(core.GetEvents()...),
+	)
+
+	users := &UserServiceDefault{ctx: ctx, logger: ctx.Logger(), config: ctx.Config(), db: ctx.DB(), passwordHasher: password.NewHasher(hash)}
+
+	return &AuthServiceDefault{ctx: ctx, logger: ctx.Logger(), config: ctx.Config(), db: ctx.DB(), user: users}, users
+}
+
+func TestLoginUpgradesPasswordHash(t *testing.T) {
+	auth, users := newTestAuthService(t, config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2: testArgon2})
+
+	legacy, err := password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashBcrypt, BcryptCost: 4}).Hash("correct horse")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	user := &models.User{Email: "user@example.com", PasswordHash: legacy}
+	if err := users.db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create the user: %v", err)
+	}
+
+	// A failed login leaves the stored hash alone
+	if token, _, err := auth.LoginPassword(user.Email, "wrong horse", "127.0.0.1", false); err != nil || token != "" {
+		t.Fatalf("expected the login to fail, got token %q, err %v", token, err)
+	}
+
+	var stored models.User
+	if err := users.db.First(&stored, user.ID).Error; err != nil || stored.PasswordHash != legacy {
+		t.Fatalf("expected the legacy hash to be kept, got %q, err %v", stored.PasswordHash, err)
+	}
+
+	token, _, err := auth.LoginPassword(user.Email, "correct horse", "127.0.0.1", false)
+	if err != nil || token == "" {
+		t.Fatalf("expected the login to succeed, got token %q, err %v", token, err)
+	}
+
+	if err := users.db.First(&stored, user.ID).Error; err != nil {
+		t.Fatalf("failed to load the user: %v", err)
+	}
+
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$") {
+		t.Fatalf("expected the hash to be upgraded to argon2id on login, got %q", stored.PasswordHash)
+	}
+
+	// The upgraded hash keeps working for the next login
+	if token, _, err := auth.LoginPassword(user.Email, "correct horse", "127.0.0.1", false); err != nil || token == "" {
+		t.Fatalf("expected the login with the upgraded hash to succeed, got token %q, err %v", token, err)
+	}
+}
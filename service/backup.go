@@ -0,0 +1,590 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var _ core.BackupService = (*BackupServiceDefault)(nil)
+var _ core.Cronable = (*BackupServiceDefault)(nil)
+
+const (
+	cronTaskCreateBackupName = "CreateBackup"
+	backupFormatVersion      = 1
+	backupManifestFile       = "manifest.json"
+	backupDatabaseFile       = "database.dump"
+	backupObjectsFile        = "objects.jsonl.gz"
+	backupFilePrefix         = "portal-backup-"
+	backupFileExtension      = ".tar"
+	backupFileTimeFormat     = "20060102T150405Z"
+)
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.BACKUP_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewBackupService()
+		},
+		Depends: []string{core.RENTER_SERVICE, core.CRON_SERVICE},
+	})
+}
+
+type BackupServiceDefault struct {
+	ctx    core.Context
+	logger *core.Logger
+	config config.Manager
+	db     *gorm.DB
+	renter core.RenterService
+	cron   core.CronService
+}
+
+// backupObject is a line of the objects file, the metadata renterd needs to serve an object without its data being
+// uploaded again
+type backupObject struct {
+	Bucket string        `json:"bucket"`
+	Key    string        `json:"key"`
+	Object object.Object `json:"object"`
+}
+
+func NewBackupService() (*BackupServiceDefault, []core.ContextBuilderOption, error) {
+	backup := &BackupServiceDefault{}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			backup.ctx = ctx
+			backup.logger = ctx.ServiceLogger(backup)
+			backup.config = ctx.Config()
+			backup.db = ctx.DB()
+			backup.renter = core.GetService[core.RenterService](ctx, core.RENTER_SERVICE)
+			backup.cron = core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+
+			backup.cron.RegisterEntity(backup)
+			return nil
+		}),
+	)
+
+	return backup, opts, nil
+}
+
+func (b *BackupServiceDefault) ID() string {
+	return core.BACKUP_SERVICE
+}
+
+func (b *BackupServiceDefault) RegisterTasks(crn core.CronService) error {
+	crn.RegisterTask(cronTaskCreateBackupName, core.CronTaskFuncHandler(b.cronTaskCreateBackup), core.CronTaskDefinitionDaily, core.CronTaskNoArgsFactory, true)
+
+	return nil
+}
+
+func (b *BackupServiceDefault) ScheduleJobs(crn core.CronService) error {
+	if !b.config.Config().Core.Backup.Enabled {
+		return nil
+	}
+
+	return crn.CreateJobIfNotExists(cronTaskCreateBackupName, nil)
+}
+
+func (b *BackupServiceDefault) CreateBackup(ctx context.Context, w io.Writer) (*core.BackupManifest, error) {
+	dir, err := os.MkdirTemp("", backupFilePrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer func(dir string) {
+		_ = os.RemoveAll(dir)
+	}(dir)
+
+	manifest := &core.BackupManifest{
+		Version:   backupFormatVersion,
+		Portal:    b.config.Config().Core.Domain,
+		Database:  b.config.Config().Core.DB.Type,
+		CreatedAt: time.Now().UTC(),
+		Files:     make(map[string]string),
+	}
+
+	uploads, err := b.backupDatabase(ctx, dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.backupObjects(ctx, dir, uploads, manifest); err != nil {
+		return nil, err
+	}
+
+	if err := writeBackupArchive(w, dir, manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// backupDatabase dumps the database and lists the uploads from a single snapshot, so the object metadata backed up
+// matches the dump
+func (b *BackupServiceDefault) backupDatabase(ctx context.Context, dir string, manifest *core.BackupManifest) ([]*models.Upload, error) {
+	var uploads []*models.Upload
+
+	sets, err := db.MigrationSets()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Uncached(b.db.WithContext(ctx)).Transaction(func(tx *gorm.DB) error {
+		status, err := db.NewMigrator(b.ctx, tx, sets).Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		manifest.Migrations = make(map[string][]string)
+		for _, migration := range status {
+			if migration.AppliedAt != nil {
+				manifest.Migrations[migration.Owner] = append(manifest.Migrations[migration.Owner], migration.ID)
+			}
+		}
+
+		if err := writeBackupFile(dir, backupDatabaseFile, manifest, func(w io.Writer) error {
+			return db.Export(ctx, tx, w, db.DumpModels())
+		}); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Upload{}).Select("hash", "cid_type", "protocol").Order("id").Find(&uploads).Error
+	}, backupTxOptions(b.db)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
+
+// backupTxOptions asks for a read-only snapshot. SQLite transactions are serializable and take no options.
+func backupTxOptions(tx *gorm.DB) []*sql.TxOptions {
+	if tx.Dialector.Name() == "sqlite" {
+		return nil
+	}
+
+	return []*sql.TxOptions{{Isolation: sql.LevelRepeatableRead, ReadOnly: true}}
+}
+
+// backupObjects exports the renterd metadata of the object and proof of every upload. Uploads whose object renterd
+// does not have are listed in the manifest instead of failing the backup, they can not be restored either way.
+func (b *BackupServiceDefault) backupObjects(ctx context.Context, dir string, uploads []*models.Upload, manifest *core.BackupManifest) error {
+	return writeBackupFile(dir, backupObjectsFile, manifest, func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		enc := json.NewEncoder(zw)
+
+		for _, upload := range uploads {
+			protocol, ok := core.GetProtocol(upload.Protocol).(core.StorageProtocol)
+			if !ok {
+				manifest.MissingObjects = append(manifest.MissingObjects, upload.Hash.B58String())
+				continue
+			}
+
+			key := protocol.EncodeFileName(NewStorageHashFromMultihash(upload.Hash, upload.CIDType, nil))
+
+			found, err := b.backupObject(ctx, enc, protocol.Name(), key)
+			if err != nil {
+				return fmt.Errorf("failed to back up %s: %w", upload.Hash.B58String(), err)
+			}
+
+			if !found {
+				manifest.MissingObjects = append(manifest.MissingObjects, upload.Hash.B58String())
+				continue
+			}
+
+			manifest.Objects++
+
+			// Only some protocols store proofs
+			if _, err := b.backupObject(ctx, enc, protocol.Name(), key+core.PROOF_EXTENSION); err != nil {
+				return fmt.Errorf("failed to back up proof of %s: %w", upload.Hash.B58String(), err)
+			}
+		}
+
+		return zw.Close()
+	})
+}
+
+func (b *BackupServiceDefault) backupObject(ctx context.Context, enc *json.Encoder, bucket string, key string) (bool, error) {
+	obj, err := b.renter.GetObjectMetadata(ctx, bucket, key)
+	if err != nil {
+		if strings.Contains(err.Error(), api.ErrObjectNotFound.Error()) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if obj.Object == nil {
+		return false, nil
+	}
+
+	return true, enc.Encode(backupObject{Bucket: bucket, Key: key, Object: *obj.Object})
+}
+
+func (b *BackupServiceDefault) RestoreBackup(ctx context.Context, r io.Reader, opts core.BackupRestoreOptions) (*core.BackupManifest, error) {
+	dir, err := os.MkdirTemp("", backupFilePrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer func(dir string) {
+		_ = os.RemoveAll(dir)
+	}(dir)
+
+	manifest, err := readBackupArchive(r, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.SkipDatabase {
+		if err := b.checkBackupSchema(ctx, manifest); err != nil {
+			return nil, err
+		}
+
+		if err := b.restoreDatabase(ctx, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.SkipObjects {
+		if err := b.restoreObjects(ctx, dir); err != nil {
+			return manifest, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// checkBackupSchema makes sure every migration the backup was made after is applied here, older backups are
+// imported into the newer schema as they are
+func (b *BackupServiceDefault) checkBackupSchema(ctx context.Context, manifest *core.BackupManifest) error {
+	sets, err := db.MigrationSets()
+	if err != nil {
+		return err
+	}
+
+	status, err := db.NewMigrator(b.ctx, b.db, sets).Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[string]bool, len(status))
+	for _, migration := range status {
+		if migration.AppliedAt != nil {
+			applied[migration.Owner+"/"+migration.ID] = true
+		}
+	}
+
+	var missing []string
+	for owner, ids := range manifest.Migrations {
+		for _, id := range ids {
+			if !applied[owner+"/"+id] {
+				missing = append(missing, owner+"/"+id)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("%w: missing %s", core.ErrBackupSchema, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func (b *BackupServiceDefault) restoreDatabase(ctx context.Context, dir string) error {
+	file, err := os.Open(filepath.Join(dir, backupDatabaseFile))
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	return db.Import(ctx, b.db, file, db.DumpModels())
+}
+
+func (b *BackupServiceDefault) restoreObjects(ctx context.Context, dir string) error {
+	file, err := os.Open(filepath.Join(dir, backupObjectsFile))
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(zr)
+	buckets := make(map[string]bool)
+
+	var errs []error
+	for {
+		var obj backupObject
+		if err := dec.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		if !buckets[obj.Bucket] {
+			if err := b.renter.CreateBucketIfNotExists(obj.Bucket); err != nil {
+				return err
+			}
+			buckets[obj.Bucket] = true
+		}
+
+		if err := b.renter.ImportObjectMetadata(ctx, obj.Bucket, obj.Key, obj.Object); err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", obj.Bucket, obj.Key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (b *BackupServiceDefault) cronTaskCreateBackup(_ *core.CronTaskNoArgs, ctx core.Context) error {
+	cfg := b.config.Config().Core.Backup
+
+	// Left over from before backups were disabled
+	if !cfg.Enabled {
+		return nil
+	}
+
+	dir := cfg.Path
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(b.config.ConfigDir(), dir)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// Written under a temporary name, so a failed backup is never mistaken for one to restore or counted as retained
+	tmp, err := os.CreateTemp(dir, "."+backupFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer func(name string) {
+		_ = os.Remove(name)
+	}(tmp.Name())
+
+	manifest, err := b.CreateBackup(ctx, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(dir, backupFilePrefix+manifest.CreatedAt.Format(backupFileTimeFormat)+backupFileExtension)
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+
+	b.logger.Info("Backup created", zap.String("file", name), zap.Int("objects", manifest.Objects), zap.Int("missing_objects", len(manifest.MissingObjects)))
+
+	return b.pruneBackups(dir, cfg.Retain)
+}
+
+// pruneBackups deletes all but the newest scheduled backups, their names sort by the time they were made
+func (b *BackupServiceDefault) pruneBackups(dir string, retain uint) error {
+	names, err := filepath.Glob(filepath.Join(dir, backupFilePrefix+"*"+backupFileExtension))
+	if err != nil {
+		return err
+	}
+
+	if uint(len(names)) <= retain {
+		return nil
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names[:uint(len(names))-retain] {
+		if err := os.Remove(name); err != nil {
+			b.logger.Error("Failed to delete old backup", zap.String("file", name), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// writeBackupFile writes a file of the archive to dir, recording its digest in the manifest
+func writeBackupFile(dir string, name string, manifest *core.BackupManifest, write func(w io.Writer) error) (err error) {
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	hash := sha256.New()
+	if err := write(io.MultiWriter(file, hash)); err != nil {
+		return err
+	}
+
+	manifest.Files[name] = hex.EncodeToString(hash.Sum(nil))
+
+	return nil
+}
+
+// writeBackupArchive writes the manifest followed by the files it lists as a tar archive, the files are compressed
+// on their own
+func writeBackupArchive(w io.Writer, dir string, manifest *core.BackupManifest) error {
+	tw := tar.NewWriter(w)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    backupManifestFile,
+		Mode:    0o600,
+		Size:    int64(len(manifestData)),
+		ModTime: manifest.CreatedAt,
+	}); err != nil {
+		return err
+	}
+
+	if _, err := tw.Write(manifestData); err != nil {
+		return err
+	}
+
+	for _, name := range []string{backupDatabaseFile, backupObjectsFile} {
+		if err := writeBackupArchiveFile(tw, dir, name, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeBackupArchiveFile(tw *tar.Writer, dir string, name string, modTime time.Time) error {
+	file, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    info.Size(),
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// readBackupArchive extracts the files of an archive to dir, checking them against the digests of its manifest
+// before anything is restored from them
+func readBackupArchive(r io.Reader, dir string) (*core.BackupManifest, error) {
+	tr := tar.NewReader(r)
+
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", core.ErrBackupCorrupt, err)
+	}
+
+	if header.Name != backupManifestFile {
+		return nil, fmt.Errorf("%w: archive does not start with %s", core.ErrBackupCorrupt, backupManifestFile)
+	}
+
+	var manifest core.BackupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %w", core.ErrBackupCorrupt, err)
+	}
+
+	if manifest.Version != backupFormatVersion {
+		return nil, fmt.Errorf("%w: %d", core.ErrBackupVersion, manifest.Version)
+	}
+
+	extracted := make(map[string]bool, len(manifest.Files))
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", core.ErrBackupCorrupt, err)
+		}
+
+		// Only names the manifest lists are extracted, which also keeps them from escaping dir
+		digest, ok := manifest.Files[header.Name]
+		if !ok || header.Name != filepath.Base(header.Name) || extracted[header.Name] {
+			return nil, fmt.Errorf("%w: unexpected file %s", core.ErrBackupCorrupt, header.Name)
+		}
+
+		if err := extractBackupFile(tr, dir, header.Name, digest); err != nil {
+			return nil, err
+		}
+
+		extracted[header.Name] = true
+	}
+
+	for _, name := range []string{backupDatabaseFile, backupObjectsFile} {
+		if !extracted[name] {
+			return nil, fmt.Errorf("%w: missing %s", core.ErrBackupCorrupt, name)
+		}
+	}
+
+	return &manifest, nil
+}
+
+func extractBackupFile(r io.Reader, dir string, name string, digest string) (err error) {
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), r); err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != digest {
+		return fmt.Errorf("%w: digest mismatch of %s", core.ErrBackupCorrupt, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"testing"
+)
+
+func newTestBackup(t *testing.T, migrate bool) *BackupServiceDefault {
+	t.Helper()
+
+	cfg := &config.Config{Core: config.CoreConfig{
+		Domain: "portal.test",
+		DB:     config.DatabaseConfig{Type: "sqlite"},
+	}}
+
+	backup := &BackupServiceDefault{}
+	ctx := newTestContext(t, cfg, db.DumpModels(), core.ContextWithService(core.BACKUP_SERVICE, backup))
+
+	backup.ctx = ctx
+	backup.logger = ctx.Logger()
+	backup.config = ctx.Config()
+	backup.db = ctx.DB()
+
+	if migrate {
+		sets, err := db.MigrationSets()
+		if err != nil {
+			t.Fatalf("failed to collect migrations: %v", err)
+		}
+
+		if _, err := db.NewMigrator(ctx, ctx.DB(), sets).Migrate(context.Background(), nil); err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+	}
+
+	return backup
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	source := newTestBackup(t, true)
+
+	users := []*models.User{
+		{Email: "alice@portal.test", FirstName: "Alice", Verified: true},
+		{Email: "bob@portal.test", FirstName: "Bob"},
+	}
+	if err := source.db.Create(&users).Error; err != nil {
+		t.Fatalf("failed to create users: %v", err)
+	}
+	if err := source.db.Delete(users[1]).Error; err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := source.CreateBackup(context.Background(), &archive)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if len(manifest.Migrations[db.CoreMigrationOwner]) == 0 {
+		t.Fatal("expected the applied core migrations in the manifest")
+	}
+
+	target := newTestBackup(t, true)
+
+	restored, err := target.RestoreBackup(context.Background(), &archive, core.BackupRestoreOptions{})
+	if err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if restored.Portal != "portal.test" {
+		t.Fatalf("expected the manifest of the backup, got portal %q", restored.Portal)
+	}
+
+	var got []models.User
+	if err := target.db.Unscoped().Order("id").Find(&got).Error; err != nil {
+		t.Fatalf("failed to read users: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(got))
+	}
+	if got[0].ID != users[0].ID || got[0].Email != "alice@portal.test" || !got[0].Verified {
+		t.Fatalf("unexpected first user: %+v", got[0])
+	}
+	if !got[1].DeletedAt.Valid {
+		t.Fatal("expected the soft deleted user to stay deleted")
+	}
+
+	// Rows created after a restore must not collide with the restored IDs
+	next := &models.User{Email: "carol@portal.test"}
+	if err := target.db.Create(next).Error; err != nil {
+		t.Fatalf("failed to create user after restore: %v", err)
+	}
+	if next.ID <= users[1].ID {
+		t.Fatalf("expected a new ID after %d, got %d", users[1].ID, next.ID)
+	}
+}
+
+func TestBackupRestoreRequiresSchema(t *testing.T) {
+	source := newTestBackup(t, true)
+
+	var archive bytes.Buffer
+	if _, err := source.CreateBackup(context.Background(), &archive); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+
+	target := newTestBackup(t, false)
+
+	if _, err := target.RestoreBackup(context.Background(), &archive, core.BackupRestoreOptions{}); !errors.Is(err, core.ErrBackupSchema) {
+		t.Fatalf("expected a schema error, got %v", err)
+	}
+}
@@ -9,34 +9,62 @@ import (
 	redislock "github.com/go-co-op/gocron-redis-lock/v2"
 	"github.com/go-co-op/gocron/v2"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.lumeweb.com/portal/config"
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/db/models"
 	"go.lumeweb.com/portal/db/types"
+	"go.lumeweb.com/portal/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"maps"
 	"math"
 	"math/rand"
 	"runtime/debug"
 	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var _ core.CronService = (*CronServiceDefault)(nil)
+var _ core.HealthChecker = (*CronServiceDefault)(nil)
 var _ gocron.Logger = (*cronLogger)(nil)
 
 const failureBackoffBaseDelay = 1 * time.Millisecond
 const failureBackoffMaxDelay = 1 * time.Hour
 const queuePollDuration = 100 * time.Millisecond
 
+var errCronSchedulerNotRunning = errors.New("cron scheduler is not running")
+
+// errCronTaskPaused is returned by the runs of jobs whose task is paused, they are deferred instead of failing
+var errCronTaskPaused = errors.New("cron task is paused")
+
 const redisQueueNamespace = "cron"
 const consumerTag = "cron-consumer"
 const consumerPrefetch = 10
 const deadJobCheckInterval = 1 * time.Minute
 const heartbeatInterval = 5 * time.Minute
 const heartbeatTimeout = 10 * time.Minute
+const queueDepthCollectTimeout = 5 * time.Second
+const cancelPollInterval = 5 * time.Second
+const pauseCacheTTL = 10 * time.Second
+const pausedRecheckInterval = 1 * time.Minute
+const leaseRetryInterval = 5 * time.Second
+
+var cronQueueDepthDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(core.METRICS_NAMESPACE, "cron", "queue_depth"),
+	"Cron jobs queued to run, by task.",
+	[]string{"task"},
+	nil,
+)
 
 func init() {
 	core.RegisterService(core.ServiceInfo{
@@ -58,13 +86,18 @@ type CronServiceDefault struct {
 	taskArgs        sync.Map
 	taskDefs        sync.Map
 	taskRecurring   sync.Map
-	queueMu         sync.Mutex
-	queues          map[string]rmq.Queue
-	redisQueueConn  rmq.Connection
+	taskBackoff     sync.Map
+	taskPriority    sync.Map
+	queue           cronQueue
+	gate            *cronGate
 	cronRunningMap  sync.Map
 	waitForStartMap sync.Map
+	runningJobs     sync.Map
+	pauses          cronPauses
+	tasksOnce       sync.Once
 	booting         bool
 	jobsAddedBoot   []uuid.UUID
+	running         atomic.Bool
 }
 
 type cancelStruct struct {
@@ -76,9 +109,25 @@ func (c *CronServiceDefault) ID() string {
 	return core.CRON_SERVICE
 }
 
+// HealthChecks reports no checks when cron is disabled on this node, a scheduler that never starts is not a failure
+func (c *CronServiceDefault) HealthChecks() []core.HealthCheck {
+	if !c.config.Config().Core.Cron.Enabled {
+		return nil
+	}
+
+	return []core.HealthCheck{{
+		Name: "cron",
+		Check: func(ctx context.Context) error {
+			if !c.running.Load() {
+				return errCronSchedulerNotRunning
+			}
+			return nil
+		},
+	}}
+}
+
 func NewCronService() (*CronServiceDefault, []core.ContextBuilderOption, error) {
 	cron := &CronServiceDefault{
-		queues:        make(map[string]rmq.Queue),
 		booting:       true,
 		jobsAddedBoot: make([]uuid.UUID, 0),
 	}
@@ -89,16 +138,23 @@ func NewCronService() (*CronServiceDefault, []core.ContextBuilderOption, error)
 			cron.config = ctx.Config()
 			cron.db = ctx.DB()
 			cron.logger = ctx.ServiceLogger(cron)
+			cron.gate = newCronGate(int(ctx.Config().Config().Core.Cron.MaxQueue))
+			ctx.Metrics().Registry().MustRegister(&cronQueueCollector{cron: cron})
 			return nil
 		}),
 		core.ContextWithStartupFunc(func(ctx core.Context) error {
-			scheduler, queue, err := newScheduler(ctx.Config(), ctx.Logger())
+			scheduler, queue, err := newScheduler(ctx.Config(), ctx.DB(), ctx.Logger())
 			if err != nil {
 				return err
 			}
 
 			cron.scheduler = scheduler
-			cron.redisQueueConn = queue
+
+			if queue != nil {
+				cron.queue = newCronRedisQueue(cron, queue)
+			} else if ctx.Config().Config().Core.ClusterEnabled() {
+				cron.queue = newCronDBQueue(cron)
+			}
 
 			return nil
 		}),
@@ -110,6 +166,38 @@ func NewCronService() (*CronServiceDefault, []core.ContextBuilderOption, error)
 	return cron, opts, nil
 }
 
+// cronQueueCollector reads the queue depth from the database at scrape time, so every node reports the whole cluster
+type cronQueueCollector struct {
+	cron *CronServiceDefault
+}
+
+func (q *cronQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cronQueueDepthDesc
+}
+
+func (q *cronQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	var depths []struct {
+		Function string
+		Count    int64
+	}
+
+	ctx, cancel := context.WithTimeout(q.cron.ctx, queueDepthCollectTimeout)
+	defer cancel()
+
+	if err := q.cron.db.WithContext(ctx).Model(&models.CronJob{}).
+		Select("function, COUNT(*) AS count").
+		Where(&models.CronJob{State: models.CronJobStateQueued}).
+		Group("function").
+		Scan(&depths).Error; err != nil {
+		q.cron.logger.Error("Failed to collect cron queue depth", zap.Error(err))
+		return
+	}
+
+	for _, depth := range depths {
+		ch <- prometheus.MustNewConstMetric(cronQueueDepthDesc, prometheus.GaugeValue, float64(depth.Count), depth.Function)
+	}
+}
+
 type cronLogger struct {
 	logger *core.Logger
 }
@@ -134,52 +222,83 @@ func NewCronLogger(logger *core.Logger) gocron.Logger {
 	return &cronLogger{logger: logger}
 }
 
-func newScheduler(cm config.Manager, logger *core.Logger) (gocron.Scheduler, rmq.Connection, error) {
+// newScheduler returns the rmq connection of the queue of a cluster using redis, clusters using the database have no
+// connection
+func newScheduler(cm config.Manager, db *gorm.DB, logger *core.Logger) (gocron.Scheduler, rmq.Connection, error) {
 	cfg := cm.Config()
-	if cfg.Core.ClusterEnabled() && cfg.Core.Clustered.Redis != nil {
-		redisClient, err := cfg.Core.Clustered.Redis.Client()
-		if err != nil {
-			return nil, nil, err
-		}
-		locker, err := redislock.NewRedisLocker(redisClient, redislock.WithTries(1), redislock.WithExpiry(heartbeatTimeout))
+	if !cfg.Core.ClusterEnabled() {
+		scheduler, err := gocron.NewScheduler(gocron.WithLogger(NewCronLogger(logger)))
 		if err != nil {
 			return nil, nil, err
 		}
 
-		errCh := make(chan error)
-		go func(errCh chan error) {
-			for err := range errCh {
-				logger.Error("rmq Background error", zap.Error(err))
-			}
-		}(errCh)
+		return scheduler, nil, nil
+	}
 
-		client, err := rmq.OpenConnectionWithRedisClient(consumerTag, redisClient, errCh)
+	queue, err := cronQueueBackend(cfg.Core)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if queue == config.CronQueueDatabase {
+		scheduler, err := gocron.NewScheduler(gocron.WithDistributedLocker(newCronDBLocker(db, heartbeatTimeout)), gocron.WithLogger(NewCronLogger(logger)))
 		if err != nil {
 			return nil, nil, err
 		}
 
-		scheduler, err := gocron.NewScheduler(gocron.WithDistributedLocker(locker), gocron.WithLogger(NewCronLogger(logger)))
-		if err != nil {
-			return nil, nil, err
+		return scheduler, nil, nil
+	}
+
+	redisClient, err := cfg.Core.Clustered.Redis.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+	locker, err := redislock.NewRedisLocker(redisClient, redislock.WithTries(1), redislock.WithExpiry(heartbeatTimeout))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	errCh := make(chan error)
+	go func(errCh chan error) {
+		for err := range errCh {
+			logger.Error("rmq Background error", zap.Error(err))
 		}
+	}(errCh)
 
-		return scheduler, client, nil
+	client, err := rmq.OpenConnectionWithRedisClient(consumerTag, redisClient, errCh)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	scheduler, err := gocron.NewScheduler(gocron.WithLogger(NewCronLogger(logger)))
+	scheduler, err := gocron.NewScheduler(gocron.WithDistributedLocker(locker), gocron.WithLogger(NewCronLogger(logger)))
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return scheduler, nil, nil
+	return scheduler, client, nil
 }
-func (c *CronServiceDefault) Start() error {
-	for _, service := range c.entities {
-		err := service.RegisterTasks(c)
-		if err != nil {
-			c.logger.Fatal("Failed to register tasks for service", zap.Error(err))
+
+// cronQueueBackend resolves the queue a cluster uses, auto picks redis when the cluster has it
+func cronQueueBackend(cfg config.CoreConfig) (config.CronQueue, error) {
+	hasRedis := cfg.Clustered != nil && cfg.Clustered.RedisEnabled()
+
+	switch cfg.Cron.Queue {
+	case config.CronQueueRedis:
+		if !hasRedis {
+			return "", errors.New("core.cron.queue redis requires core.clustered.redis")
 		}
+		return config.CronQueueRedis, nil
+	case config.CronQueueDatabase:
+		return config.CronQueueDatabase, nil
+	default:
+		if hasRedis {
+			return config.CronQueueRedis, nil
+		}
+		return config.CronQueueDatabase, nil
 	}
+}
+func (c *CronServiceDefault) Start() error {
+	c.registerTasks()
 
 	for _, service := range c.entities {
 		err := service.ScheduleJobs(c)
@@ -189,16 +308,17 @@ func (c *CronServiceDefault) Start() error {
 		}
 	}
 
-	err := c.scheduler.SetLimit(c.config.Config().Core.Cron.MaxQueue, gocron.LimitModeWait)
-	if err != nil {
-		return err
-	}
-
 	if c.config.Config().Core.Cron.Enabled {
 		c.scheduler.Start()
+		c.running.Store(true)
+
+		if c.queue != nil {
+			c.queue.start()
+		}
 	}
 
 	go c.startDeadJobDetection()
+	go c.watchCancellations()
 
 	go func() {
 		var cronJobs []models.CronJob
@@ -213,13 +333,11 @@ func (c *CronServiceDefault) Start() error {
 			}
 
 			if c.clusterMode() {
-				err = c.enqueueJob(&cronJob)
-				if err != nil {
+				if err := c.enqueueJob(&cronJob); err != nil {
 					c.logger.Error("Failed to enqueue job", zap.Error(err))
 				}
 			} else {
-				err = c.kickOffJob(&cronJob, cronJob.Failures)
-				if err != nil {
+				if err := c.kickOffJob(&cronJob, cronJob.Failures); err != nil {
 					c.logger.Error("Failed to kick off job", zap.Error(err))
 				}
 			}
@@ -234,6 +352,7 @@ func (c *CronServiceDefault) Start() error {
 }
 
 func (c *CronServiceDefault) stop() error {
+	c.running.Store(false)
 	err := c.scheduler.Shutdown()
 	if err != nil {
 		return err
@@ -254,15 +373,7 @@ func (c *CronServiceDefault) kickOffJob(job *models.CronJob, errors uint64) erro
 }
 
 func (c *CronServiceDefault) enqueueJob(job *models.CronJob) error {
-	// Get or create the queue for this job function
-	queue, err := c.getOrCreateQueue(job.Function)
-	if err != nil {
-		return fmt.Errorf("failed to get or create queue: %w", err)
-	}
-
-	// Publish the job to the queue
-	id := job.UUID[:]
-	if err := queue.Publish(string(id)); err != nil {
+	if err := c.queue.publish(job); err != nil {
 		return fmt.Errorf("failed to publish job to queue: %w", err)
 	}
 
@@ -277,48 +388,23 @@ func (c *CronServiceDefault) enqueueJob(job *models.CronJob) error {
 	return nil
 }
 
-func (c *CronServiceDefault) getOrCreateQueue(name string) (rmq.Queue, error) {
-	c.queueMu.Lock()
-	defer c.queueMu.Unlock()
-
-	// Check if the queue already exists
-	if queue, exists := c.queues[name]; exists {
-		return queue, nil
-	}
-
-	// Create a new queue
-	queue, err := c.redisQueueConn.OpenQueue(redisQueueNamespace + "." + name)
+func (c *CronServiceDefault) scheduleJob(job *models.CronJob, errors uint64) error {
+	jobDef, err := c.loadTaskDef(job)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open queue %s: %w", name, err)
-	}
-
-	// Store the queue for future use
-	c.queues[name] = queue
-
-	// Start consuming from this queue
-	if err = c.startConsuming(queue, name); err != nil {
-		return nil, fmt.Errorf("failed to start consuming from queue %s: %w", name, err)
-	}
-
-	return queue, nil
-}
-
-func (c *CronServiceDefault) startConsuming(queue rmq.Queue, name string) error {
-	// Start consuming with a prefetch of 10 and a poll duration of 100ms
-	if err := queue.StartConsuming(consumerPrefetch, queuePollDuration); err != nil {
-		return fmt.Errorf("failed to start consuming: %w", err)
+		return fmt.Errorf("failed to load task definition: %w", err)
 	}
 
-	// Add a consumer to the queue
-	_, err := queue.AddConsumer(consumerTag, NewJobConsumer(c, name))
-	if err != nil {
-		return fmt.Errorf("failed to add consumer: %w", err)
+	backoffDelay := c.calculateBackoff(job.Function, job.Failures)
+	if errors > 0 {
+		jobDef = gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(time.Now().Add(backoffDelay)))
 	}
 
-	return nil
+	return c.scheduleJobDef(job, jobDef, backoffDelay)
 }
 
-func (c *CronServiceDefault) scheduleJob(job *models.CronJob, errors uint64) error {
+// scheduleJobDef schedules a job on this node. In cluster mode the job is kept alive through the delay before it
+// starts, so it is not taken for dead.
+func (c *CronServiceDefault) scheduleJobDef(job *models.CronJob, jobDef gocron.JobDefinition, delay time.Duration) error {
 	task, err := c.prepareTask(job)
 	if err != nil {
 		return fmt.Errorf("failed to prepare task: %w", err)
@@ -342,23 +428,13 @@ func (c *CronServiceDefault) scheduleJob(job *models.CronJob, errors uint64) err
 		gocron.WithEventListeners(listeners...),
 	}
 
-	jobDef, err := c.loadTaskDef(job)
-	if err != nil {
-		return fmt.Errorf("failed to load task definition: %w", err)
-	}
-
-	backoffDelay := c.calculateBackoff(job.Failures)
-	if errors > 0 {
-		jobDef = gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(time.Now().Add(backoffDelay)))
-	}
-
 	cronJob, err := c.scheduler.NewJob(jobDef, task, options...)
 	if err != nil {
 		return fmt.Errorf("failed to schedule job: %w", err)
 	}
 
 	if c.clusterMode() {
-		c.monitorJob(cronJob, uuid.UUID(job.UUID), waitForJobCtx, backoffDelay)
+		c.monitorJob(cronJob, uuid.UUID(job.UUID), waitForJobCtx, delay)
 	}
 
 	if c.booting {
@@ -421,35 +497,27 @@ func (c *CronServiceDefault) monitorJob(job gocron.Job, id uuid.UUID, waitCtx co
 	waitForStart:
 		<-waitCtx.Done()
 
-		lock := job.Lock()
-
-		if lock == nil {
+		lease, ok := leaseOf(job.Lock())
+		if !ok {
 			c.logger.Error("Failed to get lock", zap.String("jobID", id.String()))
 			return
 		}
 
-		rlock := lock.(*redislock.RedisLock)
-
 		for {
-			until := rlock.Get().Until()
-			timeToWait := until.Sub(time.Now()) - 30*time.Second
+			// Failed extensions are retried after a while rather than right away
+			timeToWait := max(time.Until(lease.until())-30*time.Second, leaseRetryInterval)
 
 			select {
 			case <-time.After(timeToWait):
 				c.logger.Debug("Lock expired, attempting to extend", zap.String("jobID", id.String()))
 
-				err := rlock.Extend(ctx)
-				if err != nil {
-					c.logger.Debug("Failed to extend lock", zap.Error(err))
-					err = rlock.Get().Lock()
-					if err != nil {
-						c.logger.Error("Failed to lock after extending", zap.Error(err))
-						continue
-					}
+				if err := lease.extend(ctx); err != nil {
+					c.logger.Error("Failed to extend lock", zap.Error(err), zap.String("jobID", id.String()))
+					continue
 				}
 
 				// Call the heartbeat
-				if err = c.jobHeartbeat(context.Background(), id); err != nil {
+				if err := c.jobHeartbeat(context.Background(), id); err != nil {
 					c.logger.Error("Failed to update job heartbeat", zap.Error(err))
 				}
 			case <-ctx.Done():
@@ -482,27 +550,218 @@ func (c *CronServiceDefault) prepareTask(job *models.CronJob) (gocron.Task, erro
 		}
 	}
 
-	varArgs := []interface{}{
-		interface{}(struct{}{}),
-		interface{}(c.ctx),
+	var taskArgs core.CronTaskArgs = struct{}{}
+	if args != nil {
+		taskArgs = args
 	}
 
-	if args != nil {
-		varArgs = []interface{}{
-			args,
-			interface{}(c.ctx),
+	run := taskFunc.(core.CronTaskFunction[core.CronTaskArgs])
+	parent := c.jobTraceContext(job)
+	id := uuid.UUID(job.UUID)
+
+	return gocron.NewTask(func() error {
+		// Jobs cancelled from another process are still scheduled here, their record is all that is gone
+		if _, err := c.getJob(id); errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Debug("Skipping cancelled job", zap.String("jobID", job.UUID.String()))
+			return nil
+		}
+
+		if c.taskPaused(job.Function) {
+			return errCronTaskPaused
 		}
+
+		runCtx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		c.runningJobs.Store(id, cancel)
+		defer c.runningJobs.Delete(id)
+
+		release, err := c.gate.acquire(runCtx, c.gateRun(job.Function))
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		ctx, span := core.Tracer().Start(runCtx, "cron."+job.Function, trace.WithAttributes(
+			attribute.String("cron.job_id", job.UUID.String()),
+		))
+		defer span.End()
+
+		// Tasks take the portal context, scoping it to the span lets their own calls join the trace
+		err = run(taskArgs, core.WithContext(c.ctx, ctx))
+		core.RecordSpanError(span, err)
+
+		return err
+	}), nil
+}
+
+// watchCancellations cancels the contexts of running jobs once their records are deleted, which is how jobs are
+// cancelled from other processes. The jobs running on this node are checked together.
+func (c *CronServiceDefault) watchCancellations() {
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkCancellations()
+		}
+	}
+}
+
+func (c *CronServiceDefault) checkCancellations() {
+	running := make(map[types.BinaryUUID]context.CancelFunc)
+	c.runningJobs.Range(func(key, value any) bool {
+		running[types.BinaryUUID(key.(uuid.UUID))] = value.(context.CancelFunc)
+		return true
+	})
+
+	if len(running) == 0 {
+		return
+	}
+
+	ids := make([]types.BinaryUUID, 0, len(running))
+	for id := range running {
+		ids = append(ids, id)
+	}
+
+	var existing []types.BinaryUUID
+	if err := db.RetryOnLock(db.Uncached(c.db), func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.CronJob{}).Where("uuid IN ?", ids).Pluck("uuid", &existing)
+	}); err != nil {
+		c.logger.Error("Failed to check for cancelled jobs", zap.Error(err))
+		return
+	}
+
+	for _, id := range existing {
+		delete(running, id)
+	}
+
+	for id, cancel := range running {
+		c.logger.Info("Cancelling running job", zap.String("jobID", id.String()))
+		cancel()
+	}
+}
+
+// cronPauses caches the paused tasks, so runs do not each ask the database. Pauses made on other nodes are seen once
+// the cache expires.
+type cronPauses struct {
+	mu       sync.Mutex
+	tasks    map[string]bool
+	loadedAt time.Time
+}
+
+// taskPaused errs on the side of running the job when the pauses can not be loaded and none were before
+func (c *CronServiceDefault) taskPaused(function string) bool {
+	c.pauses.mu.Lock()
+	defer c.pauses.mu.Unlock()
+
+	if time.Since(c.pauses.loadedAt) < pauseCacheTTL {
+		return c.pauses.tasks[function]
+	}
+
+	var functions []string
+	if err := db.RetryOnLock(db.Uncached(c.db), func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.CronTaskPause{}).Pluck("function", &functions)
+	}); err != nil {
+		c.logger.Error("Failed to load paused tasks", zap.Error(err))
+		return c.pauses.tasks[function]
+	}
+
+	c.pauses.tasks = make(map[string]bool, len(functions))
+	for _, paused := range functions {
+		c.pauses.tasks[paused] = true
+	}
+	c.pauses.loadedAt = time.Now()
+
+	return c.pauses.tasks[function]
+}
+
+// resetPauses makes the next run load the paused tasks again, for changes made on this node
+func (c *CronServiceDefault) resetPauses() {
+	c.pauses.mu.Lock()
+	defer c.pauses.mu.Unlock()
+
+	c.pauses.loadedAt = time.Time{}
+}
+
+// registerTasks collects the tasks of the registered entities. Cron registers them when it starts, commands that
+// need to know the tasks do so without starting it.
+func (c *CronServiceDefault) registerTasks() {
+	c.tasksOnce.Do(func() {
+		for _, service := range c.entities {
+			err := service.RegisterTasks(c)
+			if err != nil {
+				c.logger.Fatal("Failed to register tasks for service", zap.Error(err))
+			}
+		}
+	})
+}
+
+// taskKnown checks a task is registered on this node or has jobs, tasks of plugins only some nodes load are known by
+// their jobs alone
+func (c *CronServiceDefault) taskKnown(function string) (bool, error) {
+	c.registerTasks()
+
+	if _, ok := c.tasks.Load(function); ok {
+		return true, nil
+	}
+
+	var count int64
+	if err := db.RetryOnLock(c.db, func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.CronJob{}).Where(&models.CronJob{Function: function}).Count(&count)
+	}); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// jobTraceContext returns the portal context carrying the span the job was created in, when there was one
+func (c *CronServiceDefault) jobTraceContext(job *models.CronJob) context.Context {
+	if job.TraceContext == "" {
+		return c.ctx
+	}
+
+	carrier := propagation.MapCarrier{}
+	if err := json.Unmarshal([]byte(job.TraceContext), &carrier); err != nil {
+		c.logger.Error("Failed to decode job trace context", zap.Error(err), zap.String("jobID", job.UUID.String()))
+		return c.ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(c.ctx, carrier)
+}
+
+// gateRun returns the limits of a task as configured
+func (c *CronServiceDefault) gateRun(function string) cronGateRun {
+	task := c.config.Config().Core.Cron.Task(function)
+
+	run := cronGateRun{
+		task:        function,
+		priority:    c.priorityOf(function),
+		concurrency: int(task.Concurrency),
+	}
+
+	if task.RateLimit > 0 {
+		run.interval = time.Minute / time.Duration(task.RateLimit)
 	}
 
-	return gocron.NewTask(taskFunc, varArgs...), nil
+	return run
 }
 
+// loadTaskDef returns the schedule of a task, recurring tasks take the one configured over their own
 func (c *CronServiceDefault) loadTaskDef(job *models.CronJob) (gocron.JobDefinition, error) {
 	taskDefFunc, ok := c.taskDefs.Load(job.Function)
 	if !ok {
 		return nil, fmt.Errorf("task definition for function %s not found", job.Function)
 	}
 
+	if task := c.config.Config().Core.Cron.Task(job.Function); task.Schedule != "" && c.isRecurring(job.Function) {
+		return gocron.CronJob(task.CronExpression(), false), nil
+	}
+
 	return taskDefFunc.(core.CronTaskDefArgsFactoryFunction)(), nil
 }
 
@@ -537,6 +796,11 @@ func (c *CronServiceDefault) listenerFuncNoError(jobID uuid.UUID, _ string) {
 			return db.Where(&job).First(&job)
 		})
 	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Debug("Job was cancelled", zap.String("jobID", jobID.String()))
+			return
+		}
+
 		c.logger.Error("Failed to fetch job",
 			zap.Error(err),
 			zap.String("jobID", jobID.String()),
@@ -575,9 +839,36 @@ func (c *CronServiceDefault) listenerFuncNoError(jobID uuid.UUID, _ string) {
 
 func (c *CronServiceDefault) listenerFuncErr(jobID uuid.UUID, jobName string, err error) {
 	c.checkConsumption()
+
+	if errors.Is(err, errCronTaskPaused) {
+		c.deferPausedJob(jobID)
+		return
+	}
+
 	c.handleJobFailure(jobID, jobName, err)
 }
 
+// deferPausedJob schedules a job that came up while its task was paused to be checked again later on this node,
+// without counting it as a failure
+func (c *CronServiceDefault) deferPausedJob(jobID uuid.UUID) {
+	c.jobDone(jobID)
+
+	job, err := c.getJob(jobID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Error("Failed to fetch job of paused task", zap.Error(err), zap.String("jobID", jobID.String()))
+		}
+		return
+	}
+
+	c.logger.Debug("Deferring job of paused task", zap.String("jobID", jobID.String()), zap.String("function", job.Function))
+
+	jobDef := gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(time.Now().Add(pausedRecheckInterval)))
+	if err := c.scheduleJobDef(job, jobDef, pausedRecheckInterval); err != nil {
+		c.logger.Error("Failed to defer job of paused task", zap.Error(err), zap.String("jobID", jobID.String()))
+	}
+}
+
 func (c *CronServiceDefault) listenerFuncPanic(jobID uuid.UUID, jobName string, recoverData any) {
 	err := fmt.Errorf("panic occurred: %v\n%s", recoverData, debug.Stack())
 	c.handleJobFailure(jobID, jobName, err)
@@ -589,6 +880,12 @@ func (c *CronServiceDefault) handleJobFailure(jobID uuid.UUID, _ string, jobErr
 	var job models.CronJob
 	job.UUID = types.BinaryUUID(jobID)
 
+	// Cancelled jobs fail with their context more often than not, there is nothing left to record
+	if _, err := c.getJob(jobID); errors.Is(err, gorm.ErrRecordNotFound) {
+		c.logger.Debug("Job was cancelled", zap.String("jobID", jobID.String()))
+		return
+	}
+
 	c.logger.Error("Job failed",
 		zap.Error(jobErr),
 		zap.String("jobID", jobID.String()),
@@ -617,6 +914,12 @@ func (c *CronServiceDefault) handleJobFailure(jobID uuid.UUID, _ string, jobErr
 		return
 	}
 
+	c.ctx.Metrics().CronJobFailures.WithLabelValues(job.Function).Inc()
+
+	if err := event.FireCronJobFailedEvent(c.ctx, &job, jobErr.Error()); err != nil {
+		c.logger.Error("Failed to fire cron job failed event", zap.Error(err), zap.String("jobID", jobID.String()))
+	}
+
 	// Log the failure (including panics) in the cron job logs
 	cronLog := &models.CronJobLog{
 		CronJobID: job.ID,
@@ -659,20 +962,50 @@ func (c *CronServiceDefault) isRecurring(funcName string) bool {
 }
 
 func (c *CronServiceDefault) checkConsumption() {
-	if !c.clusterMode() || c.redisQueueConn == nil {
+	if !c.clusterMode() || c.queue == nil {
 		return
 	}
 
-	if uint(c.scheduler.JobsWaitingInQueue()) >= c.scheduler.LimitMode().Limit() {
-		c.redisQueueConn.StopAllConsuming()
-	} else {
-		for _, queue := range c.queues {
-			err := queue.StartConsuming(consumerPrefetch, queuePollDuration)
-			if err != nil && !errors.Is(err, rmq.ErrorAlreadyConsuming) {
-				c.logger.Error("Failed to start consuming from queue", zap.Error(err))
-			}
-		}
+	c.queue.consume(!c.schedulerFull())
+}
+
+func (c *CronServiceDefault) schedulerFull() bool {
+	return c.gate.waiting() >= int(c.config.Config().Core.Cron.MaxQueue)
+}
+
+// taskGroups lists the tasks registered on this node grouped by their priority, highest first
+func (c *CronServiceDefault) taskGroups() [][]string {
+	byPriority := make(map[int][]string)
+
+	c.tasks.Range(func(key, _ any) bool {
+		name := key.(string)
+		priority := c.priorityOf(name)
+		byPriority[priority] = append(byPriority[priority], name)
+		return true
+	})
+
+	priorities := slices.Sorted(maps.Keys(byPriority))
+	slices.Reverse(priorities)
+
+	groups := make([][]string, 0, len(priorities))
+	for _, priority := range priorities {
+		groups = append(groups, byPriority[priority])
+	}
+
+	return groups
+}
+
+// priorityOf returns the priority configured for a task, or the one it was registered with
+func (c *CronServiceDefault) priorityOf(function string) int {
+	if priority := c.config.Config().Core.Cron.Task(function).Priority; priority != nil {
+		return *priority
+	}
+
+	if priority, ok := c.taskPriority.Load(function); ok {
+		return priority.(int)
 	}
+
+	return 0
 }
 
 func (c *CronServiceDefault) rescheduleJob(job *models.CronJob) error {
@@ -707,11 +1040,25 @@ func (c *CronServiceDefault) RegisterTask(name string, taskFunc core.CronTaskFun
 	c.taskArgs.Store(name, taskArgFunc)
 	if recurring {
 		c.taskRecurring.Store(name, recurring)
+	} else if c.config.Config().Core.Cron.Task(name).Schedule != "" {
+		c.logger.Warn("Ignoring schedule configured for a task that is not recurring", zap.String("task", name))
 	}
 }
 
+func (c *CronServiceDefault) SetTaskBackoff(name string, backoff core.CronTaskBackoff) {
+	c.taskBackoff.Store(name, backoff)
+}
+
+func (c *CronServiceDefault) SetTaskPriority(name string, priority int) {
+	c.taskPriority.Store(name, priority)
+}
+
 func (c *CronServiceDefault) CreateJob(function string, args any) error {
-	job, err := c.createJobRecord(function, args)
+	return c.CreateJobWithContext(context.Background(), function, args)
+}
+
+func (c *CronServiceDefault) CreateJobWithContext(ctx context.Context, function string, args any) error {
+	job, err := c.createJobRecord(ctx, function, args)
 	if err != nil {
 		return err
 	}
@@ -720,7 +1067,7 @@ func (c *CronServiceDefault) CreateJob(function string, args any) error {
 }
 
 func (c *CronServiceDefault) CreateJobScheduled(function string, args any) error {
-	job, err := c.createJobRecord(function, args)
+	job, err := c.createJobRecord(context.Background(), function, args)
 	if err != nil {
 		return err
 	}
@@ -775,12 +1122,313 @@ func (c *CronServiceDefault) JobExists(function string, args any) (bool, *models
 	return true, &job
 }
 
-func (c *CronServiceDefault) createJobRecord(function string, args any) (*models.CronJob, error) {
+func (c *CronServiceDefault) ListJobs(filter core.CronJobFilter) ([]*models.CronJob, int64, error) {
+	var jobs []*models.CronJob
+	var total int64
+
+	query := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.CronJob{}).Where(&models.CronJob{Function: filter.Function, State: filter.State})
+
+		if filter.MinFailures > 0 {
+			tx = tx.Where("failures >= ?", filter.MinFailures)
+		}
+
+		return tx
+	}
+
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		return query(tx).Count(&total)
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		tx = query(tx)
+
+		if filter.Limit > 0 {
+			tx = tx.Limit(filter.Limit)
+		}
+
+		if filter.Offset > 0 {
+			tx = tx.Offset(filter.Offset)
+		}
+
+		return tx.Order("id ASC").Find(&jobs)
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+func (c *CronServiceDefault) RetryJob(id uuid.UUID) error {
+	job, err := c.getIdleJob(id)
+	if err != nil {
+		return err
+	}
+
+	// Struct updates skip zero values, so the failure count can only be cleared through a map
+	var rowsAffected int64
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		ret := tx.Model(&models.CronJob{}).
+			Where(&models.CronJob{UUID: job.UUID, Version: job.Version}).
+			Updates(map[string]any{
+				"failures": 0,
+				"state":    models.CronJobStateQueued,
+				"version":  job.Version + 1,
+			})
+
+		rowsAffected = ret.RowsAffected
+		return ret
+	}); err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("job was updated by another process")
+	}
+
+	// Without a running scheduler the job would be scheduled in a process that never runs it, it stays queued instead
+	if !c.clusterMode() && !c.running.Load() {
+		return nil
+	}
+
+	if err := c.removeScheduledJob(id); err != nil {
+		return err
+	}
+
+	job.Failures = 0
+
+	return c.kickOffJob(job, 0)
+}
+
+func (c *CronServiceDefault) GetJob(id uuid.UUID) (*models.CronJob, error) {
+	job, err := c.getJob(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.ErrCronJobNotFound
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (c *CronServiceDefault) ListJobLogs(id uuid.UUID, limit int, offset int) ([]*models.CronJobLog, int64, error) {
+	var job models.CronJob
+
+	// Cancelled and completed jobs are soft deleted, their logs are still worth looking at
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped().Model(&models.CronJob{}).Where(&models.CronJob{UUID: types.BinaryUUID(id)}).First(&job)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, core.ErrCronJobNotFound
+		}
+		return nil, 0, err
+	}
+
+	var logs []*models.CronJobLog
+	var total int64
+
+	query := func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.CronJobLog{}).Where(&models.CronJobLog{CronJobID: job.ID})
+	}
+
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		return query(tx).Count(&total)
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		tx = query(tx)
+
+		if limit > 0 {
+			tx = tx.Limit(limit)
+		}
+
+		if offset > 0 {
+			tx = tx.Offset(offset)
+		}
+
+		return tx.Order("id DESC").Find(&logs)
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+func (c *CronServiceDefault) CancelJob(id uuid.UUID) error {
+	job, err := c.GetJob(id)
+	if err != nil {
+		return err
+	}
+
+	if err := c.removeScheduledJob(id); err != nil {
+		return err
+	}
+
+	c.jobDone(id)
+
+	if err := c.deleteJob(job); err != nil {
+		return err
+	}
+
+	// Runs on other nodes notice the record is gone
+	if cancel, ok := c.runningJobs.Load(id); ok {
+		cancel.(context.CancelFunc)()
+	}
+
+	return nil
+}
+
+func (c *CronServiceDefault) ListTasks() ([]*core.CronTask, error) {
+	tasks := make(map[string]*core.CronTask)
+
+	task := func(name string) *core.CronTask {
+		if _, ok := tasks[name]; !ok {
+			tasks[name] = &core.CronTask{Name: name}
+		}
+		return tasks[name]
+	}
+
+	c.registerTasks()
+
+	c.tasks.Range(func(key, _ any) bool {
+		task(key.(string))
+		return true
+	})
+
+	var counts []struct {
+		Function string
+		Count    int64
+	}
+
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.CronJob{}).Select("function, COUNT(*) AS count").Group("function").Scan(&counts)
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, count := range counts {
+		task(count.Function).Jobs = count.Count
+	}
+
+	var pauses []*models.CronTaskPause
+
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.CronTaskPause{}).Find(&pauses)
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, pause := range pauses {
+		t := task(pause.Function)
+		t.Paused = true
+		t.PausedAt = &pause.CreatedAt
+	}
+
+	list := make([]*core.CronTask, 0, len(tasks))
+	for _, t := range tasks {
+		list = append(list, t)
+	}
+
+	slices.SortFunc(list, func(a, b *core.CronTask) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return list, nil
+}
+
+func (c *CronServiceDefault) PauseTask(function string) error {
+	known, err := c.taskKnown(function)
+	if err != nil {
+		return err
+	}
+
+	if !known {
+		return core.ErrCronTaskInvalid
+	}
+
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.CronTaskPause{Function: function})
+	}); err != nil {
+		return err
+	}
+
+	c.resetPauses()
+
+	c.logger.Info("Task paused", zap.String("function", function))
+
+	return nil
+}
+
+func (c *CronServiceDefault) ResumeTask(function string) error {
+	if function == "" {
+		return core.ErrCronTaskInvalid
+	}
+
+	if err := db.RetryableTransaction(c.ctx, c.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(&models.CronTaskPause{Function: function}).Delete(&models.CronTaskPause{})
+	}); err != nil {
+		return err
+	}
+
+	c.resetPauses()
+
+	c.logger.Info("Task resumed", zap.String("function", function))
+
+	return nil
+}
+
+// getIdleJob fetches a job that may be changed from outside, which rules out jobs that are processing
+func (c *CronServiceDefault) getIdleJob(id uuid.UUID) (*models.CronJob, error) {
+	job, err := c.getJob(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.ErrCronJobNotFound
+		}
+		return nil, err
+	}
+
+	if job.State == models.CronJobStateProcessing {
+		return nil, core.ErrCronJobProcessing
+	}
+
+	return job, nil
+}
+
+func (c *CronServiceDefault) removeScheduledJob(id uuid.UUID) error {
+	if c.scheduler == nil {
+		return nil
+	}
+
+	if err := c.scheduler.RemoveJob(id); err != nil && !errors.Is(err, gocron.ErrJobNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *CronServiceDefault) createJobRecord(ctx context.Context, function string, args any) (*models.CronJob, error) {
 	job := models.CronJob{
 		Function: function,
 		UUID:     types.BinaryUUID(uuid.New()),
 	}
 
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	if len(carrier) > 0 {
+		bytes, err := json.Marshal(carrier)
+		if err != nil {
+			return nil, err
+		}
+
+		job.TraceContext = string(bytes)
+	}
+
 	if args != nil {
 		bytes, err := json.Marshal(args)
 		if err != nil {
@@ -887,8 +1535,9 @@ func (c *CronServiceDefault) getJob(id uuid.UUID) (*models.CronJob, error) {
 	var job models.CronJob
 	job.UUID = types.BinaryUUID(id)
 
-	if err := db.RetryOnLock(c.db, func(db *gorm.DB) *gorm.DB {
-		return c.db.Model(&job).Where(&job).First(&job)
+	// Read past the query cache, the job may have been changed or deleted by another node
+	if err := db.RetryOnLock(db.Uncached(c.db), func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&job).Where(&job).First(&job)
 	}); err != nil {
 		return nil, err
 	}
@@ -919,26 +1568,32 @@ func (c *CronServiceDefault) startDeadJobDetection() {
 		}
 	}
 }
-func (c *CronServiceDefault) calculateBackoff(failures uint64) time.Duration {
+func (c *CronServiceDefault) calculateBackoff(function string, failures uint64) time.Duration {
 	if failures == 0 {
 		return 0
 	}
-	backoffDelay := c.calculateMaxBackoffWithJitter(failures)
+
+	backoff := core.CronTaskBackoff{Base: failureBackoffBaseDelay, Max: failureBackoffMaxDelay}
+	if taskBackoff, ok := c.taskBackoff.Load(function); ok {
+		backoff = taskBackoff.(core.CronTaskBackoff)
+	}
+
+	backoffDelay := c.calculateMaxBackoffWithJitter(backoff, failures)
 	return backoffDelay
 }
 
-func (c *CronServiceDefault) calculateMaxBackoffWithJitter(failures uint64) time.Duration {
+func (c *CronServiceDefault) calculateMaxBackoffWithJitter(backoff core.CronTaskBackoff, failures uint64) time.Duration {
 	if failures == 0 {
 		return 0
 	}
 
 	// Calculate the maximum possible delay including jitter
-	baseDelay := float64(failureBackoffBaseDelay) * math.Pow(2, float64(failures))
+	baseDelay := float64(backoff.Base) * math.Pow(2, float64(failures))
 	maxDelayWithJitter := baseDelay * 1.5 // 1.5 accounts for maximum 50% jitter
 
 	// Cap the delay
-	if maxDelayWithJitter > float64(failureBackoffMaxDelay) {
-		maxDelayWithJitter = float64(failureBackoffMaxDelay)
+	if maxDelayWithJitter > float64(backoff.Max) {
+		maxDelayWithJitter = float64(backoff.Max)
 	}
 
 	return time.Duration(maxDelayWithJitter)
@@ -986,7 +1641,7 @@ func (c *CronServiceDefault) shouldHandleDeadJob(job *models.CronJob, now time.T
 	}
 
 	// For queued jobs, check if the backoff period has elapsed
-	backoffDuration := c.calculateBackoff(job.Failures)
+	backoffDuration := c.calculateBackoff(job.Function, job.Failures)
 	var backoffEndTime time.Time
 
 	if job.LastRun != nil && !job.LastRun.IsZero() {
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cronGate admits the runs of jobs on a node. At most limit runs go at once, and no more than the concurrency and
+// rate limit of their task allow. Waiting runs are admitted by the priority of their task, then in the order they
+// came in, passing over those their task holds back.
+type cronGate struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	tasks   map[string]*cronGateTask
+	waiters []*cronGateWaiter
+	timer   *time.Timer
+}
+
+type cronGateTask struct {
+	running int
+	// next is when the rate limit of the task lets another run start
+	next time.Time
+}
+
+// cronGateRun is a run to admit, with the limits of its task
+type cronGateRun struct {
+	task        string
+	priority    int
+	concurrency int
+	interval    time.Duration
+}
+
+type cronGateWaiter struct {
+	run      cronGateRun
+	admitted chan struct{}
+}
+
+func newCronGate(limit int) *cronGate {
+	return &cronGate{
+		limit: limit,
+		tasks: make(map[string]*cronGateTask),
+	}
+}
+
+// acquire waits for the run to be admitted, the returned func ends it
+func (g *cronGate) acquire(ctx context.Context, run cronGateRun) (func(), error) {
+	g.mu.Lock()
+	waiter := &cronGateWaiter{run: run, admitted: make(chan struct{})}
+	g.waiters = append(g.waiters, waiter)
+	g.dispatch()
+	g.mu.Unlock()
+
+	release := func() {
+		g.release(run.task)
+	}
+
+	select {
+	case <-waiter.admitted:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Admitted in the meantime
+	select {
+	case <-waiter.admitted:
+		g.releaseLocked(run.task)
+		return nil, ctx.Err()
+	default:
+	}
+
+	for i, w := range g.waiters {
+		if w == waiter {
+			g.waiters = append(g.waiters[:i], g.waiters[i+1:]...)
+			break
+		}
+	}
+
+	return nil, ctx.Err()
+}
+
+// waiting is the number of runs waiting to be admitted
+func (g *cronGate) waiting() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return len(g.waiters)
+}
+
+func (g *cronGate) release(task string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.releaseLocked(task)
+}
+
+func (g *cronGate) releaseLocked(task string) {
+	g.running--
+
+	if t := g.tasks[task]; t != nil {
+		t.running--
+		if t.running == 0 && time.Now().After(t.next) {
+			delete(g.tasks, task)
+		}
+	}
+
+	g.dispatch()
+}
+
+// dispatch admits waiting runs while there is room. When only rate limits hold runs back, it is called again once
+// the first of them lifts.
+func (g *cronGate) dispatch() {
+	now := time.Now()
+
+	for g.running < g.limit {
+		best := -1
+		var wake time.Time
+
+		// Waiters are in the order they came in, so the first of the highest priority wins
+
+		for i, w := range g.waiters {
+			task := g.tasks[w.run.task]
+
+			if task != nil && w.run.concurrency > 0 && task.running >= w.run.concurrency {
+				continue
+			}
+
+			if task != nil && now.Before(task.next) {
+				if wake.IsZero() || task.next.Before(wake) {
+					wake = task.next
+				}
+				continue
+			}
+
+			if best == -1 || w.run.priority > g.waiters[best].run.priority {
+				best = i
+			}
+		}
+
+		if best == -1 {
+			if !wake.IsZero() {
+				g.wakeAt(wake)
+			}
+			return
+		}
+
+		w := g.waiters[best]
+		g.waiters = append(g.waiters[:best], g.waiters[best+1:]...)
+
+		task := g.tasks[w.run.task]
+		if task == nil {
+			task = &cronGateTask{}
+			g.tasks[w.run.task] = task
+		}
+
+		task.running++
+		if w.run.interval > 0 {
+			task.next = now.Add(w.run.interval)
+		}
+
+		g.running++
+		close(w.admitted)
+	}
+}
+
+func (g *cronGate) wakeAt(t time.Time) {
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+
+	g.timer = time.AfterFunc(time.Until(t), func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.dispatch()
+	})
+}
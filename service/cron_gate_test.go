@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/samber/lo"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"slices"
+	"testing"
+	"time"
+)
+
+const gateTestTimeout = time.Second
+
+// acquireAsync acquires the run in the background, the release func is sent once it is admitted
+func acquireAsync(gate *cronGate, run cronGateRun) <-chan func() {
+	admitted := make(chan func(), 1)
+
+	go func() {
+		release, err := gate.acquire(context.Background(), run)
+		if err == nil {
+			admitted <- release
+		}
+	}()
+
+	return admitted
+}
+
+func waitForWaiters(t *testing.T, gate *cronGate, waiting int) {
+	t.Helper()
+
+	deadline := time.Now().Add(gateTestTimeout)
+	for gate.waiting() != waiting {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d runs to be waiting, got %d", waiting, gate.waiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func expectAdmitted(t *testing.T, admitted <-chan func()) func() {
+	t.Helper()
+
+	select {
+	case release := <-admitted:
+		return release
+	case <-time.After(gateTestTimeout):
+		t.Fatal("expected the run to be admitted")
+		return nil
+	}
+}
+
+func expectWaiting(t *testing.T, admitted <-chan func()) {
+	t.Helper()
+
+	select {
+	case <-admitted:
+		t.Fatal("expected the run to wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestCronGateAdmitsByPriority(t *testing.T) {
+	gate := newCronGate(1)
+
+	release, err := gate.acquire(context.Background(), cronGateRun{task: "running"})
+	if err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+
+	var order []string
+	done := make(chan struct{})
+
+	runs := []cronGateRun{
+		{task: "cleanup", priority: core.CronTaskPriorityBackground},
+		{task: "first"},
+		{task: "urgent", priority: 10},
+		{task: "second"},
+	}
+
+	for i, run := range runs {
+		go func() {
+			release, err := gate.acquire(context.Background(), run)
+			if err != nil {
+				return
+			}
+			order = append(order, run.task)
+			release()
+			done <- struct{}{}
+		}()
+
+		// Runs of the same priority are admitted in the order they came in
+		waitForWaiters(t, gate, i+1)
+	}
+
+	release()
+
+	for range runs {
+		select {
+		case <-done:
+		case <-time.After(gateTestTimeout):
+			t.Fatalf("expected every run to be admitted, got %v", order)
+		}
+	}
+
+	if expected := []string{"urgent", "first", "second", "cleanup"}; !slices.Equal(order, expected) {
+		t.Fatalf("expected the runs to be admitted as %v, got %v", expected, order)
+	}
+}
+
+func TestCronGateLimitsTaskConcurrency(t *testing.T) {
+	gate := newCronGate(3)
+
+	limited := cronGateRun{task: "limited", concurrency: 1}
+
+	releaseLimited := expectAdmitted(t, acquireAsync(gate, limited))
+	second := acquireAsync(gate, limited)
+	waitForWaiters(t, gate, 1)
+
+	// Runs of other tasks pass the run held back by its task
+	releaseOther := expectAdmitted(t, acquireAsync(gate, cronGateRun{task: "other", priority: -1}))
+	expectWaiting(t, second)
+
+	releaseLimited()
+	expectAdmitted(t, second)()
+	releaseOther()
+
+	if gate.waiting() != 0 {
+		t.Fatalf("expected no runs to be waiting, got %d", gate.waiting())
+	}
+}
+
+func TestCronGateLimitsTaskRate(t *testing.T) {
+	gate := newCronGate(10)
+
+	run := cronGateRun{task: "rated", interval: 50 * time.Millisecond}
+
+	start := time.Now()
+	expectAdmitted(t, acquireAsync(gate, run))()
+	expectAdmitted(t, acquireAsync(gate, run))()
+
+	if elapsed := time.Since(start); elapsed < run.interval {
+		t.Fatalf("expected the second run to wait for the rate limit, admitted after %s", elapsed)
+	}
+}
+
+func TestCronGateDropsCancelledRuns(t *testing.T) {
+	gate := newCronGate(1)
+
+	release, err := gate.acquire(context.Background(), cronGateRun{task: "running"})
+	if err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := gate.acquire(ctx, cronGateRun{task: "cancelled", priority: 10})
+		cancelled <- err
+	}()
+	waitForWaiters(t, gate, 1)
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the run to be cancelled, got %v", err)
+	}
+
+	next := acquireAsync(gate, cronGateRun{task: "next"})
+	waitForWaiters(t, gate, 1)
+
+	release()
+	expectAdmitted(t, next)()
+}
+
+func TestCronTaskPriorityDefaults(t *testing.T) {
+	cfg := &config.Config{Core: config.CoreConfig{Cron: config.CronConfig{
+		Tasks: map[string]config.CronTaskConfig{"overridden": {Priority: lo.ToPtr(5)}},
+	}}}
+
+	cron := &CronServiceDefault{config: &testConfigManager{cfg: cfg}, logger: &core.Logger{Logger: zap.NewNop()}}
+
+	for _, name := range []string{"default", "cleanup", "overridden"} {
+		cron.RegisterTask(name, nil, core.CronTaskDefinitionOneTimeJob, core.CronTaskNoArgsFactory, false)
+	}
+	cron.SetTaskPriority("cleanup", core.CronTaskPriorityBackground)
+	cron.SetTaskPriority("overridden", core.CronTaskPriorityBackground)
+
+	groups := cron.taskGroups()
+
+	if len(groups) != 3 || !slices.Equal(groups[0], []string{"overridden"}) || !slices.Equal(groups[1], []string{"default"}) ||
+		!slices.Equal(groups[2], []string{"cleanup"}) {
+		t.Fatalf("expected the configured priority to win over the task's own, got %v", groups)
+	}
+}
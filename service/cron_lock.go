@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	redislock "github.com/go-co-op/gocron-redis-lock/v2"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/google/uuid"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	errCronLockHeld = errors.New("cron lock is held by another node")
+	errCronLockLost = errors.New("cron lock expired and was taken by another node")
+)
+
+var _ gocron.Locker = (*cronDBLocker)(nil)
+var _ gocron.Lock = (*cronDBLock)(nil)
+var _ cronLease = (*cronDBLock)(nil)
+
+// cronLease is the lock of a job run, which expires unless it is extended while the job runs
+type cronLease interface {
+	until() time.Time
+	extend(ctx context.Context) error
+}
+
+func leaseOf(lock gocron.Lock) (cronLease, bool) {
+	switch l := lock.(type) {
+	case *redislock.RedisLock:
+		return redisLease{lock: l}, true
+	case cronLease:
+		return l, true
+	}
+
+	return nil, false
+}
+
+type redisLease struct {
+	lock *redislock.RedisLock
+}
+
+func (l redisLease) until() time.Time {
+	return l.lock.Get().Until()
+}
+
+// extend locks again once the lock has expired, unless another node took it in the meantime
+func (l redisLease) extend(ctx context.Context) error {
+	if err := l.lock.Extend(ctx); err != nil {
+		return l.lock.Get().Lock()
+	}
+
+	return nil
+}
+
+// cronDBLocker locks job runs in the database for clusters without redis. Like the redis locker it does not wait for
+// a held lock, the run is skipped instead.
+type cronDBLocker struct {
+	db     *gorm.DB
+	expiry time.Duration
+}
+
+func newCronDBLocker(db *gorm.DB, expiry time.Duration) *cronDBLocker {
+	return &cronDBLocker{db: db, expiry: expiry}
+}
+
+func (l *cronDBLocker) Lock(ctx context.Context, key string) (gocron.Lock, error) {
+	now := time.Now()
+	lock := &cronDBLock{
+		db:      l.db,
+		expiry:  l.expiry,
+		name:    key,
+		owner:   uuid.NewString(),
+		expires: now.Add(l.expiry),
+	}
+
+	var rowsAffected int64
+
+	// An expired lock is taken over, otherwise the lock is inserted unless it is held
+	if err := db.RetryOnLock(l.db.WithContext(ctx), func(tx *gorm.DB) *gorm.DB {
+		ret := tx.Model(&models.CronLock{}).
+			Where(&models.CronLock{Name: key}).
+			Where("expires_at < ?", now).
+			Updates(&models.CronLock{Owner: lock.owner, ExpiresAt: lock.expires})
+
+		rowsAffected = ret.RowsAffected
+		return ret
+	}); err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		if err := db.RetryOnLock(l.db.WithContext(ctx), func(tx *gorm.DB) *gorm.DB {
+			ret := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.CronLock{Name: key, Owner: lock.owner, ExpiresAt: lock.expires})
+
+			rowsAffected = ret.RowsAffected
+			return ret
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if rowsAffected == 0 {
+		return nil, errCronLockHeld
+	}
+
+	return lock, nil
+}
+
+type cronDBLock struct {
+	db      *gorm.DB
+	expiry  time.Duration
+	name    string
+	owner   string
+	expires time.Time
+}
+
+func (l *cronDBLock) Unlock(ctx context.Context) error {
+	return db.RetryOnLock(l.db.WithContext(ctx), func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(&models.CronLock{Name: l.name, Owner: l.owner}).Delete(&models.CronLock{})
+	})
+}
+
+func (l *cronDBLock) until() time.Time {
+	return l.expires
+}
+
+func (l *cronDBLock) extend(ctx context.Context) error {
+	expires := time.Now().Add(l.expiry)
+
+	var rowsAffected int64
+	if err := db.RetryOnLock(l.db.WithContext(ctx), func(tx *gorm.DB) *gorm.DB {
+		ret := tx.Model(&models.CronLock{}).
+			Where(&models.CronLock{Name: l.name, Owner: l.owner}).
+			Update("expires_at", expires)
+
+		rowsAffected = ret.RowsAffected
+		return ret
+	}); err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errCronLockLost
+	}
+
+	l.expires = expires
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/db/models"
+	"testing"
+	"time"
+)
+
+func newTestCronDBLocker(t *testing.T, expiry time.Duration) *cronDBLocker {
+	t.Helper()
+
+	ctx := newTestContext(t, &config.Config{}, []any{&models.CronLock{}})
+
+	return newCronDBLocker(ctx.DB(), expiry)
+}
+
+func TestCronDBLockerSkipsHeldLocks(t *testing.T) {
+	locker := newTestCronDBLocker(t, time.Minute)
+	ctx := context.Background()
+
+	lock, err := locker.Lock(ctx, "task")
+	if err != nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+
+	if _, err := locker.Lock(ctx, "task"); !errors.Is(err, errCronLockHeld) {
+		t.Fatalf("expected a held lock to be skipped, got %v", err)
+	}
+
+	if _, err := locker.Lock(ctx, "other"); err != nil {
+		t.Fatalf("expected another key to be locked, got %v", err)
+	}
+
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatalf("failed to unlock: %v", err)
+	}
+
+	if _, err := locker.Lock(ctx, "task"); err != nil {
+		t.Fatalf("expected an unlocked lock to be taken, got %v", err)
+	}
+}
+
+func TestCronDBLockerTakesOverExpiredLocks(t *testing.T) {
+	locker := newTestCronDBLocker(t, time.Minute)
+	ctx := context.Background()
+
+	expired, err := locker.Lock(ctx, "task")
+	if err != nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+
+	if err := locker.db.Model(&models.CronLock{}).Where(&models.CronLock{Name: "task"}).
+		Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("failed to expire the lock: %v", err)
+	}
+
+	lock, err := locker.Lock(ctx, "task")
+	if err != nil {
+		t.Fatalf("expected an expired lock to be taken over, got %v", err)
+	}
+
+	if err := expired.(*cronDBLock).extend(ctx); !errors.Is(err, errCronLockLost) {
+		t.Fatalf("expected extending a lock taken over to fail, got %v", err)
+	}
+
+	if err := expired.Unlock(ctx); err != nil {
+		t.Fatalf("failed to unlock: %v", err)
+	}
+
+	if _, err := locker.Lock(ctx, "task"); !errors.Is(err, errCronLockHeld) {
+		t.Fatalf("expected the stale owner not to release the lock, got %v", err)
+	}
+
+	if err := lock.(*cronDBLock).extend(ctx); err != nil {
+		t.Fatalf("expected the owner to extend the lock, got %v", err)
+	}
+}
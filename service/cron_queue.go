@@ -0,0 +1,272 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/adjust/rmq/v5"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+const dbQueuePollInterval = 1 * time.Second
+
+var _ cronQueue = (*cronRedisQueue)(nil)
+var _ cronQueue = (*cronDBQueue)(nil)
+
+// cronQueue distributes the jobs of a cluster between its nodes, a published job runs at least once on one of them
+type cronQueue interface {
+	// publish makes a queued job available to the nodes that run its task
+	publish(job *models.CronJob) error
+	// start begins taking jobs to run on this node
+	start()
+	// consume resumes or holds back taking jobs, as the scheduler has room for them or not
+	consume(enabled bool)
+}
+
+// cronRedisQueue publishes jobs to a rmq queue per task
+type cronRedisQueue struct {
+	cron    *CronServiceDefault
+	conn    rmq.Connection
+	mu      sync.Mutex
+	queues  map[string]rmq.Queue
+	started bool
+}
+
+func newCronRedisQueue(cron *CronServiceDefault, conn rmq.Connection) *cronRedisQueue {
+	return &cronRedisQueue{
+		cron:   cron,
+		conn:   conn,
+		queues: make(map[string]rmq.Queue),
+	}
+}
+
+func (q *cronRedisQueue) publish(job *models.CronJob) error {
+	// Get or create the queue for this job function
+	queue, err := q.getOrCreateQueue(job.Function)
+	if err != nil {
+		return fmt.Errorf("failed to get or create queue: %w", err)
+	}
+
+	// Publish the job to the queue
+	id := job.UUID[:]
+	return queue.Publish(string(id))
+}
+
+// start consumes from the queues opened so far, queues opened later are consumed from as soon as they are opened.
+// Until then jobs are only published, so commands can create jobs without running them.
+func (q *cronRedisQueue) start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.started = true
+
+	for name, queue := range q.queues {
+		if err := q.startConsuming(queue, name); err != nil {
+			q.cron.logger.Error("Failed to start consuming from queue", zap.String("queue", name), zap.Error(err))
+		}
+	}
+}
+
+func (q *cronRedisQueue) consume(enabled bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.started {
+		return
+	}
+
+	if !enabled {
+		q.conn.StopAllConsuming()
+		return
+	}
+
+	for _, queue := range q.queues {
+		err := queue.StartConsuming(consumerPrefetch, queuePollDuration)
+		if err != nil && !errors.Is(err, rmq.ErrorAlreadyConsuming) {
+			q.cron.logger.Error("Failed to start consuming from queue", zap.Error(err))
+		}
+	}
+}
+
+func (q *cronRedisQueue) getOrCreateQueue(name string) (rmq.Queue, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Check if the queue already exists
+	if queue, exists := q.queues[name]; exists {
+		return queue, nil
+	}
+
+	// Create a new queue
+	queue, err := q.conn.OpenQueue(redisQueueNamespace + "." + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue %s: %w", name, err)
+	}
+
+	// Store the queue for future use
+	q.queues[name] = queue
+
+	if !q.started {
+		return queue, nil
+	}
+
+	// Start consuming from this queue
+	if err = q.startConsuming(queue, name); err != nil {
+		return nil, fmt.Errorf("failed to start consuming from queue %s: %w", name, err)
+	}
+
+	return queue, nil
+}
+
+func (q *cronRedisQueue) startConsuming(queue rmq.Queue, name string) error {
+	// Start consuming with a prefetch of 10 and a poll duration of 100ms
+	if err := queue.StartConsuming(consumerPrefetch, queuePollDuration); err != nil {
+		return fmt.Errorf("failed to start consuming: %w", err)
+	}
+
+	// Add a consumer to the queue
+	_, err := queue.AddConsumer(consumerTag, NewJobConsumer(q.cron, name))
+	if err != nil {
+		return fmt.Errorf("failed to add consumer: %w", err)
+	}
+
+	return nil
+}
+
+// cronDBQueue is the queue of clusters without redis, the queued jobs in the database are the queue. Nodes poll for
+// them and claim them by marking them processing, which leases them for as long as their heartbeat is kept up. Jobs
+// of a node that dies are requeued by the dead job detection once their heartbeat times out.
+type cronDBQueue struct {
+	cron *CronServiceDefault
+	wake chan struct{}
+}
+
+func newCronDBQueue(cron *CronServiceDefault) *cronDBQueue {
+	return &cronDBQueue{
+		cron: cron,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// publish only wakes the local poller, the job is already queued in the database
+func (q *cronDBQueue) publish(_ *models.CronJob) error {
+	q.notify()
+	return nil
+}
+
+func (q *cronDBQueue) start() {
+	go q.run()
+}
+
+// consume wakes the poller once the scheduler has room again, it checks for room before every claim
+func (q *cronDBQueue) consume(enabled bool) {
+	if enabled {
+		q.notify()
+	}
+}
+
+func (q *cronDBQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *cronDBQueue) run() {
+	ticker := time.NewTicker(dbQueuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.cron.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+
+		if !q.cron.schedulerFull() {
+			q.poll()
+		}
+	}
+}
+
+// poll claims the jobs of the tasks of the highest priority first
+func (q *cronDBQueue) poll() {
+	limit := consumerPrefetch
+
+	for _, functions := range q.cron.taskGroups() {
+		jobs, err := q.claim(functions, limit)
+		if err != nil {
+			q.cron.logger.Error("Failed to claim queued jobs", zap.Error(err))
+			return
+		}
+
+		for _, job := range jobs {
+			q.cron.logger.Debug("Job consumed", zap.String("jobID", job.UUID.String()), zap.String("function", job.Function), zap.String("args", job.Args))
+
+			if err := q.cron.scheduleJob(job, job.Failures); err != nil {
+				q.cron.logger.Error("Failed to kick off job", zap.Error(err), zap.String("jobID", job.UUID.String()))
+			}
+		}
+
+		if limit -= len(jobs); limit == 0 {
+			return
+		}
+	}
+}
+
+// claim marks up to limit queued jobs of the given tasks as processing. Jobs being claimed by other nodes are skipped
+// rather than waited on.
+func (q *cronDBQueue) claim(functions []string, limit int) ([]*models.CronJob, error) {
+	var claimed []*models.CronJob
+
+	if err := db.RetryableTransaction(q.cron.ctx, db.Uncached(q.cron.db), func(tx *gorm.DB) *gorm.DB {
+		claimed = nil
+
+		query := tx.Where(&models.CronJob{State: models.CronJobStateQueued}).
+			Where("function IN ?", functions).
+			Order("id").
+			Limit(limit)
+
+		// SQLite has no row locks, it is a single node's database anyway
+		if tx.Dialector.Name() != "sqlite" {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+
+		var jobs []*models.CronJob
+		if ret := query.Find(&jobs); ret.Error != nil {
+			return ret
+		}
+
+		now := time.Now()
+
+		for _, job := range jobs {
+			ret := tx.Model(&models.CronJob{}).
+				Where("id = ? AND version = ?", job.ID, job.Version).
+				Updates(&models.CronJob{State: models.CronJobStateProcessing, LastHeartbeat: &now, Version: job.Version + 1})
+			if ret.Error != nil {
+				return ret
+			}
+
+			if ret.RowsAffected == 0 {
+				continue
+			}
+
+			job.State = models.CronJobStateProcessing
+			job.LastHeartbeat = &now
+			job.Version++
+			claimed = append(claimed, job)
+		}
+
+		return tx
+	}); err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
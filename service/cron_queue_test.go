@@ -0,0 +1,67 @@
+package service
+
+import (
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/db/models"
+	"testing"
+)
+
+func newTestCronDBQueue(t *testing.T) *cronDBQueue {
+	t.Helper()
+
+	ctx := newTestContext(t, &config.Config{}, []any{&models.CronJob{}})
+
+	return newCronDBQueue(&CronServiceDefault{ctx: ctx, db: ctx.DB()})
+}
+
+func TestCronDBQueueClaimsQueuedJobsOnce(t *testing.T) {
+	queue := newTestCronDBQueue(t)
+
+	jobs := []*models.CronJob{
+		{Function: "a", State: models.CronJobStateQueued},
+		{Function: "b", State: models.CronJobStateQueued},
+		{Function: "a", State: models.CronJobStateQueued},
+		{Function: "a", State: models.CronJobStateCompleted},
+		{Function: "a", State: models.CronJobStateQueued},
+	}
+	if err := queue.cron.db.Create(&jobs).Error; err != nil {
+		t.Fatalf("failed to create jobs: %v", err)
+	}
+
+	claimed, err := queue.claim([]string{"a"}, 2)
+	if err != nil {
+		t.Fatalf("failed to claim: %v", err)
+	}
+
+	if len(claimed) != 2 || claimed[0].ID != jobs[0].ID || claimed[1].ID != jobs[2].ID {
+		t.Fatalf("expected the two oldest queued jobs of the task to be claimed, got %+v", claimed)
+	}
+
+	for _, job := range claimed {
+		var stored models.CronJob
+		if err := queue.cron.db.First(&stored, job.ID).Error; err != nil {
+			t.Fatalf("failed to load the job: %v", err)
+		}
+
+		if stored.State != models.CronJobStateProcessing || stored.LastHeartbeat == nil || stored.Version != 1 {
+			t.Fatalf("expected a claimed job to be processing, got %+v", stored)
+		}
+	}
+
+	claimed, err = queue.claim([]string{"a"}, 10)
+	if err != nil {
+		t.Fatalf("failed to claim: %v", err)
+	}
+
+	if len(claimed) != 1 || claimed[0].ID != jobs[4].ID {
+		t.Fatalf("expected only the job left queued to be claimed, got %+v", claimed)
+	}
+
+	if claimed, err = queue.claim([]string{"a"}, 10); err != nil || len(claimed) != 0 {
+		t.Fatalf("expected nothing left to claim, got %+v, err %v", claimed, err)
+	}
+
+	if claimed, err = queue.claim([]string{"b"}, 10); err != nil || len(claimed) != 1 || claimed[0].ID != jobs[1].ID {
+		t.Fatalf("expected the job of the other task to be left for it, got %+v, err %v", claimed, err)
+	}
+}
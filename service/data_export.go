@@ -0,0 +1,606 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+var _ core.DataExportService = (*DataExportServiceDefault)(nil)
+var _ core.Cronable = (*DataExportServiceDefault)(nil)
+
+const (
+	cronTaskBuildDataExportName         = "BuildDataExport"
+	cronTaskPruneExpiredDataExportsName = "PruneExpiredDataExports"
+	dataExportPath                      = "exports"
+	dataExportManifestFile              = "manifest.json"
+	dataExportSignatureFile             = "manifest.sig"
+	dataExportManifestFormatVersion     = 1
+	dataExportPluginSectionPathPrefix   = "plugins/"
+	// dataExportStaleAfter is how long an export may sit pending or processing before it is considered abandoned, a
+	// node that dies mid-build would otherwise leave it in progress and block the deletion of the account
+	dataExportStaleAfter = 6 * time.Hour
+	// dataExportMaxExpiry is the longest S3 accepts for a presigned link
+	dataExportMaxExpiry = 7 * 24 * time.Hour
+)
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.DATA_EXPORT_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewDataExportService()
+		},
+		Depends: []string{core.USER_SERVICE, core.PIN_SERVICE, core.REQUEST_SERVICE, core.STORAGE_SERVICE, core.MAILER_SERVICE, core.CRON_SERVICE},
+	})
+}
+
+type DataExportServiceDefault struct {
+	ctx     core.Context
+	logger  *core.Logger
+	config  config.Manager
+	db      *gorm.DB
+	user    core.UserService
+	pin     core.PinService
+	request core.RequestService
+	storage core.StorageService
+	mailer  core.MailerService
+	cron    core.CronService
+}
+
+type dataExportArgs struct {
+	ExportID uint `json:"export_id"`
+}
+
+type dataExportManifest struct {
+	Version   int               `json:"version"`
+	Portal    string            `json:"portal"`
+	UserID    uint              `json:"user_id"`
+	CreatedAt time.Time         `json:"created_at"`
+	PublicKey string            `json:"public_key"`
+	Files     map[string]string `json:"files"`
+}
+
+func NewDataExportService() (*DataExportServiceDefault, []core.ContextBuilderOption, error) {
+	export := &DataExportServiceDefault{}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			export.ctx = ctx
+			export.logger = ctx.ServiceLogger(export)
+			export.config = ctx.Config()
+			export.db = ctx.DB()
+			export.user = core.GetService[core.UserService](ctx, core.USER_SERVICE)
+			export.pin = core.GetService[core.PinService](ctx, core.PIN_SERVICE)
+			export.request = core.GetService[core.RequestService](ctx, core.REQUEST_SERVICE)
+			export.storage = core.GetService[core.StorageService](ctx, core.STORAGE_SERVICE)
+			export.mailer = core.GetService[core.MailerService](ctx, core.MAILER_SERVICE)
+			export.cron = core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+
+			export.cron.RegisterEntity(export)
+			return nil
+		}),
+	)
+
+	return export, opts, nil
+}
+
+func (d DataExportServiceDefault) ID() string {
+	return core.DATA_EXPORT_SERVICE
+}
+
+func (d DataExportServiceDefault) RegisterTasks(crn core.CronService) error {
+	crn.RegisterTask(cronTaskBuildDataExportName, core.CronTaskFuncHandler(d.cronTaskBuildDataExport), core.CronTaskDefinitionOneTimeJob, dataExportArgsFactory, false)
+	crn.RegisterTask(cronTaskPruneExpiredDataExportsName, core.CronTaskFuncHandler(d.cronTaskPruneExpiredDataExports), core.CronTaskDefinitionDaily, core.CronTaskNoArgsFactory, true)
+	crn.SetTaskPriority(cronTaskPruneExpiredDataExportsName, core.CronTaskPriorityBackground)
+
+	return nil
+}
+
+func (d DataExportServiceDefault) ScheduleJobs(crn core.CronService) error {
+	return crn.CreateJobIfNotExists(cronTaskPruneExpiredDataExportsName, nil)
+}
+
+func dataExportArgsFactory() any {
+	return &dataExportArgs{}
+}
+
+func (d DataExportServiceDefault) RequestExport(userId uint) (*models.DataExport, error) {
+	exists, _, err := d.user.AccountExists(userId)
+	if err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if !exists {
+		return nil, core.NewAccountError(core.ErrKeyUserNotFound, nil)
+	}
+
+	inProgress, err := d.ExportInProgress(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if inProgress {
+		return nil, core.NewAccountError(core.ErrKeyDataExportInProgress, nil)
+	}
+
+	export := models.DataExport{
+		UserID: userId,
+		Status: models.DataExportStatusPending,
+	}
+
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&export)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if err := d.cron.CreateJobScheduled(cronTaskBuildDataExportName, &dataExportArgs{ExportID: export.ID}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDataExportFailed, err)
+	}
+
+	return &export, nil
+}
+
+func (d DataExportServiceDefault) ListExports(userId uint) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.DataExport{}).
+			Where(&models.DataExport{UserID: userId}).
+			Order("created_at DESC").
+			Find(&exports)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return exports, nil
+}
+
+func (d DataExportServiceDefault) ExportInProgress(userId uint) (bool, error) {
+	var count int64
+
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.DataExport{}).
+			Where("user_id = ? AND status IN ? AND updated_at > ?", userId, dataExportActiveStatuses(), time.Now().Add(-dataExportStaleAfter)).
+			Count(&count)
+	}); err != nil {
+		return false, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return count > 0, nil
+}
+
+func (d DataExportServiceDefault) BuildExport(exportId uint) error {
+	var export models.DataExport
+
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.DataExport{}).Preload("User").First(&export, exportId)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if export.Status == models.DataExportStatusCompleted {
+		return nil
+	}
+
+	if err := d.updateExport(&export, map[string]any{"status": models.DataExportStatusProcessing}); err != nil {
+		return err
+	}
+
+	archive, err := d.buildArchive(&export.User)
+	if err != nil {
+		d.failExport(&export, err)
+		return core.NewAccountError(core.ErrKeyDataExportFailed, err)
+	}
+
+	key := fmt.Sprintf("%s/%s.tar.gz", dataExportPath, uuid.NewString())
+	expiry := min(time.Duration(d.config.Config().Core.Account.DataExportExpiry)*time.Hour, dataExportMaxExpiry)
+
+	client, err := d.storage.S3Client(d.ctx)
+	if err != nil {
+		d.failExport(&export, err)
+		return core.NewAccountError(core.ErrKeyDataExportFailed, err)
+	}
+
+	_, err = client.PutObject(d.ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(d.config.Config().Core.Storage.S3.BufferBucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(archive),
+		ContentLength: aws.Int64(int64(len(archive))),
+		ContentType:   aws.String("application/gzip"),
+	})
+	if err != nil {
+		d.failExport(&export, err)
+		return core.NewAccountError(core.ErrKeyDataExportFailed, err)
+	}
+
+	link, err := s3.NewPresignClient(client).PresignGetObject(d.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(d.config.Config().Core.Storage.S3.BufferBucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		d.failExport(&export, err)
+		return core.NewAccountError(core.ErrKeyDataExportFailed, err)
+	}
+
+	expiresAt := time.Now().Add(expiry)
+
+	if err := d.updateExport(&export, map[string]any{
+		"status":     models.DataExportStatusCompleted,
+		"object_key": key,
+		"size":       uint64(len(archive)),
+		"expires_at": &expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	vars := map[string]any{
+		"FirstName":    export.User.FirstName,
+		"Email":        export.User.Email,
+		"DownloadLink": link.URL,
+		"ExpireTime":   expiresAt,
+		"PortalName":   d.config.Config().Core.PortalName,
+	}
+
+	return d.mailer.TemplateSend(core.MAILER_TPL_DATA_EXPORT, vars, vars, export.User.Email)
+}
+
+func (d DataExportServiceDefault) buildArchive(user *models.User) ([]byte, error) {
+	sections, err := d.collectSections(user)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	privateKey := d.config.Config().Core.Identity.PrivateKey()
+
+	manifest := dataExportManifest{
+		Version:   dataExportManifestFormatVersion,
+		Portal:    d.config.Config().Core.Domain,
+		UserID:    user.ID,
+		CreatedAt: time.Now().UTC(),
+		PublicKey: hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+		Files:     make(map[string]string, len(sections)),
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	writeFile := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: manifest.CreatedAt,
+		}); err != nil {
+			return err
+		}
+
+		_, err := tw.Write(data)
+		return err
+	}
+
+	for _, name := range names {
+		data, err := json.MarshalIndent(sections[name], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.Sum256(data)
+		manifest.Files[name] = hex.EncodeToString(hash[:])
+
+		if err := writeFile(name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	// The manifest carries the digest of every section, signing it covers the whole archive
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFile(dataExportManifestFile, manifestData); err != nil {
+		return nil, err
+	}
+
+	signature := ed25519.Sign(privateKey, manifestData)
+	if err := writeFile(dataExportSignatureFile, []byte(hex.EncodeToString(signature))); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (d DataExportServiceDefault) collectSections(user *models.User) (map[string]any, error) {
+	sections := make(map[string]any)
+
+	sections["profile.json"] = map[string]any{
+		"id":          user.ID,
+		"email":       user.Email,
+		"first_name":  user.FirstName,
+		"last_name":   user.LastName,
+		"role":        user.Role,
+		"verified":    user.Verified,
+		"otp_enabled": user.OTPEnabled,
+		"last_login":  user.LastLogin,
+		"last_ip":     user.LastLoginIP,
+		"created_at":  user.CreatedAt,
+		"updated_at":  user.UpdatedAt,
+	}
+
+	var publicKeys []models.PublicKey
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.PublicKey{}).Where(&models.PublicKey{UserID: user.ID}).Find(&publicKeys)
+	}); err != nil {
+		return nil, err
+	}
+
+	keys := make([]map[string]any, 0, len(publicKeys))
+	for _, key := range publicKeys {
+		keys = append(keys, map[string]any{
+			"key":        key.Key,
+			"created_at": key.CreatedAt,
+		})
+	}
+	sections["public_keys.json"] = keys
+
+	pins, err := d.pin.AllAccountPins(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	pinList := make([]map[string]any, 0, len(pins))
+	var usage uint64
+	for _, pin := range pins {
+		pinList = append(pinList, map[string]any{
+			"id":              pin.ID,
+			"hash":            hex.EncodeToString(pin.Upload.Hash),
+			"protocol":        pin.Upload.Protocol,
+			"size":            pin.Upload.Size,
+			"mime_type":       pin.Upload.MimeType,
+			"organization_id": pin.OrganizationID,
+			"created_at":      pin.CreatedAt,
+		})
+		usage += pin.Upload.Size
+	}
+	sections["pins.json"] = pinList
+
+	requests, err := d.request.ListRequestsByUser(d.ctx, user.ID, core.RequestFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	requestList := make([]map[string]any, 0, len(requests))
+	for _, req := range requests {
+		entry := map[string]any{
+			"id":         req.ID,
+			"operation":  req.Operation,
+			"protocol":   req.Protocol,
+			"status":     req.Status,
+			"source_ip":  req.SourceIP,
+			"hash":       hex.EncodeToString(req.Hash),
+			"size":       req.Size,
+			"mime_type":  req.MimeType,
+			"metadata":   req.Metadata,
+			"created_at": req.CreatedAt,
+		}
+
+		// Requests of protocols that are no longer loaded are exported without their protocol data
+		if handler, ok := core.GetProtocol(req.Protocol).(core.ProtocolRequestDataHandler); ok {
+			protocolData, err := handler.GetProtocolData(d.ctx, d.db.Preload("Request"), req.ID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			entry["protocol_data"] = protocolData
+		}
+
+		requestList = append(requestList, entry)
+	}
+	sections["requests.json"] = requestList
+
+	audit, err := d.collectAudit(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	sections["audit.json"] = audit
+
+	sections["usage.json"] = map[string]any{
+		"pins":          len(pins),
+		"storage_bytes": usage,
+		"requests":      len(requests),
+	}
+
+	for _, plugin := range core.GetPlugins() {
+		if plugin.DataExport == nil {
+			continue
+		}
+
+		section, err := plugin.DataExport(d.ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", plugin.ID, err)
+		}
+
+		if section != nil {
+			sections[dataExportPluginSectionPathPrefix+plugin.ID+".json"] = section
+		}
+	}
+
+	return sections, nil
+}
+
+func (d DataExportServiceDefault) collectAudit(userId uint) (map[string]any, error) {
+	var impersonations []models.ImpersonationSession
+	var suspensions []models.AccountSuspension
+	var deletions []models.AccountDeletion
+
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.ImpersonationSession{}).Where(&models.ImpersonationSession{UserID: userId}).Find(&impersonations)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.AccountSuspension{}).Where(&models.AccountSuspension{UserID: userId}).Find(&suspensions)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped().Model(&models.AccountDeletion{}).Where(&models.AccountDeletion{UserID: userId}).Find(&deletions)
+	}); err != nil {
+		return nil, err
+	}
+
+	impersonationList := make([]map[string]any, 0, len(impersonations))
+	for _, session := range impersonations {
+		impersonationList = append(impersonationList, map[string]any{
+			"admin_id":   session.AdminID,
+			"reason":     session.Reason,
+			"started_at": session.CreatedAt,
+			"expires_at": session.ExpiresAt,
+			"ended_at":   session.EndedAt,
+		})
+	}
+
+	suspensionList := make([]map[string]any, 0, len(suspensions))
+	for _, suspension := range suspensions {
+		suspensionList = append(suspensionList, map[string]any{
+			"reason":       suspension.Reason,
+			"suspended_at": suspension.CreatedAt,
+			"expires_at":   suspension.ExpiresAt,
+			"freeze_pins":  suspension.FreezePins,
+			"lifted_at":    suspension.LiftedAt,
+		})
+	}
+
+	deletionList := make([]map[string]any, 0, len(deletions))
+	for _, deletion := range deletions {
+		deletionList = append(deletionList, map[string]any{
+			"ip":           deletion.IP,
+			"requested_at": deletion.CreatedAt,
+			"cancelled_at": deletion.DeletedAt,
+		})
+	}
+
+	return map[string]any{
+		"impersonations":    impersonationList,
+		"suspensions":       suspensionList,
+		"deletion_requests": deletionList,
+	}, nil
+}
+
+func (d DataExportServiceDefault) updateExport(export *models.DataExport, updates map[string]any) error {
+	if err := db.RetryableTransaction(d.ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(export).Updates(updates)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
+func (d DataExportServiceDefault) failExport(export *models.DataExport, cause error) {
+	d.logger.Error("Failed to build data export", zap.Uint("export_id", export.ID), zap.Uint("user_id", export.UserID), zap.Error(cause))
+
+	if err := d.updateExport(export, map[string]any{
+		"status":         models.DataExportStatusFailed,
+		"status_message": cause.Error(),
+	}); err != nil {
+		d.logger.Error("Failed to update data export status", zap.Uint("export_id", export.ID), zap.Error(err))
+	}
+}
+
+func (d DataExportServiceDefault) cronTaskBuildDataExport(args *dataExportArgs, _ core.Context) error {
+	return d.BuildExport(args.ExportID)
+}
+
+func (d DataExportServiceDefault) cronTaskPruneExpiredDataExports(_ *core.CronTaskNoArgs, ctx core.Context) error {
+	var exports []*models.DataExport
+
+	if err := db.RetryableTransaction(ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.DataExport{}).
+			Where("status IN ? AND updated_at < ?", dataExportActiveStatuses(), time.Now().Add(-dataExportStaleAfter)).
+			Updates(map[string]any{
+				"status":         models.DataExportStatusFailed,
+				"status_message": "export timed out",
+			})
+	}); err != nil {
+		return err
+	}
+
+	if err := db.RetryableTransaction(ctx, d.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.DataExport{}).
+			Where("status = ? AND expires_at < ?", models.DataExportStatusCompleted, time.Now()).
+			Find(&exports)
+	}); err != nil {
+		return err
+	}
+
+	if len(exports) == 0 {
+		return nil
+	}
+
+	client, err := d.storage.S3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := d.deleteExportObject(ctx, client, export.ObjectKey); err != nil {
+			d.logger.Error("Failed to delete data export archive", zap.Uint("export_id", export.ID), zap.Error(err))
+			continue
+		}
+
+		if err := d.updateExport(export, map[string]any{"status": models.DataExportStatusExpired, "object_key": ""}); err != nil {
+			d.logger.Error("Failed to update data export status", zap.Uint("export_id", export.ID), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func dataExportActiveStatuses() []models.DataExportStatusType {
+	return []models.DataExportStatusType{models.DataExportStatusPending, models.DataExportStatusProcessing}
+}
+
+func (d DataExportServiceDefault) deleteExportObject(ctx context.Context, client *s3.Client, key string) error {
+	_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(d.config.Config().Core.Storage.S3.BufferBucket),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	gevent "github.com/gookit/event"
+	"github.com/redis/go-redis/v9"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"sync"
+)
+
+var _ core.EventBusService = (*EventBusServiceDefault)(nil)
+
+const eventBusChannel = "portal:events"
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.EVENT_BUS_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewEventBusService()
+		},
+	})
+}
+
+type EventBusServiceDefault struct {
+	ctx        core.Context
+	logger     *core.Logger
+	client     *redis.Client
+	pubsub     *redis.PubSub
+	nodeId     string
+	subscribed map[string]struct{}
+	mu         sync.RWMutex
+	wg         sync.WaitGroup
+}
+
+type eventBusMessage struct {
+	Node  string                 `json:"node"`
+	Name  string                 `json:"name"`
+	Data  json.RawMessage        `json:"data"`
+	Trace propagation.MapCarrier `json:"trace,omitempty"`
+}
+
+func NewEventBusService() (*EventBusServiceDefault, []core.ContextBuilderOption, error) {
+	bus := &EventBusServiceDefault{
+		subscribed: make(map[string]struct{}),
+	}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			bus.ctx = ctx
+			bus.logger = ctx.ServiceLogger(bus)
+			bus.nodeId = ctx.Config().Config().Core.NodeID.String()
+
+			cfg := ctx.Config().Config().Core
+			if !cfg.ClusterEnabled() || !cfg.Clustered.RedisEnabled() {
+				return nil
+			}
+
+			client, err := cfg.Clustered.Redis.Client()
+			if err != nil {
+				return err
+			}
+
+			bus.client = client
+
+			// Commands only boot services, they publish events but never take them in from other nodes
+			event.Listen[*event.BootCompleteEvent](ctx, event.EVENT_BOOT_COMPLETE, func(evt *event.BootCompleteEvent) error {
+				return bus.listen()
+			})
+
+			return nil
+		}),
+		core.ContextWithExitFunc(func(ctx core.Context) error {
+			if bus.pubsub == nil {
+				return nil
+			}
+
+			err := bus.pubsub.Close()
+			bus.wg.Wait()
+
+			return err
+		}),
+	)
+
+	return bus, opts, nil
+}
+
+func (b *EventBusServiceDefault) ID() string {
+	return core.EVENT_BUS_SERVICE
+}
+
+func (b *EventBusServiceDefault) listen() error {
+	pubsub := b.client.Subscribe(b.ctx, eventBusChannel)
+	if _, err := pubsub.Receive(b.ctx); err != nil {
+		return err
+	}
+
+	b.pubsub = pubsub
+
+	b.wg.Add(1)
+	go b.receive()
+
+	return nil
+}
+
+func (b *EventBusServiceDefault) Enabled() bool {
+	return b.client != nil
+}
+
+func (b *EventBusServiceDefault) Subscribe(eventName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribed[eventName] = struct{}{}
+}
+
+func (b *EventBusServiceDefault) Publish(ctx context.Context, evt gevent.Event) error {
+	if !b.Enabled() || !b.isSubscribed(evt.Name()) {
+		return nil
+	}
+
+	data, err := core.EncodeEventData(evt.Data())
+	if err != nil {
+		return err
+	}
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	msg, err := json.Marshal(eventBusMessage{
+		Node:  b.nodeId,
+		Name:  evt.Name(),
+		Data:  data,
+		Trace: carrier,
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.client.Publish(b.ctx, eventBusChannel, msg).Err()
+}
+
+func (b *EventBusServiceDefault) isSubscribed(eventName string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	_, ok := b.subscribed[eventName]
+
+	return ok
+}
+
+func (b *EventBusServiceDefault) receive() {
+	defer b.wg.Done()
+
+	// The channel is closed along with the subscription on exit
+	for msg := range b.pubsub.Channel() {
+		if err := b.handle(msg.Payload); err != nil {
+			b.logger.Error("Failed to handle cluster event", zap.Error(err))
+		}
+	}
+}
+
+func (b *EventBusServiceDefault) handle(payload string) error {
+	var msg eventBusMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return err
+	}
+
+	// Redis delivers our own messages back to us
+	if msg.Node == b.nodeId {
+		return nil
+	}
+
+	evt, err := core.NewEvent(msg.Name)
+	if err != nil {
+		return err
+	}
+
+	data, err := core.DecodeEventData(msg.Data)
+	if err != nil {
+		return err
+	}
+
+	data[core.EVENT_ORIGIN_NODE_KEY] = msg.Node
+
+	evt.SetName(core.ClusterEventName(msg.Name))
+	evt.SetData(data)
+
+	_, span := core.Tracer().Start(otel.GetTextMapPropagator().Extract(b.ctx, msg.Trace), "event."+evt.Name(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("event.origin_node", msg.Node)),
+	)
+	defer span.End()
+
+	// Fired directly rather than through event.DoFire, received events must not be published again
+	err = b.ctx.Event().FireEvent(evt)
+	core.RecordSpanError(span, err)
+
+	return err
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.lumeweb.com/portal/core"
+	"sync"
+	"time"
+)
+
+var _ core.HealthService = (*HealthServiceDefault)(nil)
+var _ core.HealthChecker = (*HealthServiceDefault)(nil)
+
+const (
+	healthCheckDatabase = "database"
+	healthCheckRedis    = "redis"
+	healthCheckEtcd     = "etcd"
+	healthCheckShutdown = "shutdown"
+
+	// etcdHealthKey is read to prove the cluster answers, it does not need to exist
+	etcdHealthKey = "portal/health"
+)
+
+var errHealthShuttingDown = errors.New("portal is shutting down")
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.HEALTH_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewHealthService()
+		},
+	})
+}
+
+type HealthServiceDefault struct {
+	ctx    core.Context
+	logger *core.Logger
+}
+
+func NewHealthService() (*HealthServiceDefault, []core.ContextBuilderOption, error) {
+	health := &HealthServiceDefault{}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			health.ctx = ctx
+			health.logger = ctx.ServiceLogger(health)
+			return nil
+		}),
+	)
+
+	return health, opts, nil
+}
+
+func (h *HealthServiceDefault) ID() string {
+	return core.HEALTH_SERVICE
+}
+
+func (h *HealthServiceDefault) Live() core.HealthReport {
+	return core.HealthReport{Status: core.HealthStatusOK}
+}
+
+func (h *HealthServiceDefault) Ready(ctx context.Context) core.HealthReport {
+	// The portal context is cancelled first thing on shutdown, from then on traffic should go elsewhere
+	if h.ctx.Err() != nil {
+		return core.HealthReport{
+			Status: core.HealthStatusFail,
+			Checks: []core.HealthCheckResult{{Name: healthCheckShutdown, Status: core.HealthStatusFail, Error: errHealthShuttingDown.Error()}},
+		}
+	}
+
+	checks := h.checks()
+	results := make([]core.HealthCheckResult, len(checks))
+	timeout := time.Duration(h.ctx.Config().Config().Core.Health.CheckTimeout) * time.Second
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, check, timeout)
+		}()
+	}
+	wg.Wait()
+
+	report := core.HealthReport{Status: core.HealthStatusOK, Checks: results}
+	for _, result := range results {
+		if result.Status == core.HealthStatusOK {
+			continue
+		}
+
+		if !result.Optional {
+			report.Status = core.HealthStatusFail
+			break
+		}
+
+		report.Status = core.HealthStatusDegraded
+	}
+
+	return report
+}
+
+func (h *HealthServiceDefault) HealthChecks() []core.HealthCheck {
+	checks := []core.HealthCheck{{Name: healthCheckDatabase, Check: h.checkDatabase}}
+
+	cfg := h.ctx.Config().Config().Core
+	if cfg.ClusterEnabled() {
+		if cfg.Clustered.RedisEnabled() {
+			checks = append(checks, core.HealthCheck{Name: healthCheckRedis, Check: h.checkRedis})
+		}
+
+		if cfg.Clustered.Etcd != nil {
+			checks = append(checks, core.HealthCheck{Name: healthCheckEtcd, Check: h.checkEtcd})
+		}
+	}
+
+	return checks
+}
+
+// checks collects the checks of every running service, in service order so reports read the same on every probe
+func (h *HealthServiceDefault) checks() []core.HealthCheck {
+	var checks []core.HealthCheck
+
+	for _, svcInfo := range core.GetServices() {
+		if checker, ok := h.ctx.Service(svcInfo.ID).(core.HealthChecker); ok {
+			checks = append(checks, checker.HealthChecks()...)
+		}
+	}
+
+	return checks
+}
+
+// run gives up on a check when it times out, a check that ignores its context must not hold up the probe
+func (h *HealthServiceDefault) run(ctx context.Context, check core.HealthCheck, timeout time.Duration) core.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := core.HealthCheckResult{
+		Name:     check.Name,
+		Status:   core.HealthStatusOK,
+		Optional: check.Optional,
+		Latency:  time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		result.Status = core.HealthStatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func (h *HealthServiceDefault) checkDatabase(ctx context.Context) error {
+	sqlDB, err := h.ctx.DB().DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+func (h *HealthServiceDefault) checkRedis(ctx context.Context) error {
+	client, err := h.ctx.Config().Config().Core.Clustered.Redis.Client()
+	if err != nil {
+		return err
+	}
+
+	return client.Ping(ctx).Err()
+}
+
+func (h *HealthServiceDefault) checkEtcd(ctx context.Context) error {
+	client, err := h.ctx.Config().Config().Core.Clustered.Etcd.Client()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Get(ctx, etcdHealthKey)
+
+	return err
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	dbLogger "gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+// testConfigManager serves a fixed config, the methods a test does not need are left unimplemented
+type testConfigManager struct {
+	config.Manager
+	cfg *config.Config
+}
+
+func (m *testConfigManager) Config() *config.Config {
+	return m.cfg
+}
+
+// newTestContext creates a context backed by a fresh sqlite database with the given models migrated
+func newTestContext(t *testing.T, cfg *config.Config, models []any, options ...core.ContextBuilderOption) core.Context {
+	t.Helper()
+
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "portal.db")), &gorm.Config{
+		Logger: dbLogger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	if err := gdb.AutoMigrate(models...); err != nil {
+		t.Fatalf("failed to migrate models: %v", err)
+	}
+
+	options = append([]core.ContextBuilderOption{core.ContextWithDB(gdb)}, options...)
+
+	ctx, err := core.NewContext(&testConfigManager{cfg: cfg}, &core.Logger{Logger: zap.NewNop()}, options...)
+	if err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+
+	t.Cleanup(ctx.Cancel)
+
+	return ctx
+}
@@ -4,19 +4,27 @@ import (
 	"fmt"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal/config"
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var _ core.HTTPService = (*HTTPServiceDefault)(nil)
 
+const metricsPath = "/metrics"
+
 func init() {
 	core.RegisterService(core.ServiceInfo{
 		ID: core.HTTP_SERVICE,
@@ -27,11 +35,12 @@ func init() {
 }
 
 type HTTPServiceDefault struct {
-	ctx    core.Context
-	logger *core.Logger
-	router *mux.Router
-	srv    *http.Server
-	access core.AccessService
+	ctx     core.Context
+	logger  *core.Logger
+	router  *mux.Router
+	srv     *http.Server
+	access  core.AccessService
+	serving atomic.Bool
 }
 
 var _ handlers.RecoveryHandlerLogger = (*recoverLogger)(nil)
@@ -61,6 +70,12 @@ func NewHTTPService() (*HTTPServiceDefault, []core.ContextBuilderOption, error)
 			return nil
 		}),
 		core.ContextWithExitFunc(func(ctx core.Context) error {
+			// Readiness already fails at this point, give load balancers time to notice before connections are refused
+			if delay := ctx.Config().Config().Core.Health.ShutdownDelay; delay > 0 && _http.serving.Load() {
+				_http.logger.Info("Delaying HTTP shutdown", zap.Uint("seconds", delay))
+				time.Sleep(time.Duration(delay) * time.Second)
+			}
+
 			return srv.Shutdown(ctx)
 		}),
 	)
@@ -81,6 +96,16 @@ func (h *HTTPServiceDefault) Router() *mux.Router {
 func (h *HTTPServiceDefault) Init() error {
 	h.router.Use(handlers.RecoveryHandler(handlers.RecoveryLogger(&recoverLogger{h.ctx})))
 	h.srv.Addr = ":" + strconv.FormatUint(uint64(h.ctx.Config().Config().Core.Port), 10)
+
+	// Wrapping the router rather than using router middleware also covers requests that match no route
+	h.srv.Handler = otelhttp.NewHandler(middleware.MetricsMiddleware(h.ctx)(h.router), "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.Host
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != metricsPath && !isHealthPath(r.URL.Path)
+		}),
+	)
 	for _, api := range core.GetAPIs() {
 		domain := fmt.Sprintf("%s.%s", api.Subdomain(), h.ctx.Config().Config().Core.Domain)
 		err := api.Configure(h.Router().Host(domain).Subrouter(), h.access)
@@ -96,12 +121,41 @@ func (h *HTTPServiceDefault) Init() error {
 
 	h.Router().PathPrefix("/debug/").Handler(http.DefaultServeMux).Use(authMw)
 
+	metricsCfg := h.ctx.Config().Config().Core.Metrics
+	if metricsCfg.Enabled {
+		metricsHandler := promhttp.HandlerFor(h.ctx.Metrics().Registry(), promhttp.HandlerOpts{})
+		h.Router().Handle(metricsPath, middleware.MetricsTokenMiddleware(metricsCfg.Token)(metricsHandler)).Methods(http.MethodGet)
+	}
+
+	h.registerHealthRoutes(h.Router().PathPrefix(strings.TrimSuffix(healthPathPrefix, "/")).Subrouter())
+
 	corsHandler := middleware.CorsMiddleware(nil)
 
 	rootApi := h.Router().PathPrefix("/api").Subrouter()
 	rootApi.Use(corsHandler)
 	rootApi.HandleFunc("/meta", h.apiMetaHandler).Methods(http.MethodGet)
 
+	inviteApi := rootApi.PathPrefix("/invites").Subrouter()
+	inviteApi.Use(authMw)
+	h.registerInviteRoutes(inviteApi)
+
+	exportApi := rootApi.PathPrefix("/account/export").Subrouter()
+	exportApi.Use(authMw, middleware.DenyImpersonationMiddleware())
+	h.registerDataExportRoutes(exportApi)
+
+	activityApi := rootApi.PathPrefix("/account/activity").Subrouter()
+	activityApi.Use(authMw)
+	h.registerActivityRoutes(activityApi)
+
+	webhookApi := rootApi.PathPrefix("/webhooks").Subrouter()
+	webhookApi.Use(authMw)
+	h.registerWebhookRoutes(webhookApi, false)
+
+	adminApi := rootApi.PathPrefix("/admin").Subrouter()
+	adminApi.Use(authMw, middleware.AdminMiddleware(h.ctx))
+	h.registerAdminRoutes(adminApi)
+	h.registerWebhookRoutes(adminApi.PathPrefix("/webhooks").Subrouter(), true)
+
 	return nil
 }
 
@@ -110,6 +164,12 @@ func (h *HTTPServiceDefault) apiMetaHandler(w http.ResponseWriter, r *http.Reque
 
 	metaBuilder := NewPortalMetaBuilder(h.ctx.Config().Config().Core.Domain)
 
+	accountCfg := h.ctx.Config().Config().Core.Account
+	metaBuilder.AddFeatureFlag("registration.enabled", accountCfg.RegistrationMode != config.RegistrationModeClosed)
+	metaBuilder.AddFeatureFlag("registration.invite_only", accountCfg.RegistrationMode == config.RegistrationModeInviteOnly)
+	metaBuilder.AddFeatureFlag("registration.domain_restricted", len(accountCfg.AllowedEmailDomains) > 0 || len(accountCfg.DeniedEmailDomains) > 0)
+	metaBuilder.AddFeatureFlag("invites.enabled", accountCfg.InviteQuota > 0)
+
 	for _, plugin := range core.GetPlugins() {
 		metaBuilder.AddPlugin(plugin.ID)
 	}
@@ -138,6 +198,8 @@ func (h *HTTPServiceDefault) Serve() error {
 		return err
 	}
 
+	h.serving.Store(true)
+
 	go func() {
 		defer wg.Done()
 		err := h.srv.Serve(ln)
@@ -165,3 +227,13 @@ func (h *HTTPServiceDefault) APISubdomain(id string, proto bool) string {
 
 	return fmt.Sprintf(formatter, core.GetAPI(id).Subdomain(), h.ctx.Config().Config().Core.Domain)
 }
+
+// accountError responds with the status of an account error, other errors are internal
+func (h *HTTPServiceDefault) accountError(ctx httputil.RequestContext, err error) {
+	if acctErr := core.AsAccountError(err); acctErr != nil {
+		_ = ctx.Error(acctErr, acctErr.HttpStatus())
+		return
+	}
+
+	_ = ctx.Error(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/middleware"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	activityHeartbeatInterval = 15 * time.Second
+	activityRetryInterval     = 3 * time.Second
+
+	// activitySentWindow is how long sent events are remembered, reading the log again repeats events this recent
+	activitySentWindow = time.Minute
+)
+
+var errActivityStreamUnsupported = errors.New("streaming is not supported")
+
+func (h *HTTPServiceDefault) registerActivityRoutes(router *mux.Router) {
+	router.HandleFunc("", h.activityStreamHandler).Methods(http.MethodGet)
+}
+
+func (h *HTTPServiceDefault) activityStreamHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	activity := core.GetService[core.ActivityService](h.ctx, core.ACTIVITY_SERVICE)
+
+	userId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		_ = ctx.Error(errActivityStreamUnsupported, http.StatusInternalServerError)
+		return
+	}
+
+	// Browsers send Last-Event-ID on reconnects only, so the first connection may resume through the query instead
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("last_event_id")
+	}
+
+	lastId, _ := strconv.ParseUint(lastEventId, 10, 64)
+
+	// Subscribe before reading the log so nothing recorded in between is missed, duplicates are skipped by ID
+	events, unsubscribe := activity.Subscribe(userId)
+	defer unsubscribe()
+
+	backlog, err := activity.Since(userId, uint(lastId))
+	if err != nil {
+		h.accountError(ctx, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", activityRetryInterval.Milliseconds()); err != nil {
+		return
+	}
+
+	stream := newActivityStream(w, activity, userId, uint(lastId))
+
+	for _, evt := range backlog {
+		if err := stream.write(evt); err != nil {
+			return
+		}
+	}
+
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(activityHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			stream.prune()
+		case evt, ok := <-events:
+			// Dropped for falling behind, the client reconnects and resumes from the log
+			if !ok {
+				return
+			}
+
+			if err := stream.receive(evt); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+// activityStream writes the activity of a user to a client. IDs are taken before the transactions recording the
+// activity commit, so activity can arrive out of order, and an ID skipped over may still show up. The highest ID
+// sent is where the client resumes from, events sent lately are remembered so reading the log again repeats none.
+type activityStream struct {
+	w        io.Writer
+	activity core.ActivityService
+	userId   uint
+	lastId   uint
+	sent     map[uint]time.Time
+}
+
+func newActivityStream(w io.Writer, activity core.ActivityService, userId uint, lastId uint) *activityStream {
+	return &activityStream{
+		w:        w,
+		activity: activity,
+		userId:   userId,
+		lastId:   lastId,
+		sent:     make(map[uint]time.Time),
+	}
+}
+
+// receive writes a live event. When IDs were skipped since the last one sent, the log is read again first, it holds
+// whatever was committed in the gap but did not reach the stream yet.
+func (s *activityStream) receive(evt *models.ActivityEvent) error {
+	if evt.ID > s.lastId+1 {
+		backlog, err := s.activity.Since(s.userId, s.lastId)
+		if err != nil {
+			return err
+		}
+
+		for _, logged := range backlog {
+			if err := s.write(logged); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.write(evt)
+}
+
+// write sends an event unless it was sent already. An event older than the last one sent goes without an ID, so the
+// client still resumes from the highest one.
+func (s *activityStream) write(evt *models.ActivityEvent) error {
+	if _, ok := s.sent[evt.ID]; ok {
+		return nil
+	}
+
+	var err error
+	if evt.ID > s.lastId {
+		_, err = fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", evt.ID, evt.Type, evt.Data)
+		s.lastId = evt.ID
+	} else {
+		_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", evt.Type, evt.Data)
+	}
+
+	s.sent[evt.ID] = time.Now()
+
+	return err
+}
+
+func (s *activityStream) prune() {
+	for id, sentAt := range s.sent {
+		if time.Since(sentAt) > activitySentWindow {
+			delete(s.sent, id)
+		}
+	}
+}
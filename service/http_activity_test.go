@@ -0,0 +1,64 @@
+package service
+
+import (
+	"bytes"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/datatypes"
+	"testing"
+)
+
+// testActivityLog serves Since from a fixed log
+type testActivityLog struct {
+	core.ActivityService
+	log []*models.ActivityEvent
+}
+
+func (l *testActivityLog) Since(_ uint, lastId uint) ([]*models.ActivityEvent, error) {
+	var events []*models.ActivityEvent
+	for _, evt := range l.log {
+		if evt.ID > lastId {
+			events = append(events, evt)
+		}
+	}
+
+	return events, nil
+}
+
+func activityEvent(id uint) *models.ActivityEvent {
+	evt := &models.ActivityEvent{Type: "test", Data: datatypes.JSON("{}")}
+	evt.ID = id
+	return evt
+}
+
+func TestActivityStreamKeepsLateEvents(t *testing.T) {
+	var out bytes.Buffer
+	log := &testActivityLog{}
+	stream := newActivityStream(&out, log, 1, 0)
+
+	if err := stream.receive(activityEvent(1)); err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	// 2 and 3 commit after 4, 3 has made it to the log by the time 4 arrives
+	log.log = []*models.ActivityEvent{activityEvent(1), activityEvent(3), activityEvent(4)}
+
+	for _, id := range []uint{4, 3, 2} {
+		if err := stream.receive(activityEvent(id)); err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+	}
+
+	expected := "id: 1\nevent: test\ndata: {}\n\n" +
+		"id: 3\nevent: test\ndata: {}\n\n" +
+		"id: 4\nevent: test\ndata: {}\n\n" +
+		"event: test\ndata: {}\n\n"
+
+	if out.String() != expected {
+		t.Fatalf("expected every event once, late ones without an ID, got:\n%s", out.String())
+	}
+
+	if stream.lastId != 4 {
+		t.Fatalf("expected the stream to resume from 4, got %d", stream.lastId)
+	}
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/middleware"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var errAdminInvalidUserID = errors.New("invalid user id")
+
+type adminListUsersResponse struct {
+	Users []*adminUser `json:"users"`
+	Total int64        `json:"total"`
+}
+
+type adminUser struct {
+	ID              uint       `json:"id"`
+	Email           string     `json:"email"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Role            string     `json:"role"`
+	Verified        bool       `json:"verified"`
+	OTPEnabled      bool       `json:"otp_enabled"`
+	LastLogin       *time.Time `json:"last_login"`
+	CreatedAt       time.Time  `json:"created_at"`
+	PendingDeletion bool       `json:"pending_deletion"`
+	Suspended       bool       `json:"suspended"`
+}
+
+type adminSetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type adminSuspendRequest struct {
+	Reason     string     `json:"reason"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	FreezePins bool       `json:"freeze_pins"`
+}
+
+type adminImpersonateRequest struct {
+	Reason   string `json:"reason"`
+	Duration uint   `json:"duration"`
+}
+
+type adminOutboxReplayRequest struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type adminOutboxReplayResponse struct {
+	Replayed int64 `json:"replayed"`
+}
+
+type adminImpersonateResponse struct {
+	Token     string    `json:"token"`
+	SessionID uint      `json:"session_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (h *HTTPServiceDefault) registerAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/users", h.adminListUsersHandler).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id:[0-9]+}", h.adminGetUserHandler).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id:[0-9]+}/pins", h.adminUserPinsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id:[0-9]+}/requests", h.adminUserRequestsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id:[0-9]+}/verify", h.adminVerifyUserHandler).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id:[0-9]+}/otp/disable", h.adminDisableOTPHandler).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id:[0-9]+}/password/reset", h.adminForcePasswordResetHandler).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id:[0-9]+}/role", h.adminSetRoleHandler).Methods(http.MethodPut)
+	router.HandleFunc("/users/{id:[0-9]+}/deletion", h.adminCancelDeletionHandler).Methods(http.MethodDelete)
+	router.HandleFunc("/users/{id:[0-9]+}/suspension", h.adminSuspendUserHandler).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id:[0-9]+}/suspension", h.adminLiftSuspensionHandler).Methods(http.MethodDelete)
+	router.HandleFunc("/users/{id:[0-9]+}/impersonate", h.adminImpersonateHandler).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id:[0-9]+}/impersonations", h.adminListImpersonationsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/impersonations/{id:[0-9]+}", h.adminEndImpersonationHandler).Methods(http.MethodDelete)
+	router.HandleFunc("/outbox/replay", h.adminOutboxReplayHandler).Methods(http.MethodPost)
+
+	h.registerAdminCronRoutes(router)
+}
+
+func (h *HTTPServiceDefault) adminListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	admin := core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE)
+	user := core.GetService[core.UserService](h.ctx, core.USER_SERVICE)
+
+	query := r.URL.Query()
+	filter := core.UserFilter{
+		Search: query.Get("search"),
+		Role:   query.Get("role"),
+	}
+
+	if verified, err := strconv.ParseBool(query.Get("verified")); err == nil {
+		filter.Verified = &verified
+	}
+
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+
+	users, total, err := admin.ListUsers(filter)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	resp := adminListUsersResponse{
+		Users: make([]*adminUser, 0, len(users)),
+		Total: total,
+	}
+
+	for _, u := range users {
+		pending, err := user.IsAccountPendingDeletion(u.ID)
+		if err != nil {
+			h.adminError(ctx, err)
+			return
+		}
+
+		suspended, err := user.IsAccountSuspended(u.ID)
+		if err != nil {
+			h.adminError(ctx, err)
+			return
+		}
+
+		resp.Users = append(resp.Users, &adminUser{
+			ID:              u.ID,
+			Email:           u.Email,
+			FirstName:       u.FirstName,
+			LastName:        u.LastName,
+			Role:            u.Role,
+			Verified:        u.Verified,
+			OTPEnabled:      u.OTPEnabled,
+			LastLogin:       u.LastLogin,
+			CreatedAt:       u.CreatedAt,
+			PendingDeletion: pending,
+			Suspended:       suspended,
+		})
+	}
+
+	ctx.Encode(resp)
+}
+
+func (h *HTTPServiceDefault) adminGetUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	user := core.GetService[core.UserService](h.ctx, core.USER_SERVICE)
+
+	userId, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	exists, u, err := user.AccountExists(userId)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	if !exists {
+		h.adminError(ctx, core.NewAccountError(core.ErrKeyUserNotFound, nil))
+		return
+	}
+
+	pending, err := user.IsAccountPendingDeletion(userId)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	suspended, err := user.IsAccountSuspended(userId)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	ctx.Encode(&adminUser{
+		ID:              u.ID,
+		Email:           u.Email,
+		FirstName:       u.FirstName,
+		LastName:        u.LastName,
+		Role:            u.Role,
+		Verified:        u.Verified,
+		OTPEnabled:      u.OTPEnabled,
+		LastLogin:       u.LastLogin,
+		CreatedAt:       u.CreatedAt,
+		PendingDeletion: pending,
+		Suspended:       suspended,
+	})
+}
+
+func (h *HTTPServiceDefault) adminUserPinsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	pin := core.GetService[core.PinService](h.ctx, core.PIN_SERVICE)
+
+	userId, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	pins, err := pin.AllAccountPins(userId)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	ctx.Encode(pins)
+}
+
+func (h *HTTPServiceDefault) adminUserRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	request := core.GetService[core.RequestService](h.ctx, core.REQUEST_SERVICE)
+
+	userId, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := core.RequestFilter{
+		Protocol: query.Get("protocol"),
+	}
+
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+
+	requests, err := request.ListRequestsByUser(r.Context(), userId, filter)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	ctx.Encode(requests)
+}
+
+func (h *HTTPServiceDefault) adminVerifyUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminUserAction(w, r, core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE).VerifyUser)
+}
+
+func (h *HTTPServiceDefault) adminDisableOTPHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminUserAction(w, r, core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE).DisableUserOTP)
+}
+
+func (h *HTTPServiceDefault) adminForcePasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminUserAction(w, r, core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE).ForcePasswordReset)
+}
+
+func (h *HTTPServiceDefault) adminCancelDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminUserAction(w, r, core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE).CancelAccountDeletion)
+}
+
+func (h *HTTPServiceDefault) adminSetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	admin := core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE)
+
+	userId, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	var req adminSetRoleRequest
+	if err := ctx.Decode(&req); err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	if err := admin.SetUserRole(userId, req.Role); err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPServiceDefault) adminSuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	user := core.GetService[core.UserService](h.ctx, core.USER_SERVICE)
+
+	userId, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	adminId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	var req adminSuspendRequest
+	if err := ctx.Decode(&req); err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	suspension, err := user.SuspendAccount(userId, adminId, req.Reason, req.ExpiresAt, req.FreezePins)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	ctx.Encode(suspension)
+}
+
+func (h *HTTPServiceDefault) adminLiftSuspensionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	user := core.GetService[core.UserService](h.ctx, core.USER_SERVICE)
+
+	userId, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	adminId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	if err := user.LiftAccountSuspension(userId, adminId); err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPServiceDefault) adminImpersonateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	admin := core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE)
+
+	userId, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	adminId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	var req adminImpersonateRequest
+	if err := ctx.Decode(&req); err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	if req.Reason == "" {
+		_ = ctx.Error(errors.New("a reason is required to impersonate a user"), http.StatusBadRequest)
+		return
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	token, session, err := admin.Impersonate(adminId, userId, req.Reason, ip, time.Duration(req.Duration)*time.Minute)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	ctx.Encode(&adminImpersonateResponse{
+		Token:     token,
+		SessionID: session.ID,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
+
+func (h *HTTPServiceDefault) adminListImpersonationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	admin := core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE)
+
+	userId, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := admin.ListImpersonations(userId)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	ctx.Encode(sessions)
+}
+
+func (h *HTTPServiceDefault) adminEndImpersonationHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminUserAction(w, r, core.GetService[core.AdminService](h.ctx, core.ADMIN_SERVICE).EndImpersonation)
+}
+
+// adminUserAction runs an admin action that only takes the ID from the route
+func (h *HTTPServiceDefault) adminUserAction(w http.ResponseWriter, r *http.Request, action func(id uint) error) {
+	ctx := httputil.Context(r, w)
+
+	id, ok := h.adminUserID(ctx, r)
+	if !ok {
+		return
+	}
+
+	if err := action(id); err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPServiceDefault) adminUserID(ctx httputil.RequestContext, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		_ = ctx.Error(errAdminInvalidUserID, http.StatusBadRequest)
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+func (h *HTTPServiceDefault) adminError(ctx httputil.RequestContext, err error) {
+	if acctErr := core.AsAccountError(err); acctErr != nil {
+		_ = ctx.Error(acctErr, acctErr.HttpStatus())
+		return
+	}
+
+	_ = ctx.Error(err, http.StatusInternalServerError)
+}
+
+func (h *HTTPServiceDefault) adminOutboxReplayHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	outbox := core.GetService[core.OutboxService](h.ctx, core.OUTBOX_SERVICE)
+
+	var req adminOutboxReplayRequest
+	if err := ctx.Decode(&req); err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	replayed, err := outbox.Replay(req.From, req.To)
+	if err != nil {
+		h.adminError(ctx, err)
+		return
+	}
+
+	ctx.Encode(&adminOutboxReplayResponse{Replayed: replayed})
+}
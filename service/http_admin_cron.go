@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var errAdminInvalidJobID = errors.New("invalid job id")
+
+type adminCronJob struct {
+	ID            string              `json:"id"`
+	Function      string              `json:"function"`
+	Args          string              `json:"args"`
+	State         models.CronJobState `json:"state"`
+	Failures      uint64              `json:"failures"`
+	LastRun       *time.Time          `json:"last_run"`
+	LastHeartbeat *time.Time          `json:"last_heartbeat"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+type adminListCronJobsResponse struct {
+	Jobs  []*adminCronJob `json:"jobs"`
+	Total int64           `json:"total"`
+}
+
+type adminCronJobLog struct {
+	ID        uint                  `json:"id"`
+	Type      models.CronJobLogType `json:"type"`
+	Message   string                `json:"message"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+type adminListCronJobLogsResponse struct {
+	Logs  []*adminCronJobLog `json:"logs"`
+	Total int64              `json:"total"`
+}
+
+func (h *HTTPServiceDefault) registerAdminCronRoutes(router *mux.Router) {
+	router.HandleFunc("/cron/jobs", h.adminListCronJobsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/cron/jobs/{id}", h.adminGetCronJobHandler).Methods(http.MethodGet)
+	router.HandleFunc("/cron/jobs/{id}", h.adminCancelCronJobHandler).Methods(http.MethodDelete)
+	router.HandleFunc("/cron/jobs/{id}/logs", h.adminCronJobLogsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/cron/jobs/{id}/retry", h.adminRetryCronJobHandler).Methods(http.MethodPost)
+	router.HandleFunc("/cron/tasks", h.adminListCronTasksHandler).Methods(http.MethodGet)
+	router.HandleFunc("/cron/tasks/{name}/pause", h.adminPauseCronTaskHandler).Methods(http.MethodPost)
+	router.HandleFunc("/cron/tasks/{name}/pause", h.adminResumeCronTaskHandler).Methods(http.MethodDelete)
+}
+
+func (h *HTTPServiceDefault) adminListCronJobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	cron := core.GetService[core.CronService](h.ctx, core.CRON_SERVICE)
+
+	query := r.URL.Query()
+	filter := core.CronJobFilter{
+		Function: query.Get("function"),
+		State:    models.CronJobState(query.Get("state")),
+	}
+
+	filter.MinFailures, _ = strconv.ParseUint(query.Get("min_failures"), 10, 64)
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+
+	jobs, total, err := cron.ListJobs(filter)
+	if err != nil {
+		h.cronError(ctx, err)
+		return
+	}
+
+	resp := adminListCronJobsResponse{
+		Jobs:  make([]*adminCronJob, 0, len(jobs)),
+		Total: total,
+	}
+
+	for _, job := range jobs {
+		resp.Jobs = append(resp.Jobs, newAdminCronJob(job))
+	}
+
+	ctx.Encode(resp)
+}
+
+func (h *HTTPServiceDefault) adminGetCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	cron := core.GetService[core.CronService](h.ctx, core.CRON_SERVICE)
+
+	id, ok := h.adminJobID(ctx, r)
+	if !ok {
+		return
+	}
+
+	job, err := cron.GetJob(id)
+	if err != nil {
+		h.cronError(ctx, err)
+		return
+	}
+
+	ctx.Encode(newAdminCronJob(job))
+}
+
+func (h *HTTPServiceDefault) adminCronJobLogsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	cron := core.GetService[core.CronService](h.ctx, core.CRON_SERVICE)
+
+	id, ok := h.adminJobID(ctx, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	logs, total, err := cron.ListJobLogs(id, limit, offset)
+	if err != nil {
+		h.cronError(ctx, err)
+		return
+	}
+
+	resp := adminListCronJobLogsResponse{
+		Logs:  make([]*adminCronJobLog, 0, len(logs)),
+		Total: total,
+	}
+
+	for _, log := range logs {
+		resp.Logs = append(resp.Logs, &adminCronJobLog{
+			ID:        log.ID,
+			Type:      log.Type,
+			Message:   log.Message,
+			CreatedAt: log.CreatedAt,
+		})
+	}
+
+	ctx.Encode(resp)
+}
+
+func (h *HTTPServiceDefault) adminRetryCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminCronJobAction(w, r, core.GetService[core.CronService](h.ctx, core.CRON_SERVICE).RetryJob)
+}
+
+func (h *HTTPServiceDefault) adminCancelCronJobHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminCronJobAction(w, r, core.GetService[core.CronService](h.ctx, core.CRON_SERVICE).CancelJob)
+}
+
+func (h *HTTPServiceDefault) adminListCronTasksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	cron := core.GetService[core.CronService](h.ctx, core.CRON_SERVICE)
+
+	tasks, err := cron.ListTasks()
+	if err != nil {
+		h.cronError(ctx, err)
+		return
+	}
+
+	ctx.Encode(tasks)
+}
+
+func (h *HTTPServiceDefault) adminPauseCronTaskHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminCronTaskAction(w, r, core.GetService[core.CronService](h.ctx, core.CRON_SERVICE).PauseTask)
+}
+
+func (h *HTTPServiceDefault) adminResumeCronTaskHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminCronTaskAction(w, r, core.GetService[core.CronService](h.ctx, core.CRON_SERVICE).ResumeTask)
+}
+
+// adminCronJobAction runs a cron action that only takes the job ID from the route
+func (h *HTTPServiceDefault) adminCronJobAction(w http.ResponseWriter, r *http.Request, action func(id uuid.UUID) error) {
+	ctx := httputil.Context(r, w)
+
+	id, ok := h.adminJobID(ctx, r)
+	if !ok {
+		return
+	}
+
+	if err := action(id); err != nil {
+		h.cronError(ctx, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPServiceDefault) adminCronTaskAction(w http.ResponseWriter, r *http.Request, action func(function string) error) {
+	ctx := httputil.Context(r, w)
+
+	if err := action(mux.Vars(r)["name"]); err != nil {
+		h.cronError(ctx, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPServiceDefault) adminJobID(ctx httputil.RequestContext, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		_ = ctx.Error(errAdminInvalidJobID, http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+func (h *HTTPServiceDefault) cronError(ctx httputil.RequestContext, err error) {
+	switch {
+	case errors.Is(err, core.ErrCronJobNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+	case errors.Is(err, core.ErrCronJobProcessing):
+		_ = ctx.Error(err, http.StatusConflict)
+	case errors.Is(err, core.ErrCronTaskInvalid):
+		_ = ctx.Error(err, http.StatusBadRequest)
+	default:
+		_ = ctx.Error(err, http.StatusInternalServerError)
+	}
+}
+
+func newAdminCronJob(job *models.CronJob) *adminCronJob {
+	return &adminCronJob{
+		ID:            job.UUID.String(),
+		Function:      job.Function,
+		Args:          job.Args,
+		State:         job.State,
+		Failures:      job.Failures,
+		LastRun:       job.LastRun,
+		LastHeartbeat: job.LastHeartbeat,
+		CreatedAt:     job.CreatedAt,
+		UpdatedAt:     job.UpdatedAt,
+	}
+}
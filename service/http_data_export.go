@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+)
+
+func (h *HTTPServiceDefault) registerDataExportRoutes(router *mux.Router) {
+	router.HandleFunc("", h.dataExportListHandler).Methods(http.MethodGet)
+	router.HandleFunc("", h.dataExportRequestHandler).Methods(http.MethodPost)
+}
+
+func (h *HTTPServiceDefault) dataExportListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	export := core.GetService[core.DataExportService](h.ctx, core.DATA_EXPORT_SERVICE)
+
+	userId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	exports, err := export.ListExports(userId)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(exports)
+}
+
+func (h *HTTPServiceDefault) dataExportRequestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	export := core.GetService[core.DataExportService](h.ctx, core.DATA_EXPORT_SERVICE)
+
+	userId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	request, err := export.RequestExport(userId)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(request)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal/core"
+	"net/http"
+	"strings"
+)
+
+const healthPathPrefix = "/health/"
+
+func (h *HTTPServiceDefault) registerHealthRoutes(router *mux.Router) {
+	router.HandleFunc("/live", h.healthLiveHandler).Methods(http.MethodGet)
+	router.HandleFunc("/ready", h.healthReadyHandler).Methods(http.MethodGet)
+}
+
+func (h *HTTPServiceDefault) healthLiveHandler(w http.ResponseWriter, r *http.Request) {
+	health := core.GetService[core.HealthService](h.ctx, core.HEALTH_SERVICE)
+	h.healthRespond(w, r, health.Live())
+}
+
+func (h *HTTPServiceDefault) healthReadyHandler(w http.ResponseWriter, r *http.Request) {
+	health := core.GetService[core.HealthService](h.ctx, core.HEALTH_SERVICE)
+	h.healthRespond(w, r, health.Ready(r.Context()))
+}
+
+func (h *HTTPServiceDefault) healthRespond(w http.ResponseWriter, r *http.Request, report core.HealthReport) {
+	ctx := httputil.Context(r, w)
+
+	// Probes only look at the status code, the body is for whoever is debugging a failing node
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == core.HealthStatusFail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	ctx.Encode(report)
+}
+
+func isHealthPath(path string) bool {
+	return strings.HasPrefix(path, healthPathPrefix)
+}
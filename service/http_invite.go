@@ -0,0 +1,83 @@
+package service
+
+import (
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+	"time"
+)
+
+type inviteCreateRequest struct {
+	MaxUses   uint       `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (h *HTTPServiceDefault) registerInviteRoutes(router *mux.Router) {
+	router.HandleFunc("", h.inviteListHandler).Methods(http.MethodGet)
+	router.HandleFunc("", h.inviteCreateHandler).Methods(http.MethodPost)
+	router.HandleFunc("/{code}", h.inviteRevokeHandler).Methods(http.MethodDelete)
+}
+
+func (h *HTTPServiceDefault) inviteListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	invite := core.GetService[core.InviteService](h.ctx, core.INVITE_SERVICE)
+
+	userId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	invites, err := invite.ListInvites(userId)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(invites)
+}
+
+func (h *HTTPServiceDefault) inviteCreateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	invite := core.GetService[core.InviteService](h.ctx, core.INVITE_SERVICE)
+
+	userId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	var req inviteCreateRequest
+	if err := ctx.Decode(&req); err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	code, err := invite.CreateInvite(userId, req.MaxUses, req.ExpiresAt)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(code)
+}
+
+func (h *HTTPServiceDefault) inviteRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	invite := core.GetService[core.InviteService](h.ctx, core.INVITE_SERVICE)
+
+	userId, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusUnauthorized)
+		return
+	}
+
+	if err := invite.RevokeInvite(userId, mux.Vars(r)["code"]); err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
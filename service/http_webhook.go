@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+	"strconv"
+)
+
+var errWebhookInvalidID = errors.New("invalid webhook id")
+
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+type webhookCreateResponse struct {
+	*models.WebhookEndpoint
+	// Secret is only returned once, on creation
+	Secret string `json:"secret"`
+}
+
+// webhookScopedHandler receives the owner of the endpoints it may touch, nil for admin-level endpoints
+type webhookScopedHandler func(ctx httputil.RequestContext, r *http.Request, owner *uint)
+
+func (h *HTTPServiceDefault) registerWebhookRoutes(router *mux.Router, adminLevel bool) {
+	router.HandleFunc("/events", h.webhookEventsHandler).Methods(http.MethodGet)
+	router.HandleFunc("", h.webhookScoped(adminLevel, h.webhookListHandler)).Methods(http.MethodGet)
+	router.HandleFunc("", h.webhookScoped(adminLevel, h.webhookCreateHandler)).Methods(http.MethodPost)
+	router.HandleFunc("/{webhook:[0-9]+}", h.webhookScoped(adminLevel, h.webhookGetHandler)).Methods(http.MethodGet)
+	router.HandleFunc("/{webhook:[0-9]+}", h.webhookScoped(adminLevel, h.webhookUpdateHandler)).Methods(http.MethodPut)
+	router.HandleFunc("/{webhook:[0-9]+}", h.webhookScoped(adminLevel, h.webhookDeleteHandler)).Methods(http.MethodDelete)
+	router.HandleFunc("/{webhook:[0-9]+}/deliveries", h.webhookScoped(adminLevel, h.webhookDeliveriesHandler)).Methods(http.MethodGet)
+	router.HandleFunc("/{webhook:[0-9]+}/ping", h.webhookScoped(adminLevel, h.webhookPingHandler)).Methods(http.MethodPost)
+}
+
+func (h *HTTPServiceDefault) webhookScoped(adminLevel bool, handler webhookScopedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := httputil.Context(r, w)
+
+		if adminLevel {
+			handler(ctx, r, nil)
+			return
+		}
+
+		userId, err := middleware.GetUserFromContext(r.Context())
+		if err != nil {
+			_ = ctx.Error(err, http.StatusUnauthorized)
+			return
+		}
+
+		handler(ctx, r, &userId)
+	}
+}
+
+func (h *HTTPServiceDefault) webhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	ctx.Encode(core.GetWebhookEvents())
+}
+
+func (h *HTTPServiceDefault) webhookListHandler(ctx httputil.RequestContext, _ *http.Request, owner *uint) {
+	webhook := core.GetService[core.WebhookService](h.ctx, core.WEBHOOK_SERVICE)
+
+	endpoints, err := webhook.ListEndpoints(owner)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(endpoints)
+}
+
+func (h *HTTPServiceDefault) webhookCreateHandler(ctx httputil.RequestContext, _ *http.Request, owner *uint) {
+	webhook := core.GetService[core.WebhookService](h.ctx, core.WEBHOOK_SERVICE)
+
+	var req webhookRequest
+	if err := ctx.Decode(&req); err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	endpoint, err := webhook.CreateEndpoint(owner, req.URL, req.Events, req.Description)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(&webhookCreateResponse{WebhookEndpoint: endpoint, Secret: endpoint.Secret})
+}
+
+func (h *HTTPServiceDefault) webhookGetHandler(ctx httputil.RequestContext, r *http.Request, owner *uint) {
+	endpoint, ok := h.webhookEndpoint(ctx, r, owner)
+	if !ok {
+		return
+	}
+
+	ctx.Encode(endpoint)
+}
+
+func (h *HTTPServiceDefault) webhookUpdateHandler(ctx httputil.RequestContext, r *http.Request, owner *uint) {
+	webhook := core.GetService[core.WebhookService](h.ctx, core.WEBHOOK_SERVICE)
+
+	endpoint, ok := h.webhookEndpoint(ctx, r, owner)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := ctx.Decode(&req); err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	enabled := endpoint.Enabled
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	endpoint, err := webhook.UpdateEndpoint(endpoint.ID, req.URL, req.Events, req.Description, enabled)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(endpoint)
+}
+
+func (h *HTTPServiceDefault) webhookDeleteHandler(ctx httputil.RequestContext, r *http.Request, owner *uint) {
+	webhook := core.GetService[core.WebhookService](h.ctx, core.WEBHOOK_SERVICE)
+
+	endpoint, ok := h.webhookEndpoint(ctx, r, owner)
+	if !ok {
+		return
+	}
+
+	if err := webhook.DeleteEndpoint(endpoint.ID); err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(endpoint)
+}
+
+func (h *HTTPServiceDefault) webhookDeliveriesHandler(ctx httputil.RequestContext, r *http.Request, owner *uint) {
+	webhook := core.GetService[core.WebhookService](h.ctx, core.WEBHOOK_SERVICE)
+
+	endpoint, ok := h.webhookEndpoint(ctx, r, owner)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	deliveries, err := webhook.ListDeliveries(endpoint.ID, limit, offset)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(deliveries)
+}
+
+func (h *HTTPServiceDefault) webhookPingHandler(ctx httputil.RequestContext, r *http.Request, owner *uint) {
+	webhook := core.GetService[core.WebhookService](h.ctx, core.WEBHOOK_SERVICE)
+
+	endpoint, ok := h.webhookEndpoint(ctx, r, owner)
+	if !ok {
+		return
+	}
+
+	delivery, err := webhook.Ping(endpoint.ID)
+	if err != nil {
+		h.accountError(ctx, err)
+		return
+	}
+
+	ctx.Encode(delivery)
+}
+
+// webhookEndpoint loads the endpoint named in the route, hiding endpoints of other owners as not found
+func (h *HTTPServiceDefault) webhookEndpoint(ctx httputil.RequestContext, r *http.Request, owner *uint) (*models.WebhookEndpoint, bool) {
+	webhook := core.GetService[core.WebhookService](h.ctx, core.WEBHOOK_SERVICE)
+
+	id, err := strconv.ParseUint(mux.Vars(r)["webhook"], 10, 64)
+	if err != nil {
+		_ = ctx.Error(errWebhookInvalidID, http.StatusBadRequest)
+		return nil, false
+	}
+
+	endpoint, err := webhook.GetEndpoint(uint(id))
+	if err != nil {
+		h.accountError(ctx, err)
+		return nil, false
+	}
+
+	sameOwner := (owner == nil && endpoint.UserID == nil) || (owner != nil && endpoint.UserID != nil && *owner == *endpoint.UserID)
+	if !sameOwner {
+		h.accountError(ctx, core.NewAccountError(core.ErrKeyWebhookNotFound, nil))
+		return nil, false
+	}
+
+	return endpoint, true
+}
@@ -0,0 +1,71 @@
+package access
+
+import (
+	"go.lumeweb.com/portal/core"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	UserAttributeProviderID    = "user"
+	RequestAttributeProviderID = "request"
+)
+
+var _ core.AccessAttributeProvider = (*UserAttributeProvider)(nil)
+var _ core.AccessAttributeProvider = (*RequestAttributeProvider)(nil)
+
+// UserAttributeProvider exposes the account state of the requesting user
+type UserAttributeProvider struct{}
+
+func (u UserAttributeProvider) ID() string {
+	return UserAttributeProviderID
+}
+
+func (u UserAttributeProvider) Attributes(ctx core.Context, userId uint, _ *http.Request) (map[string]any, error) {
+	userService := core.GetService[core.UserService](ctx, core.USER_SERVICE)
+
+	attrs := map[string]any{
+		"id":          userId,
+		"verified":    false,
+		"otp_enabled": false,
+		"role":        "",
+	}
+
+	exists, user, err := userService.AccountExists(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		attrs["verified"] = user.Verified
+		attrs["otp_enabled"] = user.OTPEnabled
+		attrs["role"] = user.Role
+	}
+
+	return attrs, nil
+}
+
+// RequestAttributeProvider exposes the source IP and the server time (UTC) of the request
+type RequestAttributeProvider struct{}
+
+func (r RequestAttributeProvider) ID() string {
+	return RequestAttributeProviderID
+}
+
+func (r RequestAttributeProvider) Attributes(_ core.Context, _ uint, req *http.Request) (map[string]any, error) {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+
+	now := time.Now().UTC()
+
+	return map[string]any{
+		"ip":      ip,
+		"method":  req.Method,
+		"hour":    now.Hour(),
+		"minute":  now.Minute(),
+		"weekday": int(now.Weekday()),
+	}, nil
+}
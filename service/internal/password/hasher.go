@@ -0,0 +1,232 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+var (
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+	ErrInvalidHash       = errors.New("invalid password hash")
+)
+
+var _ Algorithm = (*argon2idAlgorithm)(nil)
+var _ Algorithm = (*bcryptAlgorithm)(nil)
+
+// Algorithm hashes passwords into self-describing encoded strings
+type Algorithm interface {
+	ID() string
+	// Identifies reports whether the encoded hash was produced by this algorithm
+	Identifies(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(password string, encoded string) (bool, error)
+	// Outdated reports whether the encoded hash uses other parameters than the configured ones
+	Outdated(encoded string) bool
+}
+
+// Hasher hashes new passwords with the configured algorithm and verifies hashes of any supported algorithm
+type Hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+func NewHasher(cfg config.PasswordHashConfig) *Hasher {
+	argon := &argon2idAlgorithm{config: cfg.Argon2}
+	bcr := &bcryptAlgorithm{cost: int(cfg.BcryptCost)}
+
+	hasher := &Hasher{
+		algorithms: []Algorithm{argon, bcr},
+	}
+
+	switch cfg.Algorithm {
+	case config.PasswordHashBcrypt:
+		hasher.current = bcr
+	default:
+		hasher.current = argon
+	}
+
+	return hasher
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *Hasher) Verify(password string, encoded string) (bool, error) {
+	algorithm := h.algorithm(encoded)
+	if algorithm == nil {
+		return false, ErrUnknownHashFormat
+	}
+
+	return algorithm.Verify(password, encoded)
+}
+
+// NeedsRehash reports whether the encoded hash should be replaced by a hash of the configured algorithm and parameters
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	algorithm := h.algorithm(encoded)
+	if algorithm == nil {
+		return true
+	}
+
+	return algorithm.ID() != h.current.ID() || algorithm.Outdated(encoded)
+}
+
+func (h *Hasher) algorithm(encoded string) Algorithm {
+	for _, algorithm := range h.algorithms {
+		if algorithm.Identifies(encoded) {
+			return algorithm
+		}
+	}
+
+	return nil
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// argon2idAlgorithm encodes hashes in the PHC string format: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type argon2idAlgorithm struct {
+	config config.Argon2Config
+}
+
+func (a *argon2idAlgorithm) ID() string {
+	return config.PasswordHashArgon2id
+}
+
+func (a *argon2idAlgorithm) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (a *argon2idAlgorithm) Hash(password string) (string, error) {
+	salt := make([]byte, a.config.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, uint32(a.config.Iterations), uint32(a.config.Memory), uint8(a.config.Parallelism), uint32(a.config.KeyLength))
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		a.config.Memory,
+		a.config.Iterations,
+		a.config.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *argon2idAlgorithm) Verify(password string, encoded string) (bool, error) {
+	params, err := a.decode(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (a *argon2idAlgorithm) Outdated(encoded string) bool {
+	params, err := a.decode(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.memory != uint32(a.config.Memory) ||
+		params.iterations != uint32(a.config.Iterations) ||
+		params.parallelism != uint8(a.config.Parallelism) ||
+		len(params.salt) != int(a.config.SaltLength) ||
+		len(params.key) != int(a.config.KeyLength)
+}
+
+func (a *argon2idAlgorithm) decode(encoded string) (*argon2idParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	if version != argon2.Version {
+		return nil, ErrInvalidHash
+	}
+
+	var params argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	// argon2 panics on zero iterations or threads, and no hash of this portal uses zero memory
+	if params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	// An empty key would match any password
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	return &params, nil
+}
+
+type bcryptAlgorithm struct {
+	cost int
+}
+
+func (b *bcryptAlgorithm) ID() string {
+	return config.PasswordHashBcrypt
+}
+
+func (b *bcryptAlgorithm) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b *bcryptAlgorithm) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (b *bcryptAlgorithm) Verify(password string, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (b *bcryptAlgorithm) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+
+	return cost != b.cost
+}
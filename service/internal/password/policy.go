@@ -0,0 +1,181 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	breachedPrefixLength = 5
+	// personalInfoMinLength ignores name parts short enough to appear in passwords by coincidence
+	personalInfoMinLength = 3
+)
+
+// Policy checks passwords against the configured password policy
+type Policy struct {
+	config config.PasswordPolicyConfig
+}
+
+func NewPolicy(cfg config.PasswordPolicyConfig) *Policy {
+	return &Policy{config: cfg}
+}
+
+// Check returns every rule the password fails. The identity values, such as the email address and names
+// of the account, must not appear in the password when personal info is disallowed.
+func (p *Policy) Check(password string, identity ...string) ([]*core.AccountError, error) {
+	var violations []*core.AccountError
+
+	if uint(utf8.RuneCountInString(password)) < p.config.MinLength {
+		violations = append(violations, core.NewAccountError(core.ErrKeyPasswordTooShort, nil))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.config.RequireUpper && !hasUpper {
+		violations = append(violations, core.NewAccountError(core.ErrKeyPasswordMissingUpper, nil))
+	}
+
+	if p.config.RequireLower && !hasLower {
+		violations = append(violations, core.NewAccountError(core.ErrKeyPasswordMissingLower, nil))
+	}
+
+	if p.config.RequireDigit && !hasDigit {
+		violations = append(violations, core.NewAccountError(core.ErrKeyPasswordMissingDigit, nil))
+	}
+
+	if p.config.RequireSymbol && !hasSymbol {
+		violations = append(violations, core.NewAccountError(core.ErrKeyPasswordMissingSymbol, nil))
+	}
+
+	if p.config.MaxRepeats > 0 && longestRun(password) > p.config.MaxRepeats {
+		violations = append(violations, core.NewAccountError(core.ErrKeyPasswordTooManyRepeats, nil))
+	}
+
+	if p.config.DisallowPersonalInfo && containsPersonalInfo(password, identity) {
+		violations = append(violations, core.NewAccountError(core.ErrKeyPasswordContainsPersonalInfo, nil))
+	}
+
+	if p.config.BreachedList != "" {
+		breached, err := p.breached(password)
+		if err != nil {
+			return nil, err
+		}
+
+		if breached {
+			violations = append(violations, core.NewAccountError(core.ErrKeyPasswordBreached, nil))
+		}
+	}
+
+	return violations, nil
+}
+
+// breached looks the SHA-1 hash of the password up in the range file of its prefix, so the full list never has to be loaded
+func (p *Policy) breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	file, err := openRangeFile(p.config.BreachedList, prefix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry, countStr, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(entry, suffix) {
+			continue
+		}
+
+		count, err := strconv.ParseUint(countStr, 10, 64)
+		if err != nil {
+			// Lists without counts only hold breached hashes
+			count = 1
+		}
+
+		return count >= uint64(p.config.BreachedMinCount), nil
+	}
+
+	return false, scanner.Err()
+}
+
+func openRangeFile(dir string, prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(dir, prefix))
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return file, err
+	}
+
+	return os.Open(filepath.Join(dir, prefix+".txt"))
+}
+
+func longestRun(password string) uint {
+	var longest, run uint
+	var last rune
+
+	for i, r := range password {
+		if i > 0 && r == last {
+			run++
+		} else {
+			run = 1
+		}
+
+		if run > longest {
+			longest = run
+		}
+
+		last = r
+	}
+
+	return longest
+}
+
+func containsPersonalInfo(password string, identity []string) bool {
+	lower := strings.ToLower(password)
+
+	for _, value := range identity {
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		// Only the local part of an email address is likely to be reused in a password
+		if local, _, found := strings.Cut(value, "@"); found {
+			value = local
+		}
+
+		if utf8.RuneCountInString(value) < personalInfoMinLength {
+			continue
+		}
+
+		if strings.Contains(lower, value) {
+			return true
+		}
+	}
+
+	return false
+}
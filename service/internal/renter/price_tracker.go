@@ -38,6 +38,11 @@ const usdSymbol = "usd"
 const blocksPerMonth = 30 * 144
 const decimalsInSiacoin = 28
 
+const (
+	rateMetricLatest  = "latest"
+	rateMetricAverage = "average"
+)
+
 type PriceTracker struct {
 	ctx    core.Context
 	config config.Manager
@@ -91,6 +96,8 @@ func (p PriceTracker) recordRate(_ core.CronTaskArgs, _ core.Context) error {
 		return err
 	}
 
+	p.ctx.Metrics().SiaRate.WithLabelValues(usdSymbol, rateMetricLatest).Set(siaPrice.InexactFloat64())
+
 	var history models.SCPriceHistory
 
 	history.Rate = siaPrice
@@ -114,13 +121,20 @@ func (p PriceTracker) updatePrices(_ any, _ core.Context) error {
 	days := p.config.Config().Core.Storage.Sia.PriceHistoryDays
 
 	var _sql string
-	if p.db.Dialector.Name() == "sqlite" {
+	switch p.db.Dialector.Name() {
+	case "sqlite":
 		_sql = `
         SELECT COALESCE(AVG(rate), '0') as average_rate
         FROM sc_price_history
         WHERE created_at >= DATE('now', '-' || ? || ' days')
         `
-	} else {
+	case "postgres":
+		_sql = `
+        SELECT COALESCE(AVG(rate), 0)::text as average_rate
+        FROM sc_price_history
+        WHERE created_at >= CURRENT_DATE - make_interval(days => ?::int)
+        `
+	default:
 		_sql = `
         SELECT COALESCE(AVG(rate), '0') as average_rate
         FROM sc_price_history
@@ -153,6 +167,8 @@ func (p PriceTracker) updatePrices(_ any, _ core.Context) error {
 		return errors.New("average rate is 0")
 	}
 
+	p.ctx.Metrics().SiaRate.WithLabelValues(usdSymbol, rateMetricAverage).Set(averageRate.InexactFloat64())
+
 	ctx := context.Background()
 
 	gouge, err := p.renter.GougingSettings(ctx)
@@ -197,7 +197,10 @@ func (t *TusHandler) HandleEventResponseError(message string, httpCode int, hook
 	hook.Upload.StopUpload(resp)
 }
 
-func (t *TusHandler) CompleteUpload(ctx context.Context, identifier any) error {
+func (t *TusHandler) CompleteUpload(ctx context.Context, identifier any) (err error) {
+	ctx, end := core.StartSpan(ctx, "tus.complete_upload")
+	defer end(&err)
+
 	var exists bool
 	var _upload *models.TUSRequest
 
@@ -214,7 +217,7 @@ func (t *TusHandler) CompleteUpload(ctx context.Context, identifier any) error {
 		return gorm.ErrRecordNotFound
 	}
 
-	err := t.tusService.UploadCompleted(ctx, _upload.TUSUploadID)
+	err = t.tusService.UploadCompleted(ctx, _upload.TUSUploadID)
 	if err != nil {
 		return err
 	}
@@ -12,6 +12,7 @@ func CronTaskProcessAccountDeletionRequests(_ *core.CronTaskNoArgs, ctx core.Con
 	userService := core.GetService[core.UserService](ctx, core.USER_SERVICE)
 	pinService := core.GetService[core.PinService](ctx, core.PIN_SERVICE)
 	requestService := core.GetService[core.RequestService](ctx, core.REQUEST_SERVICE)
+	exportService := core.GetService[core.DataExportService](ctx, core.DATA_EXPORT_SERVICE)
 
 	// Get all deletion requests
 	requests, err := userService.GetAccountsPendingDeletion()
@@ -21,6 +22,17 @@ func CronTaskProcessAccountDeletionRequests(_ *core.CronTaskNoArgs, ctx core.Con
 	}
 
 	for _, request := range requests {
+		// Give a pending data export the chance to finish before the data it covers is removed
+		exporting, err := exportService.ExportInProgress(request.ID)
+		if err != nil {
+			logger.Error("Failed to check data export status", zap.Uint("user_id", request.ID), zap.Error(err))
+			continue
+		}
+
+		if exporting {
+			continue
+		}
+
 		uploadRequests, err := requestService.ListRequestsByUser(ctx, request.ID, core.RequestFilter{})
 		if err != nil {
 			return err
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+var _ core.InviteService = (*InviteServiceDefault)(nil)
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.INVITE_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewInviteService()
+		},
+	})
+}
+
+type InviteServiceDefault struct {
+	ctx    core.Context
+	config config.Manager
+	db     *gorm.DB
+	access core.AccessService
+}
+
+func NewInviteService() (*InviteServiceDefault, []core.ContextBuilderOption, error) {
+	invite := &InviteServiceDefault{}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			invite.ctx = ctx
+			invite.config = ctx.Config()
+			invite.db = ctx.DB()
+			invite.access = core.GetService[core.AccessService](ctx, core.ACCESS_SERVICE)
+			return nil
+		}),
+	)
+
+	return invite, opts, nil
+}
+
+func (i InviteServiceDefault) ID() string {
+	return core.INVITE_SERVICE
+}
+
+func (i InviteServiceDefault) CreateInvite(creatorId uint, maxUses uint, expiresAt *time.Time) (*models.InviteCode, error) {
+	isAdmin, err := i.access.UserHasRole(creatorId, core.ACCESS_ADMIN_ROLE)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAdmin {
+		var issued int64
+		if err := db.RetryableTransaction(i.ctx, i.db, func(tx *gorm.DB) *gorm.DB {
+			// Revoked invites count too, revoking and reissuing must not get around the quota
+			return tx.Unscoped().Model(&models.InviteCode{}).Where(&models.InviteCode{CreatedByID: creatorId}).Count(&issued)
+		}); err != nil {
+			return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+		}
+
+		if uint(issued) >= i.config.Config().Core.Account.InviteQuota {
+			return nil, core.NewAccountError(core.ErrKeyInviteQuotaExceeded, nil)
+		}
+
+		// Invites issued from a user quota are single use
+		maxUses = 1
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := models.InviteCode{
+		Code:        code,
+		CreatedByID: creatorId,
+		MaxUses:     maxUses,
+		ExpiresAt:   expiresAt,
+	}
+
+	if err := db.RetryableTransaction(i.ctx, i.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&invite)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return &invite, nil
+}
+
+func (i InviteServiceDefault) ListInvites(creatorId uint) ([]*models.InviteCode, error) {
+	var invites []*models.InviteCode
+
+	if err := db.RetryableTransaction(i.ctx, i.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.InviteCode{}).
+			Where(&models.InviteCode{CreatedByID: creatorId}).
+			Order("created_at DESC").
+			Find(&invites)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return invites, nil
+}
+
+func (i InviteServiceDefault) RevokeInvite(creatorId uint, code string) error {
+	invite, err := i.getInvite(code)
+	if err != nil {
+		return err
+	}
+
+	if invite.CreatedByID != creatorId {
+		isAdmin, err := i.access.UserHasRole(creatorId, core.ACCESS_ADMIN_ROLE)
+		if err != nil {
+			return err
+		}
+
+		if !isAdmin {
+			return core.NewAccountError(core.ErrKeyInviteNotFound, nil)
+		}
+	}
+
+	if err := db.RetryableTransaction(i.ctx, i.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Delete(invite)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
+func (i InviteServiceDefault) ValidateInvite(code string) error {
+	invite, err := i.getInvite(code)
+	if err != nil {
+		if core.AsAccountError(err).IsErrorType(core.ErrKeyInviteNotFound) {
+			return core.NewAccountError(core.ErrKeyInviteInvalid, nil)
+		}
+		return err
+	}
+
+	if invite.ExpiresAt != nil && invite.ExpiresAt.Before(time.Now()) {
+		return core.NewAccountError(core.ErrKeyInviteInvalid, nil)
+	}
+
+	if invite.MaxUses > 0 && invite.Uses >= invite.MaxUses {
+		return core.NewAccountError(core.ErrKeyInviteInvalid, nil)
+	}
+
+	return nil
+}
+
+func (i InviteServiceDefault) RedeemInvite(code string) error {
+	if err := i.ValidateInvite(code); err != nil {
+		return err
+	}
+
+	var rowsAffected int64
+
+	// The use count is checked again in the update so concurrent registrations cannot overuse an invite
+	if err := db.RetryableTransaction(i.ctx, i.db, func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.InviteCode{}).
+			Where("code = ? AND (max_uses = 0 OR uses < max_uses)", code).
+			Update("uses", gorm.Expr("uses + 1"))
+		rowsAffected = tx.RowsAffected
+		return tx
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if rowsAffected == 0 {
+		return core.NewAccountError(core.ErrKeyInviteInvalid, nil)
+	}
+
+	return nil
+}
+
+func (i InviteServiceDefault) ReleaseInvite(code string) error {
+	if err := db.RetryableTransaction(i.ctx, i.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.InviteCode{}).
+			Where("code = ? AND uses > 0", code).
+			Update("uses", gorm.Expr("uses - 1"))
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
+func (i InviteServiceDefault) getInvite(code string) (*models.InviteCode, error) {
+	var invite models.InviteCode
+
+	if err := db.RetryableTransaction(i.ctx, i.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.InviteCode{}).Where(&models.InviteCode{Code: code}).First(&invite)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.NewAccountError(core.ErrKeyInviteNotFound, err)
+		}
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return &invite, nil
+}
+
+func generateInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return strings.ToUpper(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)), nil
+}
@@ -1,16 +1,20 @@
 package service
 
 import (
+	"context"
 	"embed"
 	"github.com/wneessen/go-mail"
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/service/internal/mailer"
 	"io/fs"
+	"net"
+	"strconv"
 	"strings"
 	"text/template"
 )
 
 var _ core.MailerService = (*Mailer)(nil)
+var _ core.HealthChecker = (*Mailer)(nil)
 
 func init() {
 	core.RegisterService(core.ServiceInfo{
@@ -32,6 +36,26 @@ func (m *Mailer) ID() string {
 	return core.MAILER_SERVICE
 }
 
+// HealthChecks only dials the SMTP server, sending mail is left to the mailer itself. An unreachable server fails
+// mail delivery alone, so the check does not hold back readiness.
+func (m *Mailer) HealthChecks() []core.HealthCheck {
+	return []core.HealthCheck{{
+		Name: "smtp",
+		Check: func(ctx context.Context) error {
+			cfg := m.ctx.Config().Config().Core.Mail
+
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+			if err != nil {
+				return err
+			}
+
+			return conn.Close()
+		},
+		Optional: true,
+	}}
+}
+
 func NewMailerTemplate(subject *template.Template, body *template.Template) *mailer.EmailTemplate {
 	return mailer.NewMailerTemplate(subject, body)
 }
@@ -0,0 +1,564 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
+	"gorm.io/gorm"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var _ core.OrganizationService = (*OrganizationServiceDefault)(nil)
+
+var orgSlugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+const orgInviteExpiry = 7 * 24 * time.Hour
+
+// orgRolePolicies maps each organization role to the objects and actions it is granted
+var orgRolePolicies = map[core.OrganizationRole]map[string][]string{
+	core.ORG_ROLE_OWNER: {
+		core.ORG_OBJECT_PINS:     {core.ORG_ACTION_READ, core.ORG_ACTION_WRITE},
+		core.ORG_OBJECT_MEMBERS:  {core.ORG_ACTION_READ, core.ORG_ACTION_WRITE},
+		core.ORG_OBJECT_SETTINGS: {core.ORG_ACTION_READ, core.ORG_ACTION_WRITE},
+		core.ORG_OBJECT_BILLING:  {core.ORG_ACTION_READ, core.ORG_ACTION_WRITE},
+	},
+	core.ORG_ROLE_ADMIN: {
+		core.ORG_OBJECT_PINS:     {core.ORG_ACTION_READ, core.ORG_ACTION_WRITE},
+		core.ORG_OBJECT_MEMBERS:  {core.ORG_ACTION_READ, core.ORG_ACTION_WRITE},
+		core.ORG_OBJECT_SETTINGS: {core.ORG_ACTION_READ},
+		core.ORG_OBJECT_BILLING:  {core.ORG_ACTION_READ},
+	},
+	core.ORG_ROLE_MEMBER: {
+		core.ORG_OBJECT_PINS:    {core.ORG_ACTION_READ, core.ORG_ACTION_WRITE},
+		core.ORG_OBJECT_MEMBERS: {core.ORG_ACTION_READ},
+	},
+	core.ORG_ROLE_READ_ONLY: {
+		core.ORG_OBJECT_PINS:    {core.ORG_ACTION_READ},
+		core.ORG_OBJECT_MEMBERS: {core.ORG_ACTION_READ},
+	},
+}
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.ORGANIZATION_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewOrganizationService()
+		},
+		Depends: []string{core.USER_SERVICE, core.MAILER_SERVICE, core.ACCESS_SERVICE},
+	})
+}
+
+type OrganizationServiceDefault struct {
+	ctx       core.Context
+	config    config.Manager
+	db        *gorm.DB
+	user      core.UserService
+	mailer    core.MailerService
+	access    core.AccessService
+	subdomain string
+}
+
+func NewOrganizationService() (*OrganizationServiceDefault, []core.ContextBuilderOption, error) {
+	org := &OrganizationServiceDefault{}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			org.ctx = ctx
+			org.config = ctx.Config()
+			org.db = ctx.DB()
+			org.user = core.GetService[core.UserService](ctx, core.USER_SERVICE)
+			org.mailer = core.GetService[core.MailerService](ctx, core.MAILER_SERVICE)
+			org.access = core.GetService[core.AccessService](ctx, core.ACCESS_SERVICE)
+
+			event.Listen[*event.UserServiceSubdomainSetEvent](ctx, event.EVENT_USER_SERVICE_SUBDOMAIN_SET, func(evt *event.UserServiceSubdomainSetEvent) error {
+				org.subdomain = evt.Subdomain()
+				return nil
+			})
+			return nil
+		}),
+	)
+
+	return org, opts, nil
+}
+
+func (o OrganizationServiceDefault) ID() string {
+	return core.ORGANIZATION_SERVICE
+}
+
+func (o OrganizationServiceDefault) CreateOrganization(ownerId uint, name string) (*models.Organization, error) {
+	exists, _, err := o.user.AccountExists(ownerId)
+	if err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if !exists {
+		return nil, core.NewAccountError(core.ErrKeyUserNotFound, nil)
+	}
+
+	slug := orgSlug(name)
+
+	var count int64
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.Organization{}).Where(&models.Organization{Slug: slug}).Count(&count)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if count > 0 {
+		return nil, core.NewAccountError(core.ErrKeyOrganizationExists, nil)
+	}
+
+	org := models.Organization{
+		Name:    name,
+		Slug:    slug,
+		OwnerID: ownerId,
+	}
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Create(&org).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         ownerId,
+			Role:           string(core.ORG_ROLE_OWNER),
+		})
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	for role, objects := range orgRolePolicies {
+		for obj, acts := range objects {
+			for _, act := range acts {
+				if err := o.access.RegisterOrgPolicy(org.ID, string(role), obj, act); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := o.access.AssignOrgRoleToUser(ownerId, org.ID, string(core.ORG_ROLE_OWNER)); err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+func (o OrganizationServiceDefault) GetOrganization(orgId uint) (*models.Organization, error) {
+	var org models.Organization
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.Organization{}).First(&org, orgId)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.NewAccountError(core.ErrKeyOrganizationNotFound, err)
+		}
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return &org, nil
+}
+
+func (o OrganizationServiceDefault) ListUserOrganizations(userId uint) ([]*models.Organization, error) {
+	var orgs []*models.Organization
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.Organization{}).
+			Joins("JOIN organization_members ON organization_members.organization_id = organizations.id AND organization_members.deleted_at IS NULL").
+			Where("organization_members.user_id = ?", userId).
+			Find(&orgs)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return orgs, nil
+}
+
+func (o OrganizationServiceDefault) UpdateOrganization(orgId uint, updates map[string]any) error {
+	if _, err := o.GetOrganization(orgId); err != nil {
+		return err
+	}
+
+	if name, ok := updates["name"].(string); ok {
+		slug := orgSlug(name)
+
+		var count int64
+		if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+			return tx.Model(&models.Organization{}).Where(&models.Organization{Slug: slug}).Where("id <> ?", orgId).Count(&count)
+		}); err != nil {
+			return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+		}
+
+		if count > 0 {
+			return core.NewAccountError(core.ErrKeyOrganizationExists, nil)
+		}
+
+		updates["slug"] = slug
+	}
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.Organization{}).Where("id = ?", orgId).Updates(updates)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
+func (o OrganizationServiceDefault) DeleteOrganization(orgId uint) error {
+	if _, err := o.GetOrganization(orgId); err != nil {
+		return err
+	}
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Model(&models.Pin{}).Where("organization_id = ?", orgId).Update("organization_id", nil).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := tx.Where(&models.OrganizationInvite{OrganizationID: orgId}).Delete(&models.OrganizationInvite{}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := tx.Unscoped().Where(&models.OrganizationMember{OrganizationID: orgId}).Delete(&models.OrganizationMember{}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		return tx.Delete(&models.Organization{}, orgId)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return o.access.DeleteOrgPolicies(orgId)
+}
+
+func (o OrganizationServiceDefault) AddMember(orgId uint, userId uint, role core.OrganizationRole) error {
+	if !role.Valid() {
+		return core.NewAccountError(core.ErrKeyOrganizationInvalidRole, nil)
+	}
+
+	if _, err := o.GetOrganization(orgId); err != nil {
+		return err
+	}
+
+	exists, _, err := o.user.AccountExists(userId)
+	if err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if !exists {
+		return core.NewAccountError(core.ErrKeyUserNotFound, nil)
+	}
+
+	if _, err := o.getMember(orgId, userId); err == nil {
+		return core.NewAccountError(core.ErrKeyOrganizationMemberExists, nil)
+	} else if !core.AsAccountError(err).IsErrorType(core.ErrKeyOrganizationMemberNotFound) {
+		return err
+	}
+
+	member := models.OrganizationMember{
+		OrganizationID: orgId,
+		UserID:         userId,
+		Role:           string(role),
+	}
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&member)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return o.access.AssignOrgRoleToUser(userId, orgId, string(role))
+}
+
+func (o OrganizationServiceDefault) UpdateMemberRole(orgId uint, userId uint, role core.OrganizationRole) error {
+	if !role.Valid() {
+		return core.NewAccountError(core.ErrKeyOrganizationInvalidRole, nil)
+	}
+
+	member, err := o.getMember(orgId, userId)
+	if err != nil {
+		return err
+	}
+
+	if core.OrganizationRole(member.Role) == core.ORG_ROLE_OWNER && role != core.ORG_ROLE_OWNER {
+		if err := o.ensureAnotherOwner(orgId, userId); err != nil {
+			return err
+		}
+	}
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(member).Update("role", string(role))
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if err := o.access.RemoveOrgRolesFromUser(userId, orgId); err != nil {
+		return err
+	}
+
+	return o.access.AssignOrgRoleToUser(userId, orgId, string(role))
+}
+
+func (o OrganizationServiceDefault) RemoveMember(orgId uint, userId uint) error {
+	member, err := o.getMember(orgId, userId)
+	if err != nil {
+		return err
+	}
+
+	if core.OrganizationRole(member.Role) == core.ORG_ROLE_OWNER {
+		if err := o.ensureAnotherOwner(orgId, userId); err != nil {
+			return err
+		}
+	}
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped().Delete(member)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return o.access.RemoveOrgRolesFromUser(userId, orgId)
+}
+
+func (o OrganizationServiceDefault) ListMembers(orgId uint) ([]*models.OrganizationMember, error) {
+	var members []*models.OrganizationMember
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.OrganizationMember{}).
+			Where(&models.OrganizationMember{OrganizationID: orgId}).
+			Preload("User").
+			Find(&members)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return members, nil
+}
+
+func (o OrganizationServiceDefault) GetMemberRole(orgId uint, userId uint) (core.OrganizationRole, error) {
+	member, err := o.getMember(orgId, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return core.OrganizationRole(member.Role), nil
+}
+
+func (o OrganizationServiceDefault) InviteMember(orgId uint, inviterId uint, email string, role core.OrganizationRole) (*models.OrganizationInvite, error) {
+	if !role.Valid() {
+		return nil, core.NewAccountError(core.ErrKeyOrganizationInvalidRole, nil)
+	}
+
+	if o.subdomain == "" {
+		return nil, core.NewAccountError(core.ErrKeyAccountSubdomainNotSet, nil)
+	}
+
+	org, err := o.GetOrganization(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := o.CheckAccess(inviterId, orgId, core.ORG_OBJECT_MEMBERS, core.ORG_ACTION_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, core.NewAccountError(core.ErrKeyOrganizationPermissionDenied, nil)
+	}
+
+	// Only owners may hand out ownership
+	if role == core.ORG_ROLE_OWNER {
+		inviterRole, err := o.GetMemberRole(orgId, inviterId)
+		if err != nil {
+			return nil, err
+		}
+
+		if inviterRole != core.ORG_ROLE_OWNER {
+			return nil, core.NewAccountError(core.ErrKeyOrganizationPermissionDenied, nil)
+		}
+	}
+
+	exists, inviter, err := o.user.AccountExists(inviterId)
+	if err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if !exists {
+		return nil, core.NewAccountError(core.ErrKeyUserNotFound, nil)
+	}
+
+	invite := models.OrganizationInvite{
+		OrganizationID: orgId,
+		InvitedByID:    inviterId,
+		Email:          email,
+		Role:           string(role),
+		Token:          core.GenerateSecurityToken(),
+		ExpiresAt:      time.Now().Add(orgInviteExpiry),
+	}
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&invite)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	queryVars := url.Values{}
+	queryVars.Set("token", invite.Token)
+	inviteUrl := fmt.Sprintf("%s/organization/invite?%s", fmt.Sprintf("https://%s.%s", o.subdomain, o.config.Config().Core.Domain), queryVars.Encode())
+
+	vars := map[string]interface{}{
+		"Email":            email,
+		"OrganizationName": org.Name,
+		"InviterName":      strings.TrimSpace(fmt.Sprintf("%s %s", inviter.FirstName, inviter.LastName)),
+		"Role":             string(role),
+		"InviteLink":       inviteUrl,
+		"ExpireTime":       invite.ExpiresAt,
+		"PortalName":       o.config.Config().Core.PortalName,
+	}
+
+	if err := o.mailer.TemplateSend(core.MAILER_TPL_ORG_INVITE, vars, vars, email); err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+func (o OrganizationServiceDefault) AcceptInvite(token string, userId uint) (*models.OrganizationMember, error) {
+	var invite models.OrganizationInvite
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.OrganizationInvite{}).
+			Where(&models.OrganizationInvite{Token: token}).
+			First(&invite)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.NewAccountError(core.ErrKeyOrganizationInviteInvalid, err)
+		}
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if invite.AcceptedAt != nil {
+		return nil, core.NewAccountError(core.ErrKeyOrganizationInviteInvalid, nil)
+	}
+
+	if invite.ExpiresAt.Before(time.Now()) {
+		return nil, core.NewAccountError(core.ErrKeySecurityTokenExpired, nil)
+	}
+
+	exists, user, err := o.user.AccountExists(userId)
+	if err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if !exists {
+		return nil, core.NewAccountError(core.ErrKeyUserNotFound, nil)
+	}
+
+	if !strings.EqualFold(user.Email, invite.Email) {
+		return nil, core.NewAccountError(core.ErrKeyOrganizationInviteInvalid, nil)
+	}
+
+	if err := o.AddMember(invite.OrganizationID, userId, core.OrganizationRole(invite.Role)); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&invite).Update("accepted_at", &now)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return o.getMember(invite.OrganizationID, userId)
+}
+
+func (o OrganizationServiceDefault) CheckAccess(userId uint, orgId uint, obj, act string) (bool, error) {
+	return o.access.CheckOrgAccess(userId, orgId, obj, act)
+}
+
+func (o OrganizationServiceDefault) OrganizationUsage(orgId uint) (uint64, error) {
+	var usage uint64
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.Pin{}).
+			Select("COALESCE(SUM(uploads.size), 0)").
+			Joins("JOIN uploads ON uploads.id = pins.upload_id").
+			Where("pins.organization_id = ?", orgId).
+			Scan(&usage)
+	}); err != nil {
+		return 0, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return usage, nil
+}
+
+func (o OrganizationServiceDefault) QuotaExceeded(orgId uint, additional uint64) (bool, error) {
+	org, err := o.GetOrganization(orgId)
+	if err != nil {
+		return false, err
+	}
+
+	if org.StorageQuota == 0 {
+		return false, nil
+	}
+
+	usage, err := o.OrganizationUsage(orgId)
+	if err != nil {
+		return false, err
+	}
+
+	return usage+additional > org.StorageQuota, nil
+}
+
+func (o OrganizationServiceDefault) getMember(orgId uint, userId uint) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.OrganizationMember{}).
+			Where(&models.OrganizationMember{OrganizationID: orgId, UserID: userId}).
+			First(&member)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.NewAccountError(core.ErrKeyOrganizationMemberNotFound, err)
+		}
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return &member, nil
+}
+
+func (o OrganizationServiceDefault) ensureAnotherOwner(orgId uint, userId uint) error {
+	var count int64
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.OrganizationMember{}).
+			Where(&models.OrganizationMember{OrganizationID: orgId, Role: string(core.ORG_ROLE_OWNER)}).
+			Where("user_id != ?", userId).
+			Count(&count)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if count == 0 {
+		return core.NewAccountError(core.ErrKeyOrganizationLastOwner, nil)
+	}
+
+	return nil
+}
+
+func orgSlug(name string) string {
+	return strings.Trim(orgSlugInvalidChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
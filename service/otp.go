@@ -3,6 +3,9 @@ package service
 import (
 	"go.lumeweb.com/portal/config"
 	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
 	"gorm.io/gorm"
 )
 
@@ -91,9 +94,36 @@ func (o OTPServiceDefault) OTPEnable(userId uint, code string) error {
 		return core.ErrInvalidOTPCode
 	}
 
-	return o.user.UpdateAccountInfo(userId, map[string]interface{}{"otp_enabled": true})
+	return o.updateOTP(userId, map[string]any{"otp_enabled": true}, func(tx *gorm.DB) error {
+		return event.FireUserOTPEnabledEventTx(o.ctx, tx, userId)
+	})
 }
 
 func (o OTPServiceDefault) OTPDisable(userId uint) error {
-	return o.user.UpdateAccountInfo(userId, map[string]interface{}{"otp_enabled": false, "otp_secret": ""})
+	return o.updateOTP(userId, map[string]any{"otp_enabled": false, "otp_secret": ""}, func(tx *gorm.DB) error {
+		return event.FireUserOTPDisabledEventTx(o.ctx, tx, userId)
+	})
+}
+
+// updateOTP updates the OTP settings of the account and queues the event describing the change in the same transaction
+func (o OTPServiceDefault) updateOTP(userId uint, info map[string]any, fire func(tx *gorm.DB) error) error {
+	var user models.User
+	user.ID = userId
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Model(&user).Where(&user).Updates(info).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := fire(tx); err != nil {
+			_ = tx.AddError(err)
+		}
+
+		return tx
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
 }
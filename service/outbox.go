@@ -0,0 +1,296 @@
+package service
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+var _ core.OutboxService = (*OutboxServiceDefault)(nil)
+var _ core.Cronable = (*OutboxServiceDefault)(nil)
+
+const (
+	cronTaskPruneOutboxEventsName = "PruneOutboxEvents"
+
+	// outboxClaimLease is how long a node may hold an event before another node takes it over. Failed events keep
+	// their claim, so it is also the delay before they are retried.
+	outboxClaimLease = 5 * time.Minute
+)
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.OUTBOX_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewOutboxService()
+		},
+		Depends: []string{core.CRON_SERVICE},
+	})
+}
+
+type OutboxServiceDefault struct {
+	ctx    core.Context
+	logger *core.Logger
+	config config.Manager
+	db     *gorm.DB
+	cron   core.CronService
+	nodeId string
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewOutboxService() (*OutboxServiceDefault, []core.ContextBuilderOption, error) {
+	outbox := &OutboxServiceDefault{
+		stop: make(chan struct{}),
+	}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			outbox.ctx = ctx
+			outbox.logger = ctx.ServiceLogger(outbox)
+			outbox.config = ctx.Config()
+			outbox.db = ctx.DB()
+			outbox.cron = core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+			outbox.nodeId = ctx.Config().Config().Core.NodeID.String()
+
+			outbox.cron.RegisterEntity(outbox)
+
+			// Commands only boot services, events they queue are dispatched by the next portal that starts
+			event.Listen[*event.BootCompleteEvent](ctx, event.EVENT_BOOT_COMPLETE, func(evt *event.BootCompleteEvent) error {
+				outbox.wg.Add(1)
+				go outbox.run()
+				return nil
+			})
+
+			return nil
+		}),
+		core.ContextWithExitFunc(func(ctx core.Context) error {
+			close(outbox.stop)
+			outbox.wg.Wait()
+			return nil
+		}),
+	)
+
+	return outbox, opts, nil
+}
+
+func (o *OutboxServiceDefault) ID() string {
+	return core.OUTBOX_SERVICE
+}
+
+func (o *OutboxServiceDefault) RegisterTasks(crn core.CronService) error {
+	crn.RegisterTask(cronTaskPruneOutboxEventsName, core.CronTaskFuncHandler(o.cronTaskPruneOutboxEvents), core.CronTaskDefinitionDaily, core.CronTaskNoArgsFactory, true)
+	crn.SetTaskPriority(cronTaskPruneOutboxEventsName, core.CronTaskPriorityBackground)
+
+	return nil
+}
+
+func (o *OutboxServiceDefault) ScheduleJobs(crn core.CronService) error {
+	return crn.CreateJobIfNotExists(cronTaskPruneOutboxEventsName, nil)
+}
+
+func (o *OutboxServiceDefault) Enqueue(tx *gorm.DB, eventName string, data map[string]any) error {
+	encoded, err := core.EncodeEventData(data)
+	if err != nil {
+		return fmt.Errorf("event %s: %w", eventName, err)
+	}
+
+	return tx.Create(&models.OutboxEvent{
+		EventID: uuid.NewString(),
+		Name:    eventName,
+		Data:    encoded,
+	}).Error
+}
+
+func (o *OutboxServiceDefault) Dispatch() (int, error) {
+	var events []*models.OutboxEvent
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.OutboxEvent{}).
+			Where("dispatched_at IS NULL AND dead_at IS NULL AND (claimed_at IS NULL OR claimed_at < ?)", time.Now().Add(-outboxClaimLease)).
+			Order("id ASC").
+			Limit(int(o.config.Config().Core.Outbox.BatchSize)).
+			Find(&events)
+	}); err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+
+	for _, evt := range events {
+		claimed, err := o.claim(evt)
+		if err != nil {
+			return dispatched, err
+		}
+
+		// Another node got there first
+		if !claimed {
+			continue
+		}
+
+		if err := o.deliver(evt); err != nil {
+			o.logger.Error("Failed to dispatch outbox event", zap.String("event", evt.Name), zap.String("event_id", evt.EventID), zap.Uint("attempts", evt.Attempts+1), zap.Error(err))
+
+			changes := map[string]any{
+				"attempts":   evt.Attempts + 1,
+				"last_error": err.Error(),
+				"claimed_by": "",
+			}
+
+			if evt.Attempts+1 >= o.config.Config().Core.Outbox.MaxAttempts {
+				o.logger.Error("Outbox event failed every attempt, setting it aside until replayed", zap.String("event", evt.Name), zap.String("event_id", evt.EventID))
+
+				now := time.Now()
+				changes["dead_at"] = &now
+				changes["claimed_at"] = nil
+			}
+
+			if err := o.update(evt, changes); err != nil {
+				return dispatched, err
+			}
+
+			continue
+		}
+
+		now := time.Now()
+		if err := o.update(evt, map[string]any{
+			"dispatched_at": &now,
+			"attempts":      evt.Attempts + 1,
+			"last_error":    "",
+			"claimed_by":    "",
+			"claimed_at":    nil,
+		}); err != nil {
+			return dispatched, err
+		}
+
+		dispatched++
+	}
+
+	return dispatched, nil
+}
+
+func (o *OutboxServiceDefault) Replay(from time.Time, to time.Time) (int64, error) {
+	if !to.After(from) {
+		return 0, core.NewAccountError(core.ErrKeyOutboxInvalidReplayRange, nil)
+	}
+
+	var replayed int64
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.OutboxEvent{}).
+			Where("created_at >= ? AND created_at < ? AND (dispatched_at IS NOT NULL OR dead_at IS NOT NULL)", from, to).
+			Updates(map[string]any{
+				"dispatched_at": nil,
+				"dead_at":       nil,
+				"attempts":      0,
+				"last_error":    "",
+				"claimed_by":    "",
+				"claimed_at":    nil,
+			})
+		replayed = tx.RowsAffected
+
+		return tx
+	}); err != nil {
+		return 0, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return replayed, nil
+}
+
+func (o *OutboxServiceDefault) run() {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(o.config.Config().Core.Outbox.PollInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Keep going while full batches come back so a backlog drains without waiting for the next tick
+		for {
+			dispatched, err := o.Dispatch()
+			if err != nil {
+				o.logger.Error("Failed to dispatch outbox events", zap.Error(err))
+				break
+			}
+
+			if dispatched < int(o.config.Config().Core.Outbox.BatchSize) {
+				break
+			}
+
+			select {
+			case <-o.stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// claim takes the event for this node, it reports false if another node holds a live claim on it
+func (o *OutboxServiceDefault) claim(evt *models.OutboxEvent) (bool, error) {
+	now := time.Now()
+	claimed := false
+
+	if err := db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.OutboxEvent{}).
+			Where("id = ? AND dispatched_at IS NULL AND dead_at IS NULL AND (claimed_at IS NULL OR claimed_at < ?)", evt.ID, now.Add(-outboxClaimLease)).
+			Updates(map[string]any{"claimed_by": o.nodeId, "claimed_at": &now})
+		claimed = tx.RowsAffected == 1
+
+		return tx
+	}); err != nil {
+		return false, err
+	}
+
+	return claimed, nil
+}
+
+func (o *OutboxServiceDefault) deliver(evt *models.OutboxEvent) error {
+	data, err := core.DecodeEventData(evt.Data)
+	if err != nil {
+		return err
+	}
+
+	data[core.OUTBOX_EVENT_ID_KEY] = evt.EventID
+
+	// A fresh instance, the registered one is shared with every direct fire of the event and must not keep the ID
+	target, err := core.NewEvent(evt.Name)
+	if err != nil {
+		return err
+	}
+
+	target.SetData(data)
+
+	return event.DoFire(o.ctx, target, false)
+}
+
+func (o *OutboxServiceDefault) update(evt *models.OutboxEvent, changes map[string]any) error {
+	return db.RetryableTransaction(o.ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(evt).Updates(changes)
+	})
+}
+
+func (o *OutboxServiceDefault) cronTaskPruneOutboxEvents(_ *core.CronTaskNoArgs, ctx core.Context) error {
+	retention := o.config.Config().Core.Outbox.Retention
+	if retention == 0 {
+		return nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -int(retention))
+
+	return db.RetryableTransaction(ctx, o.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped().Where("dispatched_at < ? OR dead_at < ?", cutoff, cutoff).Delete(&models.OutboxEvent{})
+	})
+}
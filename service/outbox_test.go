@@ -0,0 +1,215 @@
+package service
+
+import (
+	"errors"
+	gevent "github.com/gookit/event"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestOutbox(t *testing.T, maxAttempts uint) *OutboxServiceDefault {
+	t.Helper()
+
+	cfg := &config.Config{Core: config.CoreConfig{Outbox: config.OutboxConfig{
+		PollInterval: 1000,
+		BatchSize:    10,
+		MaxAttempts:  maxAttempts,
+	}}}
+
+	outbox := &OutboxServiceDefault{nodeId: "node-a"}
+	ctx := newTestContext(t, cfg, []any{&models.OutboxEvent{}},
+		core.ContextWithService(core.OUTBOX_SERVICE, outbox),
+		core.ContextWithEvents(core.GetEvents()...),
+	)
+
+	outbox.ctx = ctx
+	outbox.logger = ctx.Logger()
+	outbox.config = ctx.Config()
+	outbox.db = ctx.DB()
+
+	return outbox
+}
+
+// expireClaims lets events failed by a previous dispatch be picked up again without waiting for the lease
+func expireClaims(t *testing.T, outbox *OutboxServiceDefault) {
+	t.Helper()
+
+	if err := outbox.db.Model(&models.OutboxEvent{}).Where("claimed_at IS NOT NULL").
+		Update("claimed_at", time.Now().Add(-2*outboxClaimLease)).Error; err != nil {
+		t.Fatalf("failed to expire claims: %v", err)
+	}
+}
+
+func TestOutboxDeliversCommittedEventsOnce(t *testing.T) {
+	outbox := newTestOutbox(t, 3)
+
+	var received []uint
+	var eventIds []string
+	outbox.ctx.Event().On(event.EVENT_USER_OTP_ENABLED, gevent.ListenerFunc(func(evt gevent.Event) error {
+		received = append(received, evt.Get("user_id").(uint))
+		eventIds = append(eventIds, event.OutboxEventID(evt))
+		return nil
+	}))
+
+	if err := outbox.db.Transaction(func(tx *gorm.DB) error {
+		return event.FireUserOTPEnabledEventTx(outbox.ctx, tx, 42)
+	}); err != nil {
+		t.Fatalf("committed transaction failed: %v", err)
+	}
+
+	errRollback := errors.New("rollback")
+	if err := outbox.db.Transaction(func(tx *gorm.DB) error {
+		if err := event.FireUserOTPEnabledEventTx(outbox.ctx, tx, 43); err != nil {
+			return err
+		}
+		return errRollback
+	}); !errors.Is(err, errRollback) {
+		t.Fatalf("expected the transaction to roll back, got %v", err)
+	}
+
+	dispatched, err := outbox.Dispatch()
+	if err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+
+	if dispatched != 1 || len(received) != 1 || received[0] != 42 {
+		t.Fatalf("expected only the committed event to be delivered, dispatched %d, received %v", dispatched, received)
+	}
+
+	var stored models.OutboxEvent
+	if err := outbox.db.First(&stored).Error; err != nil {
+		t.Fatalf("failed to load the event: %v", err)
+	}
+
+	if eventIds[0] == "" || eventIds[0] != stored.EventID {
+		t.Fatalf("expected listeners to receive the outbox event ID %q, got %q", stored.EventID, eventIds[0])
+	}
+
+	if stored.DispatchedAt == nil || stored.Attempts != 1 {
+		t.Fatalf("expected the event to be marked dispatched after one attempt, got %+v", stored)
+	}
+
+	if dispatched, err := outbox.Dispatch(); err != nil || dispatched != 0 || len(received) != 1 {
+		t.Fatalf("expected a dispatched event not to be delivered again, dispatched %d, err %v", dispatched, err)
+	}
+}
+
+func TestOutboxLeavesDirectFiresWithoutEventID(t *testing.T) {
+	outbox := newTestOutbox(t, 3)
+
+	var eventIds []string
+	outbox.ctx.Event().On(event.EVENT_USER_OTP_ENABLED, gevent.ListenerFunc(func(evt gevent.Event) error {
+		eventIds = append(eventIds, event.OutboxEventID(evt))
+		return nil
+	}))
+
+	if err := outbox.db.Transaction(func(tx *gorm.DB) error {
+		return event.FireUserOTPEnabledEventTx(outbox.ctx, tx, 42)
+	}); err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+
+	if _, err := outbox.Dispatch(); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+
+	if err := event.FireUserOTPEnabledEvent(outbox.ctx, 42); err != nil {
+		t.Fatalf("fire failed: %v", err)
+	}
+
+	if len(eventIds) != 2 || eventIds[0] == "" || eventIds[1] != "" {
+		t.Fatalf("expected only the outbox delivery to carry an event ID, got %q", eventIds)
+	}
+}
+
+func TestOutboxSetsAsideEventsAfterMaxAttempts(t *testing.T) {
+	outbox := newTestOutbox(t, 2)
+
+	calls := 0
+	failing := true
+	outbox.ctx.Event().On(event.EVENT_USER_OTP_DISABLED, gevent.ListenerFunc(func(evt gevent.Event) error {
+		calls++
+		if failing {
+			return errors.New("listener failed")
+		}
+		return nil
+	}))
+
+	if err := outbox.db.Transaction(func(tx *gorm.DB) error {
+		return event.FireUserOTPDisabledEventTx(outbox.ctx, tx, 7)
+	}); err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if _, err := outbox.Dispatch(); err != nil {
+			t.Fatalf("dispatch %d failed: %v", attempt, err)
+		}
+		expireClaims(t, outbox)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected delivery to stop after 2 attempts, got %d", calls)
+	}
+
+	var stored models.OutboxEvent
+	if err := outbox.db.First(&stored).Error; err != nil {
+		t.Fatalf("failed to load the event: %v", err)
+	}
+
+	if stored.DeadAt == nil || stored.DispatchedAt != nil || stored.Attempts != 2 || stored.LastError == "" {
+		t.Fatalf("expected the event to be dead after 2 attempts, got %+v", stored)
+	}
+
+	replayed, err := outbox.Replay(stored.CreatedAt.Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil || replayed != 1 {
+		t.Fatalf("expected the dead event to be replayed, replayed %d, err %v", replayed, err)
+	}
+
+	failing = false
+
+	if dispatched, err := outbox.Dispatch(); err != nil || dispatched != 1 || calls != 3 {
+		t.Fatalf("expected the replayed event to be delivered, dispatched %d, calls %d, err %v", dispatched, calls, err)
+	}
+}
+
+func TestOutboxStripsUserCredentials(t *testing.T) {
+	outbox := newTestOutbox(t, 1)
+
+	user := &models.User{
+		Email:        "user@example.com",
+		PasswordHash: "secret-hash",
+		OTPSecret:    "secret-otp",
+	}
+	user.ID = 5
+
+	if err := outbox.db.Transaction(func(tx *gorm.DB) error {
+		return event.FireUserPasswordChangedEventTx(outbox.ctx, tx, user)
+	}); err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+
+	var stored models.OutboxEvent
+	if err := outbox.db.First(&stored).Error; err != nil {
+		t.Fatalf("failed to load the event: %v", err)
+	}
+
+	data := string(stored.Data)
+	if strings.Contains(data, "secret-hash") || strings.Contains(data, "secret-otp") {
+		t.Fatalf("expected credentials to be left out of the outbox, got %s", data)
+	}
+
+	if !strings.Contains(data, "user@example.com") {
+		t.Fatalf("expected the user to be kept in the outbox, got %s", data)
+	}
+
+	if user.PasswordHash != "secret-hash" {
+		t.Fatal("expected the user of the caller to be left untouched")
+	}
+}
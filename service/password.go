@@ -123,22 +123,30 @@ func (p PasswordResetServiceDefault) ResetPassword(email string, token string, p
 		return core.NewAccountError(core.ErrKeySecurityTokenExpired, nil)
 	}
 
-	passwordHash, err := p.user.HashPassword(password)
+	passwordHash, err := p.user.HashUserPassword(password, &reset.User)
 	if err != nil {
 		return err
 	}
 
-	err = p.user.UpdateAccountInfo(reset.UserID, map[string]interface{}{"password_hash": passwordHash})
-	if err != nil {
-		return err
-	}
-
-	reset = models.PasswordReset{
-		UserID: reset.UserID,
-	}
+	reset.User.PasswordHash = passwordHash
 
+	// The password, the removal of the reset tokens and the event describing the change are committed together
 	if err := db.RetryableTransaction(p.ctx, p.db, func(tx *gorm.DB) *gorm.DB {
-		return tx.Where(&reset).Delete(&reset)
+		if err := tx.Model(&reset.User).Update("password_hash", passwordHash).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := tx.Where(&models.PasswordReset{UserID: reset.UserID}).Delete(&models.PasswordReset{}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := event.FireUserPasswordChangedEventTx(p.ctx, tx, &reset.User); err != nil {
+			_ = tx.AddError(err)
+		}
+
+		return tx
 	}); err != nil {
 		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
 	}
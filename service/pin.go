@@ -8,6 +8,7 @@ import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"reflect"
@@ -16,13 +17,16 @@ import (
 
 var _ core.PinService = (*PinServiceDefault)(nil)
 
+// pinQuotaWarningPercent is the share of an organization quota in use at which pinning users are warned
+const pinQuotaWarningPercent = 90
+
 func init() {
 	core.RegisterService(core.ServiceInfo{
 		ID: core.PIN_SERVICE,
 		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
 			return NewPinService()
 		},
-		Depends: []string{core.UPLOAD_SERVICE},
+		Depends: []string{core.UPLOAD_SERVICE, core.ORGANIZATION_SERVICE},
 	})
 }
 
@@ -32,6 +36,7 @@ type PinServiceDefault struct {
 	config   config.Manager
 	db       *gorm.DB
 	metadata core.UploadService
+	org      core.OrganizationService
 }
 
 func NewPinService() (*PinServiceDefault, []core.ContextBuilderOption, error) {
@@ -44,6 +49,7 @@ func NewPinService() (*PinServiceDefault, []core.ContextBuilderOption, error) {
 			pinService.config = ctx.Config()
 			pinService.db = ctx.DB()
 			pinService.metadata = core.GetService[core.UploadService](ctx, core.UPLOAD_SERVICE)
+			pinService.org = core.GetService[core.OrganizationService](ctx, core.ORGANIZATION_SERVICE)
 			return nil
 		}),
 	)
@@ -58,8 +64,7 @@ func (p PinServiceDefault) ID() string {
 func (p PinServiceDefault) AllAccountPins(id uint) ([]*models.Pin, error) {
 	var pins []*models.Pin
 	if err := db.RetryableTransaction(p.ctx, p.db, func(tx *gorm.DB) *gorm.DB {
-		return tx.Where("user_id = ?", id).
-			Preload("Upload").
+		return tx.Scopes(applyPinFilters(core.PinFilter{UserID: id, PersonalOnly: true})).
 			Find(&pins)
 	}); err != nil {
 		return nil, err
@@ -68,10 +73,24 @@ func (p PinServiceDefault) AllAccountPins(id uint) ([]*models.Pin, error) {
 	return pins, nil
 }
 
+func (p PinServiceDefault) OrganizationPins(orgId uint) ([]*models.Pin, error) {
+	var pins []*models.Pin
+	if err := db.RetryableTransaction(p.ctx, p.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("organization_id = ?", orgId).
+			Preload("Upload").
+			Find(&pins)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyPinsRetrievalFailed, err)
+	}
+
+	return pins, nil
+}
+
 func (p PinServiceDefault) AccountPins(id uint, createdAfter uint64) ([]*models.Pin, error) {
 	ctx := context.Background()
 	filter := core.PinFilter{
 		UserID:       id,
+		PersonalOnly: true,
 		CreatedAfter: time.Unix(int64(createdAfter), 0),
 		Limit:        1000, // Set an appropriate limit
 	}
@@ -96,8 +115,9 @@ func (p PinServiceDefault) AccountPins(id uint, createdAfter uint64) ([]*models.
 func (p PinServiceDefault) DeletePinByHash(hash core.StorageHash, userId uint) error {
 	ctx := context.Background()
 	pin, err := p.QueryPin(ctx, nil, core.PinFilter{
-		Hash:   hash,
-		UserID: userId,
+		Hash:         hash,
+		UserID:       userId,
+		PersonalOnly: true,
 	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -136,8 +156,84 @@ func (p PinServiceDefault) PinByID(uploadId uint, userId uint, protocolData any)
 	return err
 }
 
+func (p PinServiceDefault) OrganizationPinByHash(hash core.StorageHash, userId uint, orgId uint, protocolData any) error {
+	ctx := context.Background()
+	upload, err := p.metadata.GetUpload(ctx, hash)
+	if err != nil {
+		return err
+	}
+
+	return p.OrganizationPinByID(upload.ID, userId, orgId, protocolData)
+}
+
+func (p PinServiceDefault) OrganizationPinByID(uploadId uint, userId uint, orgId uint, protocolData any) error {
+	ctx := context.Background()
+	pin := &models.Pin{
+		UserID:         userId,
+		OrganizationID: &orgId,
+		UploadID:       uploadId,
+	}
+
+	_, err := p.CreatePin(ctx, pin, protocolData)
+	return err
+}
+
+// UploadPinnedGlobal is asked on every download, so it is answered by a read replica
 func (p PinServiceDefault) UploadPinnedGlobal(hash core.StorageHash) (bool, error) {
-	return p.UploadPinnedByUser(hash, 0)
+	ctx := context.Background()
+	upload, err := p.metadata.GetUpload(ctx, hash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var pin models.Pin
+	if err := db.ReplicaRead(ctx, p.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.Pin{}).Select("id").Scopes(applyPinFilters(core.PinFilter{UploadID: upload.ID})).First(&pin)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (p PinServiceDefault) UploadFrozen(hash core.StorageHash) (bool, error) {
+	ctx := context.Background()
+	upload, err := p.metadata.GetUpload(ctx, hash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var total, unfrozen int64
+
+	if err := db.RetryableTransaction(p.ctx, p.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.Pin{}).Where("upload_id = ?", upload.ID).Count(&total)
+	}); err != nil {
+		return false, err
+	}
+
+	if total == 0 {
+		return false, nil
+	}
+
+	if err := db.RetryableTransaction(p.ctx, p.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.Pin{}).
+			Where("upload_id = ?", upload.ID).
+			Where("NOT EXISTS (SELECT 1 FROM account_suspensions WHERE account_suspensions.user_id = pins.user_id AND account_suspensions.freeze_pins = ? AND account_suspensions.lifted_at IS NULL AND account_suspensions.deleted_at IS NULL AND (account_suspensions.expires_at IS NULL OR account_suspensions.expires_at > ?))", true, time.Now()).
+			Count(&unfrozen)
+	}); err != nil {
+		return false, err
+	}
+
+	return unfrozen == 0, nil
 }
 
 func (p PinServiceDefault) UploadPinnedByUser(hash core.StorageHash, userId uint) (bool, error) {
@@ -153,6 +249,7 @@ func (p PinServiceDefault) UploadPinnedByUser(hash core.StorageHash, userId uint
 	filter := core.PinFilter{UploadID: upload.ID}
 	if userId != 0 {
 		filter.UserID = userId
+		filter.PersonalOnly = true
 	}
 
 	pin, err := p.QueryPin(ctx, nil, filter)
@@ -182,9 +279,26 @@ func (p PinServiceDefault) GetPinsByUploadID(ctx context.Context, uploadID uint)
 }
 
 func (p *PinServiceDefault) CreatePin(ctx context.Context, pin *models.Pin, protocolData any) (*models.Pin, error) {
+	if pin.OrganizationID != nil {
+		if err := p.checkOrganizationPin(ctx, pin); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := p.ctx.DB().Transaction(func(tx *gorm.DB) error {
 		return db.RetryOnLock(tx, func(db *gorm.DB) *gorm.DB {
-			return db.WithContext(ctx).Preload("Upload").FirstOrCreate(pin, &models.Pin{
+			db = db.WithContext(ctx).Preload("Upload")
+
+			// Personal and organization pins of the same upload are tracked separately. An organization pins an upload
+			// once, whichever of its members pinned it first.
+			if pin.OrganizationID != nil {
+				return db.FirstOrCreate(pin, &models.Pin{
+					UploadID:       pin.UploadID,
+					OrganizationID: pin.OrganizationID,
+				})
+			}
+
+			return db.Where("organization_id IS NULL").FirstOrCreate(pin, &models.Pin{
 				UploadID: pin.UploadID,
 				UserID:   pin.UserID,
 			})
@@ -207,9 +321,90 @@ func (p *PinServiceDefault) CreatePin(ctx context.Context, pin *models.Pin, prot
 		return nil, err
 	}
 
+	if pin.OrganizationID != nil {
+		p.warnQuota(ctx, pin)
+	}
+
 	return pin, nil
 }
 
+// warnQuota lets the user know when their pin took the organization past the quota warning threshold. Only the pin
+// crossing the threshold warns, so the warning is not repeated for every pin after it.
+func (p *PinServiceDefault) warnQuota(ctx context.Context, pin *models.Pin) {
+	orgId := *pin.OrganizationID
+
+	org, err := p.org.GetOrganization(orgId)
+	if err != nil {
+		p.logger.Error("Failed to get organization for quota warning", zap.Uint("organization_id", orgId), zap.Error(err))
+		return
+	}
+
+	if org.StorageQuota == 0 {
+		return
+	}
+
+	upload, err := p.metadata.GetUploadByID(ctx, pin.UploadID)
+	if err != nil {
+		p.logger.Error("Failed to get upload for quota warning", zap.Uint("upload_id", pin.UploadID), zap.Error(err))
+		return
+	}
+
+	usage, err := p.org.OrganizationUsage(orgId)
+	if err != nil {
+		p.logger.Error("Failed to get organization usage for quota warning", zap.Uint("organization_id", orgId), zap.Error(err))
+		return
+	}
+
+	threshold := org.StorageQuota * pinQuotaWarningPercent / 100
+	if usage < threshold || usage-upload.Size >= threshold {
+		return
+	}
+
+	if err := event.FireStorageQuotaWarningEvent(p.ctx, pin.UserID, orgId, usage, org.StorageQuota); err != nil {
+		p.logger.Error("Failed to fire quota warning", zap.Uint("organization_id", orgId), zap.Error(err))
+	}
+}
+
+// checkOrganizationPin ensures the user may pin on behalf of the organization and that the upload fits in its quota
+func (p *PinServiceDefault) checkOrganizationPin(ctx context.Context, pin *models.Pin) error {
+	orgId := *pin.OrganizationID
+
+	allowed, err := p.org.CheckAccess(pin.UserID, orgId, core.ORG_OBJECT_PINS, core.ORG_ACTION_WRITE)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return core.NewAccountError(core.ErrKeyOrganizationPermissionDenied, nil)
+	}
+
+	existing, err := p.QueryPin(ctx, nil, core.PinFilter{UploadID: pin.UploadID, OrganizationID: orgId})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	// Re-pinning an upload the organization already holds does not consume quota
+	if existing != nil {
+		return nil
+	}
+
+	upload, err := p.metadata.GetUploadByID(ctx, pin.UploadID)
+	if err != nil {
+		return err
+	}
+
+	exceeded, err := p.org.QuotaExceeded(orgId, upload.Size)
+	if err != nil {
+		return err
+	}
+
+	if exceeded {
+		return core.NewAccountError(core.ErrKeyOrganizationQuotaExceeded, nil)
+	}
+
+	return nil
+}
+
 func (p PinServiceDefault) UpdatePin(ctx context.Context, pin *models.Pin) error {
 	return p.ctx.DB().Transaction(func(tx *gorm.DB) error {
 		return db.RetryOnLock(tx, func(db *gorm.DB) *gorm.DB {
@@ -380,6 +575,14 @@ func applyPinFilters(filter core.PinFilter) func(*gorm.DB) *gorm.DB {
 			db = db.Where("pins.user_id = ?", filter.UserID)
 		}
 
+		if filter.OrganizationID != 0 {
+			db = db.Where("pins.organization_id = ?", filter.OrganizationID)
+		}
+
+		if filter.PersonalOnly {
+			db = db.Where("pins.organization_id IS NULL")
+		}
+
 		if !filter.CreatedAfter.IsZero() {
 			db = db.Where("pins.created_at > ?", filter.CreatedAfter)
 		}
@@ -422,6 +625,10 @@ func applyProtocolPinFilters(filter core.PinFilter) func(*gorm.DB) *gorm.DB {
 			db = db.Where("Pin.user_id = ?", filter.UserID)
 		}
 
+		if filter.OrganizationID != 0 {
+			db = db.Where("Pin.organization_id = ?", filter.OrganizationID)
+		}
+
 		if !filter.CreatedAfter.IsZero() {
 			db = db.Where("Pin.created_at > ?", filter.CreatedAfter)
 		}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"testing"
+)
+
+// testOrganizationAccess answers every organization access check with allowed
+type testOrganizationAccess struct {
+	core.OrganizationService
+	allowed bool
+}
+
+func (o testOrganizationAccess) CheckAccess(userId uint, orgId uint, obj, act string) (bool, error) {
+	return o.allowed, nil
+}
+
+func newTestPinService(t *testing.T) *PinServiceDefault {
+	t.Helper()
+
+	ctx := newTestContext(t, &config.Config{}, []any{&models.User{}, &models.Organization{}, &models.Upload{}, &models.Pin{}})
+
+	return &PinServiceDefault{ctx: ctx, db: ctx.DB(), logger: ctx.Logger()}
+}
+
+func TestAccountPinsLeaveOutOrganizationPins(t *testing.T) {
+	pins := newTestPinService(t)
+
+	user := &models.User{Email: "member@example.com"}
+	org := &models.Organization{Name: "org", Slug: "org"}
+	upload := &models.Upload{Protocol: "test"}
+	for _, model := range []any{user, org, upload} {
+		if err := pins.db.Create(model).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", model, err)
+		}
+	}
+
+	personal := &models.Pin{UploadID: upload.ID, UserID: user.ID}
+	shared := &models.Pin{UploadID: upload.ID, UserID: user.ID, OrganizationID: &org.ID}
+	if err := pins.db.Create([]*models.Pin{personal, shared}).Error; err != nil {
+		t.Fatalf("failed to create pins: %v", err)
+	}
+
+	all, err := pins.AllAccountPins(user.ID)
+	if err != nil {
+		t.Fatalf("failed to list pins: %v", err)
+	}
+
+	if len(all) != 1 || all[0].ID != personal.ID {
+		t.Fatalf("expected only the personal pin, got %+v", all)
+	}
+
+	recent, err := pins.AccountPins(user.ID, 0)
+	if err != nil {
+		t.Fatalf("failed to list pins: %v", err)
+	}
+
+	if len(recent) != 1 || recent[0].ID != personal.ID {
+		t.Fatalf("expected only the personal pin, got %+v", recent)
+	}
+
+	orgPins, err := pins.OrganizationPins(org.ID)
+	if err != nil || len(orgPins) != 1 || orgPins[0].ID != shared.ID {
+		t.Fatalf("expected the organization pin to be listed for the organization, got %+v, err %v", orgPins, err)
+	}
+}
+
+func TestOrganizationPinRequiresAccess(t *testing.T) {
+	pins := newTestPinService(t)
+	pins.org = testOrganizationAccess{allowed: false}
+
+	user := &models.User{Email: "outsider@example.com"}
+	org := &models.Organization{Name: "org", Slug: "org"}
+	upload := &models.Upload{Protocol: "test"}
+	for _, model := range []any{user, org, upload} {
+		if err := pins.db.Create(model).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", model, err)
+		}
+	}
+
+	err := pins.OrganizationPinByID(upload.ID, user.ID, org.ID, nil)
+	if acctErr := core.AsAccountError(err); acctErr == nil || !acctErr.IsErrorType(core.ErrKeyOrganizationPermissionDenied) {
+		t.Fatalf("expected the pin to be denied, got %v", err)
+	}
+
+	var count int64
+	if err := pins.db.Model(&models.Pin{}).Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("expected no pin to be created, got %d, err %v", count, err)
+	}
+}
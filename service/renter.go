@@ -9,6 +9,7 @@ import (
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/db/models"
 	renterInternal "go.lumeweb.com/portal/service/internal/renter"
+	"go.opentelemetry.io/otel/trace"
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/renterd/api"
 	autoPilotClient "go.sia.tech/renterd/autopilot"
@@ -19,12 +20,14 @@ import (
 	"gorm.io/gorm"
 	"io"
 	"math"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
 )
 
 var _ core.RenterService = (*RenterDefault)(nil)
+var _ core.HealthChecker = (*RenterDefault)(nil)
 
 func init() {
 	core.RegisterService(core.ServiceInfo{
@@ -80,8 +83,48 @@ func (r *RenterDefault) ID() string {
 	return core.RENTER_SERVICE
 }
 
-func (r *RenterDefault) CreateBucketIfNotExists(bucket string) error {
-	_, err := r.busClient.Bucket(context.Background(), bucket)
+func (r *RenterDefault) HealthChecks() []core.HealthCheck {
+	return []core.HealthCheck{{
+		Name:  "renterd",
+		Check: r.checkBus,
+	}}
+}
+
+// checkBus requests the bus state itself, the bus client takes no context and would outlive a timed out check
+func (r *RenterDefault) checkBus(ctx context.Context) error {
+	addrURL, err := url.Parse(r.config.Config().Core.Storage.Sia.URL)
+	if err != nil {
+		return err
+	}
+
+	addrURL.Path = "/api/bus/state"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addrURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth("", r.config.Config().Core.Storage.Sia.Key)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("renterd bus responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (r *RenterDefault) CreateBucketIfNotExists(bucket string) (err error) {
+	defer r.observe(context.Background(), "create_bucket", time.Now(), &err)
+
+	_, err = r.busClient.Bucket(context.Background(), bucket)
 
 	if err == nil {
 		return nil
@@ -103,9 +146,11 @@ func (r *RenterDefault) CreateBucketIfNotExists(bucket string) error {
 	return nil
 }
 
-func (r *RenterDefault) UploadObject(ctx context.Context, file io.Reader, bucket string, fileName string) error {
+func (r *RenterDefault) UploadObject(ctx context.Context, file io.Reader, bucket string, fileName string) (err error) {
+	defer r.observe(ctx, "upload_object", time.Now(), &err)
+
 	fileName = "/" + strings.TrimLeft(fileName, "/")
-	_, err := r.workerClient.UploadObject(ctx, file, bucket, fileName, api.UploadObjectOptions{})
+	_, err = r.workerClient.UploadObject(ctx, file, bucket, fileName, api.UploadObjectOptions{})
 
 	if err != nil {
 		return err
@@ -114,7 +159,9 @@ func (r *RenterDefault) UploadObject(ctx context.Context, file io.Reader, bucket
 	return nil
 }
 
-func (r *RenterDefault) ImportObjectMetadata(ctx context.Context, bucket string, fileName string, object_ object.Object) error {
+func (r *RenterDefault) ImportObjectMetadata(ctx context.Context, bucket string, fileName string, object_ object.Object) (err error) {
+	defer r.observe(ctx, "import_object_metadata", time.Now(), &err)
+
 	cfg, err := r.autoPilotClient.Config()
 	if err != nil {
 		return err
@@ -152,12 +199,16 @@ func (r *RenterDefault) init() error {
 	return nil
 }
 
-func (r *RenterDefault) GetObject(ctx context.Context, bucket string, fileName string, options api.DownloadObjectOptions) (*api.GetObjectResponse, error) {
+func (r *RenterDefault) GetObject(ctx context.Context, bucket string, fileName string, options api.DownloadObjectOptions) (_ *api.GetObjectResponse, err error) {
+	defer r.observe(ctx, "get_object", time.Now(), &err)
+
 	fileName = "/" + strings.TrimLeft(fileName, "/")
 	return r.workerClient.GetObject(ctx, bucket, fileName, options)
 }
 
-func (r *RenterDefault) GetObjectMetadata(ctx context.Context, bucket string, fileName string) (*api.Object, error) {
+func (r *RenterDefault) GetObjectMetadata(ctx context.Context, bucket string, fileName string) (_ *api.Object, err error) {
+	defer r.observe(ctx, "get_object_metadata", time.Now(), &err)
+
 	ret, err := r.busClient.Object(ctx, bucket, fileName, api.GetObjectOptions{})
 
 	if err != nil {
@@ -167,12 +218,16 @@ func (r *RenterDefault) GetObjectMetadata(ctx context.Context, bucket string, fi
 	return ret.Object, nil
 }
 
-func (r *RenterDefault) DeleteObjectMetadata(ctx context.Context, bucket string, fileName string) error {
+func (r *RenterDefault) DeleteObjectMetadata(ctx context.Context, bucket string, fileName string) (err error) {
+	defer r.observe(ctx, "delete_object_metadata", time.Now(), &err)
+
 	return r.busClient.DeleteObject(ctx, bucket, fileName, api.DeleteObjectOptions{})
 }
 
-func (r *RenterDefault) GetSetting(ctx context.Context, setting string, out any) error {
-	err := r.busClient.Setting(ctx, setting, out)
+func (r *RenterDefault) GetSetting(ctx context.Context, setting string, out any) (err error) {
+	defer r.observe(ctx, "get_setting", time.Now(), &err)
+
+	err = r.busClient.Setting(ctx, setting, out)
 
 	if err != nil {
 		return err
@@ -199,7 +254,9 @@ func (r *RenterDefault) UploadExists(ctx context.Context, bucket string, fileNam
 	return true, &siaUpload, nil
 }
 
-func (r *RenterDefault) UploadObjectMultipart(ctx context.Context, params *core.MultipartUploadParams) error {
+func (r *RenterDefault) UploadObjectMultipart(ctx context.Context, params *core.MultipartUploadParams) (err error) {
+	defer r.observe(ctx, "upload_object_multipart", time.Now(), &err)
+
 	size := params.Size
 	rf := params.ReaderFactory
 	bucket := params.Bucket
@@ -323,11 +380,15 @@ func (r *RenterDefault) UploadObjectMultipart(ctx context.Context, params *core.
 	return nil
 }
 
-func (r *RenterDefault) DeleteObject(ctx context.Context, bucket string, fileName string) error {
+func (r *RenterDefault) DeleteObject(ctx context.Context, bucket string, fileName string) (err error) {
+	defer r.observe(ctx, "delete_object", time.Now(), &err)
+
 	return r.workerClient.DeleteObject(ctx, bucket, fileName, api.DeleteObjectOptions{})
 }
 
-func (r *RenterDefault) UpdateGougingSettings(ctx context.Context, settings api.GougingSettings) error {
+func (r *RenterDefault) UpdateGougingSettings(ctx context.Context, settings api.GougingSettings) (err error) {
+	defer r.observe(ctx, "update_setting", time.Now(), &err)
+
 	return r.busClient.UpdateSetting(ctx, api.SettingGouging, settings)
 }
 
@@ -363,3 +424,18 @@ func (r *RenterDefault) SlabSize(ctx context.Context) (uint64, error) {
 
 	return uint64(settings.MinShards * rhpv2.SectorSize), nil
 }
+
+// observe records the latency of a renterd call and counts it as failed when *err is set on return. The renterd
+// clients do not take part in tracing, so the call is traced here as a span covering it.
+func (r *RenterDefault) observe(ctx context.Context, operation string, start time.Time, err *error) {
+	_, span := core.Tracer().Start(ctx, "renter."+operation, trace.WithTimestamp(start), trace.WithSpanKind(trace.SpanKindClient))
+	core.RecordSpanError(span, *err)
+	span.End()
+
+	metrics := r.ctx.Metrics()
+	metrics.RenterCallDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if *err != nil {
+		metrics.RenterCallErrors.WithLabelValues(operation).Inc()
+	}
+}
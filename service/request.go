@@ -7,10 +7,12 @@ import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"reflect"
 	"strings"
+	"time"
 )
 
 var _ core.RequestService = (*RequestServiceDefault)(nil)
@@ -25,6 +27,7 @@ func init() {
 		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
 			return NewRequestService()
 		},
+		Depends: []string{core.ORGANIZATION_SERVICE},
 	})
 }
 
@@ -32,6 +35,7 @@ type RequestServiceDefault struct {
 	ctx    core.Context
 	logger *core.Logger
 	db     *gorm.DB
+	org    core.OrganizationService
 }
 
 func NewRequestService() (*RequestServiceDefault, []core.ContextBuilderOption, error) {
@@ -42,6 +46,7 @@ func NewRequestService() (*RequestServiceDefault, []core.ContextBuilderOption, e
 			req.ctx = ctx
 			req.logger = ctx.ServiceLogger(req)
 			req.db = ctx.DB()
+			req.org = core.GetService[core.OrganizationService](ctx, core.ORGANIZATION_SERVICE)
 			return nil
 		}),
 	)
@@ -54,6 +59,18 @@ func (r *RequestServiceDefault) ID() string {
 }
 
 func (r *RequestServiceDefault) CreateRequest(ctx context.Context, req *models.Request, protocolData any, uploadData any) (*models.Request, error) {
+	// Uploads for an organization end up as organization pins, so they need the same access
+	if req.OrganizationID != nil {
+		allowed, err := r.org.CheckAccess(req.UserID, *req.OrganizationID, core.ORG_OBJECT_PINS, core.ORG_ACTION_WRITE)
+		if err != nil {
+			return nil, err
+		}
+
+		if !allowed {
+			return nil, core.NewAccountError(core.ErrKeyOrganizationPermissionDenied, nil)
+		}
+	}
+
 	if !core.ProtocolHasDataRequestHandler(req.Protocol) {
 		r.logger.Panic("protocol %s does not have a data request handler", zap.String("protocol", req.Protocol))
 		return nil, nil
@@ -101,9 +118,13 @@ func (r *RequestServiceDefault) CreateRequest(ctx context.Context, req *models.R
 		req.Status = models.RequestStatusPending
 	}
 
+	created := false
+
 	if err := r.ctx.DB().Transaction(func(tx *gorm.DB) error {
 		return db.RetryOnLock(tx, func(db *gorm.DB) *gorm.DB {
-			return db.WithContext(ctx).FirstOrCreate(&newReq, req)
+			db = db.WithContext(ctx).FirstOrCreate(&newReq, req)
+			created = db.RowsAffected > 0
+			return db
 		})
 	}); err != nil {
 		return nil, err
@@ -117,6 +138,12 @@ func (r *RequestServiceDefault) CreateRequest(ctx context.Context, req *models.R
 		if err := uploadDataHandler.CreateUploadData(ctx, r.ctx.DB().WithContext(r.ctx), newReq.ID, uploadData); err != nil {
 			return nil, err
 		}
+
+		if created {
+			if err := event.FireUploadStartedEvent(r.ctx, &newReq); err != nil {
+				r.logger.Error("Failed to fire upload started event", zap.Uint("request_id", newReq.ID), zap.Error(err))
+			}
+		}
 	}
 
 	return &newReq, nil
@@ -264,6 +291,17 @@ func (r *RequestServiceDefault) CompleteRequest(ctx context.Context, id uint) er
 		if err = uploadHandler.CompleteUploadData(ctx, r.db, id); err != nil {
 			return err
 		}
+
+		// Completing an already completed request again is not a new completion
+		if req.Status != models.RequestStatusCompleted {
+			req.Status = models.RequestStatusCompleted
+
+			r.ctx.Metrics().ObserveTransfer(core.METRICS_DIRECTION_UPLOAD, req.Protocol, req.Size, time.Since(req.CreatedAt))
+
+			if err := event.FireUploadCompletedEvent(r.ctx, req); err != nil {
+				r.logger.Error("Failed to fire upload completed event", zap.Uint("request_id", req.ID), zap.Error(err))
+			}
+		}
 	}
 
 	return nil
@@ -315,12 +353,10 @@ func (r *RequestServiceDefault) ListRequestsByUser(ctx context.Context, userID u
 	var req models.Request
 
 	req.UserID = userID
-	err := r.ctx.DB().Transaction(func(tx *gorm.DB) error {
-		return db.RetryOnLock(tx, func(db *gorm.DB) *gorm.DB {
-			return db.WithContext(ctx).Where(&req).Scopes(
-				applyFilters(filter),
-			).Find(&requests)
-		})
+	err := db.RetryOnLock(db.Replica(ctx, r.ctx.DB()), func(db *gorm.DB) *gorm.DB {
+		return db.Where(&req).Scopes(
+			applyFilters(filter),
+		).Find(&requests)
 	})
 	if err != nil {
 		return nil, err
@@ -330,13 +366,11 @@ func (r *RequestServiceDefault) ListRequestsByUser(ctx context.Context, userID u
 
 func (r *RequestServiceDefault) ListRequestsByStatus(ctx context.Context, status string, filter core.RequestFilter) ([]*models.Request, error) {
 	var requests []*models.Request
-	err := r.ctx.DB().Transaction(func(tx *gorm.DB) error {
-		return db.RetryOnLock(tx, func(db *gorm.DB) *gorm.DB {
-			return db.WithContext(ctx).Where("status = ?", status).
-				Scopes(
-					applyFilters(filter),
-				).Find(&requests)
-		})
+	err := db.RetryOnLock(db.Replica(ctx, r.ctx.DB()), func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ?", status).
+			Scopes(
+				applyFilters(filter),
+			).Find(&requests)
 	})
 	if err != nil {
 		return nil, err
@@ -345,11 +379,39 @@ func (r *RequestServiceDefault) ListRequestsByStatus(ctx context.Context, status
 }
 
 func (r *RequestServiceDefault) UpdateRequestStatus(ctx context.Context, id uint, status models.RequestStatusType) error {
-	return r.ctx.DB().Transaction(func(tx *gorm.DB) error {
+	req, err := r.GetRequest(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	previousStatus := req.Status
+
+	err = r.ctx.DB().Transaction(func(tx *gorm.DB) error {
 		return db.RetryOnLock(tx, func(db *gorm.DB) *gorm.DB {
 			return db.WithContext(ctx).Model(&models.Request{}).Where("id = ?", id).Update("status", status)
 		})
 	})
+	if err != nil {
+		return err
+	}
+
+	if previousStatus == status {
+		return nil
+	}
+
+	req.Status = status
+
+	if err := event.FireRequestStatusUpdatedEvent(r.ctx, req, previousStatus); err != nil {
+		return err
+	}
+
+	if status == models.RequestStatusFailed && isUploadOperation(req.Operation) {
+		if err := event.FireUploadFailedEvent(r.ctx, req); err != nil {
+			r.logger.Error("Failed to fire upload failed event", zap.Uint("request_id", req.ID), zap.Error(err))
+		}
+	}
+
+	return nil
 }
 
 func (r *RequestServiceDefault) RequestExists(ctx context.Context, id uint) (bool, error) {
@@ -615,6 +677,9 @@ func applyFilters(filter core.RequestFilter) func(*gorm.DB) *gorm.DB {
 		if filter.UserID > 0 {
 			db = db.Where("Request.user_id = ?", filter.UserID)
 		}
+		if filter.OrganizationID > 0 {
+			db = db.Where("Request.organization_id = ?", filter.OrganizationID)
+		}
 		if filter.Limit > 0 {
 			db = db.Limit(filter.Limit)
 		}
@@ -637,6 +702,9 @@ func applyUploadDataFilters(filter core.RequestFilter) func(*gorm.DB) *gorm.DB {
 		if filter.UserID > 0 {
 			db = db.Where("Request.user_id = ?", filter.UserID)
 		}
+		if filter.OrganizationID > 0 {
+			db = db.Where("Request.organization_id = ?", filter.OrganizationID)
+		}
 		if filter.Limit > 0 {
 			db = db.Limit(filter.Limit)
 		}
@@ -17,11 +17,14 @@ import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/db/models"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
 	"go.sia.tech/renterd/api"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"io"
 	"math"
+	"net/http"
 	"sort"
 	"sync"
 	"time"
@@ -29,6 +32,7 @@ import (
 
 var _ core.StorageService = (*StorageServiceDefault)(nil)
 var _ core.StorageHash = (*StorageHashDefault)(nil)
+var _ core.HealthChecker = (*StorageServiceDefault)(nil)
 
 func init() {
 	core.RegisterService(core.ServiceInfo{
@@ -195,6 +199,26 @@ func (s StorageServiceDefault) ID() string {
 	return core.STORAGE_SERVICE
 }
 
+func (s StorageServiceDefault) HealthChecks() []core.HealthCheck {
+	return []core.HealthCheck{{
+		Name: "s3",
+		Check: func(ctx context.Context) error {
+			client, err := s.S3Client(ctx)
+			if err != nil {
+				return err
+			}
+
+			_, err = client.HeadBucket(ctx, &s3.HeadBucketInput{
+				Bucket: aws.String(s.config.Config().Core.Storage.S3.BufferBucket),
+			})
+
+			return err
+		},
+		// Only uploads go through the buffer bucket
+		Optional: true,
+	}}
+}
+
 // readerPool manages a pool of readers for large, potentially non-seekable data streams
 type readerPool struct {
 	readers []io.ReadCloser
@@ -248,7 +272,10 @@ func (rp *readerPool) Close() {
 	rp.readers = rp.readers[:0] // Clear the slice
 }
 
-func (s StorageServiceDefault) UploadObject(ctx context.Context, request core.StorageUploadRequest) (*models.Upload, error) {
+func (s StorageServiceDefault) UploadObject(ctx context.Context, request core.StorageUploadRequest) (_ *models.Upload, err error) {
+	ctx, end := core.StartSpan(ctx, "storage.upload_object", attribute.String("protocol", request.Protocol().Name()), attribute.Int64("size", int64(request.Size())))
+	defer end(&err)
+
 	rp := newReaderPool(s.logger)
 	defer rp.Close()
 
@@ -257,7 +284,6 @@ func (s StorageServiceDefault) UploadObject(ctx context.Context, request core.St
 	}
 
 	var hash core.StorageHash
-	var err error
 
 	if request.Hash() != nil {
 		hash = request.Hash()
@@ -378,7 +404,10 @@ func (s StorageServiceDefault) getObjectProof(protocol core.StorageProtocol, dat
 	return hashResult, nil
 }
 
-func (s StorageServiceDefault) DownloadObject(ctx context.Context, protocol core.StorageProtocol, objectHash core.StorageHash, start int64) (io.ReadCloser, error) {
+func (s StorageServiceDefault) DownloadObject(ctx context.Context, protocol core.StorageProtocol, objectHash core.StorageHash, start int64) (_ io.ReadCloser, err error) {
+	ctx, end := core.StartSpan(ctx, "storage.download_object", attribute.String("protocol", protocol.Name()))
+	defer end(&err)
+
 	var partialRange *api.DownloadRange = nil
 
 	upload, err := s.metadata.GetUpload(ctx, objectHash)
@@ -446,6 +475,7 @@ func (s StorageServiceDefault) S3Client(ctx context.Context) (*s3.Client, error)
 			"",
 		)),
 		awsConfig.WithEndpointResolverWithOptions(customResolver),
+		awsConfig.WithHTTPClient(&http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}),
 	)
 	if err != nil {
 		return nil, err
@@ -454,7 +484,10 @@ func (s StorageServiceDefault) S3Client(ctx context.Context) (*s3.Client, error)
 	return s3.NewFromConfig(cfg), nil
 }
 
-func (s StorageServiceDefault) S3MultipartUpload(ctx context.Context, data io.ReadCloser, bucket, key string, size uint64) error {
+func (s StorageServiceDefault) S3MultipartUpload(ctx context.Context, data io.ReadCloser, bucket, key string, size uint64) (err error) {
+	ctx, end := core.StartSpan(ctx, "storage.s3_multipart_upload", attribute.String("bucket", bucket), attribute.Int64("size", int64(size)))
+	defer end(&err)
+
 	client, err := s.S3Client(ctx)
 	if err != nil {
 		return err
@@ -123,6 +123,14 @@ func (t *TUSServiceDefault) Uploads(ctx context.Context, uploaderID uint) ([]*mo
 }
 
 func (t *TUSServiceDefault) CreateUpload(ctx context.Context, hash core.StorageHash, uploadID string, uploaderID uint, uploaderIP string, protocol core.StorageProtocol, mimeType string) (*models.TUSRequest, error) {
+	return t.createUpload(ctx, hash, uploadID, uploaderID, nil, uploaderIP, protocol, mimeType)
+}
+
+func (t *TUSServiceDefault) CreateOrganizationUpload(ctx context.Context, hash core.StorageHash, uploadID string, uploaderID uint, orgID uint, uploaderIP string, protocol core.StorageProtocol, mimeType string) (*models.TUSRequest, error) {
+	return t.createUpload(ctx, hash, uploadID, uploaderID, &orgID, uploaderIP, protocol, mimeType)
+}
+
+func (t *TUSServiceDefault) createUpload(ctx context.Context, hash core.StorageHash, uploadID string, uploaderID uint, orgID *uint, uploaderIP string, protocol core.StorageProtocol, mimeType string) (*models.TUSRequest, error) {
 	var hashBytes []byte
 
 	if hash != nil {
@@ -130,13 +138,14 @@ func (t *TUSServiceDefault) CreateUpload(ctx context.Context, hash core.StorageH
 	}
 
 	upload := &models.Request{
-		Hash:      hashBytes,
-		Protocol:  protocol.Name(),
-		Operation: models.RequestOperationTusUpload,
-		Status:    models.RequestStatusPending,
-		UserID:    uploaderID,
-		SourceIP:  uploaderIP,
-		MimeType:  mimeType,
+		Hash:           hashBytes,
+		Protocol:       protocol.Name(),
+		Operation:      models.RequestOperationTusUpload,
+		Status:         models.RequestStatusPending,
+		UserID:         uploaderID,
+		OrganizationID: orgID,
+		SourceIP:       uploaderIP,
+		MimeType:       mimeType,
 	}
 
 	if hash != nil {
@@ -148,6 +157,8 @@ func (t *TUSServiceDefault) CreateUpload(ctx context.Context, hash core.StorageH
 		return nil, err
 	}
 
+	t.ctx.Metrics().TUSActiveUploads.WithLabelValues(protocol.Name()).Inc()
+
 	dataReq, err := t.requests.GetUploadData(ctx, request.ID)
 
 	if err != nil {
@@ -186,6 +197,10 @@ func (t *TUSServiceDefault) UploadCompleted(ctx context.Context, uploadID string
 		return core.ErrUploadNotFound
 	}
 
+	if upload.Request.Status != models.RequestStatusCompleted {
+		t.uploadInactive(upload)
+	}
+
 	if upload.Request.Status == models.RequestStatusDuplicate {
 		return nil
 	}
@@ -205,9 +220,18 @@ func (t *TUSServiceDefault) DeleteUpload(ctx context.Context, uploadID string) e
 		return err
 	}
 
+	if upload.Request.Status != models.RequestStatusCompleted {
+		t.uploadInactive(upload)
+	}
+
 	return nil
 }
 
+// uploadInactive is per node, an upload finished on another node than it was created on shows up in the sum across nodes
+func (t *TUSServiceDefault) uploadInactive(upload *models.TUSRequest) {
+	t.ctx.Metrics().TUSActiveUploads.WithLabelValues(upload.Request.Protocol).Dec()
+}
+
 func (t *TUSServiceDefault) SetHash(ctx context.Context, uploadID string, hash core.StorageHash) error {
 	exists, upload := t.UploadExists(ctx, uploadID)
 
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"testing"
+)
+
+// testStorageProtocol is a protocol that is only ever asked for its name
+type testStorageProtocol struct {
+	core.StorageProtocol
+}
+
+func (testStorageProtocol) Name() string {
+	return "test"
+}
+
+func TestOrganizationUploadRequiresAccess(t *testing.T) {
+	ctx := newTestContext(t, &config.Config{}, []any{&models.Request{}})
+	requests := &RequestServiceDefault{ctx: ctx, logger: ctx.Logger(), db: ctx.DB(), org: testOrganizationAccess{allowed: false}}
+	tus := &TUSServiceDefault{ctx: ctx, db: ctx.DB(), logger: ctx.Logger(), requests: requests}
+
+	if _, err := tus.CreateOrganizationUpload(context.Background(), nil, "upload", 1, 1, "127.0.0.1", testStorageProtocol{}, ""); err == nil {
+		t.Fatal("expected the upload to be denied")
+	} else if acctErr := core.AsAccountError(err); acctErr == nil || !acctErr.IsErrorType(core.ErrKeyOrganizationPermissionDenied) {
+		t.Fatalf("expected the upload to be denied, got %v", err)
+	}
+
+	var count int64
+	if err := ctx.DB().Model(&models.Request{}).Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("expected no request to be created, got %d, err %v", count, err)
+	}
+}
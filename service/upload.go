@@ -6,6 +6,8 @@ import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -21,8 +23,9 @@ func init() {
 }
 
 type UploadServiceDefault struct {
-	ctx core.Context
-	db  *gorm.DB
+	ctx    core.Context
+	db     *gorm.DB
+	logger *core.Logger
 }
 
 func NewMetadataService() (*UploadServiceDefault, []core.ContextBuilderOption, error) {
@@ -32,6 +35,10 @@ func NewMetadataService() (*UploadServiceDefault, []core.ContextBuilderOption, e
 		core.ContextWithStartupFunc(func(ctx core.Context) error {
 			meta.ctx = ctx
 			meta.db = ctx.DB()
+			meta.logger = ctx.ServiceLogger(meta)
+
+			event.Listen[*event.DownloadCompletedEvent](ctx, event.EVENT_DOWNLOAD_COMPLETED, meta.handleDownloadCompleted)
+
 			return nil
 		}),
 	)
@@ -83,11 +90,12 @@ func (m *UploadServiceDefault) SaveUpload(ctx context.Context, upload *models.Up
 	})
 }
 
+// GetUpload is on the path of every download, so it is served by a read replica
 func (m *UploadServiceDefault) GetUpload(ctx context.Context, objectHash core.StorageHash) (*models.Upload, error) {
 	var upload models.Upload
 	upload.Hash = objectHash.Multihash()
 
-	if err := db.RetryableTransaction(m.ctx, m.db, func(tx *gorm.DB) *gorm.DB {
+	if err := db.ReplicaRead(ctx, m.db, func(tx *gorm.DB) *gorm.DB {
 		return tx.Model(&upload).Where(&upload).First(&upload)
 	}); err != nil {
 		return nil, err
@@ -106,9 +114,17 @@ func (m *UploadServiceDefault) DeleteUpload(ctx context.Context, objectHash core
 		return err
 	}
 
-	return db.RetryableTransaction(m.ctx, m.db, func(tx *gorm.DB) *gorm.DB {
+	if err := db.RetryableTransaction(m.ctx, m.db, func(tx *gorm.DB) *gorm.DB {
 		return tx.Delete(&upload)
-	})
+	}); err != nil {
+		return err
+	}
+
+	if err := event.FireUploadDeletedEvent(m.ctx, &upload); err != nil {
+		m.logger.Error("Failed to fire upload deleted event", zap.Uint("upload_id", upload.ID), zap.Error(err))
+	}
+
+	return nil
 }
 
 func (m *UploadServiceDefault) GetAllUploads(ctx context.Context) ([]*models.Upload, error) {
@@ -137,3 +153,16 @@ func (m *UploadServiceDefault) GetUploadByID(ctx context.Context, uploadID uint)
 
 	return &upload, nil
 }
+
+// handleDownloadCompleted records the download against the protocol the upload was made with
+func (m *UploadServiceDefault) handleDownloadCompleted(evt *event.DownloadCompletedEvent) error {
+	upload, err := m.GetUploadByID(m.ctx, evt.UploadID())
+	if err != nil {
+		m.logger.Error("Failed to fetch upload for download metrics", zap.Uint("upload_id", evt.UploadID()), zap.Error(err))
+		return nil
+	}
+
+	m.ctx.Metrics().ObserveTransfer(core.METRICS_DIRECTION_DOWNLOAD, upload.Protocol, evt.Bytes(), evt.Duration())
+
+	return nil
+}
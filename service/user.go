@@ -9,11 +9,12 @@ import (
 	"go.lumeweb.com/portal/db"
 	"go.lumeweb.com/portal/db/models"
 	"go.lumeweb.com/portal/event"
+	"go.lumeweb.com/portal/service/internal/password"
 	"go.lumeweb.com/portal/service/internal/user"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -26,18 +27,23 @@ func init() {
 		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
 			return NewUserService()
 		},
-		Depends: []string{core.MAILER_SERVICE, core.CRON_SERVICE},
+		Depends: []string{core.MAILER_SERVICE, core.CRON_SERVICE, core.INVITE_SERVICE, core.OUTBOX_SERVICE},
 	})
 }
 
 type UserServiceDefault struct {
 	ctx       core.Context
+	logger    *core.Logger
 	config    config.Manager
 	db        *gorm.DB
 	mailer    core.MailerService
 	cron      core.CronService
 	subdomain string
 	access    core.AccessService
+	invite    core.InviteService
+
+	passwordPolicy *password.Policy
+	passwordHasher *password.Hasher
 }
 
 func NewUserService() (*UserServiceDefault, []core.ContextBuilderOption, error) {
@@ -46,11 +52,15 @@ func NewUserService() (*UserServiceDefault, []core.ContextBuilderOption, error)
 	opts := core.ContextOptions(
 		core.ContextWithStartupFunc(func(ctx core.Context) error {
 			_user.ctx = ctx
+			_user.logger = ctx.ServiceLogger(_user)
 			_user.config = ctx.Config()
 			_user.db = ctx.DB()
 			_user.mailer = core.GetService[core.MailerService](ctx, core.MAILER_SERVICE)
 			_user.cron = core.GetService[core.CronService](ctx, core.CRON_SERVICE)
 			_user.access = core.GetService[core.AccessService](ctx, core.ACCESS_SERVICE)
+			_user.invite = core.GetService[core.InviteService](ctx, core.INVITE_SERVICE)
+			_user.passwordPolicy = password.NewPolicy(ctx.Config().Config().Core.Account.PasswordPolicy)
+			_user.passwordHasher = password.NewHasher(ctx.Config().Config().Core.Account.PasswordHash)
 
 			_user.cron.RegisterEntity(_user)
 
@@ -67,6 +77,7 @@ func NewUserService() (*UserServiceDefault, []core.ContextBuilderOption, error)
 
 func (u UserServiceDefault) RegisterTasks(crn core.CronService) error {
 	crn.RegisterTask(user.CronTaskProcessAccountDeletionRequestsName, core.CronTaskFuncHandler(user.CronTaskProcessAccountDeletionRequests), core.CronTaskDefinitionDaily, core.CronTaskNoArgsFactory, true)
+	crn.SetTaskPriority(user.CronTaskProcessAccountDeletionRequestsName, core.CronTaskPriorityBackground)
 
 	return nil
 }
@@ -111,15 +122,103 @@ func (u UserServiceDefault) AccountExists(id uint) (bool, *models.User, error) {
 }
 
 func (u UserServiceDefault) HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return u.HashUserPassword(password, nil)
+}
+
+func (u UserServiceDefault) HashUserPassword(password string, user *models.User) (string, error) {
+	if err := u.ValidatePassword(password, user); err != nil {
+		return "", err
+	}
+
+	return u.hashPassword(password)
+}
+
+func (u UserServiceDefault) ValidatePassword(password string, user *models.User) error {
+	var identity []string
+	if user != nil {
+		identity = []string{user.Email, user.FirstName, user.LastName}
+	}
+
+	violations, err := u.passwordPolicy.Check(password, identity...)
+	if err != nil {
+		return core.NewAccountError(core.ErrKeyPasswordPolicyCheckFailed, err)
+	}
+
+	if len(violations) > 0 {
+		return core.NewPolicyError(core.ErrKeyPasswordPolicyViolation, violations)
+	}
+
+	return nil
+}
+
+func (u UserServiceDefault) hashPassword(password string) (string, error) {
+	hash, err := u.passwordHasher.Hash(password)
 	if err != nil {
 		return "", core.NewAccountError(core.ErrKeyHashingFailed, err)
 	}
-	return string(bytes), nil
+	return hash, nil
+}
+
+func (u UserServiceDefault) VerifyPassword(user *models.User, password string) bool {
+	return u.validPassword(user, password)
+}
+
+func (u UserServiceDefault) UpgradePasswordHash(user *models.User, password string) error {
+	if !u.passwordHasher.NeedsRehash(user.PasswordHash) {
+		return nil
+	}
+
+	// The policy is not checked again, an existing password must keep working after an upgrade
+	passwordHash, err := u.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := u.UpdateAccountInfo(user.ID, map[string]any{"password_hash": passwordHash}); err != nil {
+		return err
+	}
+
+	user.PasswordHash = passwordHash
+
+	return nil
 }
 
 func (u UserServiceDefault) CreateAccount(email string, password string, verifyEmail bool) (*models.User, error) {
-	passwordHash, err := u.HashPassword(password)
+	return u.CreateAccountWithInvite(email, password, "", verifyEmail)
+}
+
+func (u UserServiceDefault) CreateAccountWithInvite(email string, password string, inviteCode string, verifyEmail bool) (_ *models.User, err error) {
+	if err := u.ValidatePassword(password, &models.User{Email: email}); err != nil {
+		return nil, err
+	}
+
+	redeemed, err := u.checkRegistrationPolicy(email, inviteCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if redeemed {
+		defer func() {
+			if err != nil {
+				_ = u.invite.ReleaseInvite(inviteCode)
+			}
+		}()
+	}
+
+	return u.createAccount(email, password, verifyEmail)
+}
+
+func (u UserServiceDefault) CreateAccountUnrestricted(email string, password string, verifyEmail bool) (*models.User, error) {
+	if err := u.ValidatePassword(password, &models.User{Email: email}); err != nil {
+		return nil, err
+	}
+
+	return u.createAccount(email, password, verifyEmail)
+}
+
+// createAccount creates the account once the password and registration policies have been checked
+func (u UserServiceDefault) createAccount(email string, password string, verifyEmail bool) (*models.User, error) {
+	passwordHash, err := u.hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +236,25 @@ func (u UserServiceDefault) CreateAccount(email string, password string, verifyE
 			return tx
 		}
 		isFirstUser = count == 0
-		return tx.Create(&_user)
+		_user.Verified = isFirstUser
+
+		if err := tx.Create(&_user).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := event.FireUserCreatedEventTx(u.ctx, tx, &_user); err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if isFirstUser || !verifyEmail {
+			if err := event.FireUserActivatedEventTx(u.ctx, tx, &_user); err != nil {
+				_ = tx.AddError(err)
+			}
+		}
+
+		return tx
 	})
 
 	if err != nil {
@@ -153,11 +270,6 @@ func (u UserServiceDefault) CreateAccount(email string, password string, verifyE
 	}
 
 	if isFirstUser {
-		_user.Verified = true
-		if err := u.UpdateAccountInfo(_user.ID, map[string]interface{}{"verified": true}); err != nil {
-			return nil, err
-		}
-
 		if err := u.access.AssignRoleToUser(_user.ID, core.ACCESS_ADMIN_ROLE); err != nil {
 			return nil, core.NewAccountError(core.ErrKeyAssigningAdminRoleFailed, err)
 		}
@@ -171,17 +283,66 @@ func (u UserServiceDefault) CreateAccount(email string, password string, verifyE
 		return nil, core.NewAccountError(core.ErrorAssigningUserRoleFailed, err)
 	}
 
-	if err := event.FireUserCreatedEvent(u.ctx, &_user); err != nil {
-		return nil, err
+	return &_user, nil
+}
+
+// checkRegistrationPolicy enforces the registration mode and email domain lists, redeeming the invite code if one is required.
+// The first account is always allowed so a fresh portal can be bootstrapped.
+func (u UserServiceDefault) checkRegistrationPolicy(email string, inviteCode string) (bool, error) {
+	var count int64
+	if err := db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.User{}).Count(&count)
+	}); err != nil {
+		return false, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
 	}
 
-	if isFirstUser || !verifyEmail {
-		if err := event.FireUserActivatedEvent(u.ctx, &_user); err != nil {
-			return nil, err
+	if count == 0 {
+		return false, nil
+	}
+
+	cfg := u.config.Config().Core.Account
+
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at != -1 {
+		domain = strings.ToLower(email[at+1:])
+	}
+
+	if emailDomainListed(domain, cfg.DeniedEmailDomains) {
+		return false, core.NewAccountError(core.ErrKeyEmailDomainNotAllowed, nil)
+	}
+
+	if len(cfg.AllowedEmailDomains) > 0 && !emailDomainListed(domain, cfg.AllowedEmailDomains) {
+		return false, core.NewAccountError(core.ErrKeyEmailDomainNotAllowed, nil)
+	}
+
+	switch cfg.RegistrationMode {
+	case config.RegistrationModeClosed:
+		return false, core.NewAccountError(core.ErrKeyRegistrationClosed, nil)
+	case config.RegistrationModeInviteOnly:
+		if inviteCode == "" {
+			return false, core.NewAccountError(core.ErrKeyRegistrationInviteRequired, nil)
+		}
+
+		if err := u.invite.RedeemInvite(inviteCode); err != nil {
+			return false, err
 		}
+
+		return true, nil
 	}
 
-	return &_user, nil
+	return false, nil
+}
+
+// emailDomainListed checks if the domain, or a parent domain of it, is in the list
+func emailDomainListed(domain string, list []string) bool {
+	for _, entry := range list {
+		entry = strings.ToLower(strings.TrimPrefix(entry, "@"))
+		if domain == entry || strings.HasSuffix(domain, "."+entry) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (u UserServiceDefault) UpdateAccountName(userId uint, firstName string, lastName string) error {
@@ -210,13 +371,16 @@ func (u UserServiceDefault) UpdateAccountEmail(userId uint, email string, passwo
 		return core.NewAccountError(core.ErrKeyUpdatingSameEmail, nil)
 	}
 
-	return u.UpdateAccountInfo(userId, map[string]any{
-		"email": email,
+	oldEmail := user.Email
+
+	return u.updateAccountWithEvent(userId, map[string]any{"email": email}, func(tx *gorm.DB) error {
+		user.Email = email
+		return event.FireUserEmailChangedEventTx(u.ctx, tx, user, oldEmail)
 	})
 }
 
 func (u UserServiceDefault) UpdateAccountPassword(userId uint, password string, newPassword string) error {
-	valid, _, err := u.ValidLoginByUserID(userId, password)
+	valid, user, err := u.ValidLoginByUserID(userId, password)
 	if err != nil {
 		return err
 	}
@@ -225,13 +389,18 @@ func (u UserServiceDefault) UpdateAccountPassword(userId uint, password string,
 		return core.NewAccountError(core.ErrKeyInvalidPassword, nil)
 	}
 
-	passwordHash, err := u.HashPassword(newPassword)
+	if err := u.ValidatePassword(newPassword, user); err != nil {
+		return err
+	}
+
+	passwordHash, err := u.hashPassword(newPassword)
 	if err != nil {
 		return err
 	}
 
-	return u.UpdateAccountInfo(userId, map[string]any{
-		"password_hash": passwordHash,
+	return u.updateAccountWithEvent(userId, map[string]any{"password_hash": passwordHash}, func(tx *gorm.DB) error {
+		user.PasswordHash = passwordHash
+		return event.FireUserPasswordChangedEventTx(u.ctx, tx, user)
 	})
 }
 
@@ -267,9 +436,13 @@ func (u UserServiceDefault) ValidLoginByUserID(id uint, password string) (bool,
 }
 
 func (u UserServiceDefault) validPassword(user *models.User, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if user.PasswordHash == "" {
+		return false
+	}
 
-	return err == nil
+	valid, err := u.passwordHasher.Verify(password, user.PasswordHash)
+
+	return err == nil && valid
 }
 
 func (u UserServiceDefault) UpdateAccountInfo(userId uint, info map[string]any) error {
@@ -285,6 +458,29 @@ func (u UserServiceDefault) UpdateAccountInfo(userId uint, info map[string]any)
 	return nil
 }
 
+// updateAccountWithEvent updates the account and queues the event describing the change in the same transaction
+func (u UserServiceDefault) updateAccountWithEvent(userId uint, info map[string]any, fire func(tx *gorm.DB) error) error {
+	var user models.User
+	user.ID = userId
+
+	if err := db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Model(&user).Where(&user).Updates(info).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := fire(tx); err != nil {
+			_ = tx.AddError(err)
+		}
+
+		return tx
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
 func (u UserServiceDefault) ValidLoginByUserObj(user *models.User, password string) bool {
 	return u.validPassword(user, password)
 }
@@ -421,45 +617,53 @@ func (u UserServiceDefault) VerifyEmail(email string, token string) error {
 		return core.NewAccountError(core.ErrKeySecurityInvalidToken, nil)
 	}
 
+	user := verification.User
+	activated := !user.Verified
 	updateFields := make(map[string]interface{})
 
-	if !verification.User.Verified {
+	if activated {
 		updateFields["verified"] = true
+		user.Verified = true
 	}
 
 	if len(verification.NewEmail) > 0 {
 		updateFields["email"] = verification.NewEmail
+		user.Email = verification.NewEmail
 	}
 
-	if len(updateFields) > 0 {
-		err := u.UpdateAccountInfo(verification.UserID, updateFields)
-		if err != nil {
-			return err
+	// The account is only reported activated once the verification is committed
+	if err := db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
+		if len(updateFields) > 0 {
+			if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(updateFields).Error; err != nil {
+				_ = tx.AddError(err)
+				return tx
+			}
 		}
-	}
 
-	verification = models.EmailVerification{
-		UserID: verification.UserID,
-	}
+		if err := tx.Where(&models.EmailVerification{UserID: user.ID}).Delete(&models.EmailVerification{}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
 
-	if err := db.RetryOnLock(u.db, func(db *gorm.DB) *gorm.DB {
-		return db.Where(&verification).Delete(&verification)
+		if activated {
+			if err := event.FireUserActivatedEventTx(u.ctx, tx, &user); err != nil {
+				_ = tx.AddError(err)
+			}
+		}
+
+		return tx
 	}); err != nil {
 		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
 	}
 
-	err := event.FireUserActivatedEvent(u.ctx, &verification.User)
-	if err != nil {
-		return err
-	}
-
 	return nil
 }
 
 func (u *UserServiceDefault) DeleteAccount(userId uint) error {
-	return db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
+	var _user models.User
+
+	if err := db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
 		// First, check if the user exists
-		var _user models.User
 		if err := tx.First(&_user, userId).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				_ = tx.AddError(errors.New("user not found"))
@@ -481,8 +685,16 @@ func (u *UserServiceDefault) DeleteAccount(userId uint) error {
 			return tx
 		}
 
+		if err := event.FireUserDeletionExecutedEventTx(u.ctx, tx, &_user); err != nil {
+			_ = tx.AddError(err)
+		}
+
 		return tx
-	})
+	}); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (u *UserServiceDefault) IsAccountPendingDeletion(userId uint) (bool, error) {
@@ -496,8 +708,9 @@ func (u *UserServiceDefault) IsAccountPendingDeletion(userId uint) (bool, error)
 }
 
 func (u *UserServiceDefault) RequestAccountDeletion(userId uint, userIP string) error {
-	return db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
-		var user models.User
+	var user models.User
+
+	if err := db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
 		if err := tx.First(&user, userId).Error; err != nil {
 			_ = tx.AddError(err)
 			return tx
@@ -518,14 +731,150 @@ func (u *UserServiceDefault) RequestAccountDeletion(userId uint, userIP string)
 			deletion.UserID = userId
 			deletion.IP = userIP
 
-			return tx.Create(&deletion)
+			if err := tx.Create(&deletion).Error; err != nil {
+				_ = tx.AddError(err)
+				return tx
+			}
+
+			if err := event.FireUserDeletionRequestedEventTx(u.ctx, tx, &user, userIP); err != nil {
+				_ = tx.AddError(err)
+			}
+
+			return tx
 		}
 
 		_ = tx.AddError(core.NewAccountError(core.ErrKeyAccountDeletionRequestAlreadyExists, nil))
 		return tx
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *UserServiceDefault) CancelAccountDeletion(userId uint) error {
+	pending, err := u.IsAccountPendingDeletion(userId)
+	if err != nil {
+		return err
+	}
+
+	if !pending {
+		return core.NewAccountError(core.ErrKeyAccountDeletionRequestNotFound, nil)
+	}
+
+	return db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(&models.AccountDeletion{UserID: userId}).Delete(&models.AccountDeletion{})
 	})
 }
 
+func (u *UserServiceDefault) SuspendAccount(userId uint, actorId uint, reason string, expiresAt *time.Time, freezePins bool) (*models.AccountSuspension, error) {
+	exists, _, err := u.AccountExists(userId)
+	if err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if !exists {
+		return nil, core.NewAccountError(core.ErrKeyUserNotFound, nil)
+	}
+
+	suspension := models.AccountSuspension{
+		UserID:        userId,
+		Reason:        reason,
+		SuspendedByID: actorId,
+		ExpiresAt:     expiresAt,
+		FreezePins:    freezePins,
+	}
+
+	now := time.Now()
+
+	// A new suspension replaces any active one
+	if err := db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Model(&models.AccountSuspension{}).
+			Where("user_id = ? AND lifted_at IS NULL", userId).
+			Updates(map[string]any{"lifted_at": &now, "lifted_by_id": actorId}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := tx.Create(&suspension).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := event.FireUserSuspendedEventTx(u.ctx, tx, &suspension); err != nil {
+			_ = tx.AddError(err)
+		}
+
+		return tx
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return &suspension, nil
+}
+
+func (u *UserServiceDefault) LiftAccountSuspension(userId uint, actorId uint) error {
+	suspension, err := u.GetAccountSuspension(userId)
+	if err != nil {
+		return err
+	}
+
+	if suspension == nil {
+		return core.NewAccountError(core.ErrKeyAccountNotSuspended, nil)
+	}
+
+	now := time.Now()
+	suspension.LiftedAt = &now
+	suspension.LiftedByID = &actorId
+
+	if err := db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Model(suspension).Updates(map[string]any{"lifted_at": &now, "lifted_by_id": actorId}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if err := event.FireUserUnsuspendedEventTx(u.ctx, tx, suspension); err != nil {
+			_ = tx.AddError(err)
+		}
+
+		return tx
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
+func (u *UserServiceDefault) GetAccountSuspension(userId uint) (*models.AccountSuspension, error) {
+	var suspensions []*models.AccountSuspension
+
+	if err := db.RetryableTransaction(u.ctx, u.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.AccountSuspension{}).
+			Where("user_id = ? AND lifted_at IS NULL", userId).
+			Order("created_at DESC").
+			Find(&suspensions)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	for _, suspension := range suspensions {
+		if suspension.Active() {
+			return suspension, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (u *UserServiceDefault) IsAccountSuspended(userId uint) (bool, error) {
+	suspension, err := u.GetAccountSuspension(userId)
+	if err != nil {
+		return false, err
+	}
+
+	return suspension != nil, nil
+}
+
 func (u *UserServiceDefault) GetAccountsPendingDeletion() ([]*models.User, error) {
 	var users []*models.User
 	gracePeriod := time.Duration(u.config.Config().Core.Account.DeletionGracePeriod) * time.Hour
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
+	"go.lumeweb.com/portal/service/internal/password"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testArgon2 keeps the argon2id parameters small so the tests hash quickly
+var testArgon2 = config.Argon2Config{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newTestUserService(t *testing.T) *UserServiceDefault {
+	t.Helper()
+
+	outbox := &OutboxServiceDefault{nodeId: "node-a"}
+	ctx := newTestContext(t, &config.Config{}, []any{&models.User{}, &models.EmailVerification{}, &models.OutboxEvent{}},
+		core.ContextWithService(core.OUTBOX_SERVICE, outbox),
+		core.ContextWithEvents(core.GetEvents()...),
+	)
+
+	outbox.ctx = ctx
+	outbox.logger = ctx.Logger()
+	outbox.config = ctx.Config()
+	outbox.db = ctx.DB()
+
+	return &UserServiceDefault{ctx: ctx, logger: ctx.Logger(), config: ctx.Config(), db: ctx.DB()}
+}
+
+// activatedUsers returns the IDs of the users in the queued user.activated events
+func activatedUsers(t *testing.T, users *UserServiceDefault) []uint {
+	t.Helper()
+
+	var queued []*models.OutboxEvent
+	if err := users.db.Where(&models.OutboxEvent{Name: event.EVENT_USER_ACTIVATED}).Find(&queued).Error; err != nil {
+		t.Fatalf("failed to load the outbox: %v", err)
+	}
+
+	ids := make([]uint, 0, len(queued))
+	for _, evt := range queued {
+		data, err := core.DecodeEventData(evt.Data)
+		if err != nil {
+			t.Fatalf("failed to decode the event: %v", err)
+		}
+		ids = append(ids, data["user"].(*models.User).ID)
+	}
+
+	return ids
+}
+
+func TestVerifyEmailQueuesActivation(t *testing.T) {
+	users := newTestUserService(t)
+
+	user := &models.User{Email: "user@example.com"}
+	if err := users.db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create the user: %v", err)
+	}
+
+	verification := &models.EmailVerification{UserID: user.ID, Token: "token", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := users.db.Create(verification).Error; err != nil {
+		t.Fatalf("failed to create the verification: %v", err)
+	}
+
+	if err := users.VerifyEmail(user.Email, "token"); err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+
+	var stored models.User
+	if err := users.db.First(&stored, user.ID).Error; err != nil || !stored.Verified {
+		t.Fatalf("expected the user to be verified, got %+v, err %v", stored, err)
+	}
+
+	if ids := activatedUsers(t, users); len(ids) != 1 || ids[0] != user.ID {
+		t.Fatalf("expected one activation of user %d to be queued, got %v", user.ID, ids)
+	}
+}
+
+func TestAdminVerifyUserQueuesActivation(t *testing.T) {
+	users := newTestUserService(t)
+	admin := &AdminServiceDefault{ctx: users.ctx, logger: users.logger, db: users.db, user: users}
+
+	user := &models.User{Email: "user@example.com"}
+	if err := users.db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create the user: %v", err)
+	}
+
+	if err := admin.VerifyUser(user.ID); err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+
+	if ids := activatedUsers(t, users); len(ids) != 1 || ids[0] != user.ID {
+		t.Fatalf("expected one activation of user %d to be queued, got %v", user.ID, ids)
+	}
+}
+
+func TestPasswordHasherVerifiesSupportedAlgorithms(t *testing.T) {
+	argon := password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2: testArgon2})
+	bcr := password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashBcrypt, BcryptCost: 4})
+
+	for name, hasher := range map[string]*password.Hasher{"argon2id": argon, "bcrypt": bcr} {
+		hash, err := hasher.Hash("correct horse")
+		if err != nil {
+			t.Fatalf("%s: failed to hash: %v", name, err)
+		}
+
+		// Either hasher verifies hashes of both algorithms, so switching the configured one keeps old hashes working
+		for _, verifier := range []*password.Hasher{argon, bcr} {
+			if valid, err := verifier.Verify("correct horse", hash); err != nil || !valid {
+				t.Fatalf("%s: expected the password to verify, got %v, err %v", name, valid, err)
+			}
+
+			if valid, err := verifier.Verify("wrong horse", hash); err != nil || valid {
+				t.Fatalf("%s: expected a wrong password to fail, got %v, err %v", name, valid, err)
+			}
+		}
+	}
+}
+
+func TestPasswordHasherRejectsMalformedHashes(t *testing.T) {
+	hasher := password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2: testArgon2})
+
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+	} {
+		if valid, err := hasher.Verify("", hash); err == nil || valid {
+			t.Fatalf("expected %q to be rejected, got %v, err %v", hash, valid, err)
+		}
+
+		if !hasher.NeedsRehash(hash) {
+			t.Fatalf("expected %q to need a rehash", hash)
+		}
+	}
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	hasher := password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2: testArgon2})
+
+	current, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	if hasher.NeedsRehash(current) {
+		t.Fatal("expected a hash with the configured parameters to be kept")
+	}
+
+	stronger := testArgon2
+	stronger.Iterations = 2
+	outdated := password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2: stronger})
+	if !outdated.NeedsRehash(current) {
+		t.Fatal("expected a hash with other parameters to need a rehash")
+	}
+
+	bcr := password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashBcrypt, BcryptCost: 4})
+	if !bcr.NeedsRehash(current) {
+		t.Fatal("expected a hash of another algorithm to need a rehash")
+	}
+}
+
+func TestUpgradePasswordHash(t *testing.T) {
+	users := newTestUserService(t)
+	users.passwordHasher = password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2: testArgon2})
+
+	legacy, err := password.NewHasher(config.PasswordHashConfig{Algorithm: config.PasswordHashBcrypt, BcryptCost: 4}).Hash("correct horse")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	user := &models.User{Email: "user@example.com", PasswordHash: legacy}
+	if err := users.db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create the user: %v", err)
+	}
+
+	if err := users.UpgradePasswordHash(user, "correct horse"); err != nil {
+		t.Fatalf("failed to upgrade the hash: %v", err)
+	}
+
+	var stored models.User
+	if err := users.db.First(&stored, user.ID).Error; err != nil {
+		t.Fatalf("failed to load the user: %v", err)
+	}
+
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$") || stored.PasswordHash != user.PasswordHash {
+		t.Fatalf("expected the stored hash to be upgraded to argon2id, got %q", stored.PasswordHash)
+	}
+
+	if !users.VerifyPassword(&stored, "correct horse") {
+		t.Fatal("expected the password to verify against the upgraded hash")
+	}
+
+	// A current hash is left alone
+	if err := users.UpgradePasswordHash(&stored, "correct horse"); err != nil {
+		t.Fatalf("failed to upgrade the hash: %v", err)
+	}
+
+	var again models.User
+	if err := users.db.First(&again, user.ID).Error; err != nil || again.PasswordHash != stored.PasswordHash {
+		t.Fatalf("expected the current hash to be kept, got %q, err %v", again.PasswordHash, err)
+	}
+}
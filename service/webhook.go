@@ -0,0 +1,587 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	gevent "github.com/gookit/event"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.lumeweb.com/portal/db/models"
+	"go.lumeweb.com/portal/event"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var _ core.WebhookService = (*WebhookServiceDefault)(nil)
+var _ core.Cronable = (*WebhookServiceDefault)(nil)
+
+const (
+	cronTaskDeliverWebhookName = "DeliverWebhook"
+
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookIDHeader        = "X-Webhook-ID"
+
+	// webhookResponseLogLimit caps how much of a response body is kept in the delivery log
+	webhookResponseLogLimit = 1024
+
+	// Failed deliveries are retried after 3 minutes, doubling up to 6 hours, so an endpoint has hours to recover
+	// before the last attempt
+	webhookRetryBaseDelay = time.Minute
+	webhookRetryMaxDelay  = 6 * time.Hour
+)
+
+var errWebhookPrivateAddress = errors.New("webhook target resolves to a private address")
+
+func init() {
+	core.RegisterService(core.ServiceInfo{
+		ID: core.WEBHOOK_SERVICE,
+		Factory: func() (core.Service, []core.ContextBuilderOption, error) {
+			return NewWebhookService()
+		},
+		Depends: []string{core.CRON_SERVICE},
+	})
+}
+
+type WebhookServiceDefault struct {
+	ctx    core.Context
+	logger *core.Logger
+	config config.Manager
+	db     *gorm.DB
+	cron   core.CronService
+	client *http.Client
+}
+
+type webhookDeliveryArgs struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+type webhookEnvelope struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+func NewWebhookService() (*WebhookServiceDefault, []core.ContextBuilderOption, error) {
+	webhook := &WebhookServiceDefault{}
+
+	opts := core.ContextOptions(
+		core.ContextWithStartupFunc(func(ctx core.Context) error {
+			webhook.ctx = ctx
+			webhook.logger = ctx.ServiceLogger(webhook)
+			webhook.config = ctx.Config()
+			webhook.db = ctx.DB()
+			webhook.cron = core.GetService[core.CronService](ctx, core.CRON_SERVICE)
+			webhook.client = newWebhookClient(ctx.Config().Config().Core.Webhooks)
+
+			webhook.cron.RegisterEntity(webhook)
+
+			for _, name := range core.GetWebhookEvents() {
+				ctx.Event().On(name, gevent.ListenerFunc(webhook.handleEvent))
+			}
+
+			return nil
+		}),
+	)
+
+	return webhook, opts, nil
+}
+
+func newWebhookClient(cfg config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: time.Duration(cfg.Timeout) * time.Second,
+	}
+
+	// The check runs on the resolved address so DNS cannot be used to reach internal services
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+				return errWebhookPrivateAddress
+			}
+
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (w WebhookServiceDefault) ID() string {
+	return core.WEBHOOK_SERVICE
+}
+
+func (w WebhookServiceDefault) RegisterTasks(crn core.CronService) error {
+	crn.RegisterTask(cronTaskDeliverWebhookName, core.CronTaskFuncHandler(w.cronTaskDeliverWebhook), core.CronTaskDefinitionOneTimeJob, webhookDeliveryArgsFactory, false)
+	crn.SetTaskBackoff(cronTaskDeliverWebhookName, core.CronTaskBackoff{Base: webhookRetryBaseDelay, Max: webhookRetryMaxDelay})
+
+	return nil
+}
+
+func (w WebhookServiceDefault) ScheduleJobs(_ core.CronService) error {
+	return nil
+}
+
+func webhookDeliveryArgsFactory() any {
+	return &webhookDeliveryArgs{}
+}
+
+func (w WebhookServiceDefault) CreateEndpoint(userId *uint, endpointUrl string, events []string, description string) (*models.WebhookEndpoint, error) {
+	if err := validateWebhookEndpoint(endpointUrl, events); err != nil {
+		return nil, err
+	}
+
+	if userId != nil {
+		var count int64
+		if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+			return tx.Model(&models.WebhookEndpoint{}).Where("user_id = ?", *userId).Count(&count)
+		}); err != nil {
+			return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+		}
+
+		if limit := w.config.Config().Core.Webhooks.MaxEndpointsPerUser; limit > 0 && uint(count) >= limit {
+			return nil, core.NewAccountError(core.ErrKeyWebhookLimitReached, nil)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	endpoint := models.WebhookEndpoint{
+		UserID:      userId,
+		URL:         endpointUrl,
+		Secret:      hex.EncodeToString(secret),
+		Events:      strings.Join(events, ","),
+		Description: description,
+		Enabled:     true,
+	}
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&endpoint)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return &endpoint, nil
+}
+
+func (w WebhookServiceDefault) UpdateEndpoint(id uint, endpointUrl string, events []string, description string, enabled bool) (*models.WebhookEndpoint, error) {
+	if err := validateWebhookEndpoint(endpointUrl, events); err != nil {
+		return nil, err
+	}
+
+	endpoint, err := w.GetEndpoint(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(endpoint).Updates(map[string]any{
+			"url":         endpointUrl,
+			"events":      strings.Join(events, ","),
+			"description": description,
+			"enabled":     enabled,
+		})
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return w.GetEndpoint(id)
+}
+
+func (w WebhookServiceDefault) GetEndpoint(id uint) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.WebhookEndpoint{}).First(&endpoint, id)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.NewAccountError(core.ErrKeyWebhookNotFound, err)
+		}
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return &endpoint, nil
+}
+
+func (w WebhookServiceDefault) ListEndpoints(userId *uint) ([]*models.WebhookEndpoint, error) {
+	var endpoints []*models.WebhookEndpoint
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.WebhookEndpoint{})
+
+		if userId == nil {
+			tx = tx.Where("user_id IS NULL")
+		} else {
+			tx = tx.Where("user_id = ?", *userId)
+		}
+
+		return tx.Order("id ASC").Find(&endpoints)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return endpoints, nil
+}
+
+func (w WebhookServiceDefault) DeleteEndpoint(id uint) error {
+	endpoint, err := w.GetEndpoint(id)
+	if err != nil {
+		return err
+	}
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Where("endpoint_id = ? AND status = ?", endpoint.ID, models.WebhookDeliveryStatusPending).
+			Delete(&models.WebhookDelivery{}).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		return tx.Delete(endpoint)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
+func (w WebhookServiceDefault) ListDeliveries(endpointId uint, limit int, offset int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.WebhookDelivery{}).
+			Preload("Log").
+			Where(&models.WebhookDelivery{EndpointID: endpointId})
+
+		if limit > 0 {
+			tx = tx.Limit(limit)
+		}
+
+		if offset > 0 {
+			tx = tx.Offset(offset)
+		}
+
+		return tx.Order("id DESC").Find(&deliveries)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return deliveries, nil
+}
+
+func (w WebhookServiceDefault) Ping(endpointId uint) (*models.WebhookDelivery, error) {
+	endpoint, err := w.GetEndpoint(endpointId)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := w.createDelivery(endpoint, uuid.NewString(), core.WEBHOOK_EVENT_PING, map[string]any{"endpoint_id": endpoint.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	// Pings are attempted once so the result can be shown right away
+	if err := w.attempt(endpoint, delivery, true); err != nil {
+		w.logger.Debug("Webhook ping failed", zap.Uint("endpoint_id", endpoint.ID), zap.Error(err))
+	}
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.WebhookDelivery{}).Preload("Log").First(delivery, delivery.ID)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return delivery, nil
+}
+
+func (w WebhookServiceDefault) Dispatch(eventName string, userId uint, payload any) error {
+	return w.dispatch(eventName, uuid.NewString(), userId, payload)
+}
+
+// dispatch creates a delivery of the event for every subscribed endpoint, all of them sharing eventId as the ID of
+// the envelope
+func (w WebhookServiceDefault) dispatch(eventName string, eventId string, userId uint, payload any) error {
+	var endpoints []*models.WebhookEndpoint
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.WebhookEndpoint{}).Where("enabled = ?", true)
+
+		// Portal wide events only reach admin-level endpoints
+		if userId == 0 {
+			tx = tx.Where("user_id IS NULL")
+		} else {
+			tx = tx.Where("(user_id IS NULL OR user_id = ?)", userId)
+		}
+
+		return tx.Find(&endpoints)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	for _, endpoint := range endpoints {
+		if !endpoint.Subscribed(eventName) {
+			continue
+		}
+
+		delivery, err := w.createDelivery(endpoint, eventId, eventName, payload)
+		if err != nil {
+			return err
+		}
+
+		if err := w.cron.CreateJob(cronTaskDeliverWebhookName, &webhookDeliveryArgs{DeliveryID: delivery.ID}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w WebhookServiceDefault) Deliver(deliveryId uint) error {
+	var delivery models.WebhookDelivery
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.WebhookDelivery{}).Preload("Endpoint").First(&delivery, deliveryId)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The endpoint was deleted along with its pending deliveries
+			return nil
+		}
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if delivery.Status != models.WebhookDeliveryStatusPending {
+		return nil
+	}
+
+	if !delivery.Endpoint.Enabled {
+		return w.updateDelivery(&delivery, map[string]any{"status": models.WebhookDeliveryStatusFailed})
+	}
+
+	finalAttempt := delivery.Attempts+1 >= w.config.Config().Core.Webhooks.MaxAttempts
+
+	return w.attempt(&delivery.Endpoint, &delivery, finalAttempt)
+}
+
+func (w WebhookServiceDefault) handleEvent(evt gevent.Event) error {
+	payloadFunc, ok := core.GetWebhookEvent(evt.Name())
+	if !ok {
+		return nil
+	}
+
+	// A failed delivery must not be reported through webhooks, each report would be a delivery that can fail in turn
+	if failed, ok := evt.(*event.CronJobFailedEvent); ok && failed.Job() != nil && failed.Job().Function == cronTaskDeliverWebhookName {
+		return nil
+	}
+
+	userId, payload, err := payloadFunc(evt)
+	if err != nil {
+		w.logger.Error("Failed to build webhook payload", zap.String("event", evt.Name()), zap.Error(err))
+		return nil
+	}
+
+	if payload == nil {
+		return nil
+	}
+
+	// Events from the outbox keep their ID across redeliveries and replays, so receivers can discard duplicates
+	eventId := event.OutboxEventID(evt)
+	if eventId == "" {
+		eventId = uuid.NewString()
+	}
+
+	// Webhook failures must never break the code path that fired the event
+	if err := w.dispatch(evt.Name(), eventId, userId, payload); err != nil {
+		w.logger.Error("Failed to dispatch webhook", zap.String("event", evt.Name()), zap.Error(err))
+	}
+
+	return nil
+}
+
+func (w WebhookServiceDefault) createDelivery(endpoint *models.WebhookEndpoint, eventId string, eventName string, payload any) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        eventId,
+		Event:     eventName,
+		CreatedAt: time.Now().UTC(),
+		Data:      payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.WebhookDelivery{
+		EndpointID: endpoint.ID,
+		EventID:    eventId,
+		Event:      eventName,
+		Payload:    body,
+		Status:     models.WebhookDeliveryStatusPending,
+	}
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&delivery)
+	}); err != nil {
+		return nil, core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return &delivery, nil
+}
+
+// attempt sends the delivery once and logs the result. A failed attempt returns an error so the cron service
+// retries it with backoff, unless it was the final attempt.
+func (w WebhookServiceDefault) attempt(endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery, final bool) error {
+	start := time.Now()
+	statusCode, response, sendErr := w.send(endpoint, delivery)
+
+	logEntry := models.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		StatusCode: statusCode,
+		Response:   response,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+
+	if sendErr == nil && (statusCode < 200 || statusCode > 299) {
+		sendErr = fmt.Errorf("endpoint responded with status %d", statusCode)
+	}
+
+	if sendErr != nil {
+		logEntry.Error = sendErr.Error()
+	}
+
+	updates := map[string]any{"attempts": delivery.Attempts + 1}
+
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		updates["status"] = models.WebhookDeliveryStatusDelivered
+		updates["delivered_at"] = &now
+	case final:
+		updates["status"] = models.WebhookDeliveryStatusFailed
+	}
+
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		if err := tx.Create(&logEntry).Error; err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		return tx.Model(delivery).Updates(updates)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	if sendErr != nil && !final {
+		return core.NewAccountError(core.ErrKeyWebhookDeliveryFailed, sendErr)
+	}
+
+	return nil
+}
+
+func (w WebhookServiceDefault) send(endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("%s Webhooks", w.config.Config().Core.PortalName))
+	req.Header.Set(webhookEventHeader, delivery.Event)
+	req.Header.Set(webhookIDHeader, delivery.EventID)
+	req.Header.Set(webhookSignatureHeader, fmt.Sprintf("t=%s,v1=%s", timestamp, signWebhookPayload(endpoint.Secret, timestamp, delivery.Payload)))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	response, err := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLogLimit))
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+
+	return resp.StatusCode, string(response), nil
+}
+
+func (w WebhookServiceDefault) updateDelivery(delivery *models.WebhookDelivery, updates map[string]any) error {
+	if err := db.RetryableTransaction(w.ctx, w.db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(delivery).Updates(updates)
+	}); err != nil {
+		return core.NewAccountError(core.ErrKeyDatabaseOperationFailed, err)
+	}
+
+	return nil
+}
+
+func (w WebhookServiceDefault) cronTaskDeliverWebhook(args *webhookDeliveryArgs, _ core.Context) error {
+	return w.Deliver(args.DeliveryID)
+}
+
+// signWebhookPayload signs the timestamp along with the body so a captured request cannot be replayed later
+func signWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validateWebhookEndpoint(endpointUrl string, events []string) error {
+	parsed, err := url.Parse(endpointUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return core.NewAccountError(core.ErrKeyWebhookInvalidURL, err)
+	}
+
+	if len(events) == 0 {
+		return core.NewAccountError(core.ErrKeyWebhookInvalidEvent, nil)
+	}
+
+	for _, name := range events {
+		if name == core.WEBHOOK_EVENT_ALL {
+			continue
+		}
+
+		if _, ok := core.GetWebhookEvent(name); !ok {
+			return core.NewAccountError(core.ErrKeyWebhookInvalidEvent, nil, fmt.Sprintf("Unknown webhook event: %s.", name))
+		}
+	}
+
+	return nil
+}
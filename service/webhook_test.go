@@ -0,0 +1,106 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/db/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifyWebhookSignature checks a signature header the way a receiver would, from the secret and the raw body only
+func verifyWebhookSignature(header string, secret string, body []byte) (time.Time, bool) {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return time.Unix(unix, 0), hmac.Equal(mac.Sum(nil), expected)
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"pin.created"}`)
+
+	// HMAC-SHA256 of "<timestamp>.<body>", so receivers can check it with any standard library
+	if signature := signWebhookPayload("whsec_test", "1700000000", body); signature != "15ff0aacc0f1a1c50d89b2f12169eedb88224b0a08ee9b91411d0603b8f3bc51" {
+		t.Fatalf("unexpected signature %s", signature)
+	}
+
+	header := "t=1700000000,v1=" + signWebhookPayload("whsec_test", "1700000000", body)
+
+	if _, ok := verifyWebhookSignature(header, "whsec_test", body); !ok {
+		t.Fatal("expected the signature to verify")
+	}
+
+	if _, ok := verifyWebhookSignature(header, "whsec_other", body); ok {
+		t.Fatal("expected another secret to fail")
+	}
+
+	if _, ok := verifyWebhookSignature(header, "whsec_test", []byte(`{"event":"pin.deleted"}`)); ok {
+		t.Fatal("expected a tampered body to fail")
+	}
+
+	// The timestamp is signed, moving it to replay the request breaks the signature
+	replayed := "t=1800000000,v1=" + signWebhookPayload("whsec_test", "1700000000", body)
+	if _, ok := verifyWebhookSignature(replayed, "whsec_test", body); ok {
+		t.Fatal("expected a changed timestamp to fail")
+	}
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	var header string
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(webhookSignatureHeader)
+		received, _ = io.ReadAll(r.Body)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{Core: config.CoreConfig{Webhooks: config.WebhookConfig{Timeout: 5, AllowPrivateNetworks: true}}}
+	ctx := newTestContext(t, cfg, nil)
+	webhooks := &WebhookServiceDefault{ctx: ctx, logger: ctx.Logger(), config: ctx.Config(), client: newWebhookClient(cfg.Core.Webhooks)}
+
+	endpoint := &models.WebhookEndpoint{URL: server.URL, Secret: "whsec_test"}
+	delivery := &models.WebhookDelivery{Event: "pin.created", EventID: "event", Payload: []byte(`{"event":"pin.created"}`)}
+
+	status, _, err := webhooks.send(endpoint, delivery)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("expected the delivery to succeed, got %d, err %v", status, err)
+	}
+
+	signedAt, ok := verifyWebhookSignature(header, endpoint.Secret, received)
+	if !ok {
+		t.Fatalf("expected the delivered signature %q to verify", header)
+	}
+
+	if age := time.Since(signedAt); age < -time.Minute || age > time.Minute {
+		t.Fatalf("expected the signature to carry the current time, got %s", signedAt)
+	}
+}